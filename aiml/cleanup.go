@@ -0,0 +1,216 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package aiml
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Suggestion is a cleanup of the entries of a set or a map
+type Suggestion struct {
+	File    string   `json:"file"`
+	Kind    string   `json:"kind"` // duplicate, case, typo or punctuation
+	Entries []string `json:"entries"`
+	Message string   `json:"message"`
+}
+
+// listEntry is an entry of a set or the key of a map entry
+type listEntry struct {
+	text  string // As written
+	upper string // Upper cased with the whitespace collapsed, as matched
+	value string // The value of a map entry
+}
+
+// CleanupList analyzes the entries of a set or the keys of a map and
+// suggests cleanups keeping large lists maintainable: the duplicates, the
+// entries only differing by case, which the matching does not distinguish,
+// the entries with punctuation, which the normalized inputs never contain,
+// and the entries within one or two letters of each other, which may be
+// typos. The type of the file is determined by the extension of name.
+func CleanupList(name string, data []byte) ([]Suggestion, error) {
+	var raw [][]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("File is not a JSON list [%s] - %v", name, err)
+	}
+	isMap := filepath.Ext(name) != ".set"
+	var entries []listEntry
+	for _, r := range raw {
+		if len(r) == 0 {
+			continue
+		}
+		e := listEntry{text: strings.Join(r, " ")}
+		if isMap {
+			e.text = r[0]
+			if len(r) > 1 {
+				e.value = r[1]
+			}
+		}
+		e.upper = strings.ToUpper(collapse(e.text))
+		entries = append(entries, e)
+	}
+	var res []Suggestion
+	add := func(kind string, texts []string, format string, args ...interface{}) {
+		res = append(res, Suggestion{File: name, Kind: kind, Entries: texts, Message: fmt.Sprintf(format, args...)})
+	}
+	byUpper := make(map[string][]listEntry)
+	var distinct []string
+	for _, e := range entries {
+		if byUpper[e.upper] == nil {
+			distinct = append(distinct, e.upper)
+		}
+		byUpper[e.upper] = append(byUpper[e.upper], e)
+		if strings.IndexFunc(e.text, func(r rune) bool { return unicode.IsPunct(r) && r != '\'' }) >= 0 {
+			add("punctuation", []string{e.text}, "entry [%s] has punctuation, which the normalized inputs never contain", e.text)
+		}
+	}
+	for _, upper := range distinct {
+		same := byUpper[upper]
+		if len(same) < 2 {
+			continue
+		}
+		texts := make([]string, len(same))
+		spellings := make(map[string]bool)
+		values := make(map[string]bool)
+		for i, e := range same {
+			texts[i] = e.text
+			spellings[e.text] = true
+			values[e.value] = true
+		}
+		switch {
+		case isMap && len(values) > 1:
+			add("duplicate", texts, "key [%s] is defined %d times with different values, only one applies", same[0].text, len(same))
+		case len(spellings) == 1:
+			add("duplicate", texts, "entry [%s] is defined %d times", same[0].text, len(same))
+		default:
+			add("case", texts, "entries only differ by case, which the matching ignores - keep one")
+		}
+	}
+	for _, cluster := range typoClusters(distinct) {
+		texts := make([]string, len(cluster))
+		for i, upper := range cluster {
+			texts[i] = byUpper[upper][0].text
+		}
+		add("typo", texts, "entries only differ by a letter or two, some may be misspelled")
+	}
+	return res, nil
+}
+
+// CleanupFiles analyzes the sets and maps in paths, ignoring the other files
+func CleanupFiles(paths []string) ([]Suggestion, error) {
+	res := []Suggestion{}
+	for _, path := range paths {
+		if ext := filepath.Ext(path); ext != ".set" && ext != ".map" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		s, err := CleanupList(path, data)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, s...)
+	}
+	return res, nil
+}
+
+// maxTypos is the edit distance under which two entries may be the same word misspelled
+func maxTypos(s string) int {
+	switch n := utf8.RuneCountInString(s); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	}
+	return 2
+}
+
+// typoClusters groups the entries within maxTypos edits of each other. The
+// short entries and the ones with digits, like years, are not grouped.
+func typoClusters(entries []string) [][]string {
+	var words []string
+	for _, e := range entries {
+		if maxTypos(e) > 0 && strings.IndexFunc(e, unicode.IsDigit) < 0 {
+			words = append(words, e)
+		}
+	}
+	sort.SliceStable(words, func(i, j int) bool {
+		return utf8.RuneCountInString(words[i]) < utf8.RuneCountInString(words[j])
+	})
+	parent := make([]int, len(words))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	runes := make([][]rune, len(words))
+	for i, w := range words {
+		runes[i] = []rune(w)
+	}
+	for i := range words {
+		typos := maxTypos(words[i])
+		// The words are sorted by length, so the next ones are too long to be close past the first one
+		for j := i + 1; j < len(words) && len(runes[j])-len(runes[i]) <= typos; j++ {
+			if limit := min(typos, maxTypos(words[j])); distance(runes[i], runes[j], limit) <= limit {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+	groups := make(map[int][]string)
+	var roots []int
+	for i, w := range words {
+		r := find(i)
+		if groups[r] == nil {
+			roots = append(roots, r)
+		}
+		groups[r] = append(groups[r], w)
+	}
+	var res [][]string
+	for _, r := range roots {
+		if len(groups[r]) > 1 {
+			res = append(res, groups[r])
+		}
+	}
+	return res
+}
+
+// distance returns the Levenshtein distance of a and b, or limit+1 once it exceeds limit
+func distance(a, b []rune, limit int) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		lowest := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			lowest = min(lowest, cur[j])
+		}
+		if lowest > limit {
+			return limit + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,75 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package aiml
+
+import (
+	"sort"
+	"strings"
+)
+
+// CategoryCoverage is the number of utterances of a corpus a category matched
+type CategoryCoverage struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Pattern string `json:"pattern"`
+	That    string `json:"that,omitempty"`
+	Topic   string `json:"topic,omitempty"`
+	Default bool   `json:"default,omitempty"`
+	Hits    int    `json:"hits"`
+}
+
+// Unmatched is an utterance of a corpus only matched by a default category, or by none
+type Unmatched struct {
+	Input string `json:"input"`
+	Count int    `json:"count"`
+}
+
+// CoverageReport is the result of matching a corpus of utterances
+type CoverageReport struct {
+	Utterances int                `json:"utterances"`
+	Matched    int                `json:"matched"`    // The utterances matched by a category which is not a default one
+	Categories []CategoryCoverage `json:"categories"` // In the order of the files
+	Unmatched  []Unmatched        `json:"unmatched"`  // The most frequent first
+}
+
+// Coverage matches each utterance of the corpus, without that or topic, and
+// reports how many each category matched and which utterances only the
+// default categories answer, to guide where to author new content. The
+// utterances are grouped by their normalized words.
+func (m *Matcher) Coverage(utterances []string) *CoverageReport {
+	r := &CoverageReport{Categories: make([]CategoryCoverage, len(m.matches)), Unmatched: []Unmatched{}}
+	index := make(map[*Match]int)
+	for i, c := range m.matches {
+		index[c] = i
+		r.Categories[i] = CategoryCoverage{File: c.File, Line: c.Line, Pattern: c.Pattern, That: c.That, Topic: c.Topic, Default: c.Default}
+	}
+	unmatched := make(map[string]int)
+	for _, u := range utterances {
+		if strings.TrimSpace(u) == "" {
+			continue
+		}
+		r.Utterances++
+		match, ok := m.Match(u, "", "")
+		if ok {
+			r.Categories[index[match]].Hits++
+		}
+		if ok && !match.Default {
+			r.Matched++
+			continue
+		}
+		key := strings.Join(inputTokens(u), " ")
+		if unmatched[key] == 0 {
+			r.Unmatched = append(r.Unmatched, Unmatched{Input: strings.TrimSpace(u)})
+		}
+		unmatched[key]++
+	}
+	for i, u := range r.Unmatched {
+		r.Unmatched[i].Count = unmatched[strings.Join(inputTokens(u.Input), " ")]
+	}
+	sort.SliceStable(r.Unmatched, func(i, j int) bool {
+		return r.Unmatched[i].Count > r.Unmatched[j].Count
+	})
+	return r
+}
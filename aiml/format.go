@@ -0,0 +1,119 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package aiml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+const indent = "  "
+
+// Format rewrites an AIML file in the canonical layout: one element per line for
+// the aiml, topic and category elements, indented by two spaces, with the pattern,
+// that and topic patterns on a single line. Template content is kept as is.
+func Format(data []byte) ([]byte, error) {
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return format(doc), nil
+}
+
+// format writes the document in the canonical layout
+func format(doc *Node) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	for _, n := range doc.Children {
+		writeBlock(&buf, n, 0)
+	}
+	return buf.Bytes()
+}
+
+// isBlock returns true for the elements laid out one per line
+func isBlock(n, parent *Node) bool {
+	switch n.Name {
+	case "aiml", "category":
+		return true
+	case "topic":
+		return parent == nil || parent.Name == "aiml"
+	}
+	return false
+}
+
+func writeBlock(buf *bytes.Buffer, n *Node, depth int) {
+	prefix := strings.Repeat(indent, depth)
+	switch n.Kind {
+	case TextNode:
+		if text := strings.TrimSpace(n.Text); text != "" {
+			buf.WriteString(prefix + textEscaper.Replace(text) + "\n")
+		}
+		return
+	case CommentNode:
+		buf.WriteString(prefix + "<!--" + n.Text + "-->\n")
+		return
+	}
+	buf.WriteString(prefix)
+	writeStart(buf, n, len(n.Children) == 0)
+	if len(n.Children) == 0 {
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	for _, c := range n.Children {
+		if c.Kind == ElementNode && !isBlock(c, n) {
+			buf.WriteString(prefix + indent)
+			if c.Name == "pattern" || c.Name == "that" || c.Name == "topic" {
+				writeStart(buf, c, len(c.Children) == 0)
+				if len(c.Children) > 0 {
+					buf.WriteString(collapse(c.Inner()))
+					buf.WriteString("</" + c.Name + ">")
+				}
+			} else {
+				writeInline(buf, c)
+			}
+			buf.WriteByte('\n')
+			continue
+		}
+		writeBlock(buf, c, depth+1)
+	}
+	buf.WriteString(prefix + "</" + n.Name + ">\n")
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+)
+
+func writeStart(w *bytes.Buffer, n *Node, empty bool) {
+	w.WriteString("<" + n.Name)
+	for _, a := range n.Attr {
+		w.WriteString(" " + xmlName(a.Name) + `="` + attrEscaper.Replace(a.Value) + `"`)
+	}
+	if empty {
+		w.WriteString("/>")
+	} else {
+		w.WriteString(">")
+	}
+}
+
+// writeInline serializes the node as is
+func writeInline(w *bytes.Buffer, n *Node) {
+	switch n.Kind {
+	case TextNode:
+		w.WriteString(textEscaper.Replace(n.Text))
+	case CommentNode:
+		w.WriteString("<!--" + n.Text + "-->")
+	case ElementNode:
+		writeStart(w, n, len(n.Children) == 0)
+		if len(n.Children) > 0 {
+			for _, c := range n.Children {
+				writeInline(w, c)
+			}
+			w.WriteString("</" + n.Name + ">")
+		}
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package aiml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GraphNode is a category or a topic of the conversation graph
+type GraphNode struct {
+	Id      string `json:"id"`
+	Kind    string `json:"kind"` // category or topic
+	Pattern string `json:"pattern,omitempty"`
+	That    string `json:"that,omitempty"`
+	Topic   string `json:"topic,omitempty"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Orphan  bool   `json:"orphan,omitempty"` // The that or topic of the category is never produced by the bot
+}
+
+// GraphEdge links a category to the category its srai reaches, to the
+// categories whose that matches its response, or to the topic it sets
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to,omitempty"` // Empty if the srai only reaches the default category
+	Kind string `json:"kind"`         // srai, that or topic
+	Text string `json:"text,omitempty"`
+}
+
+// Graph is the conversation flow of a bot
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// graphCategory is a category of the graph with its matching tokens
+type graphCategory struct {
+	id                   string
+	file                 string
+	pattern, that, topic []string
+	Category
+}
+
+// maxResponses bounds the responses enumerated for the templates with many random and condition elements
+const maxResponses = 32
+
+// formatting are the template elements which output their content
+var formatting = map[string]bool{
+	"formal": true, "uppercase": true, "lowercase": true, "sentence": true, "set": true,
+	"a": true, "b": true, "i": true, "u": true, "em": true, "strong": true,
+	"p": true, "div": true, "span": true, "font": true, "ul": true, "ol": true,
+}
+
+// silent are the template elements which output nothing
+var silent = map[string]bool{"think": true, "oob": true, "learn": true, "learnf": true}
+
+// responses returns the texts the template element may output, with the
+// parts only known when talking replaced by the dynamic token
+func responses(n *Node) []string {
+	res := []string{""}
+	for _, c := range n.Children {
+		var alts []string
+		switch {
+		case c.Kind == TextNode:
+			alts = []string{c.Text}
+		case c.Kind != ElementNode || silent[c.Name]:
+			continue
+		case c.Name == "random" || c.Name == "condition":
+			fallback := c.Name == "random"
+			for _, li := range c.Elements("li") {
+				alts = append(alts, responses(li)...)
+				fallback = fallback || li.Attribute("value") == ""
+			}
+			if len(c.Elements("li")) == 0 {
+				alts = responses(c)
+			}
+			if !fallback {
+				alts = append(alts, "")
+			}
+		case c.Name == "br":
+			alts = []string{" "}
+		case formatting[c.Name]:
+			alts = responses(c)
+		default:
+			alts = []string{" " + dynamic + " "}
+		}
+		var next []string
+		for _, r := range res {
+			for _, a := range alts {
+				if len(next) < maxResponses {
+					next = append(next, r+a)
+				}
+			}
+		}
+		res = next
+	}
+	return res
+}
+
+// lastSentence returns the words of the last sentence of a response, which the that patterns match
+func lastSentence(s string) []string {
+	sentences := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '.' || r == '!' || r == '?'
+	})
+	for i := len(sentences) - 1; i >= 0; i-- {
+		if words := inputTokens(sentences[i]); len(words) > 0 {
+			return words
+		}
+	}
+	return nil
+}
+
+// elementsNamed returns the elements with the name under the node, at any depth
+func elementsNamed(n *Node, name string) []*Node {
+	var res []*Node
+	for _, c := range n.Children {
+		if c.Kind != ElementNode {
+			continue
+		}
+		if c.Name == name {
+			res = append(res, c)
+		}
+		res = append(res, elementsNamed(c, name)...)
+	}
+	return res
+}
+
+// BuildGraph builds the conversation graph of the AIML files given by name.
+// The srai edges link a category to the category with the highest priority
+// pattern matching its srai, ignoring the that and topic; the srai only
+// matched by a pattern made of wildcards, like the default category, have
+// no target. The that edges link a category to the categories whose that
+// matches the last sentence of one of its responses, and the topic edges
+// link a category to the topics it sets. The parts of the srai and
+// responses only known when talking are matched by the wildcards only.
+func BuildGraph(files map[string][]byte) (*Graph, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var cats []*graphCategory
+	for _, name := range names {
+		doc, err := Parse(files[name])
+		if err != nil {
+			return nil, fmt.Errorf("File is not valid AIML [%s] - %v", name, err)
+		}
+		for _, c := range doc.Categories() {
+			gc := &graphCategory{id: fmt.Sprintf("%s:%d", name, c.Line), file: name, Category: c}
+			gc.pattern, gc.that, gc.topic = contextTokens(c)
+			cats = append(cats, gc)
+		}
+	}
+	g := &Graph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	topics := make(map[string][]string)
+	for _, c := range cats {
+		if c.topic != nil && !wildcardsOnly(c.topic) {
+			topics[strings.Join(c.topic, " ")] = c.topic
+		}
+	}
+	reached := make(map[string]bool)
+	for _, c := range cats {
+		if c.Template == nil {
+			continue
+		}
+		g.sraiEdges(c, cats)
+		for _, set := range elementsNamed(c.Template, "set") {
+			if set.Attribute("name") != "topic" {
+				continue
+			}
+			for _, r := range responses(set) {
+				words := inputTokens(r)
+				for topic, tokens := range topics {
+					if matchWords(tokens, words) && !reached[c.id+" topic "+topic] {
+						reached[c.id+" topic "+topic] = true
+						reached["topic:"+topic] = true
+						g.Edges = append(g.Edges, GraphEdge{From: c.id, To: "topic:" + topic, Kind: "topic", Text: strings.TrimSpace(r)})
+					}
+				}
+			}
+		}
+		for _, r := range responses(c.Template) {
+			words := lastSentence(r)
+			for _, t := range cats {
+				if t.that == nil || wildcardsOnly(t.that) || reached[c.id+" that "+t.id] || !matchWords(t.that, words) {
+					continue
+				}
+				reached[c.id+" that "+t.id] = true
+				reached[t.id] = true
+				g.Edges = append(g.Edges, GraphEdge{From: c.id, To: t.id, Kind: "that"})
+			}
+		}
+	}
+	for _, c := range cats {
+		n := GraphNode{Id: c.id, Kind: "category", Pattern: c.Pattern, That: c.That, Topic: c.Topic, File: c.file, Line: c.Line}
+		topic := strings.Join(c.topic, " ")
+		n.Orphan = c.that != nil && !wildcardsOnly(c.that) && !reached[c.id] ||
+			topics[topic] != nil && !reached["topic:"+topic]
+		g.Nodes = append(g.Nodes, n)
+	}
+	sorted := make([]string, 0, len(topics))
+	for topic := range topics {
+		sorted = append(sorted, topic)
+	}
+	sort.Strings(sorted)
+	for _, topic := range sorted {
+		g.Nodes = append(g.Nodes, GraphNode{Id: "topic:" + topic, Kind: "topic", Topic: topic, Orphan: !reached["topic:"+topic]})
+	}
+	return g, nil
+}
+
+// sraiEdges adds the edges of the srai of the category
+func (g *Graph) sraiEdges(c *graphCategory, cats []*graphCategory) {
+	seen := make(map[string]bool)
+	for _, srai := range elementsNamed(c.Template, "srai") {
+		for _, r := range responses(srai) {
+			words := inputTokens(r)
+			text := strings.Join(words, " ")
+			if seen[text] || strings.Trim(text, dynamic+" ") == "" {
+				continue
+			}
+			seen[text] = true
+			text = strings.ReplaceAll(text, dynamic, "*")
+			var best []string
+			var targets []string
+			for _, t := range cats {
+				if !matchWords(t.pattern, words) {
+					continue
+				}
+				switch {
+				case best == nil || higher(t.pattern, best):
+					best, targets = t.pattern, []string{t.id}
+				case !higher(best, t.pattern):
+					targets = append(targets, t.id)
+				}
+			}
+			if best == nil || wildcardsOnly(best) {
+				g.Edges = append(g.Edges, GraphEdge{From: c.id, Kind: "srai", Text: text})
+				continue
+			}
+			for _, t := range targets {
+				g.Edges = append(g.Edges, GraphEdge{From: c.id, To: t, Kind: "srai", Text: text})
+			}
+		}
+	}
+}
+
+// GraphFiles builds the conversation graph of the AIML files in paths, ignoring the other files
+func GraphFiles(paths []string) (*Graph, error) {
+	files := make(map[string][]byte)
+	for _, path := range paths {
+		if filepath.Ext(path) != ".aiml" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files[path] = data
+	}
+	return BuildGraph(files)
+}
+
+// Orphans returns the categories and topics which can never be reached
+func (g *Graph) Orphans() []GraphNode {
+	var res []GraphNode
+	for _, n := range g.Nodes {
+		if n.Orphan {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// Unresolved returns the srai edges only reaching the default category
+func (g *Graph) Unresolved() []GraphEdge {
+	var res []GraphEdge
+	for _, e := range g.Edges {
+		if e.Kind == "srai" && e.To == "" {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// WriteDOT writes the graph in the GraphViz DOT language, with a cluster per
+// topic. The orphans are drawn dashed and red, and the srai only reaching
+// the default category point to red text.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("digraph bot {\n\trankdir=LR;\n\tnode [shape=box, fontsize=10];\n")
+	clusters := make(map[string][]GraphNode)
+	var order []string
+	for _, n := range g.Nodes {
+		if _, ok := clusters[n.Topic]; !ok {
+			order = append(order, n.Topic)
+		}
+		clusters[n.Topic] = append(clusters[n.Topic], n)
+	}
+	for _, topic := range order {
+		indent := "\t"
+		if topic != "" {
+			fmt.Fprintf(&buf, "\tsubgraph %s {\n\t\tlabel=%s;\n", strconv.Quote("cluster_"+topic), strconv.Quote("topic "+topic))
+			indent = "\t\t"
+		}
+		for _, n := range clusters[topic] {
+			label := n.Pattern
+			if n.That != "" {
+				label += "\nthat " + n.That
+			}
+			attrs := "label=" + strconv.Quote(label)
+			if n.Kind == "topic" {
+				attrs = "label=" + strconv.Quote("topic "+n.Topic) + ", shape=ellipse"
+			}
+			if n.Orphan {
+				attrs += ", style=dashed, color=red"
+			}
+			fmt.Fprintf(&buf, "%s%s [%s];\n", indent, strconv.Quote(n.Id), attrs)
+		}
+		if topic != "" {
+			buf.WriteString("\t}\n")
+		}
+	}
+	for i, e := range g.Edges {
+		to := strconv.Quote(e.To)
+		if e.To == "" {
+			to = strconv.Quote(fmt.Sprintf("unresolved:%d", i))
+			fmt.Fprintf(&buf, "\t%s [label=%s, shape=plaintext, fontcolor=red];\n", to, strconv.Quote(e.Text))
+		}
+		attrs := ""
+		switch e.Kind {
+		case "that":
+			attrs = " [style=dotted, label=\"that\"]"
+		case "topic":
+			attrs = " [style=dashed, label=\"topic\"]"
+		}
+		fmt.Fprintf(&buf, "\t%s -> %s%s;\n", strconv.Quote(e.From), to, attrs)
+	}
+	buf.WriteString("}\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,292 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package aiml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Severity is the level of a lint issue
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // The file will not compile or will not behave as intended
+	SeverityWarning Severity = "warning" // The file is suspicious but valid
+)
+
+// Issue is a problem found in a bot file
+type Issue struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line,omitempty"` // Zero if the issue is not tied to a line
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s: %s", i.File, i.Line, i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.File, i.Severity, i.Message)
+}
+
+// templateTags are the AIML 2.0 template elements, pandorabots rich media
+// elements and the HTML elements commonly used in replies
+var templateTags = map[string]bool{
+	"bot": true, "condition": true, "date": true, "denormalize": true, "eval": true, "explode": true,
+	"first": true, "formal": true, "gender": true, "get": true, "gossip": true, "id": true, "input": true,
+	"interval": true, "javascript": true, "learn": true, "learnf": true, "li": true, "loop": true,
+	"lowercase": true, "map": true, "name": true, "normalize": true, "oob": true, "person": true,
+	"person2": true, "program": true, "random": true, "request": true, "response": true, "rest": true,
+	"sentence": true, "set": true, "size": true, "sr": true, "srai": true, "sraix": true, "star": true,
+	"system": true, "that": true, "thatstar": true, "think": true, "topic": true, "topicstar": true,
+	"uppercase": true, "value": true, "var": true, "vocabulary": true,
+	"button": true, "card": true, "carousel": true, "delay": true, "hint": true, "image": true,
+	"link": true, "list": true, "olist": true, "postback": true, "reply": true, "split": true,
+	"subtitle": true, "text": true, "title": true, "url": true, "video": true, "criteria": true,
+	"a": true, "b": true, "br": true, "div": true, "em": true, "font": true, "i": true, "img": true,
+	"ol": true, "p": true, "span": true, "strong": true, "u": true, "ul": true,
+}
+
+// patternTags are the elements allowed in patterns
+var patternTags = map[string]bool{"bot": true, "set": true, "name": true}
+
+// located is a category with the file it was found in, for duplicate detection
+type located struct {
+	file string
+	Category
+}
+
+// linter accumulates the issues of a set of files
+type linter struct {
+	issues     []Issue
+	categories []located
+}
+
+func (l *linter) add(file string, line int, severity Severity, format string, args ...interface{}) {
+	l.issues = append(l.issues, Issue{File: file, Line: line, Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+// Lint checks a single bot file. The type of the file is determined by the extension of name.
+func Lint(name string, data []byte) []Issue {
+	l := &linter{}
+	l.lint(name, data)
+	l.duplicates()
+	l.shadowed()
+	return l.issues
+}
+
+// LintFiles checks the files in paths, including categories duplicated or shadowed across files
+func LintFiles(paths []string) ([]Issue, error) {
+	l := &linter{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		l.lint(path, data)
+	}
+	l.duplicates()
+	l.shadowed()
+	return l.issues, nil
+}
+
+func (l *linter) lint(name string, data []byte) {
+	switch filepath.Ext(name) {
+	case ".aiml":
+		l.lintAIML(name, data)
+	case ".set":
+		l.lintList(name, data, 0)
+	case ".map", ".substitution", ".properties", ".pdefaults":
+		l.lintList(name, data, 2)
+	default:
+		l.add(name, 0, SeverityError, "Extension is not recognized [%s]", filepath.Ext(name))
+	}
+}
+
+func (l *linter) lintAIML(name string, data []byte) {
+	doc, err := Parse(data)
+	if err != nil {
+		if se, ok := err.(*SyntaxError); ok {
+			l.add(name, se.Line, SeverityError, "%s", se.Msg)
+		} else {
+			l.add(name, 0, SeverityError, "%v", err)
+		}
+		return
+	}
+	root := doc.Root()
+	if root == nil || root.Name != "aiml" {
+		l.add(name, 1, SeverityError, "root element must be <aiml>")
+		return
+	}
+	l.checkContainer(name, root)
+	for _, c := range doc.Categories() {
+		l.checkCategory(name, c)
+		l.categories = append(l.categories, located{name, c})
+	}
+}
+
+// checkContainer checks the aiml and topic elements only hold categories
+func (l *linter) checkContainer(name string, n *Node) {
+	for _, c := range n.Children {
+		switch {
+		case c.Kind == TextNode && strings.TrimSpace(c.Text) != "":
+			l.add(name, c.Line, SeverityError, "unexpected text in <%s>", n.Name)
+		case c.Kind != ElementNode || c.Name == "category":
+		case c.Name == "topic" && n.Name == "aiml":
+			if c.Attribute("name") == "" {
+				l.add(name, c.Line, SeverityError, "<topic> without a name attribute")
+			}
+			l.checkContainer(name, c)
+		default:
+			l.add(name, c.Line, SeverityError, "unexpected element <%s> in <%s>", c.Name, n.Name)
+		}
+	}
+}
+
+func (l *linter) checkCategory(name string, c Category) {
+	counts := make(map[string]int)
+	for _, e := range c.Node.Children {
+		switch {
+		case e.Kind == TextNode && strings.TrimSpace(e.Text) != "":
+			l.add(name, e.Line, SeverityError, "unexpected text in <category>")
+		case e.Kind != ElementNode:
+		case e.Name == "pattern" || e.Name == "that" || e.Name == "topic" || e.Name == "template":
+			counts[e.Name]++
+			if counts[e.Name] == 2 {
+				l.add(name, e.Line, SeverityError, "category has more than one <%s>", e.Name)
+			}
+			if e.Name == "template" {
+				l.checkTemplate(name, e)
+			} else {
+				l.checkPattern(name, e)
+			}
+		default:
+			l.add(name, e.Line, SeverityError, "unexpected element <%s> in <category>", e.Name)
+		}
+	}
+	switch {
+	case counts["pattern"] == 0:
+		l.add(name, c.Line, SeverityError, "category without a <pattern>")
+	case c.Pattern == "":
+		l.add(name, c.Line, SeverityError, "empty <pattern>")
+	}
+	if counts["template"] == 0 {
+		l.add(name, c.Line, SeverityError, "category without a <template>")
+	}
+}
+
+func (l *linter) checkPattern(name string, p *Node) {
+	for _, e := range p.Children {
+		switch e.Kind {
+		case ElementNode:
+			if !patternTags[e.Name] {
+				l.add(name, e.Line, SeverityError, "element <%s> is not allowed in <%s>", e.Name, p.Name)
+			}
+		case TextNode:
+			for _, w := range strings.Fields(e.Text) {
+				if len(w) > 1 && strings.ContainsAny(w, "*_#^") && !strings.HasPrefix(w, "$") {
+					l.add(name, e.Line, SeverityWarning, "wildcard in [%s] is not a separate word and matches literally", w)
+				}
+			}
+		}
+	}
+}
+
+func (l *linter) checkTemplate(name string, n *Node) {
+	for _, e := range n.Children {
+		if e.Kind != ElementNode {
+			continue
+		}
+		if !templateTags[e.Name] && !strings.Contains(e.Name, ":") {
+			l.add(name, e.Line, SeverityWarning, "unknown element <%s> in template", e.Name)
+		}
+		if e.Name == "srai" && strings.TrimSpace(e.Inner()) == "" {
+			l.add(name, e.Line, SeverityWarning, "empty <srai>")
+		}
+		l.checkTemplate(name, e)
+	}
+}
+
+// duplicates reports the categories with the same pattern, that and topic
+func (l *linter) duplicates() {
+	first := make(map[string]located)
+	for _, c := range l.categories {
+		if c.Pattern == "" {
+			continue
+		}
+		key := c.Key()
+		if f, ok := first[key]; ok {
+			l.add(c.file, c.Line, SeverityError, "duplicate category [%s], first defined at %s:%d", c.Pattern, f.file, f.Line)
+			continue
+		}
+		first[key] = c
+	}
+}
+
+// lintList checks a JSON list file. Each entry must be a list of strings
+// with the given number of elements, or any number if size is zero.
+func (l *linter) lintList(name string, data []byte, size int) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		line := 0
+		if se, ok := err.(*json.SyntaxError); ok {
+			line = 1 + bytes.Count(data[:se.Offset], []byte("\n"))
+		}
+		l.add(name, line, SeverityError, "invalid JSON list - %v", err)
+		return
+	}
+	seen := make(map[string]int)
+	for i, raw := range entries {
+		var values []string
+		if err := json.Unmarshal(raw, &values); err != nil {
+			l.add(name, 0, SeverityError, "entry %d must be a list of strings", i+1)
+			continue
+		}
+		switch {
+		case size == 0 && len(values) == 0:
+			l.add(name, 0, SeverityError, "entry %d is empty", i+1)
+			continue
+		case size > 0 && len(values) != size:
+			l.add(name, 0, SeverityError, "entry %d must have %d elements but has %d", i+1, size, len(values))
+			continue
+		}
+		key := strings.ToUpper(strings.Join(values, " "))
+		if size > 0 {
+			key = strings.ToUpper(values[0])
+		}
+		if prev, ok := seen[key]; ok {
+			l.add(name, 0, SeverityWarning, "entry %d duplicates entry %d [%s]", i+1, prev, values[0])
+			continue
+		}
+		seen[key] = i + 1
+	}
+}
+
+// Errors returns the number of issues with the error severity
+func Errors(issues []Issue) int {
+	n := 0
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			n++
+		}
+	}
+	return n
+}
+
+// SortIssues sorts the issues by file and line
+func SortIssues(issues []Issue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+}
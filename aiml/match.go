@@ -0,0 +1,240 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package aiml
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// dynamic stands for the input words only known when talking, like the
+// result of a <star/> or a <get/>. It is matched by the wildcards only.
+const dynamic = "\x00"
+
+// patternTokens returns the words and wildcards of a pattern, that or topic
+// element. The <set> and <bot> elements are returned as "<set NAME>" and
+// "<bot NAME>".
+func patternTokens(p *Node) []string {
+	var tokens []string
+	for _, c := range p.Children {
+		switch {
+		case c.Kind == TextNode:
+			tokens = append(tokens, strings.Fields(strings.ToUpper(c.Text))...)
+		case c.Kind != ElementNode:
+		case c.Name == "set":
+			tokens = append(tokens, "<set "+strings.ToLower(collapse(c.Inner()+c.Attribute("name")))+">")
+		case c.Name == "name":
+			tokens = append(tokens, "<bot name>")
+		default:
+			tokens = append(tokens, "<bot "+strings.ToLower(c.Attribute("name"))+">")
+		}
+	}
+	return tokens
+}
+
+// isElement reports whether the pattern token is a <set> or a <bot>
+func isElement(t string) bool {
+	return strings.HasPrefix(t, "<set ") || strings.HasPrefix(t, "<bot ")
+}
+
+// inputTokens returns the words of an input normalized for matching: upper
+// cased and without punctuation
+func inputTokens(s string) []string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return unicode.ToUpper(r)
+		case r == '\'':
+			return -1
+		case r == rune(dynamic[0]):
+			return r
+		}
+		return ' '
+	}, s)
+	return strings.Fields(s)
+}
+
+// matchWords reports whether the input words match the pattern tokens
+func matchWords(pattern, input []string) bool {
+	if len(pattern) == 0 {
+		return len(input) == 0
+	}
+	min := 1
+	switch t := pattern[0]; {
+	case t == "^" || t == "#":
+		min = 0
+	case t == "*" || t == "_" || isElement(t):
+	default:
+		return len(input) > 0 && input[0] == strings.TrimPrefix(t, "$") && matchWords(pattern[1:], input[1:])
+	}
+	for i := min; i <= len(input); i++ {
+		if matchWords(pattern[1:], input[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenPriority returns the AIML 2.0 matching priority of a pattern token
+func tokenPriority(t string) int {
+	switch {
+	case strings.HasPrefix(t, "$"):
+		return 6
+	case t == "#":
+		return 5
+	case t == "_":
+		return 4
+	case isElement(t):
+		return 2
+	case t == "^":
+		return 1
+	case t == "*":
+		return 0
+	}
+	return 3
+}
+
+// higher reports whether the pattern a has priority over the pattern b when both match
+func higher(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if pa, pb := tokenPriority(a[i]), tokenPriority(b[i]); pa != pb {
+			return pa > pb
+		}
+	}
+	return len(a) > len(b)
+}
+
+// wildcardsOnly reports whether the pattern is made of wildcards only, like the default category
+func wildcardsOnly(pattern []string) bool {
+	for _, t := range pattern {
+		switch t {
+		case "*", "_", "^", "#":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// contextTokens returns the tokens of the pattern, that and topic of the
+// category. The that and topic are nil when the category has none.
+func contextTokens(c Category) (pattern, that, topic []string) {
+	if c.Node != nil {
+		for _, p := range c.Node.Children {
+			if p.Kind != ElementNode {
+				continue
+			}
+			switch p.Name {
+			case "pattern":
+				pattern = patternTokens(p)
+			case "that":
+				that = patternTokens(p)
+			case "topic":
+				topic = patternTokens(p)
+			}
+		}
+	}
+	if topic == nil && c.Topic != "" {
+		topic = strings.Fields(strings.ToUpper(c.Topic))
+	}
+	return pattern, that, topic
+}
+
+// Match is the category matching an input
+type Match struct {
+	File    string
+	Default bool // The pattern is made of wildcards only, like the default category
+	Category
+}
+
+// Matcher matches inputs against AIML categories locally, following the
+// AIML 2.0 priorities of the wildcards. The sets and bot properties of the
+// patterns match any words, and the substitutions of the bot are not applied.
+type Matcher struct {
+	matches []*Match
+	paths   [][]string
+}
+
+// NewMatcher creates a matcher for the categories of the AIML files given by name
+func NewMatcher(files map[string][]byte) (*Matcher, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	m := &Matcher{}
+	for _, name := range names {
+		doc, err := Parse(files[name])
+		if err != nil {
+			return nil, fmt.Errorf("File is not valid AIML [%s] - %v", name, err)
+		}
+		for _, c := range doc.Categories() {
+			pattern, that, topic := contextTokens(c)
+			if that == nil {
+				that = []string{"*"}
+			}
+			if topic == nil {
+				topic = []string{"*"}
+			}
+			m.matches = append(m.matches, &Match{File: name, Default: wildcardsOnly(pattern), Category: c})
+			m.paths = append(m.paths, matchPath(pattern, that, topic))
+		}
+	}
+	return m, nil
+}
+
+// MatcherFiles creates a matcher for the AIML files in paths, ignoring the other files
+func MatcherFiles(paths []string) (*Matcher, error) {
+	files := make(map[string][]byte)
+	for _, path := range paths {
+		if filepath.Ext(path) != ".aiml" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files[path] = data
+	}
+	return NewMatcher(files)
+}
+
+// matchPath joins the input, that and topic like the AIML graph does, so the priorities apply across them
+func matchPath(input, that, topic []string) []string {
+	path := append(append([]string{}, input...), "<THAT>")
+	path = append(append(path, that...), "<TOPIC>")
+	return append(path, topic...)
+}
+
+// Match returns the category with the highest priority matching the input in
+// the context of the that and topic, which may be empty, or false if none matches
+func (m *Matcher) Match(input, that, topic string) (*Match, bool) {
+	words := inputTokens(input)
+	if len(words) == 0 {
+		return nil, false
+	}
+	thatWords, topicWords := lastSentence(that), inputTokens(topic)
+	if len(thatWords) == 0 {
+		thatWords = []string{dynamic}
+	}
+	if len(topicWords) == 0 {
+		topicWords = []string{dynamic}
+	}
+	path := matchPath(words, thatWords, topicWords)
+	best := -1
+	for i, p := range m.paths {
+		if matchWords(p, path) && (best < 0 || higher(p, m.paths[best])) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil, false
+	}
+	return m.matches[best], true
+}
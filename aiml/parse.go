@@ -0,0 +1,228 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package aiml parses, checks and formats the AIML and personality files of
+// pandorabots bots locally, without calling the API.
+package aiml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// NodeKind is the type of an AIML document node
+type NodeKind int
+
+const (
+	DocumentNode NodeKind = iota // The root of a parsed file
+	ElementNode
+	TextNode
+	CommentNode
+)
+
+// Node is a node of a parsed AIML document
+type Node struct {
+	Kind     NodeKind
+	Name     string     // The element name, including the namespace prefix if any
+	Attr     []xml.Attr // The element attributes
+	Text     string     // The content of text and comment nodes
+	Children []*Node
+	Line     int // The line the node starts at
+}
+
+// SyntaxError is returned when a file is not well formed
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// lineCounter converts input offsets to line numbers
+type lineCounter struct {
+	data   []byte
+	offset int64
+	line   int
+}
+
+func (lc *lineCounter) lineAt(offset int64) int {
+	if offset > int64(len(lc.data)) {
+		offset = int64(len(lc.data))
+	}
+	if offset < lc.offset {
+		lc.offset, lc.line = 0, 1
+	}
+	lc.line += bytes.Count(lc.data[lc.offset:offset], []byte("\n"))
+	lc.offset = offset
+	return lc.line
+}
+
+func xmlName(n xml.Name) string {
+	if n.Space != "" {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
+
+// Parse parses an AIML file into a document node
+func Parse(data []byte) (*Node, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = true
+	d.Entity = xml.HTMLEntity
+	lc := &lineCounter{data: data, line: 1}
+	doc := &Node{Kind: DocumentNode, Line: 1}
+	stack := []*Node{doc}
+	for {
+		line := lc.lineAt(d.InputOffset())
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if se, ok := err.(*xml.SyntaxError); ok {
+				return nil, &SyntaxError{Line: se.Line, Msg: se.Msg}
+			}
+			return nil, &SyntaxError{Line: line, Msg: err.Error()}
+		}
+		parent := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &Node{Kind: ElementNode, Name: xmlName(t.Name), Attr: t.Attr, Line: line}
+			parent.Children = append(parent.Children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) == 1 || parent.Name != xmlName(t.Name) {
+				return nil, &SyntaxError{Line: line, Msg: fmt.Sprintf("unexpected end element </%s>", xmlName(t.Name))}
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) == 1 && len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+			parent.Children = append(parent.Children, &Node{Kind: TextNode, Text: string(t), Line: line})
+		case xml.Comment:
+			parent.Children = append(parent.Children, &Node{Kind: CommentNode, Text: string(t), Line: line})
+		}
+	}
+	if len(stack) > 1 {
+		n := stack[len(stack)-1]
+		return nil, &SyntaxError{Line: lc.lineAt(int64(len(data))), Msg: fmt.Sprintf("element <%s> started on line %d is not closed", n.Name, n.Line)}
+	}
+	return doc, nil
+}
+
+// Root returns the first element of the document
+func (n *Node) Root() *Node {
+	for _, c := range n.Children {
+		if c.Kind == ElementNode {
+			return c
+		}
+	}
+	return nil
+}
+
+// Attribute returns the value of the attribute with the name, or an empty string
+func (n *Node) Attribute(name string) string {
+	for _, a := range n.Attr {
+		if xmlName(a.Name) == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Elements returns the child elements with the name
+func (n *Node) Elements(name string) []*Node {
+	var res []*Node
+	for _, c := range n.Children {
+		if c.Kind == ElementNode && c.Name == name {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// Inner returns the content of the node serialized as XML
+func (n *Node) Inner() string {
+	var buf bytes.Buffer
+	for _, c := range n.Children {
+		writeInline(&buf, c)
+	}
+	return buf.String()
+}
+
+// Category is an AIML category with the context it matches in
+type Category struct {
+	Pattern  string // The pattern with the whitespace collapsed
+	That     string // The that pattern, empty if the category has none
+	Topic    string // The topic pattern, empty if the category has none
+	Template *Node  // The template element, nil if missing
+	Line     int
+	Node     *Node // The category element
+}
+
+// Key returns the normalized pattern, that and topic identifying the category
+func (c Category) Key() string {
+	norm := func(s string) string {
+		if s == "" {
+			return "*"
+		}
+		return strings.ToUpper(s)
+	}
+	return norm(c.Pattern) + " <that> " + norm(c.That) + " <topic> " + norm(c.Topic)
+}
+
+// collapse trims the string and collapses the runs of whitespace to single spaces
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// Categories returns the categories of the document, including the ones in topics
+func (n *Node) Categories() []Category {
+	root := n
+	if n.Kind == DocumentNode {
+		root = n.Root()
+	}
+	var res []Category
+	if root == nil {
+		return res
+	}
+	var walk func(parent *Node, topic string)
+	walk = func(parent *Node, topic string) {
+		for _, e := range parent.Children {
+			if e.Kind != ElementNode {
+				continue
+			}
+			switch e.Name {
+			case "topic":
+				walk(e, e.Attribute("name"))
+			case "category":
+				c := Category{Topic: topic, Line: e.Line, Node: e}
+				for _, p := range e.Children {
+					if p.Kind != ElementNode {
+						continue
+					}
+					switch p.Name {
+					case "pattern":
+						c.Pattern = collapse(p.Inner())
+					case "that":
+						c.That = collapse(p.Inner())
+					case "topic":
+						c.Topic = collapse(p.Inner())
+					case "template":
+						c.Template = p
+					}
+				}
+				res = append(res, c)
+			}
+		}
+	}
+	walk(root, "")
+	return res
+}
@@ -0,0 +1,117 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package aiml
+
+import (
+	"strings"
+)
+
+// covers reports whether the pattern a matches every input the pattern b matches
+func covers(a, b []string) bool {
+	if len(a) == 0 {
+		return len(b) == 0
+	}
+	switch t := a[0]; {
+	case t == "^" || t == "#":
+		for i := 0; i <= len(b); i++ {
+			if covers(a[1:], b[i:]) {
+				return true
+			}
+		}
+		return false
+	case t == "*" || t == "_":
+		for i := 1; i <= len(b); i++ {
+			if nonEmpty(b[:i]) && covers(a[1:], b[i:]) {
+				return true
+			}
+		}
+		return false
+	case isElement(t):
+		return len(b) > 0 && b[0] == t && covers(a[1:], b[1:])
+	}
+	return len(b) > 0 && strings.TrimPrefix(b[0], "$") == strings.TrimPrefix(a[0], "$") && covers(a[1:], b[1:])
+}
+
+// nonEmpty reports whether the pattern tokens always match at least one word
+func nonEmpty(tokens []string) bool {
+	for _, t := range tokens {
+		if t != "^" && t != "#" {
+			return true
+		}
+	}
+	return false
+}
+
+// shadowing is a category of the linted files with the tokens of its pattern, that and topic
+type shadowing struct {
+	path  []string
+	words map[string]bool // The words of the path, to skip the categories which cannot cover it quickly
+	located
+}
+
+// shadowed reports the categories which can never match because a category
+// with a higher priority matches every input they match, like "_ HELLO"
+// makes "HI HELLO" unreachable, in any file. The categories with the same
+// pattern, that and topic are reported by duplicates.
+func (l *linter) shadowed() {
+	var cats, wildcards []*shadowing
+	for _, c := range l.categories {
+		if c.Pattern == "" {
+			continue
+		}
+		pattern, that, topic := contextTokens(c.Category)
+		if that == nil {
+			that = []string{"*"}
+		}
+		if topic == nil {
+			topic = []string{"*"}
+		}
+		s := &shadowing{path: matchPath(pattern, that, topic), words: make(map[string]bool), located: c}
+		wildcard := false
+		for _, t := range s.path {
+			switch {
+			case t == "*" || t == "_" || t == "^" || t == "#":
+				wildcard = true
+			case !isElement(t):
+				s.words[strings.TrimPrefix(t, "$")] = true
+			}
+		}
+		cats = append(cats, s)
+		if wildcard {
+			wildcards = append(wildcards, s)
+		}
+	}
+	for _, b := range cats {
+		for _, a := range wildcards {
+			if a == b || a.Key() == b.Key() || !subset(a.words, b.words) || !higher(a.path, b.path) || !covers(a.path, b.path) {
+				continue
+			}
+			l.add(b.file, b.Line, SeverityWarning, "category [%s] can never match, [%s] at %s:%d has priority for all its inputs", label(b.Category), label(a.Category), a.file, a.Line)
+			break
+		}
+	}
+}
+
+// label returns the pattern of the category, with its that and topic if any
+func label(c Category) string {
+	s := c.Pattern
+	if c.That != "" {
+		s += " <that> " + c.That
+	}
+	if c.Topic != "" {
+		s += " <topic> " + c.Topic
+	}
+	return s
+}
+
+// subset reports whether all the words of a are in b
+func subset(a, b map[string]bool) bool {
+	for w := range a {
+		if !b[w] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package aiml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// legacyTags are the AIML 1.x shortcuts of the input and that elements, and their index
+var legacyTags = map[string][2]string{
+	"justthat":       {"input", "2"},
+	"beforethat":     {"input", "3"},
+	"justbeforethat": {"that", "2,1"},
+}
+
+// upgrader rewrites an AIML 1.x document
+type upgrader struct {
+	name   string
+	issues []Issue
+}
+
+func (u *upgrader) add(line int, severity Severity, format string, args ...interface{}) {
+	u.issues = append(u.issues, Issue{File: u.name, Line: line, Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+// Upgrade rewrites the AIML 1.x constructs of a file into their AIML 2.0
+// equivalents, since the pandorabots compiler targets AIML 2.0, and returns it
+// in the layout of Format. The constructs needing manual attention are
+// reported: errors for the ones AIML 2.0 does not support, and warnings for
+// the ones which behave differently.
+//
+// The rewrites are the version of the aiml element, the ISO-8859-1 encoding, the html namespace
+// prefixes, the get_name and set_name predicate shortcuts, the justthat,
+// beforethat and justbeforethat shortcuts, the deprecated gossip and secure
+// elements, which are replaced by their content, and the pattern words,
+// which are upper cased.
+func Upgrade(name string, data []byte) ([]byte, []Issue, error) {
+	doc, err := Parse(latin1ToUTF8(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	u := &upgrader{name: name}
+	if root := doc.Root(); root != nil && root.Name == "aiml" {
+		setAttr(root, "version", "2.0")
+		attrs := root.Attr[:0]
+		for _, a := range root.Attr {
+			if xmlName(a.Name) != "xmlns:html" {
+				attrs = append(attrs, a)
+			}
+		}
+		root.Attr = attrs
+	}
+	u.upgrade(doc, false)
+	return format(doc), u.issues, nil
+}
+
+// encodingRe matches the encoding of the XML declaration
+var encodingRe = regexp.MustCompile(`^(\s*<\?xml[^>]*encoding=["'])([^"']+)`)
+
+// latin1ToUTF8 converts the files declared as ISO-8859-1, common for AIML 1.x,
+// to UTF-8, as the parser and the pandorabots compiler expect
+func latin1ToUTF8(data []byte) []byte {
+	m := encodingRe.FindSubmatchIndex(data)
+	if m == nil {
+		return data
+	}
+	switch strings.ToLower(string(data[m[4]:m[5]])) {
+	case "iso-8859-1", "latin1", "latin-1", "windows-1252":
+	default:
+		return data
+	}
+	var buf bytes.Buffer
+	buf.Write(data[:m[4]])
+	buf.WriteString("UTF-8")
+	for _, b := range data[m[5]:] {
+		buf.WriteRune(rune(b))
+	}
+	return buf.Bytes()
+}
+
+// setAttr sets the value of the attribute of the element, adding it if missing
+func setAttr(n *Node, name, value string) {
+	for i, a := range n.Attr {
+		if xmlName(a.Name) == name {
+			n.Attr[i].Value = value
+			return
+		}
+	}
+	n.Attr = append(n.Attr, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+}
+
+// upgrade rewrites the children of the node
+func (u *upgrader) upgrade(n *Node, pattern bool) {
+	var children []*Node
+	for _, c := range n.Children {
+		switch c.Kind {
+		case TextNode:
+			if pattern {
+				u.upgradePattern(c)
+			}
+			children = append(children, c)
+			continue
+		case ElementNode:
+		default:
+			children = append(children, c)
+			continue
+		}
+		c.Name = strings.TrimPrefix(c.Name, "html:")
+		if legacy, ok := legacyTags[c.Name]; ok {
+			c.Name = legacy[0]
+			setAttr(c, "index", legacy[1])
+		}
+		switch {
+		case strings.HasPrefix(c.Name, "get_"), strings.HasPrefix(c.Name, "set_"):
+			predicate := c.Name[len("get_"):]
+			c.Name = c.Name[:len("get")]
+			setAttr(c, "name", predicate)
+		case c.Name == "gossip", c.Name == "secure":
+			u.add(c.Line, SeverityWarning, "<%s> is not supported by AIML 2.0, it was replaced by its content", c.Name)
+			u.upgrade(c, pattern)
+			children = append(children, c.Children...)
+			continue
+		case c.Name == "personf":
+			c.Name = "person"
+			u.add(c.Line, SeverityWarning, "<personf> was replaced by <person>, which does not URL encode the text")
+		case c.Name == "javascript", c.Name == "system":
+			u.add(c.Line, SeverityError, "<%s> is not supported by pandorabots, use <sraix> or <oob> instead", c.Name)
+		case c.Name == "learn" && len(c.Elements("category")) == 0:
+			u.add(c.Line, SeverityError, "<learn> takes categories in AIML 2.0, not the file to load")
+		case c.Name == "condition" || c.Name == "li":
+			for _, attr := range []string{"contains", "exists"} {
+				if c.Attribute(attr) != "" {
+					u.add(c.Line, SeverityError, "The %s attribute of <%s> is not supported by AIML 2.0", attr, c.Name)
+				}
+			}
+		case c.Name == "if":
+			u.add(c.Line, SeverityError, "<if> is not supported by AIML 2.0, use <condition> instead")
+		}
+		u.upgrade(c, pattern || c.Name == "pattern" || c.Name == "that" && n.Name == "category" || c.Name == "topic" && n.Name == "category")
+		children = append(children, c)
+	}
+	n.Children = children
+}
+
+// upgradePattern upper cases the words of a pattern, reporting the AIML 2.0 wildcards
+func (u *upgrader) upgradePattern(n *Node) {
+	n.Text = strings.ToUpper(n.Text)
+	for _, w := range strings.Fields(n.Text) {
+		switch {
+		case w == "#" || w == "^":
+			u.add(n.Line, SeverityWarning, "%s is a zero or more words wildcard in AIML 2.0", w)
+		case strings.HasPrefix(w, "$"):
+			u.add(n.Line, SeverityWarning, "%s has priority over the other patterns in AIML 2.0", w)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// AnonymizeSaltEnv is the environment variable of the salt of the pseudonyms, as used by pbcli
+const AnonymizeSaltEnv = "PB_ANONYMIZE_SALT"
+
+// Anonymizer replaces the client names by pseudonyms, the HMAC-SHA256 of the
+// name keyed by a secret salt, so the names of the end users, often emails or
+// phone numbers, are neither sent to pandorabots nor stored in the
+// transcripts. The same name always has the same pseudonym, so the bot memory
+// and the sessions of the users are kept. The mapping from the pseudonyms
+// back to the names is only kept in a local Storage, sealed by the Encryptor.
+type Anonymizer struct {
+	Prefix    string     // The prefix of the pseudonyms, anon- by default
+	Storage   Storage    // Keeps the mapping to the client names, nil to not keep it
+	Encryptor *Encryptor // Seals the mapping, nil to keep it as is
+
+	salt  []byte
+	mu    sync.Mutex
+	saved map[string]bool // The pseudonyms already in the storage
+}
+
+// NewAnonymizer creates an anonymizer with the salt, which must be kept
+// secret and at least 16 bytes, keeping the mapping in the storage
+func NewAnonymizer(salt []byte, storage Storage, e *Encryptor) (*Anonymizer, error) {
+	if len(salt) < 16 {
+		return nil, fmt.Errorf("Anonymization salt is not valid - it must be at least 16 bytes, not %d", len(salt))
+	}
+	return &Anonymizer{Prefix: "anon-", Storage: storage, Encryptor: e, salt: salt, saved: make(map[string]bool)}, nil
+}
+
+// hash returns the pseudonym of the client name without keeping the mapping
+func (a *Anonymizer) hash(clientName string) string {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(clientName))
+	return a.Prefix + hex.EncodeToString(mac.Sum(nil)[:12])
+}
+
+// pseudonymOf returns the pseudonym of the client name, or the client name if it is a pseudonym, without keeping the mapping
+func (a *Anonymizer) pseudonymOf(clientName string) string {
+	if a.IsPseudonym(clientName) {
+		return clientName
+	}
+	return a.hash(clientName)
+}
+
+// mappingName returns the name of the mapping of the pseudonym in the storage
+func mappingName(pseudonym string) string {
+	return "pseudonyms/" + pseudonym
+}
+
+// IsPseudonym reports whether the client name is a pseudonym of the anonymizer
+func (a *Anonymizer) IsPseudonym(clientName string) bool {
+	return strings.HasPrefix(clientName, a.Prefix) && len(clientName) == len(a.Prefix)+24
+}
+
+// Pseudonym returns the pseudonym of the client name, keeping the mapping the
+// first time. Empty names and pseudonyms are returned as is.
+func (a *Anonymizer) Pseudonym(clientName string) (string, error) {
+	if clientName == "" || a.IsPseudonym(clientName) {
+		return clientName, nil
+	}
+	pseudonym := a.hash(clientName)
+	if a.Storage == nil {
+		return pseudonym, nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.saved[pseudonym] {
+		return pseudonym, nil
+	}
+	data, err := a.Encryptor.Seal([]byte(clientName))
+	if err != nil {
+		return "", err
+	}
+	if err = a.Storage.Put(mappingName(pseudonym), bytes.NewReader(data)); err != nil {
+		return "", fmt.Errorf("Unable to keep the pseudonym of the client name - %v", err)
+	}
+	a.saved[pseudonym] = true
+	return pseudonym, nil
+}
+
+// Reveal returns the client name of the pseudonym from the mapping
+func (a *Anonymizer) Reveal(pseudonym string) (string, error) {
+	if a.Storage == nil {
+		return "", fmt.Errorf("Pseudonym cannot be revealed [%s] - the mapping is not kept", pseudonym)
+	}
+	var buf bytes.Buffer
+	if err := a.Storage.Get(mappingName(pseudonym), &buf); err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("Pseudonym is not known [%s]", pseudonym)
+		}
+		return "", err
+	}
+	data, err := a.Encryptor.Open(buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Forget deletes the mapping of the client name or pseudonym, so the pseudonym cannot be revealed anymore
+func (a *Anonymizer) Forget(clientName string) error {
+	if a.Storage == nil {
+		return nil
+	}
+	pseudonym := a.pseudonymOf(clientName)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.saved, pseudonym)
+	return a.Storage.Delete(mappingName(pseudonym))
+}
+
+// SetAnonymizer sends the pseudonyms of the client names of the talk requests
+// instead of the names. Wrap the transcript stores with Transcripts to store
+// the pseudonyms too.
+func SetAnonymizer(a *Anonymizer) OptionFunc {
+	return func(c *Client) error {
+		c.anonymizer = a
+		return nil
+	}
+}
+
+// Anonymized returns the pseudonym sent for the client name with
+// SetAnonymizer, or the client name without an anonymizer. Pass the client
+// names through it before sending them off-host, e.g. to webhooks or logs.
+func (c *Client) Anonymized(clientName string) string {
+	if c.anonymizer == nil || clientName == "" {
+		return clientName
+	}
+	return c.anonymizer.pseudonymOf(clientName)
+}
+
+// Transcripts returns a TranscriptStore storing the entries in the store with
+// the pseudonyms of the client names. The queries and the deletions by client
+// name select the entries of its pseudonym, and the deletions forget the
+// mapping, so nothing links the remaining data to the end user.
+func (a *Anonymizer) Transcripts(store TranscriptStore) TranscriptStore {
+	return &anonymizedTranscripts{a: a, store: store}
+}
+
+type anonymizedTranscripts struct {
+	a     *Anonymizer
+	store TranscriptStore
+}
+
+func (t *anonymizedTranscripts) Append(e *TranscriptEntry) error {
+	anonymized := *e
+	var err error
+	if anonymized.ClientName, err = t.a.Pseudonym(e.ClientName); err != nil {
+		return err
+	}
+	err = t.store.Append(&anonymized)
+	e.Turn = anonymized.Turn
+	return err
+}
+
+func (t *anonymizedTranscripts) Query(q TranscriptQuery) ([]TranscriptEntry, error) {
+	if q.ClientName != "" {
+		q.ClientName = t.a.pseudonymOf(q.ClientName)
+	}
+	return t.store.Query(q)
+}
+
+func (t *anonymizedTranscripts) Delete(clientName string) (int, error) {
+	pseudonym := t.a.pseudonymOf(clientName)
+	n, err := t.store.Delete(pseudonym)
+	if err != nil {
+		return n, err
+	}
+	return n, t.a.Forget(pseudonym)
+}
@@ -0,0 +1,182 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sort"
+)
+
+// ProgressFunc is called after each file of a long running operation is processed.
+// done is the number of files processed so far out of total and err is the
+// outcome for the file.
+type ProgressFunc func(file string, done, total int, err error)
+
+// CopyOptions controls how files are copied into a bot by CloneBot and Restore
+type CopyOptions struct {
+	Create   bool         // Create the target bot if it does not exist
+	Delete   bool         // Delete files of the target bot that are not copied
+	Verify   bool         // Verify the target bot after copying
+	Progress ProgressFunc // Optional progress callback
+}
+
+// botExists checks if the bot is one of the application bots
+func (c *Client) botExists(name string) (bool, error) {
+	bots, err := c.List()
+	if err != nil {
+		return false, err
+	}
+	for _, b := range bots {
+		if b.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// copyContents uploads the files to the bot according to the options,
+// reporting the progress as the operation. It stops at the first failure.
+func (c *Client) copyContents(operation, name string, contents map[string][]byte, opts CopyOptions) error {
+	if opts.Create {
+		exists, err := c.botExists(name)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if err = c.CreateBot(name); err != nil {
+				return err
+			}
+		}
+	}
+	var stale []string
+	if opts.Delete {
+		list, err := c.ListFiles(name)
+		if err != nil {
+			return err
+		}
+		keys := make(map[string]bool)
+		for file := range contents {
+			keys[fileKey(file)] = true
+		}
+		for _, file := range remoteFileNames(list) {
+			if !keys[fileKey(file)] {
+				stale = append(stale, file)
+			}
+		}
+	}
+	files := make([]string, 0, len(contents))
+	for file := range contents {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	total := len(files) + len(stale)
+	t := c.track(operation, name, total)
+	for i, file := range files {
+		err := c.UploadFile(name, file, bytes.NewReader(contents[file]))
+		if opts.Progress != nil {
+			opts.Progress(file, i+1, total, err)
+		}
+		t.step(file, "upload", i+1, err)
+		if err != nil {
+			return t.finish(i, err)
+		}
+	}
+	for i, file := range stale {
+		err := c.DeleteFile(name, file)
+		if opts.Progress != nil {
+			opts.Progress(file, len(files)+i+1, total, err)
+		}
+		t.step(file, "delete", len(files)+i+1, err)
+		if err != nil {
+			return t.finish(len(files)+i, err)
+		}
+	}
+	if opts.Verify {
+		t.verify(total)
+		return t.finish(total, c.Verify(name))
+	}
+	return t.finish(total, nil)
+}
+
+// CloneBot copies all the files of the src bot to the dst bot.
+func (c *Client) CloneBot(src, dst string, opts CopyOptions) error {
+	contents, err := c.fileContents(src)
+	if err != nil {
+		return err
+	}
+	return c.copyContents("clone", dst, contents, opts)
+}
+
+// Backup writes all the files of the bot to w as a zip archive
+// which can later be used with Restore.
+func (c *Client) Backup(name string, w io.Writer) error {
+	t := c.track("backup", name, 1)
+	err := c.DownloadFiles(name, w)
+	if err == nil {
+		t.step(name+".zip", "download", 1, nil)
+		return t.finish(1, nil)
+	}
+	return t.finish(0, err)
+}
+
+// BackupToPath writes the backup zip archive of the bot to path, encrypted if the client has an Encryptor
+func (c *Client) BackupToPath(name, path string) error {
+	var buf bytes.Buffer
+	if err := c.Backup(name, &buf); err != nil {
+		return err
+	}
+	return c.encryptor.WriteFile(path, buf.Bytes(), 0600)
+}
+
+// Restore uploads the files of a backup zip archive to the bot. An encrypted
+// archive is decrypted with the Encryptor of the client.
+func (c *Client) Restore(name string, backup io.Reader, opts CopyOptions) error {
+	data, err := io.ReadAll(backup)
+	if err != nil {
+		return err
+	}
+	if data, err = c.encryptor.Open(data); err != nil {
+		return err
+	}
+	contents, err := unzipFiles(data)
+	if err != nil {
+		return err
+	}
+	return c.copyContents("restore", name, contents, opts)
+}
+
+// RestoreFromPath uploads the files of the backup zip archive at path to the bot
+func (c *Client) RestoreFromPath(name, path string, opts CopyOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.Restore(name, f, opts)
+}
+
+// BackupToStorage stores the backup zip archive of the bot in the storage under key, encrypted if the client has an Encryptor
+func (c *Client) BackupToStorage(name string, s Storage, key string) error {
+	var buf bytes.Buffer
+	if err := c.Backup(name, &buf); err != nil {
+		return err
+	}
+	data, err := c.encryptor.Seal(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return s.Put(key, bytes.NewReader(data))
+}
+
+// RestoreFromStorage uploads the files of the backup zip archive stored in the storage under key to the bot
+func (c *Client) RestoreFromStorage(name string, s Storage, key string, opts CopyOptions) error {
+	var buf bytes.Buffer
+	if err := s.Get(key, &buf); err != nil {
+		return err
+	}
+	return c.Restore(name, &buf, opts)
+}
@@ -0,0 +1,139 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupTimeFormat is the UTC timestamp in the names of the scheduled backups
+const backupTimeFormat = "20060102-150405"
+
+// BackupRetention is which backups of each bot a BackupScheduler keeps. The
+// most recent backup is always kept.
+type BackupRetention struct {
+	Keep   int           // The number of most recent backups kept, zero for no limit
+	MaxAge time.Duration // The older backups are deleted, zero for no limit
+}
+
+// BackupResult is the outcome of the backup of a bot by a BackupScheduler
+type BackupResult struct {
+	Bot     string
+	Name    string   // The name of the backup in the storage
+	Deleted []string // The older backups deleted by the retention policy
+	Err     error
+}
+
+// BackupScheduler backs up bots to a storage on a schedule, deleting the
+// backups the retention policy does not keep. The backups of a bot are
+// named BOT/BOT-TIMESTAMP.zip and can be restored with RestoreFromStorage.
+type BackupScheduler struct {
+	Client *Client
+	// Bots are the bots to back up, empty for all the bots of the application at each run
+	Bots      []string
+	Schedule  Schedule
+	Storage   Storage
+	Retention BackupRetention
+	// OnBackup receives the outcome of each backup, nil to log the failures to the error log of the client
+	OnBackup func(r BackupResult)
+}
+
+// NewBackupScheduler creates a scheduler backing up the bots, all the bots of the application if none is given
+func NewBackupScheduler(c *Client, schedule Schedule, storage Storage, bots ...string) *BackupScheduler {
+	return &BackupScheduler{Client: c, Schedule: schedule, Storage: storage, Bots: bots}
+}
+
+// BackupNow backs up the bots at once and applies the retention policy
+func (s *BackupScheduler) BackupNow() ([]BackupResult, error) {
+	bots := s.Bots
+	if len(bots) == 0 {
+		list, err := s.Client.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range list {
+			bots = append(bots, b.Name)
+		}
+	}
+	var results []BackupResult
+	for _, bot := range bots {
+		now := time.Now()
+		r := BackupResult{Bot: bot, Name: bot + "/" + bot + "-" + now.UTC().Format(backupTimeFormat) + ".zip"}
+		if r.Err = s.Client.BackupToStorage(bot, s.Storage, r.Name); r.Err == nil {
+			r.Deleted, r.Err = s.prune(bot, now)
+		}
+		if s.OnBackup != nil {
+			s.OnBackup(r)
+		} else if r.Err != nil {
+			s.Client.errorf("Backup of %s failed - %v", bot, r.Err)
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// prune deletes the backups of the bot the retention policy does not keep
+func (s *BackupScheduler) prune(bot string, now time.Time) ([]string, error) {
+	if s.Retention.Keep <= 0 && s.Retention.MaxAge <= 0 {
+		return nil, nil
+	}
+	prefix := bot + "/" + bot + "-"
+	names, err := s.Storage.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+	type backup struct {
+		name string
+		at   time.Time
+	}
+	var backups []backup
+	for _, name := range names {
+		// The other files are not backups of the scheduler
+		at, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".zip"))
+		if err == nil && strings.HasSuffix(name, ".zip") {
+			backups = append(backups, backup{name, at})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].at.After(backups[j].at)
+	})
+	var deleted []string
+	for i, b := range backups {
+		if i == 0 {
+			continue
+		}
+		if s.Retention.Keep > 0 && i >= s.Retention.Keep || s.Retention.MaxAge > 0 && now.Sub(b.at) > s.Retention.MaxAge {
+			if err := s.Storage.Delete(b.name); err != nil {
+				return deleted, err
+			}
+			deleted = append(deleted, b.name)
+		}
+	}
+	return deleted, nil
+}
+
+// Run backs up the bots on the schedule until the context is cancelled
+func (s *BackupScheduler) Run(ctx context.Context) error {
+	for {
+		next := s.Schedule.Next(time.Now())
+		if next.IsZero() {
+			return errors.New("Schedule never runs")
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		if _, err := s.BackupNow(); err != nil {
+			s.Client.errorf("Scheduled backup failed - %v", err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultParallelism is how many bots or files the batch operations process at once
+const DefaultParallelism = 4
+
+// SetParallelism sets how many bots or files the batch operations, like
+// ForEachBot, process at once. Zero means DefaultParallelism.
+func SetParallelism(n int) OptionFunc {
+	return func(c *Client) error {
+		if n < 0 {
+			return errors.New("Parallelism cannot be negative")
+		}
+		c.parallelism = n
+		return nil
+	}
+}
+
+// BatchError holds the errors of the items of a batch operation which failed
+type BatchError struct {
+	Errors map[string]error // The errors by bot or file name
+	Total  int              // The number of items of the operation
+}
+
+func (e *BatchError) Error() string {
+	names := e.Names()
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s: %v", name, e.Errors[name])
+	}
+	return fmt.Sprintf("%d of %d failed - %s", len(names), e.Total, strings.Join(parts, "; "))
+}
+
+// Unwrap returns the errors, so errors.Is and errors.As match any of them
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, name := range e.Names() {
+		errs = append(errs, e.Errors[name])
+	}
+	return errs
+}
+
+// Names returns the sorted names of the failed items
+func (e *BatchError) Names() []string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForEach calls fn for each name, with at most parallelism calls at once, zero
+// for DefaultParallelism. All the names are processed even if some fail, and
+// the failures are returned in a *BatchError. Once the context is done the
+// names not started yet fail with the error of the context.
+func ForEach(ctx context.Context, names []string, parallelism int, fn func(ctx context.Context, name string) error) error {
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+	var (
+		mu   sync.Mutex
+		errs = make(map[string]error)
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, parallelism)
+	)
+	fail := func(name string, err error) {
+		mu.Lock()
+		errs[name] = err
+		mu.Unlock()
+	}
+	for _, name := range names {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			fail(name, err)
+			continue
+		}
+		wg.Add(1)
+		go func(name string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := fn(ctx, name); err != nil {
+				fail(name, err)
+			}
+		}(name)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return &BatchError{Errors: errs, Total: len(names)}
+	}
+	return nil
+}
+
+// ForEachBot calls fn for each bot of the application in parallel, like ForEach
+// with the parallelism of SetParallelism, e.g. to verify all the bots
+func (c *Client) ForEachBot(ctx context.Context, fn func(ctx context.Context, bot BotEntry) error) error {
+	bots, err := c.List()
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]BotEntry, len(bots))
+	names := make([]string, len(bots))
+	for i, b := range bots {
+		byName[b.Name], names[i] = b, b.Name
+	}
+	return ForEach(ctx, names, c.parallelism, func(ctx context.Context, name string) error {
+		return fn(ctx, byName[name])
+	})
+}
+
+// ForEachFile calls fn for each file of the bot in parallel, like ForEach with
+// the parallelism of SetParallelism. The file names have their extension, as
+// GetFile and UploadFile expect them.
+func (c *Client) ForEachFile(ctx context.Context, bot string, fn func(ctx context.Context, filename string) error) error {
+	files, err := c.ListFiles(bot)
+	if err != nil {
+		return err
+	}
+	return ForEach(ctx, remoteFileNames(files), c.parallelism, fn)
+}
@@ -0,0 +1,153 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BenchOptions control a load test
+type BenchOptions struct {
+	Sessions int                                   // Number of concurrent conversations, at least 1
+	Duration time.Duration                         // How long to run. Zero runs until Requests are sent.
+	Requests int                                   // Stop after this many requests, zero for no limit
+	Inputs   []string                              // The inputs each session sends in turn. Defaults to "Hello".
+	Progress func(done int, elapsed time.Duration) // Called after each request, from the session goroutines
+}
+
+// BenchResult is the summary of a load test
+type BenchResult struct {
+	Bot        string        `json:"bot"`
+	Sessions   int           `json:"sessions"`
+	Requests   int           `json:"requests"`
+	Errors     int           `json:"errors"`
+	Elapsed    time.Duration `json:"elapsed"`
+	Throughput float64       `json:"throughput"` // Successful requests per second
+	Min        time.Duration `json:"min"`
+	Mean       time.Duration `json:"mean"`
+	P50        time.Duration `json:"p50"`
+	P90        time.Duration `json:"p90"`
+	P95        time.Duration `json:"p95"`
+	P99        time.Duration `json:"p99"`
+	Max        time.Duration `json:"max"`
+	FirstError string        `json:"firstError,omitempty"`
+}
+
+// percentile returns the latency below which p percent of the sorted latencies fall
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted))*p/100+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+// Bench load tests the bot with concurrent sessions and reports the latency
+// distribution of the talk requests. Failed requests are counted, not returned.
+func (c *Client) Bench(ctx context.Context, bot string, opts BenchOptions) (*BenchResult, error) {
+	if opts.Sessions < 1 {
+		return nil, fmt.Errorf("Sessions must be at least 1")
+	}
+	if opts.Duration <= 0 && opts.Requests <= 0 {
+		return nil, fmt.Errorf("Either a duration or a number of requests is required")
+	}
+	inputs := opts.Inputs
+	if len(inputs) == 0 {
+		inputs = []string{"Hello"}
+	}
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		result    = &BenchResult{Bot: bot, Sessions: opts.Sessions}
+		wg        sync.WaitGroup
+	)
+	// take reserves the next request, returning false once the limit is reached
+	take := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if opts.Requests > 0 && result.Requests >= opts.Requests {
+			return false
+		}
+		result.Requests++
+		return true
+	}
+	tracker := c.track("bench", bot, opts.Requests)
+	lastEvent := time.Duration(0)
+	start := time.Now()
+	for s := 0; s < opts.Sessions; s++ {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+			clientName := fmt.Sprintf("pbbench-%d-%d", start.UnixNano(), s)
+			sessionId := 0
+			for i := 0; ctx.Err() == nil && take(); i++ {
+				t := time.Now()
+				reply, err := c.Talk(bot, inputs[i%len(inputs)], clientName, sessionId, false)
+				elapsed := time.Since(t)
+				mu.Lock()
+				if err != nil {
+					result.Errors++
+					if result.FirstError == "" {
+						result.FirstError = err.Error()
+					}
+				} else {
+					sessionId = reply.SessionId
+					latencies = append(latencies, elapsed)
+				}
+				done := result.Requests
+				// The events are sent once a second at most, not for each request
+				report := time.Since(start)-lastEvent >= time.Second
+				if report {
+					lastEvent = time.Since(start)
+				}
+				mu.Unlock()
+				if opts.Progress != nil {
+					opts.Progress(done, time.Since(start))
+				}
+				if report {
+					tracker.step("", "talk", done, nil)
+				}
+			}
+		}(s)
+	}
+	wg.Wait()
+	result.Elapsed = time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	if n := len(latencies); n > 0 {
+		var total time.Duration
+		for _, l := range latencies {
+			total += l
+		}
+		result.Min, result.Max, result.Mean = latencies[0], latencies[n-1], total/time.Duration(n)
+		result.P50 = percentile(latencies, 50)
+		result.P90 = percentile(latencies, 90)
+		result.P95 = percentile(latencies, 95)
+		result.P99 = percentile(latencies, 99)
+		result.Throughput = float64(n) / result.Elapsed.Seconds()
+	}
+	var err error
+	if result.Errors > 0 {
+		err = fmt.Errorf("%d of %d requests failed", result.Errors, result.Requests)
+	}
+	tracker.finish(result.Requests, err)
+	return result, nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AliasTarget is the bot a routing alias points at
+type AliasTarget struct {
+	Bot      string    `json:"bot"`
+	Previous string    `json:"previous,omitempty"` // The bot the alias pointed at before the last switch
+	Switched time.Time `json:"switched"`
+}
+
+// Aliases maps routing aliases to bots. The aliases are kept in a Storage,
+// named aliases/ALIAS, so the gateways resolving an alias follow the switches
+// of a BlueGreen deployment, even from another host with an S3Storage.
+type Aliases struct {
+	Storage Storage
+	TTL     time.Duration // How long a resolved alias is cached, zero to read the storage on each Resolve
+
+	mu    sync.Mutex
+	cache map[string]cachedAlias
+}
+
+type cachedAlias struct {
+	bot string
+	at  time.Time
+}
+
+// NewAliases creates the aliases kept in the storage, cached for 5 seconds
+func NewAliases(s Storage) *Aliases {
+	return &Aliases{Storage: s, TTL: 5 * time.Second}
+}
+
+func aliasKey(alias string) string {
+	return "aliases/" + alias
+}
+
+// Get returns the target of the alias, failing with an error satisfying os.IsNotExist if the alias is not set
+func (a *Aliases) Get(alias string) (*AliasTarget, error) {
+	var buf bytes.Buffer
+	if err := a.Storage.Get(aliasKey(alias), &buf); err != nil {
+		return nil, err
+	}
+	t := &AliasTarget{}
+	if err := json.Unmarshal(buf.Bytes(), t); err != nil {
+		return nil, fmt.Errorf("Alias [%s] is not valid - %v", alias, err)
+	}
+	return t, nil
+}
+
+// Resolve returns the bot the alias points at
+func (a *Aliases) Resolve(alias string) (string, error) {
+	a.mu.Lock()
+	cached, ok := a.cache[alias]
+	a.mu.Unlock()
+	if ok && time.Since(cached.at) < a.TTL {
+		return cached.bot, nil
+	}
+	t, err := a.Get(alias)
+	if err != nil {
+		return "", err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cache == nil {
+		a.cache = make(map[string]cachedAlias)
+	}
+	a.cache[alias] = cachedAlias{t.Bot, time.Now()}
+	return t.Bot, nil
+}
+
+// Set points the alias at the bot, keeping the bot it pointed at as the previous one
+func (a *Aliases) Set(alias, bot string) error {
+	t := AliasTarget{Bot: bot, Switched: time.Now().UTC()}
+	current, err := a.Get(alias)
+	switch {
+	case err == nil && current.Bot != bot:
+		t.Previous = current.Bot
+	case err == nil:
+		t.Previous = current.Previous
+	case !os.IsNotExist(err):
+		return err
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if err = a.Storage.Put(aliasKey(alias), bytes.NewReader(data)); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.cache, alias)
+	return nil
+}
+
+// BlueGreen deploys a bot as two bots, NAME-blue and NAME-green, behind the
+// NAME routing alias. New content goes to the idle bot, which only becomes
+// live once it compiles and passes the regression suites. The previous bot
+// is left as is, so rolling back is an instant switch of the alias.
+type BlueGreen struct {
+	Client  *Client
+	Aliases *Aliases
+	Name    string   // The alias of the deployment
+	Suites  []*Suite // The regression suites the idle bot must pass before the switch
+}
+
+// DeployResult is the outcome of a BlueGreen deployment
+type DeployResult struct {
+	Bot      string         `json:"bot"`      // The idle bot the content was deployed to
+	Previous string         `json:"previous"` // The live bot before the deployment, empty for the first one
+	Sync     *SyncResult    `json:"sync"`
+	Suites   []*SuiteResult `json:"suites"`
+	Switched bool           `json:"switched"` // True if the alias now points at the deployed bot
+}
+
+// NewBlueGreen creates the blue/green deployment of the name alias
+func NewBlueGreen(c *Client, aliases *Aliases, name string) *BlueGreen {
+	return &BlueGreen{Client: c, Aliases: aliases, Name: name}
+}
+
+// Bots returns the names of the two bots of the deployment
+func (b *BlueGreen) Bots() (blue, green string) {
+	return b.Name + "-blue", b.Name + "-green"
+}
+
+// Live returns the bot the alias points at, empty if nothing was deployed yet
+func (b *BlueGreen) Live() (string, error) {
+	t, err := b.Aliases.Get(b.Name)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return t.Bot, nil
+}
+
+// Idle returns the bot the next deployment goes to, the blue one if nothing was deployed yet
+func (b *BlueGreen) Idle() (string, error) {
+	live, err := b.Live()
+	if err != nil {
+		return "", err
+	}
+	return b.other(live), nil
+}
+
+// other returns the bot of the deployment which is not the live one
+func (b *BlueGreen) other(live string) string {
+	blue, green := b.Bots()
+	if live == blue {
+		return green
+	}
+	return blue
+}
+
+// Deploy uploads the personality files of dir to the idle bot, deleting the
+// files dir does not have, verifies it and runs the regression suites. If
+// they all pass the alias is switched to the idle bot, otherwise the live bot
+// keeps receiving the traffic and the error is returned with the result.
+func (b *BlueGreen) Deploy(dir string) (*DeployResult, error) {
+	live, err := b.Live()
+	if err != nil {
+		return nil, err
+	}
+	idle := b.other(live)
+	exists, err := b.Client.botExists(idle)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err = b.Client.CreateBot(idle); err != nil {
+			return nil, err
+		}
+	}
+	result := &DeployResult{Bot: idle, Previous: live}
+	if result.Sync, err = b.Client.SyncDir(idle, dir, SyncOptions{Delete: true, Verify: true}); err != nil {
+		return nil, err
+	}
+	if failed := result.Sync.Failed(); failed > 0 {
+		return result, fmt.Errorf("%d of %d actions failed deploying to bot [%s]", failed, len(result.Sync.Actions), idle)
+	}
+	if result.Sync.VerifyErr != nil {
+		return result, result.Sync.VerifyErr
+	}
+	failed := 0
+	for _, s := range b.Suites {
+		res, err := b.Client.RunSuite(idle, s)
+		if err != nil {
+			return result, err
+		}
+		result.Suites = append(result.Suites, res)
+		failed += res.Failed
+	}
+	if failed > 0 {
+		return result, fmt.Errorf("%d regression cases failed on bot [%s] - the alias was not switched", failed, idle)
+	}
+	if err = b.Aliases.Set(b.Name, idle); err != nil {
+		return result, err
+	}
+	result.Switched = true
+	return result, nil
+}
+
+// Rollback switches the alias back to the bot it pointed at before the last
+// deployment and returns it
+func (b *BlueGreen) Rollback() (string, error) {
+	t, err := b.Aliases.Get(b.Name)
+	if os.IsNotExist(err) || (err == nil && t.Previous == "") {
+		return "", fmt.Errorf("Deployment [%s] has no previous bot to roll back to", b.Name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return t.Previous, b.Aliases.Set(b.Name, t.Previous)
+}
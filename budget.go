@@ -0,0 +1,272 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The errors of the calls of a Budget. Unavailable reports both.
+var (
+	ErrBudgetExhausted = errors.New("Latency budget is exhausted, the call was skipped")
+	ErrCallDeadline    = errors.New("Call exceeded its deadline of the latency budget")
+)
+
+// BudgetCall is the outcome of a call of a Budget
+type BudgetCall struct {
+	Name     string
+	Deadline time.Duration // The time the call was allowed, -1 without a limit
+	Elapsed  time.Duration
+	Skipped  bool  // The call was not made, there was not enough budget left
+	TimedOut bool  // The call did not return before its deadline
+	Err      error // The error of the call, ErrBudgetExhausted if skipped and ErrCallDeadline if timed out
+}
+
+// Budget bounds the latency of a fan-out, like a fallback chain or a canary
+// compare, by allocating the deadlines of its calls from an overall budget
+// counted from its creation. It reports the calls made, timed out and
+// skipped. Like the hedged requests, the calls are not cancelled at their
+// deadline, their replies are discarded.
+type Budget struct {
+	Total time.Duration // The overall budget, zero for no limit
+	// MinCall is the least deadline of a call, whatever the reserve of the
+	// next calls, and the time kept for each fallback of a FallbackChain.
+	// Defaults to 50 milliseconds.
+	MinCall time.Duration
+
+	start time.Time
+	mu    sync.Mutex
+	calls []BudgetCall
+}
+
+// NewBudget starts a budget of total for the calls of a fan-out, giving them at least minCall
+func NewBudget(total, minCall time.Duration) *Budget {
+	return &Budget{Total: total, MinCall: minCall, start: time.Now()}
+}
+
+func (b *Budget) minCall() time.Duration {
+	if b.MinCall <= 0 {
+		return 50 * time.Millisecond
+	}
+	return b.MinCall
+}
+
+// Remaining returns the time left in the budget, or -1 if there is no limit
+func (b *Budget) Remaining() time.Duration {
+	if b.Total <= 0 {
+		return -1
+	}
+	if left := b.Total - time.Since(b.start); left > 0 {
+		return left
+	}
+	return 0
+}
+
+// Call runs the talk with the time left in the budget minus reserve as its
+// deadline, keeping the reserve for the next calls, e.g. the fallbacks of a
+// chain, but at least MinCall if the budget allows. The call is skipped,
+// returning ErrBudgetExhausted, if less than half of MinCall is left. It is
+// safe to make calls concurrently.
+func (b *Budget) Call(name string, reserve time.Duration, talk func() (*Reply, error)) (*Reply, error) {
+	call := BudgetCall{Name: name, Deadline: -1}
+	if left := b.Remaining(); left >= 0 {
+		if left < b.minCall()/2 {
+			call.Deadline, call.Skipped, call.Err = 0, true, ErrBudgetExhausted
+			b.record(call)
+			return nil, call.Err
+		}
+		call.Deadline = left - reserve
+		if call.Deadline < b.minCall() {
+			call.Deadline = b.minCall()
+		}
+		if call.Deadline > left {
+			call.Deadline = left
+		}
+	}
+	type result struct {
+		reply *Reply
+		err   error
+	}
+	results := make(chan result, 1)
+	start := time.Now()
+	go func() {
+		reply, err := talk()
+		results <- result{reply, err}
+	}()
+	var r result
+	if call.Deadline < 0 {
+		r = <-results
+	} else {
+		timer := time.NewTimer(call.Deadline)
+		defer timer.Stop()
+		select {
+		case r = <-results:
+		case <-timer.C:
+			r.err, call.TimedOut = ErrCallDeadline, true
+		}
+	}
+	call.Elapsed, call.Err = time.Since(start), r.err
+	b.record(call)
+	return r.reply, r.err
+}
+
+func (b *Budget) record(call BudgetCall) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, call)
+}
+
+// Calls returns the outcome of the calls, in the order they ended
+func (b *Budget) Calls() []BudgetCall {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]BudgetCall(nil), b.calls...)
+}
+
+// Skipped returns the names of the calls skipped for lack of budget
+func (b *Budget) Skipped() []string {
+	var names []string
+	for _, c := range b.Calls() {
+		if c.Skipped {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// NamedTalk is a talk of a fan-out, named in the reports of its Budget
+type NamedTalk struct {
+	Name string
+	Talk TalkFunc
+}
+
+// FallbackChain tries the fallbacks in order when the talk fails, e.g. a
+// simpler bot then a FAQ bot, all within the latency budget. Each call keeps
+// MinCall of the budget for every fallback after it, so a slow primary bot
+// cannot starve the chain. Add its Middleware to a Conversation.
+type FallbackChain struct {
+	Fallbacks []NamedTalk
+	Budget    time.Duration // The latency budget of the chain, zero for no limit
+	MinCall   time.Duration // See Budget.MinCall
+	// Errors reports whether the error moves to the next fallback, the other
+	// errors are returned. Defaults to Unavailable.
+	Errors func(err error) bool
+	// OnReport receives the calls of each input, e.g. to log the skipped ones, nil to ignore them
+	OnReport func(key string, calls []BudgetCall)
+}
+
+// NewFallbackChain creates a chain of the fallbacks within the latency budget
+func NewFallbackChain(budget time.Duration, fallbacks ...NamedTalk) *FallbackChain {
+	return &FallbackChain{Fallbacks: fallbacks, Budget: budget}
+}
+
+// Middleware returns the middleware trying the fallbacks when the next talk fails.
+// The replies list the skipped calls in their Info.
+func (fc *FallbackChain) Middleware() Middleware {
+	errs := fc.Errors
+	if errs == nil {
+		errs = Unavailable
+	}
+	return func(next TalkFunc) TalkFunc {
+		return func(key, input string) (*Reply, error) {
+			b := NewBudget(fc.Budget, fc.MinCall)
+			links := append([]NamedTalk{{Name: "primary", Talk: next}}, fc.Fallbacks...)
+			var reply *Reply
+			err := ErrBudgetExhausted
+			for i, l := range links {
+				reserve := time.Duration(len(links)-1-i) * b.minCall()
+				talk := l.Talk
+				r, callErr := b.Call(l.Name, reserve, func() (*Reply, error) {
+					return talk(key, input)
+				})
+				if callErr != ErrBudgetExhausted {
+					reply, err = r, callErr
+				}
+				if callErr == nil || !errs(callErr) {
+					break
+				}
+			}
+			if fc.OnReport != nil {
+				fc.OnReport(key, b.Calls())
+			}
+			if err != nil {
+				return nil, err
+			}
+			if skipped := b.Skipped(); len(skipped) > 0 {
+				res := *reply
+				res.Info.Skipped = skipped
+				reply = &res
+			}
+			return reply, nil
+		}
+	}
+}
+
+// CanaryResult compares the replies of the talk and of the canary to an input
+type CanaryResult struct {
+	Key         string
+	Input       string
+	Reply       *Reply // The reply sent to the user
+	Err         error
+	CanaryReply *Reply
+	CanaryErr   error // ErrBudgetExhausted or ErrCallDeadline if the canary was skipped or too slow
+	Calls       []BudgetCall
+}
+
+// Same reports whether the talk and the canary answered with the same responses
+func (r *CanaryResult) Same() bool {
+	if r.Err != nil || r.CanaryErr != nil {
+		return r.Err != nil && r.CanaryErr != nil
+	}
+	return strings.Join(r.Reply.Responses, "\n") == strings.Join(r.CanaryReply.Responses, "\n")
+}
+
+// Canary sends the inputs to a canary too, e.g. a Conversation with the next
+// version of the bot, to compare it with the current one on real traffic.
+// The users are answered by the talk as soon as it replies, the canary is
+// compared in the background. Both calls have the latency budget, so a slow
+// talk is bounded too. Add its Middleware to a Conversation.
+type Canary struct {
+	Talk    TalkFunc
+	Budget  time.Duration // The latency budget of the calls, zero for no limit
+	MinCall time.Duration // See Budget.MinCall
+	// OnCompare receives the comparison of each input, from another goroutine
+	OnCompare func(r CanaryResult)
+}
+
+// NewCanary creates a canary calling the talk within the latency budget
+func NewCanary(talk TalkFunc, budget time.Duration, onCompare func(r CanaryResult)) *Canary {
+	return &Canary{Talk: talk, Budget: budget, OnCompare: onCompare}
+}
+
+// Middleware returns the middleware sending the inputs to the next talk and to the canary
+func (cn *Canary) Middleware() Middleware {
+	return func(next TalkFunc) TalkFunc {
+		return func(key, input string) (*Reply, error) {
+			b := NewBudget(cn.Budget, cn.MinCall)
+			canary := make(chan CanaryResult, 1)
+			go func() {
+				r, err := b.Call("canary", 0, func() (*Reply, error) {
+					return cn.Talk(key, input)
+				})
+				canary <- CanaryResult{CanaryReply: r, CanaryErr: err}
+			}()
+			reply, err := b.Call("primary", 0, func() (*Reply, error) {
+				return next(key, input)
+			})
+			if cn.OnCompare != nil {
+				go func() {
+					r := <-canary
+					r.Key, r.Input, r.Reply, r.Err, r.Calls = key, input, reply, err, b.Calls()
+					cn.OnCompare(r)
+				}()
+			}
+			return reply, err
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"container/list"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ReplyCache caches the replies of stateless bots, like FAQ bots, whose
+// answers do not depend on the conversation. Repeated inputs are answered
+// from the cache, cutting the latency and the API usage. The inputs are
+// matched ignoring case and punctuation, like the AIML patterns match them.
+//
+// The cached replies do not reach the bot, so its predicates and that are not
+// updated. Do not cache bots keeping a state per client.
+type ReplyCache struct {
+	TTL  time.Duration // How long the replies are cached. Defaults to 5 minutes.
+	Size int           // The maximum number of cached replies. Defaults to 1000.
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     list.List // Most recently used first
+}
+
+// cacheEntry is a cached reply
+type cacheEntry struct {
+	key     string
+	reply   Reply
+	expires time.Time
+}
+
+// NewReplyCache creates a cache of size replies kept for ttl
+func NewReplyCache(ttl time.Duration, size int) *ReplyCache {
+	return &ReplyCache{TTL: ttl, Size: size}
+}
+
+// SetReplyCache answers the talk requests from the cache. Only the requests
+// without that, topic, reset, trace, reload or extra are cached, and the
+// cached replies have the session ID of the request.
+func SetReplyCache(cache *ReplyCache) OptionFunc {
+	return func(c *Client) error {
+		if cache.TTL < 0 || cache.Size < 0 {
+			return errors.New("Cache TTL and size cannot be negative")
+		}
+		c.cache = cache
+		return nil
+	}
+}
+
+// cacheKey returns the key of the input of the bot, normalized like the AIML
+// patterns match it
+func cacheKey(bot, input string) string {
+	words := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return bot + "\x00" + strings.Join(words, " ")
+}
+
+// copyReply returns a copy of the reply not sharing its responses and commands
+func copyReply(r Reply) Reply {
+	r.Responses = append([]string{}, r.Responses...)
+	r.Commands = append([]Command(nil), r.Commands...)
+	return r
+}
+
+// Get returns the cached reply of the input of the bot
+func (rc *ReplyCache) Get(bot, input string) (*Reply, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	el, ok := rc.entries[cacheKey(bot, input)]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	if time.Now().After(e.expires) {
+		rc.lru.Remove(el)
+		delete(rc.entries, e.key)
+		return nil, false
+	}
+	rc.lru.MoveToFront(el)
+	reply := copyReply(e.reply)
+	return &reply, true
+}
+
+// Put caches the reply to the input of the bot, evicting the least recently used replies over the size
+func (rc *ReplyCache) Put(bot, input string, reply *Reply) {
+	ttl, size := rc.TTL, rc.Size
+	if ttl == 0 {
+		ttl = 5 * time.Minute
+	}
+	if size == 0 {
+		size = 1000
+	}
+	key := cacheKey(bot, input)
+	e := &cacheEntry{key: key, reply: copyReply(*reply), expires: time.Now().Add(ttl)}
+	e.reply.SessionId, e.reply.Trace, e.reply.Info = 0, nil, ReplyInfo{}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.entries == nil {
+		rc.entries = make(map[string]*list.Element)
+	}
+	if el, ok := rc.entries[key]; ok {
+		el.Value = e
+		rc.lru.MoveToFront(el)
+		return
+	}
+	rc.entries[key] = rc.lru.PushFront(e)
+	for rc.lru.Len() > size {
+		el := rc.lru.Back()
+		rc.lru.Remove(el)
+		delete(rc.entries, el.Value.(*cacheEntry).key)
+	}
+}
+
+// Len returns the number of cached replies, including the expired ones not evicted yet
+func (rc *ReplyCache) Len() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.lru.Len()
+}
+
+// Purge removes all the cached replies, e.g. after the bot files changed
+func (rc *ReplyCache) Purge() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.entries = nil
+	rc.lru.Init()
+}
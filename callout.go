@@ -0,0 +1,176 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultCalloutTag is the element marking the callouts in the responses
+const DefaultCalloutTag = "callout"
+
+// Callout answers the query of a bot with the data of an external service
+type Callout func(service, query string) (string, error)
+
+// Callouts bring dynamic data to static AIML bots, like the sraix element but
+// resolved by the client. The bot answers with a callout element, e.g.
+//
+//	The weather in London is <callout service="weather">London</callout>
+//
+// and the element is replaced with the result of the callout of the service.
+// Unknown elements are passed through by the bot, so the templates can use
+// <star/> and <get/> in the query.
+type Callouts struct {
+	Tag      string             // The element marking the callouts. Defaults to DefaultCalloutTag.
+	Services map[string]Callout // The callouts by service. The callout of the empty service answers the other services.
+	Failed   string             // The text replacing the callouts which failed or have no service
+	ErrorLog *log.Logger        // Logs the failed callouts, nil to ignore them
+
+	once sync.Once
+	re   *regexp.Regexp
+}
+
+// SetCallouts sets the callouts expanded in the responses of every talk reply, before the reply filters
+func SetCallouts(cs *Callouts) OptionFunc {
+	return func(c *Client) error {
+		c.callouts = cs
+		return nil
+	}
+}
+
+var serviceAttrRe = regexp.MustCompile(`\bservice\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+func (cs *Callouts) errorf(format string, args ...interface{}) {
+	if cs.ErrorLog != nil {
+		cs.ErrorLog.Printf(format, args...)
+	}
+}
+
+func (cs *Callouts) call(service, query string) string {
+	callout, ok := cs.Services[service]
+	if !ok {
+		callout, ok = cs.Services[""]
+	}
+	if !ok {
+		cs.errorf("No callout for service [%s]", service)
+		return cs.Failed
+	}
+	result, err := callout(service, query)
+	if err != nil {
+		cs.errorf("Callout to service [%s] failed - %v", service, err)
+		return cs.Failed
+	}
+	return strings.TrimSpace(result)
+}
+
+// Expand replaces the callout elements of the response with their results
+func (cs *Callouts) Expand(response string) string {
+	cs.once.Do(func() {
+		tag := cs.Tag
+		if tag == "" {
+			tag = DefaultCalloutTag
+		}
+		tag = regexp.QuoteMeta(tag)
+		cs.re = regexp.MustCompile(`(?is)<` + tag + `\b([^<>]*?)(?:/>|>(.*?)</` + tag + `\s*>)`)
+	})
+	return cs.re.ReplaceAllStringFunc(response, func(element string) string {
+		m := cs.re.FindStringSubmatch(element)
+		var service string
+		if attr := serviceAttrRe.FindStringSubmatch(m[1]); attr != nil {
+			service = html.UnescapeString(attr[1] + attr[2])
+		}
+		query := strings.TrimSpace(html.UnescapeString(tagRe.ReplaceAllString(m[2], "")))
+		return cs.call(service, query)
+	})
+}
+
+// Filter expands the callouts of the responses, a ReplyFilter
+func (cs *Callouts) Filter(responses []string) []string {
+	return mapResponses(responses, func(r string) string {
+		return strings.TrimSpace(cs.Expand(r))
+	})
+}
+
+// HTTPCallout calls an HTTP service with the query in a parameter of a GET
+// request. The result is read from the TextField of a JSON response, or is
+// the whole body of a text response.
+type HTTPCallout struct {
+	Url          string
+	QueryParam   string       // The parameter of the query. Defaults to q.
+	ServiceParam string       // The parameter of the service, empty to not send it
+	Header       http.Header  // Additional headers, e.g. the authorization
+	TextField    string       // The field of the JSON response holding the result. Defaults to text.
+	HttpClient   *http.Client // Defaults to http.DefaultClient
+}
+
+// Call sends the query to the endpoint and returns the result, a Callout
+func (h *HTTPCallout) Call(service, query string) (string, error) {
+	u, err := url.Parse(h.Url)
+	if err != nil {
+		return "", err
+	}
+	params := u.Query()
+	queryParam := h.QueryParam
+	if queryParam == "" {
+		queryParam = "q"
+	}
+	params.Set(queryParam, query)
+	if h.ServiceParam != "" {
+		params.Set(h.ServiceParam, service)
+	}
+	u.RawQuery = params.Encode()
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range h.Header {
+		req.Header[k] = v
+	}
+	client := h.HttpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", &APIError{StatusCode: resp.StatusCode, Body: data}
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "application/json" {
+		return string(data), nil
+	}
+	field := h.TextField
+	if field == "" {
+		field = "text"
+	}
+	var res map[string]interface{}
+	if err = json.Unmarshal(data, &res); err != nil {
+		return "", &DecodeError{Err: err, Body: data}
+	}
+	switch v := res[field].(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", fmt.Errorf("Result field [%s] not found in the response", field)
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
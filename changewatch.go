@@ -0,0 +1,192 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// FileChange is a change of a file of a bot found by a ChangeWatcher
+type FileChange struct {
+	Bot      string    `json:"bot"`
+	File     string    `json:"file"`
+	Change   string    `json:"change"` // added, modified or removed
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"` // The modification time of the removed files is the last one seen
+}
+
+// ChangeWatcher polls the file listings of bots and reports the files
+// added, modified or removed since the previous poll, e.g. so teams notice
+// when someone edits a production bot in the pandorabots web UI. The first
+// poll records the state of the files without reporting changes.
+type ChangeWatcher struct {
+	Client *Client
+	// Bots are the bots to watch, empty for all the bots of the application,
+	// including the ones created while watching
+	Bots []string
+	// Interval is the time between polls. Defaults to a minute.
+	Interval time.Duration
+	// OnChange receives the changes of each poll finding some, nil to only post them to the webhook
+	OnChange func(changes []FileChange)
+	// Webhook is the URL the changes of each poll finding some are posted to as
+	// a JSON object with a changes list, empty for none
+	Webhook string
+	// OnError receives the errors of the polls and of the webhook, nil to log them to the error log of the client
+	OnError func(err error)
+
+	files map[string]map[string]BotFile // The files of each bot at the previous poll
+}
+
+// NewChangeWatcher creates a watcher of the bots, all the bots of the application if none is given
+func NewChangeWatcher(c *Client, bots ...string) *ChangeWatcher {
+	return &ChangeWatcher{Client: c, Bots: bots}
+}
+
+// listingFiles returns the files of a listing by file name, with their extension
+func listingFiles(list BotFiles) map[string]BotFile {
+	files := make(map[string]BotFile)
+	add := func(list []BotFile, kind string) {
+		for _, f := range list {
+			name := f.Name
+			if filepath.Ext(name) != "."+kind {
+				name += "." + kind
+			}
+			files[name] = f
+		}
+	}
+	add(list.Files, "aiml")
+	add(list.Sets, "set")
+	add(list.Maps, "map")
+	add(list.Substitutions, "substitution")
+	add(list.Properties, "properties")
+	add(list.Pdefaults, "pdefaults")
+	return files
+}
+
+// Poll lists the files of the bots and returns the changes since the
+// previous poll, sorted by bot and file. The bots which cannot be listed keep
+// their previous state and the first error is returned with the changes of
+// the others.
+func (w *ChangeWatcher) Poll() ([]FileChange, error) {
+	bots := w.Bots
+	if len(bots) == 0 {
+		list, err := w.Client.List()
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range list {
+			bots = append(bots, b.Name)
+		}
+	}
+	first := w.files == nil
+	if first {
+		w.files = make(map[string]map[string]BotFile)
+	}
+	var changes []FileChange
+	var firstErr error
+	for _, bot := range bots {
+		list, err := w.Client.ListFiles(bot)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		cur := listingFiles(list)
+		prev, known := w.files[bot]
+		w.files[bot] = cur
+		if first || !known && len(w.Bots) > 0 {
+			continue
+		}
+		for name, f := range cur {
+			p, ok := prev[name]
+			switch {
+			case !ok:
+				changes = append(changes, FileChange{Bot: bot, File: name, Change: "added", Size: f.Size, Modified: f.Modified.Time})
+			case !p.Modified.Equal(f.Modified.Time) || p.Size != f.Size:
+				changes = append(changes, FileChange{Bot: bot, File: name, Change: "modified", Size: f.Size, Modified: f.Modified.Time})
+			}
+		}
+		for name, p := range prev {
+			if _, ok := cur[name]; !ok {
+				changes = append(changes, FileChange{Bot: bot, File: name, Change: "removed", Size: p.Size, Modified: p.Modified.Time})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Bot != changes[j].Bot {
+			return changes[i].Bot < changes[j].Bot
+		}
+		return changes[i].File < changes[j].File
+	})
+	return changes, firstErr
+}
+
+// post sends the changes to the webhook
+func (w *ChangeWatcher) post(ctx context.Context, changes []FileChange) error {
+	body, err := json.Marshal(map[string][]FileChange{"changes": changes})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", w.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.Client.c.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Webhook failed [%s] - %s", w.Webhook, resp.Status)
+	}
+	return nil
+}
+
+// Run polls the bots until the context is cancelled, reporting the changes
+// to OnChange and the webhook
+func (w *ChangeWatcher) Run(ctx context.Context) error {
+	onError := w.OnError
+	if onError == nil {
+		onError = func(err error) {
+			w.Client.errorf("Watching the bot files failed - %v", err)
+		}
+	}
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		changes, err := w.Poll()
+		if err != nil {
+			onError(err)
+		}
+		if len(changes) > 0 {
+			if w.OnChange != nil {
+				w.OnChange(changes)
+			}
+			if w.Webhook != "" {
+				if err = w.post(ctx, changes); err != nil {
+					onError(err)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
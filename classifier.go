@@ -0,0 +1,177 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Classification is what a Classifier detected in an input
+type Classification struct {
+	// Tags are added to the Tags of the session, e.g. negative or billing
+	Tags []string
+	// Escalate hands the conversation over to a human agent with the Escalator of the Conversation
+	Escalate bool
+	// Bot answers the input and the next ones of the session instead of the
+	// bot of the Conversation, empty to keep the bot
+	Bot string
+}
+
+// Classifier detects the sentiment, the topics or the keywords of the inputs
+// of a Conversation, e.g. with a sentiment analysis service. The context has
+// the session before the input is sent.
+type Classifier interface {
+	// Classify returns what was detected in the input, nil for nothing
+	Classify(ctx ReplyContext) (*Classification, error)
+}
+
+// ClassifierFunc is a Classifier calling a function
+type ClassifierFunc func(ctx ReplyContext) (*Classification, error)
+
+func (f ClassifierFunc) Classify(ctx ReplyContext) (*Classification, error) {
+	return f(ctx)
+}
+
+// ClassifierRule classifies the inputs matching its pattern
+type ClassifierRule struct {
+	Pattern  *regexp.Regexp
+	Tag      string
+	Escalate bool
+	Bot      string
+}
+
+// KeywordClassifier is a Classifier matching the inputs against keywords
+// and regular expressions. The classification of an input combines the rules
+// it matches; the first matching rule with a bot selects the bot.
+type KeywordClassifier struct {
+	Rules []ClassifierRule
+}
+
+// NewKeywordClassifier creates a classifier with the rules
+func NewKeywordClassifier(rules ...ClassifierRule) *KeywordClassifier {
+	return &KeywordClassifier{Rules: rules}
+}
+
+// KeywordRule returns a rule tagging the inputs containing one of the words, ignoring case
+func KeywordRule(tag string, words ...string) ClassifierRule {
+	parts := make([]string, len(words))
+	for i, w := range words {
+		parts[i] = regexp.QuoteMeta(w)
+	}
+	return ClassifierRule{Pattern: regexp.MustCompile(`(?i)(^|\W)(` + strings.Join(parts, "|") + `)($|\W)`), Tag: tag}
+}
+
+// RegexpRule returns a rule tagging the inputs matching the regular expression, ignoring case
+func RegexpRule(tag, expr string) (ClassifierRule, error) {
+	re, err := regexp.Compile("(?i)" + expr)
+	if err != nil {
+		return ClassifierRule{}, fmt.Errorf("Classifier expression is not valid [%s] - %v", expr, err)
+	}
+	return ClassifierRule{Pattern: re, Tag: tag}, nil
+}
+
+func (k *KeywordClassifier) Classify(ctx ReplyContext) (*Classification, error) {
+	var c *Classification
+	for i := range k.Rules {
+		r := &k.Rules[i]
+		if !r.Pattern.MatchString(ctx.Input) {
+			continue
+		}
+		if c == nil {
+			c = &Classification{}
+		}
+		if r.Tag != "" {
+			c.Tags = append(c.Tags, r.Tag)
+		}
+		c.Escalate = c.Escalate || r.Escalate
+		if c.Bot == "" {
+			c.Bot = r.Bot
+		}
+	}
+	return c, nil
+}
+
+// ParseKeywordClassifier reads the rules of a keyword classifier, one per
+// line as TAG: PATTERNS -> ACTIONS. The patterns are comma separated words,
+// or regular expressions between slashes. The optional actions are
+// escalate, and bot NAME to switch the session to another bot:
+//
+//	# Empty lines and lines starting with # are ignored
+//	negative: hate, awful, /not (happy|satisfied)/ -> escalate
+//	billing: invoice, refund -> bot billing-bot
+func ParseKeywordClassifier(r io.Reader) (*KeywordClassifier, error) {
+	k := NewKeywordClassifier()
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tag, rest, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(tag) == "" {
+			return nil, fmt.Errorf("Classifier rule is not valid [%s] at line %d - it must be TAG: PATTERNS -> ACTIONS", line, n)
+		}
+		tag = strings.TrimSpace(tag)
+		patterns, actions, _ := strings.Cut(rest, "->")
+		var rules []ClassifierRule
+		var words []string
+		for _, p := range strings.Split(patterns, ",") {
+			p = strings.TrimSpace(p)
+			if len(p) > 1 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+				rule, err := RegexpRule(tag, p[1:len(p)-1])
+				if err != nil {
+					return nil, fmt.Errorf("%v at line %d", err, n)
+				}
+				rules = append(rules, rule)
+			} else if p != "" {
+				words = append(words, p)
+			}
+		}
+		if len(words) > 0 {
+			rules = append(rules, KeywordRule(tag, words...))
+		}
+		if len(rules) == 0 {
+			return nil, fmt.Errorf("Classifier rule has no patterns [%s] at line %d", line, n)
+		}
+		for _, a := range strings.Split(actions, ",") {
+			a = strings.TrimSpace(a)
+			for i := range rules {
+				switch {
+				case a == "":
+				case a == "escalate":
+					rules[i].Escalate = true
+				case strings.HasPrefix(a, "bot "):
+					rules[i].Bot = strings.TrimSpace(strings.TrimPrefix(a, "bot "))
+				default:
+					return nil, fmt.Errorf("Classifier action is not valid [%s] at line %d - it must be escalate or bot NAME", a, n)
+				}
+			}
+		}
+		k.Rules = append(k.Rules, rules...)
+	}
+	return k, scanner.Err()
+}
+
+// classify applies the classification of the input by the Classifier to the session
+func (cv *Conversation) classify(key, input string, s *Session) (*Classification, error) {
+	c, err := cv.Classifier.Classify(ReplyContext{Bot: cv.botOf(s), Key: key, Input: input, Session: *s})
+	if err != nil || c == nil {
+		return nil, err
+	}
+	for _, tag := range c.Tags {
+		if !s.HasTag(tag) {
+			s.Tags = append(s.Tags, tag)
+		}
+	}
+	if c.Bot != "" && c.Bot != cv.botOf(s) {
+		// The pandorabots sessions are per bot
+		s.Bot, s.SessionId = c.Bot, 0
+	}
+	return c, nil
+}
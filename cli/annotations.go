@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+	"github.com/demisto/pb-go/aiml"
+)
+
+// problem is a lint issue or compile message reported as an annotation
+type problem struct {
+	Rule     string // lint or compile
+	File     string
+	Line     int // Zero if the problem is not tied to a line
+	Severity aiml.Severity
+	Message  string
+}
+
+// annotationOutput reports whether the -output flag asks for annotations
+func annotationOutput() bool {
+	return *output == outputGitHub || *output == outputSARIF
+}
+
+func lintProblems(issues []aiml.Issue) []problem {
+	problems := make([]problem, len(issues))
+	for i, issue := range issues {
+		problems[i] = problem{"lint", issue.File, issue.Line, issue.Severity, issue.Message}
+	}
+	return problems
+}
+
+// compileProblems converts the compile messages, locating the files of the messages under dir if not empty
+func compileProblems(messages []pb.CompileMessage, dir string) []problem {
+	problems := make([]problem, len(messages))
+	for i, m := range messages {
+		file := m.File
+		if dir != "" && file != "" {
+			file = localPath(dir, file)
+		}
+		problems[i] = problem{"compile", file, m.Line, aiml.SeverityError, m.Message}
+	}
+	return problems
+}
+
+// localPath returns the path of the first file named name under dir, or the path it would have at the top of dir
+func localPath(dir, name string) string {
+	found := ""
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || found != "" {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && info.Name() == name {
+			found = path
+		}
+		return nil
+	})
+	if found == "" {
+		found = filepath.Join(dir, name)
+	}
+	return found
+}
+
+// githubEscape escapes the text of a workflow command, and its properties if property is true
+func githubEscape(s string, property bool) string {
+	s = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+	if property {
+		s = strings.NewReplacer(":", "%3A", ",", "%2C").Replace(s)
+	}
+	return s
+}
+
+// githubAnnotation formats the problem as a GitHub Actions workflow command, shown inline in pull requests
+func githubAnnotation(p problem) string {
+	props := []string{}
+	if p.File != "" {
+		props = append(props, "file="+githubEscape(filepath.ToSlash(p.File), true))
+	}
+	if p.Line > 0 {
+		props = append(props, fmt.Sprintf("line=%d", p.Line))
+	}
+	props = append(props, "title="+githubEscape("AIML "+p.Rule, true))
+	return fmt.Sprintf("::%s %s::%s", p.Severity, strings.Join(props, ","), githubEscape(p.Message, false))
+}
+
+// SARIF 2.1.0 report types, with the properties code scanning uses
+type (
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifRegion struct {
+		StartLine int `json:"startLine"`
+	}
+	sarifLocation struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region *sarifRegion `json:"region,omitempty"`
+		} `json:"physicalLocation"`
+	}
+	sarifResult struct {
+		RuleId    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations,omitempty"`
+	}
+	sarifRule struct {
+		Id               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifRun struct {
+		Tool struct {
+			Driver struct {
+				Name           string      `json:"name"`
+				InformationUri string      `json:"informationUri"`
+				Rules          []sarifRule `json:"rules"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+)
+
+// writeSARIF writes the problems as a SARIF log, e.g. for GitHub code scanning
+func writeSARIF(w io.Writer, problems []problem) error {
+	run := sarifRun{Results: []sarifResult{}}
+	run.Tool.Driver.Name = "pbcli"
+	run.Tool.Driver.InformationUri = "https://github.com/demisto/pb-go"
+	run.Tool.Driver.Rules = []sarifRule{
+		{"lint", sarifMessage{"Problem found in a local AIML, set, map or substitution file"}},
+		{"compile", sarifMessage{"Compile error of the bot on pandorabots"}},
+	}
+	for _, p := range problems {
+		r := sarifResult{RuleId: p.Rule, Level: string(p.Severity), Message: sarifMessage{p.Message}}
+		if p.File != "" {
+			var l sarifLocation
+			l.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(p.File)
+			if p.Line > 0 {
+				l.PhysicalLocation.Region = &sarifRegion{StartLine: p.Line}
+			}
+			r.Locations = []sarifLocation{l}
+		}
+		run.Results = append(run.Results, r)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(sarifLog{Schema: "https://json.schemastore.org/sarif-2.1.0.json", Version: "2.1.0", Runs: []sarifRun{run}})
+}
+
+// printProblems prints the problems in the annotation format of the -output flag
+func printProblems(problems []problem) error {
+	if *output == outputSARIF {
+		return writeSARIF(os.Stdout, problems)
+	}
+	for _, p := range problems {
+		fmt.Println(githubAnnotation(p))
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// progress prints the progress of file operations to standard error, unless
+// the client reports it as JSON
+func progress(file string, done, total int, err error) {
+	if *progressMode == "json" {
+		return
+	}
+	if err != nil {
+		errorf("[%d/%d] %s FAILED: %v", done, total, file, err)
+		return
+	}
+	statusf("[%d/%d] %s", done, total, file)
+}
+
+// copyFlags adds the flags of the commands copying files into a bot
+func copyFlags(cmd *command) *pb.CopyOptions {
+	opts := &pb.CopyOptions{Progress: progress}
+	cmd.fs.BoolVar(&opts.Create, "create", true, "Create the target bot if it does not exist.")
+	cmd.fs.BoolVar(&opts.Delete, "delete", false, "Delete files of the target bot that are not copied.")
+	cmd.fs.BoolVar(&opts.Verify, "verify", true, "Verify the target bot after copying.")
+	return opts
+}
+
+func cloneCmd() *command {
+	cmd := newCommand("clone", "SRC DST", "Copy all the files of one bot to another, e.g. to promote staging to production")
+	opts := copyFlags(cmd)
+	cmd.run = func(args []string) error {
+		if len(args) != 2 {
+			return usagef("You must specify the source and destination bot names")
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		if err = c.CloneBot(args[0], args[1], *opts); err != nil {
+			return err
+		}
+		success("Bot %s successfully cloned to %s.", args[0], args[1])
+		return nil
+	}
+	return cmd
+}
+
+// openStorage opens a storage from a location: s3://BUCKET/PREFIX for an S3
+// bucket, with the credentials of the AWS environment variables, or a directory
+func openStorage(location string) pb.Storage {
+	if rest, ok := strings.CutPrefix(location, "s3://"); ok {
+		bucket, prefix, _ := strings.Cut(rest, "/")
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		return pb.NewS3Storage(bucket, prefix)
+	}
+	return pb.NewDirStorage(location)
+}
+
+// s3Object splits an s3://BUCKET/KEY location into the storage of the bucket and the key
+func s3Object(location string) (pb.Storage, string, bool) {
+	rest, ok := strings.CutPrefix(location, "s3://")
+	if !ok {
+		return nil, "", false
+	}
+	bucket, key, _ := strings.Cut(rest, "/")
+	return pb.NewS3Storage(bucket, ""), key, key != ""
+}
+
+// defaultBackupPath returns a timestamped path for a backup of the bot in the backups directory next to the configuration file
+func defaultBackupPath(name string) (string, error) {
+	base := defaultConfigPath()
+	if base == "" {
+		return "", fmt.Errorf("Unable to determine the backups directory")
+	}
+	dir := filepath.Join(filepath.Dir(base), "backups")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.zip", name, time.Now().Format("20060102-150405"))), nil
+}
+
+// backupBeforeDelete backs up the bot to the backups directory next to the configuration file
+func backupBeforeDelete(c *pb.Client, name string) (string, error) {
+	path, err := defaultBackupPath(name)
+	if err != nil {
+		return "", err
+	}
+	return path, c.BackupToPath(name, path)
+}
+
+func backupCmd() *command {
+	cmd := newCommand("backup", "", "Backup all the files of a bot to a zip archive")
+	name := nameFlag(cmd.fs)
+	out := cmd.fs.String("out", "", "The backup file, or s3://BUCKET/KEY. Defaults to BOT-TIMESTAMP.zip in the current directory.")
+	schedule := cmd.fs.String("schedule", "", "Run as a daemon backing up on this cron schedule, like \"0 3 * * *\" or \"@every 6h\", the bot or all the bots if -name is not given.")
+	dir := cmd.fs.String("dir", "backups", "The directory of the scheduled backups, or s3://BUCKET/PREFIX.")
+	keep := cmd.fs.Int("keep", 0, "The number of scheduled backups kept per bot, zero for no limit.")
+	maxAge := cmd.fs.Duration("max-age", 0, "Delete the scheduled backups older than this, like 720h, zero for no limit.")
+	cmd.run = func(args []string) error {
+		if *schedule != "" {
+			return scheduleBackups(*name, *schedule, *dir, pb.BackupRetention{Keep: *keep, MaxAge: *maxAge})
+		}
+		if err := requireName(name); err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		path := *out
+		if path == "" {
+			path = fmt.Sprintf("%s-%s.zip", *name, time.Now().Format("20060102-150405"))
+		}
+		if s, key, ok := s3Object(path); ok {
+			err = c.BackupToStorage(*name, s, key)
+		} else {
+			err = c.BackupToPath(*name, path)
+		}
+		if err != nil {
+			return err
+		}
+		success("Bot %s backed up to %s.", *name, path)
+		return nil
+	}
+	return cmd
+}
+
+// scheduleBackups backs up the bot, or all the bots if it is empty, to the storage at dir on the schedule until interrupted
+func scheduleBackups(bot, spec, dir string, retention pb.BackupRetention) error {
+	schedule, err := pb.ParseSchedule(spec)
+	if err != nil {
+		return usagef("%v", err)
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	var s *pb.BackupScheduler
+	if bot != "" {
+		s = pb.NewBackupScheduler(c, schedule, openStorage(dir), bot)
+	} else {
+		s = pb.NewBackupScheduler(c, schedule, openStorage(dir))
+	}
+	location := func(name string) string {
+		if strings.HasPrefix(dir, "s3://") {
+			return strings.TrimSuffix(dir, "/") + "/" + name
+		}
+		return filepath.Join(dir, filepath.FromSlash(name))
+	}
+	s.Retention = retention
+	s.OnBackup = func(r pb.BackupResult) {
+		stamp := time.Now().Format("2006-01-02 15:04:05")
+		if r.Err != nil {
+			errorf("%s backup %s FAILED: %v", stamp, r.Bot, r.Err)
+			return
+		}
+		fmt.Printf("%s backup %s to %s\n", stamp, r.Bot, location(r.Name))
+		for _, name := range r.Deleted {
+			fmt.Printf("%s delete %s\n", stamp, location(name))
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	info("Backing up to %s on schedule %s, next at %s. Press Ctrl+C to stop.", dir, schedule, schedule.Next(time.Now()).Format("2006-01-02 15:04"))
+	return s.Run(ctx)
+}
+
+func restoreCmd() *command {
+	cmd := newCommand("restore", "FILE", "Restore the files of a bot from a backup zip archive, a file or s3://BUCKET/KEY")
+	name := nameFlag(cmd.fs)
+	opts := copyFlags(cmd)
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		if len(args) != 1 {
+			return usagef("You must specify the backup file to restore")
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		if s, key, ok := s3Object(args[0]); ok {
+			err = c.RestoreFromStorage(*name, s, key, *opts)
+		} else {
+			err = c.RestoreFromPath(*name, args[0], *opts)
+		}
+		if err != nil {
+			return err
+		}
+		success("Bot %s successfully restored from %s.", *name, args[0])
+		return nil
+	}
+	return cmd
+}
+
+func exportCmd() *command {
+	cmd := newCommand("export", "DIR", "Write the files of a bot to a directory in a layout suited to git, optionally committing the changes")
+	name := nameFlag(cmd.fs)
+	commit := cmd.fs.Bool("commit", false, "Commit the changed files to the git repository of the directory.")
+	message := cmd.fs.String("message", "", "The commit message. Defaults to Export BOT.")
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		if len(args) != 1 {
+			return usagef("You must specify the directory to export to")
+		}
+		var opts pb.GitExportOptions
+		if *commit {
+			msg := *message
+			if msg == "" {
+				msg = "Export " + *name
+			}
+			opts.Commit = pb.GitCommit(msg)
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		res, err := c.ExportForGit(*name, args[0], opts)
+		if err != nil {
+			return err
+		}
+		for _, path := range res.Changed {
+			info("Changed %s", path)
+		}
+		switch {
+		case len(res.Changed) == 0:
+			success("Bot %s has not changed since the last export.", *name)
+		case *commit:
+			success("Bot %s exported to %s and %d changed files committed.", *name, args[0], len(res.Changed))
+		default:
+			success("Bot %s exported to %s, %d files changed.", *name, args[0], len(res.Changed))
+		}
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// batchResult is the outcome of a single input of a batch
+type batchResult struct {
+	Line      int    `json:"line"`
+	Input     string `json:"input"`
+	Response  string `json:"response"`
+	SessionId int    `json:"sessionid"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// readInputs reads the non empty lines of the file
+func readInputs(path string) ([]batchResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var inputs []batchResult
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		if line := strings.TrimSpace(s.Text()); line != "" {
+			inputs = append(inputs, batchResult{Line: n, Input: line})
+		}
+	}
+	return inputs, s.Err()
+}
+
+// runBatch talks every input of the file to the bot, spreading the inputs
+// over the given number of concurrent sessions, and writes the pairs to out
+// as CSV, or in the JSON or YAML format of -output.
+func runBatch(c *pb.Client, bot, path string, sessions int, out io.Writer) error {
+	results, err := readInputs(path)
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	for s := 0; s < sessions; s++ {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+			clientName := fmt.Sprintf("pbcli-batch-%d-%d", time.Now().UnixNano(), s)
+			sessionId := 0
+			// Session s handles every inputs with index s modulo sessions
+			for i := s; i < len(results); i += sessions {
+				r := &results[i]
+				start := time.Now()
+				reply, err := c.Talk(bot, r.Input, clientName, sessionId, false)
+				r.LatencyMs = time.Since(start).Milliseconds()
+				if err != nil {
+					r.Error = err.Error()
+					continue
+				}
+				sessionId = reply.SessionId
+				r.SessionId = reply.SessionId
+				r.Response = strings.Join(reply.Responses, " ")
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+		}
+	}
+	// The responses may hold tabs and new lines, so the table is written as CSV
+	if *output == outputTable || *output == outputCSV {
+		w := csv.NewWriter(out)
+		w.Write([]string{"line", "input", "response", "sessionid", "latency_ms", "error"})
+		for _, r := range results {
+			w.Write([]string{strconv.Itoa(r.Line), r.Input, r.Response, strconv.Itoa(r.SessionId), strconv.FormatInt(r.LatencyMs, 10), r.Error})
+		}
+		w.Flush()
+		err = w.Error()
+	} else {
+		err = writeResult(out, results, nil)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d inputs failed", failed, len(results))
+	}
+	statusf("%d inputs processed.", len(results))
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+)
+
+func benchCmd() *command {
+	cmd := newCommand("bench", "", "Load test a bot with concurrent sessions and report the latency percentiles")
+	name := nameFlag(cmd.fs)
+	sessions := cmd.fs.Int("sessions", 10, "Number of concurrent sessions.")
+	duration := cmd.fs.Duration("duration", 30*time.Second, "How long to run. Zero runs until -requests are sent.")
+	requests := cmd.fs.Int("requests", 0, "Stop after this many requests. Zero for no limit.")
+	input := cmd.fs.String("input", "Hello", "The input the sessions send.")
+	inputs := cmd.fs.String("inputs", "", "File with an input per line that the sessions send in turn, instead of -input.")
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		opts := pb.BenchOptions{Sessions: *sessions, Duration: *duration, Requests: *requests, Inputs: []string{*input}}
+		if *inputs != "" {
+			lines, err := readInputs(*inputs)
+			if err != nil {
+				return err
+			}
+			opts.Inputs = nil
+			for _, l := range lines {
+				opts.Inputs = append(opts.Inputs, l.Input)
+			}
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		// Report the progress once a second
+		var mu sync.Mutex
+		last := time.Duration(0)
+		opts.Progress = func(done int, elapsed time.Duration) {
+			mu.Lock()
+			defer mu.Unlock()
+			if elapsed-last >= time.Second && *progressMode != "json" {
+				last = elapsed
+				statusf("%v: %d requests", elapsed.Round(time.Second), done)
+			}
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		res, err := c.Bench(ctx, *name, opts)
+		if err != nil {
+			return usageError(err.Error())
+		}
+		err = printResult(res, func(w io.Writer) {
+			row(w, "Bot:", res.Bot)
+			row(w, "Sessions:", res.Sessions)
+			row(w, "Requests:", fmt.Sprintf("%d (%d failed)", res.Requests, res.Errors))
+			row(w, "Elapsed:", res.Elapsed.Round(time.Millisecond))
+			row(w, "Throughput:", fmt.Sprintf("%.1f requests/s", res.Throughput))
+			fmt.Fprintln(w)
+			row(w, "MIN", "MEAN", "P50", "P90", "P95", "P99", "MAX")
+			ms := func(d time.Duration) string { return d.Round(time.Millisecond / 10).String() }
+			row(w, ms(res.Min), ms(res.Mean), ms(res.P50), ms(res.P90), ms(res.P95), ms(res.P99), ms(res.Max))
+		})
+		if err != nil {
+			return err
+		}
+		if res.Errors > 0 {
+			warnf("%d of %d requests failed, the first with: %s", res.Errors, res.Requests, res.FirstError)
+		}
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// aliasesFlag adds the flag locating the routing aliases of the blue/green deployments
+func aliasesFlag(fs *flag.FlagSet) *string {
+	return fs.String("aliases", "", "Where the routing aliases of the blue/green deployments are kept, a directory or s3://BUCKET/PREFIX. Defaults to the directory of the configuration file.")
+}
+
+// openAliases opens the routing aliases at the location of the -aliases flag
+func openAliases(location string) (*pb.Aliases, error) {
+	if location == "" {
+		base := defaultConfigPath()
+		if base == "" {
+			return nil, fmt.Errorf("Unable to determine the aliases directory, use -aliases")
+		}
+		location = filepath.Dir(base)
+	}
+	return pb.NewAliases(openStorage(location)), nil
+}
+
+// blueGreenFlags adds the flags of the blue/green commands and returns the deployment they select
+func blueGreenFlags(cmd *command) func() (*pb.BlueGreen, error) {
+	alias := cmd.fs.String("alias", "", "The routing alias of the deployment, served by pbcli serve -route PATH=@ALIAS. The bots are ALIAS-blue and ALIAS-green.")
+	location := aliasesFlag(cmd.fs)
+	return func() (*pb.BlueGreen, error) {
+		if *alias == "" {
+			return nil, usagef("You must specify the alias of the deployment with -alias")
+		}
+		aliases, err := openAliases(*location)
+		if err != nil {
+			return nil, err
+		}
+		c, err := newClient()
+		if err != nil {
+			return nil, err
+		}
+		return pb.NewBlueGreen(c, aliases, *alias), nil
+	}
+}
+
+func blueGreenDeployCmd() *command {
+	cmd := newCommand("deploy", "DIR", "Upload a local directory to the idle bot, verify and test it, then switch the alias to it")
+	deployment := blueGreenFlags(cmd)
+	suites := cmd.fs.String("suite", "", "Comma separated test suite files the idle bot must pass before the switch.")
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			return usagef("You must specify the directory to deploy")
+		}
+		b, err := deployment()
+		if err != nil {
+			return err
+		}
+		if *suites != "" {
+			for _, path := range strings.Split(*suites, ",") {
+				suite, err := loadSuite(strings.TrimSpace(path))
+				if err != nil {
+					return err
+				}
+				b.Suites = append(b.Suites, suite)
+			}
+		}
+		res, err := b.Deploy(args[0])
+		if res == nil {
+			return err
+		}
+		if perr := printDeployResult(res); perr != nil {
+			return perr
+		}
+		if err != nil {
+			if res.Sync.VerifyErr != nil && *output == outputTable {
+				printVerify(res.Sync.VerifyErr)
+			}
+			return err
+		}
+		success("Deployed to %s, alias %s switched from %s.", res.Bot, b.Name, liveLabel(res.Previous))
+		return nil
+	}
+	return cmd
+}
+
+func printDeployResult(res *pb.DeployResult) error {
+	return printResult(res, func(w io.Writer) {
+		for _, a := range res.Sync.Actions {
+			if a.Err != nil {
+				row(w, a.Op, a.File, "FAILED: "+a.Err.Error())
+			} else {
+				row(w, a.Op, a.File)
+			}
+		}
+		fmt.Fprintf(w, "%d files changed on %s, %d unchanged\n", len(res.Sync.Actions), res.Bot, res.Sync.Unchanged)
+		for _, s := range res.Suites {
+			fmt.Fprintf(w, "Suite %s: %d passed, %d failed\n", s.Name, s.Passed, s.Failed)
+			for _, cr := range s.Cases {
+				if !cr.Passed {
+					row(w, "FAILED", cr.Name, cr.Failure())
+				}
+			}
+		}
+	})
+}
+
+// liveLabel describes the live bot of a deployment in messages
+func liveLabel(bot string) string {
+	if bot == "" {
+		return "no bot"
+	}
+	return bot
+}
+
+func blueGreenStatusCmd() *command {
+	cmd := newCommand("status", "", "Show the live and idle bots of a blue/green deployment")
+	deployment := blueGreenFlags(cmd)
+	cmd.run = func(args []string) error {
+		b, err := deployment()
+		if err != nil {
+			return err
+		}
+		live, err := b.Live()
+		if err != nil {
+			return err
+		}
+		idle, err := b.Idle()
+		if err != nil {
+			return err
+		}
+		view := struct {
+			Alias string `json:"alias"`
+			Live  string `json:"live,omitempty"`
+			Idle  string `json:"idle"`
+		}{b.Name, live, idle}
+		return printResult(view, func(w io.Writer) {
+			row(w, "Alias:", view.Alias)
+			row(w, "Live:", liveLabel(view.Live))
+			row(w, "Idle:", view.Idle)
+		})
+	}
+	return cmd
+}
+
+func blueGreenRollbackCmd() *command {
+	cmd := newCommand("rollback", "", "Switch the alias back to the bot it pointed at before the last deployment")
+	deployment := blueGreenFlags(cmd)
+	cmd.run = func(args []string) error {
+		b, err := deployment()
+		if err != nil {
+			return err
+		}
+		bot, err := b.Rollback()
+		if err != nil {
+			return err
+		}
+		success("Alias %s switched back to %s.", b.Name, bot)
+		return nil
+	}
+	return cmd
+}
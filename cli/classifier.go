@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// classifierFlag adds the flag of the rules classifying the inputs of the
+// chat adapters, and returns the classifier it configures
+func classifierFlag(fs *flag.FlagSet) func() (pb.Classifier, error) {
+	path := fs.String("classify", "", "File of the rules tagging the inputs, escalating them to -handoff or switching them to another bot, one per line as TAG: WORDS or /REGEXP/ -> escalate, bot NAME.")
+	return func() (pb.Classifier, error) {
+		if *path == "" {
+			return nil, nil
+		}
+		f, err := os.Open(*path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		k, err := pb.ParseKeywordClassifier(f)
+		if err != nil {
+			return nil, usagef("%v", err)
+		}
+		return k, nil
+	}
+}
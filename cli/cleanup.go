@@ -0,0 +1,36 @@
+package main
+
+import (
+	"io"
+	"strings"
+
+	"github.com/demisto/pb-go/aiml"
+)
+
+func cleanupCmd() *command {
+	cmd := newCommand("cleanup", "PATH...", "Suggest cleanups of local sets and maps: duplicates, case variants, punctuation and typos")
+	cmd.run = func(args []string) error {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		files, err := expandFiles(args)
+		if err != nil {
+			return err
+		}
+		suggestions, err := aiml.CleanupFiles(files)
+		if err != nil {
+			return err
+		}
+		err = printResult(suggestions, func(w io.Writer) {
+			for _, s := range suggestions {
+				row(w, s.File, s.Kind, strings.Join(s.Entries, " | "), s.Message)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		success("%d cleanups suggested.", len(suggestions))
+		return nil
+	}
+	return cmd
+}
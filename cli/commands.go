@@ -0,0 +1,645 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// commands is the list of the top level pbcli commands
+var commands = []*command{
+	listCmd(),
+	newGroup("bot", "Manage bots", botCreateCmd(), botDeleteCmd(), botFilesCmd(), botDownloadCmd()),
+	newGroup("file", "Manage bot files", fileUploadCmd(), fileBroadcastCmd(), fileDownloadCmd(), fileDeleteCmd()),
+	newGroup("set", "Import and export the sets of a bot as CSV", csvCmds("set")...),
+	newGroup("map", "Import and export the maps of a bot as CSV", csvCmds("map")...),
+	newGroup("learned", "Inspect and prune the categories a bot learned with <learnf>", learnedListCmd(), learnedPruneCmd()),
+	verifyCmd(),
+	talkCmd(),
+	syncCmd(),
+	watchCmd(),
+	monitorCmd(),
+	cloneCmd(),
+	promoteCmd(),
+	newGroup("bluegreen", "Deploy a bot as a blue and a green bot behind a routing alias of pbcli serve", blueGreenDeployCmd(), blueGreenStatusCmd(), blueGreenRollbackCmd()),
+	backupCmd(),
+	restoreCmd(),
+	exportCmd(),
+	testCmd(),
+	statsCmd(),
+	reportCmd(),
+	docsCmd(),
+	diffCmd(),
+	grepCmd(),
+	serveCmd(),
+	discordCmd(),
+	emailCmd(),
+	ircCmd(),
+	matrixCmd(),
+	replayCmd(),
+	transcriptsCmd(),
+	newGroup("userdata", "Export or delete the data kept about an end user, for privacy requests like the GDPR", userDataExportCmd(), userDataDeleteCmd(), userDataRevealCmd()),
+	lintCmd(),
+	importCmd(),
+	fmtCmd(),
+	upgradeCmd(),
+	graphCmd(),
+	coverageCmd(),
+	cleanupCmd(),
+	initCmd(),
+	benchCmd(),
+	loginCmd(),
+	logoutCmd(),
+	whoamiCmd(),
+	newGroup("profile", "Manage credentials profiles", profileAddCmd(), profileListCmd(), profileUseCmd(), profileRemoveCmd(), profileBotCmd()),
+}
+
+// nameFlag adds the bot name flag to the flag set
+func nameFlag(fs *flag.FlagSet) *string {
+	return fs.String("name", "", "The bot name to use. Defaults to the configured bot.")
+}
+
+// typingFlag adds the flag simulating the typing of the messages of the chat adapters
+func typingFlag(fs *flag.FlagSet) *float64 {
+	return fs.Float64("typing", 0, "Delay the messages as if typed at this many characters per second, e.g. 25. Disabled by default.")
+}
+
+// typing returns the typing simulation of the -typing flag, nil when disabled
+func typing(charsPerSecond float64) *pb.Typing {
+	if charsPerSecond <= 0 {
+		return nil
+	}
+	return pb.NewTyping(charsPerSecond)
+}
+
+// sessionFlags adds the flags of the session policy of the chat adapters
+func sessionFlags(fs *flag.FlagSet) *pb.SessionPolicy {
+	p := &pb.SessionPolicy{}
+	fs.DurationVar(&p.IdleTimeout, "idle-timeout", 0, "Start a new session for the users inactive this long, e.g. 30m. Zero to keep the sessions.")
+	fs.IntVar(&p.MaxTurns, "max-turns", 0, "Start a new session after this many inputs of a user. Zero for no limit.")
+	fs.BoolVar(&p.Reset, "reset-expired", false, "Clear the bot memory of the users whose session expired.")
+	return p
+}
+
+// fallbackFlag adds the flag of the canned reply of the chat adapters when the bot is unavailable
+func fallbackFlag(fs *flag.FlagSet) *string {
+	return fs.String("fallback", "", "Answer with this text when the bot is unavailable, e.g. \""+pb.DefaultFallbackReply+"\". Empty to not answer.")
+}
+
+// fallback returns the fallback answering with the reply, nil when there is no reply
+func fallback(reply string) *pb.Fallback {
+	if reply == "" {
+		return nil
+	}
+	f := pb.NewFallback(reply)
+	f.OnError = func(err error) {
+		warnf("Bot is unavailable, answered with the fallback - %v", err)
+	}
+	return f
+}
+
+// rateLimitFlags adds the flags throttling the users of the chat adapters
+func rateLimitFlags(fs *flag.FlagSet) *pb.RateLimiter {
+	l := &pb.RateLimiter{}
+	fs.IntVar(&l.Inputs, "rate-limit", 0, "Messages each user can send per minute, zero for no limit.")
+	fs.StringVar(&l.Reply, "rate-limit-reply", pb.DefaultRateLimitReply, "Reply to the users sending messages too fast.")
+	return l
+}
+
+// limiter returns the limiter of the flags, nil when there is no limit
+func limiter(l *pb.RateLimiter) *pb.RateLimiter {
+	if l.Inputs <= 0 {
+		return nil
+	}
+	return l
+}
+
+// requireName validates that the bot name was given, falling back to the
+// configured bot and telling where it comes from
+func requireName(name *string) error {
+	if *name == "" && cfg.Bot != "" {
+		*name = cfg.Bot
+		statusf("Using bot %s, the default bot of %s", *name, defaultBotSource())
+	}
+	if *name == "" {
+		return usagef("You must specify the bot name with -name or set a default bot with pbcli profile bot")
+	}
+	return nil
+}
+
+func listCmd() *command {
+	cmd := newCommand("list", "", "List the bots of the application")
+	cmd.run = func(args []string) error {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		res, err := c.List()
+		if err != nil {
+			return err
+		}
+		return printResult(res, func(w io.Writer) {
+			row(w, "NAME", "LANGUAGE", "COMPILED", "OPEN", "DESCRIPTION")
+			for _, b := range res {
+				row(w, b.Name, b.Language.Name(), b.Compiled, b.Open, b.Description)
+			}
+		})
+	}
+	return cmd
+}
+
+func botCreateCmd() *command {
+	cmd := newCommand("create", "", "Create a bot")
+	name := nameFlag(cmd.fs)
+	template := cmd.fs.String("template", "", "Template bot directory or zip archive to render with the -values, upload and verify.")
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		if *template != "" {
+			if err = c.CreateBotFromTemplate(*name, *template, nil); err != nil {
+				return printVerify(err)
+			}
+			success("Bot %s successfully created from %s.", *name, *template)
+			return nil
+		}
+		if err = c.CreateBot(*name); err != nil {
+			return err
+		}
+		success("Bot successfully created.")
+		return nil
+	}
+	return cmd
+}
+
+func botDeleteCmd() *command {
+	cmd := newCommand("delete", "", "Delete a bot")
+	name := nameFlag(cmd.fs)
+	force := cmd.fs.Bool("force", false, "Delete without asking for confirmation.")
+	backup := cmd.fs.Bool("backup", true, "Back up the bot files to the pbcli backups directory before deleting.")
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		if !*force {
+			if err := confirm(fmt.Sprintf("permanently delete the bot %s and all its files", *name), *name); err != nil {
+				return err
+			}
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		if *backup {
+			path, err := backupBeforeDelete(c, *name)
+			if err != nil {
+				return fmt.Errorf("Backup failed, the bot was not deleted - %v", err)
+			}
+			info("Bot %s backed up to %s", *name, path)
+		}
+		if err = c.DeleteBot(*name); err != nil {
+			return err
+		}
+		success("Bot successfully deleted.")
+		return nil
+	}
+	return cmd
+}
+
+func botFilesCmd() *command {
+	cmd := newCommand("files", "", "List the files of a bot")
+	name := nameFlag(cmd.fs)
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		res, err := c.ListFiles(*name)
+		if err != nil {
+			return err
+		}
+		return printResult(res, func(w io.Writer) {
+			row(w, "KIND", "NAME", "SIZE", "ITEMS", "MODIFIED")
+			kinds := []struct {
+				kind  string
+				files []pb.BotFile
+			}{
+				{"file", res.Files},
+				{"set", res.Sets},
+				{"map", res.Maps},
+				{"substitution", res.Substitutions},
+				{"properties", res.Properties},
+				{"pdefaults", res.Pdefaults},
+			}
+			for _, k := range kinds {
+				for _, f := range k.files {
+					row(w, k.kind, f.Name, f.Size, f.Items, f.Modified.Format("2006-01-02 15:04:05"))
+				}
+			}
+		})
+	}
+	return cmd
+}
+
+func botDownloadCmd() *command {
+	cmd := newCommand("download", "", "Download the files of a bot as a zip archive")
+	name := nameFlag(cmd.fs)
+	out := cmd.fs.String("out", "", "Output file. If not specified will write to standard output.")
+	types := cmd.fs.String("type", "", "Comma separated file types to download: "+strings.Join(pb.FileTypes, ", ")+". Defaults to all.")
+	since := cmd.fs.String("since", "", "Only download files modified after this time, as a date, RFC 3339 time or a duration ago like 24h.")
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		var filter pb.FileFilter
+		if *types != "" {
+			filter.Types = strings.Split(*types, ",")
+			if err := filter.Validate(); err != nil {
+				return usageError(err.Error())
+			}
+		}
+		if *since != "" {
+			t, err := parseSince(*since)
+			if err != nil {
+				return err
+			}
+			filter.Since = t
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		if len(filter.Types) > 0 || !filter.Since.IsZero() {
+			w := io.Writer(os.Stdout)
+			if *out != "" {
+				f, err := os.Create(*out)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			n, err := c.DownloadFilteredFiles(*name, filter, w)
+			if err != nil {
+				return err
+			}
+			if *out != "" {
+				success("%d bot files successfully downloaded.", n)
+			}
+			return nil
+		}
+		if *out == "" {
+			return c.DownloadFiles(*name, os.Stdout)
+		}
+		if err = c.DownloadFilesToPath(*name, *out); err != nil {
+			return err
+		}
+		success("Bot files successfully downloaded.")
+		return nil
+	}
+	return cmd
+}
+
+// parseSince parses a point in time given as a date, an RFC 3339 time or a duration before now
+func parseSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, usagef("Invalid time [%s] - use a date, an RFC 3339 time or a duration", s)
+}
+
+func fileUploadCmd() *command {
+	cmd := newCommand("upload", "FILE|DIR|GLOB...", "Upload personality files to a bot")
+	name := nameFlag(cmd.fs)
+	parallel := cmd.fs.Int("parallel", 4, "Number of files to upload concurrently.")
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return usagef("You must specify the files to upload")
+		}
+		if *parallel < 1 {
+			return usagef("Parallel must be at least 1")
+		}
+		files, err := expandFiles(args)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return usagef("No personality files found in %s", strings.Join(args, " "))
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		if len(files) == 1 {
+			if err = c.UploadFileFromPath(*name, files[0]); err != nil {
+				return err
+			}
+			success("File successfully uploaded.")
+			return nil
+		}
+		errs := make([]error, len(files))
+		sem := make(chan struct{}, *parallel)
+		var wg sync.WaitGroup
+		for i, f := range files {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, f string) {
+				defer wg.Done()
+				errs[i] = c.UploadFileFromPath(*name, f)
+				<-sem
+			}(i, f)
+		}
+		wg.Wait()
+		type uploadResult struct {
+			File  string `json:"file"`
+			Error string `json:"error,omitempty"`
+		}
+		results := make([]uploadResult, len(files))
+		failed := 0
+		for i, f := range files {
+			results[i].File = f
+			if errs[i] != nil {
+				results[i].Error = errs[i].Error()
+				failed++
+			}
+		}
+		err = printResult(results, func(w io.Writer) {
+			for _, r := range results {
+				if r.Error != "" {
+					row(w, "FAILED", r.File, r.Error)
+				} else {
+					row(w, "OK", r.File)
+				}
+			}
+			fmt.Fprintf(w, "%d uploaded, %d failed\n", len(files)-failed, failed)
+		})
+		if err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d files failed to upload", failed, len(files))
+		}
+		return nil
+	}
+	return cmd
+}
+
+func fileDownloadCmd() *command {
+	cmd := newCommand("download", "FILE", "Download a personality file of a bot")
+	name := nameFlag(cmd.fs)
+	out := cmd.fs.String("out", "", "Output file. If not specified will write to standard output.")
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		if len(args) != 1 {
+			return usagef("You must specify the file name to download")
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		if *out != "" {
+			if err = downloadFileToPath(c, *name, args[0], *out); err != nil {
+				return err
+			}
+			success("File successfully downloaded.")
+			return nil
+		}
+		return c.GetFile(*name, args[0], os.Stdout)
+	}
+	return cmd
+}
+
+// downloadFileToPath downloads the file of the bot to a temporary file renamed
+// once complete, so a failed download does not leave an empty or partial file
+func downloadFileToPath(c *pb.Client, name, filename, path string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	err = c.GetFile(name, filename, f)
+	if err == nil {
+		// Unlike with os.Create, the temporary file is only readable by its owner
+		err = f.Chmod(0644)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func fileDeleteCmd() *command {
+	cmd := newCommand("delete", "FILE", "Delete a personality file from a bot")
+	name := nameFlag(cmd.fs)
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		if len(args) != 1 {
+			return usagef("You must specify the file name to delete")
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		if err = c.DeleteFile(*name, args[0]); err != nil {
+			return err
+		}
+		success("File successfully deleted.")
+		return nil
+	}
+	return cmd
+}
+
+func verifyCmd() *command {
+	cmd := newCommand("verify", "", "Verify / compile a bot")
+	name := nameFlag(cmd.fs)
+	watch := cmd.fs.Bool("watch", false, "Keep running and re-verify whenever the bot files change.")
+	interval := cmd.fs.Duration("interval", 5*time.Second, "How often to check the bot files for changes with -watch.")
+	all := cmd.fs.Bool("all", false, "Verify all the bots of the application in parallel and report which ones do not compile.")
+	dir := cmd.fs.String("dir", "", "Local directory of the bot files, to point the github and sarif annotations of the compile errors at them.")
+	cmd.run = func(args []string) error {
+		if *all {
+			if *watch {
+				return usagef("Watch is not supported with -all")
+			}
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			results, err := c.VerifyAll(context.Background())
+			if results == nil {
+				return err
+			}
+			return printFleet(results, false)
+		}
+		if err := requireName(name); err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		if *watch {
+			return watchVerify(c, *name, *interval)
+		}
+		return printVerifyDir(c.Verify(*name), *dir)
+	}
+	return cmd
+}
+
+func talkCmd() *command {
+	cmd := newCommand("talk", "", "Talk with a bot")
+	name := nameFlag(cmd.fs)
+	input := cmd.fs.String("input", "", "Input to talk. If not specified starts an interactive session.")
+	sessionFile := cmd.fs.String("session-file", "", "File to keep the session in, so conversations continue across invocations.")
+	jsonOut := cmd.fs.Bool("json", false, "Print the full reply as JSON, like -output json, instead of the response text.")
+	ssml := cmd.fs.Bool("ssml", false, "Print the responses converted to SSML for text to speech engines.")
+	filters := cmd.fs.String("filter", "", "Comma separated reply filters: breaks, html, entities, whitespace, or all, markdown or mrkdwn to convert the HTML, and split=N to split the responses longer than N characters at the sentences.")
+	trace := cmd.fs.Bool("trace", false, "Request the matching trace of the reply, shown with -json.")
+	transcript := cmd.fs.String("transcript", "", "File to record the conversation in, for \"pbcli replay\". Appends to a transcript of the same bot.")
+	audio := cmd.fs.String("audio", "", "Audio file to transcribe with the -stt endpoint and talk instead of -input.")
+	sttUrl := cmd.fs.String("stt", os.Getenv("PB_STT_URL"), "Speech to text HTTP endpoint for -audio. Defaults to $PB_STT_URL, authorized with the bearer token in $PB_STT_TOKEN.")
+	sttForm := cmd.fs.String("stt-form", "", "Post the audio as a multipart form file in this field, e.g. file, instead of as the body.")
+	speak := cmd.fs.String("speak", "", "Audio file to write the responses to, synthesized by the -tts endpoint.")
+	ttsUrl := cmd.fs.String("tts", os.Getenv("PB_TTS_URL"), "Text to speech HTTP endpoint for -speak. Defaults to $PB_TTS_URL, authorized with the bearer token in $PB_TTS_TOKEN.")
+	ttsField := cmd.fs.String("tts-field", "", "Post the plain text in this field of a JSON request, e.g. input, instead of the SSML as the body.")
+	predicates := paramFlags{}
+	cmd.fs.Var(predicates, "set", "Set a predicate before talking, as NAME=VALUE. Can be repeated. The bot needs the categories of aiml/predicates.aiml of \"pbcli init\".")
+	ttsParams := paramFlags{}
+	cmd.fs.Var(ttsParams, "tts-param", "Additional field of the -tts-field JSON request, as KEY=VALUE, e.g. voice=alloy. Can be repeated.")
+	fromFile := cmd.fs.String("from-file", "", "Batch mode - talk each line of the file and write the input/response pairs, as CSV or with -output json or yaml.")
+	sessions := cmd.fs.Int("sessions", 1, "Batch mode - number of concurrent sessions to spread the inputs over.")
+	out := cmd.fs.String("out", "", "Batch mode - output file. If not specified will write to standard output.")
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		var options []pb.OptionFunc
+		if *filters != "" {
+			option, err := parseFilters(*filters)
+			if err != nil {
+				return err
+			}
+			options = append(options, option)
+		}
+		c, err := newClient(options...)
+		if err != nil {
+			return err
+		}
+		if *speak != "" && *ttsUrl == "" {
+			return usagef("You must specify the text to speech endpoint with -tts or PB_TTS_URL")
+		}
+		if *fromFile != "" {
+			if *sessions < 1 {
+				return usagef("Sessions must be at least 1")
+			}
+			w := io.Writer(os.Stdout)
+			if *out != "" {
+				f, err := os.Create(*out)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			return runBatch(c, *name, *fromFile, *sessions, w)
+		}
+		if *audio != "" {
+			if *input, err = transcribeFile(*audio, *sttUrl, *sttForm); err != nil {
+				return err
+			}
+			statusf("> %s", *input)
+		}
+		if *input != "" {
+			var s talkSession
+			if *sessionFile != "" {
+				if s, err = resumeSession(*sessionFile, *name); err != nil {
+					return err
+				}
+			}
+			if len(predicates) > 0 {
+				if s.ClientName == "" {
+					// The predicates are kept per client name
+					s.ClientName = newClientName()
+				}
+				if s.SessionId, err = setPredicates(c, *name, s.ClientName, s.SessionId, predicates); err != nil {
+					return err
+				}
+			}
+			res, err := c.TalkDebug(*name, *input, s.ClientName, s.SessionId, false, "", "", false, false, *trace, false)
+			if err != nil {
+				return err
+			}
+			verbosef("Replied in %v with %d requests, cached %v, hedged %v", res.Info.Latency.Round(time.Millisecond), res.Info.Attempts, res.Info.Cached, res.Info.Hedged)
+			if *jsonOut || *output != outputTable {
+				if *jsonOut {
+					*output = outputJSON
+				}
+				if err = printResult(res, nil); err != nil {
+					return err
+				}
+			} else if *ssml {
+				fmt.Println(pb.ToSSML(res.Responses))
+			} else {
+				fmt.Println(res)
+			}
+			if *speak != "" {
+				if err = speakFile(*speak, *ttsUrl, *ttsField, ttsParams, res.Responses); err != nil {
+					return err
+				}
+			}
+			if *transcript != "" {
+				t, err := loadTranscript(*transcript, *name)
+				if err != nil {
+					return err
+				}
+				if s.ClientName != "" {
+					t.ClientName = s.ClientName
+				}
+				t.Record(*input, res)
+				if err = t.WriteToPath(*transcript); err != nil {
+					return err
+				}
+			}
+			if *sessionFile != "" {
+				s.SessionId = res.SessionId
+				return saveSession(*sessionFile, s)
+			}
+			return nil
+		}
+		r := &repl{c: c, bot: *name, sessionFile: *sessionFile, transcriptFile: *transcript}
+		return r.run()
+	}
+	return cmd
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// botCacheTTL is how long the bot names used for completion are cached
+const botCacheTTL = 5 * time.Minute
+
+const bashCompletion = `# bash completion for pbcli
+_pbcli() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local IFS=$'\n'
+    COMPREPLY=($(pbcli __complete -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+    if [ ${#COMPREPLY[@]} -eq 0 ]; then
+        COMPREPLY=($(compgen -f -- "$cur"))
+    fi
+}
+complete -o filenames -F _pbcli pbcli
+`
+
+const zshCompletion = `#compdef pbcli
+# zsh completion for pbcli
+_pbcli() {
+    local -a candidates
+    candidates=("${(@f)$(pbcli __complete -- "${words[@]:1:$((CURRENT-1))}" 2>/dev/null)}")
+    if [[ -n "${candidates[1]}" ]]; then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+compdef _pbcli pbcli
+`
+
+const fishCompletion = `# fish completion for pbcli
+function __pbcli_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    pbcli __complete -- $tokens[2..-1] 2>/dev/null
+end
+complete -c pbcli -a '(__pbcli_complete)'
+`
+
+func completionCmd() *command {
+	cmd := newCommand("completion", "bash|zsh|fish", "Print the shell completion script, e.g. source <(pbcli completion bash)")
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			return usagef("You must specify the shell - bash, zsh or fish")
+		}
+		switch args[0] {
+		case "bash":
+			fmt.Print(bashCompletion)
+		case "zsh":
+			fmt.Print(zshCompletion)
+		case "fish":
+			fmt.Print(fishCompletion)
+		default:
+			return usagef("Shell [%s] is not supported - must be one of bash/zsh/fish", args[0])
+		}
+		return nil
+	}
+	return cmd
+}
+
+// completeCmd is the hidden command the completion scripts call with the words
+// typed so far (the last one being the word to complete). It prints one candidate per line.
+func completeCmd() *command {
+	cmd := newCommand("__complete", "WORDS...", "Print completion candidates")
+	cmd.hidden = true
+	cmd.run = func(args []string) error {
+		for _, c := range complete(args) {
+			fmt.Println(c)
+		}
+		return nil
+	}
+	return cmd
+}
+
+// takesValue returns true if the flag expects a value
+func takesValue(f *flag.Flag) bool {
+	if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		return false
+	}
+	return true
+}
+
+func flagNames(fs *flag.FlagSet) []string {
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, "-"+f.Name)
+	})
+	return names
+}
+
+// complete returns the candidates for the last word
+func complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur, words := words[len(words)-1], words[:len(words)-1]
+	fs := flag.CommandLine
+	cmds := commands
+	var leaf *command
+	prev := ""
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		prev = w
+		if strings.HasPrefix(w, "-") {
+			// Skip the value of flags that take one
+			if f := fs.Lookup(strings.TrimLeft(w, "-")); f != nil && takesValue(f) && !strings.Contains(w, "=") && i+1 < len(words) {
+				i++
+				prev = words[i]
+			}
+			continue
+		}
+		if leaf != nil {
+			continue
+		}
+		c := findCommand(cmds, w)
+		if c == nil {
+			return nil
+		}
+		if len(c.subs) > 0 {
+			cmds = c.subs
+		} else {
+			leaf, fs = c, c.fs
+		}
+	}
+
+	var candidates []string
+	switch {
+	case strings.TrimLeft(prev, "-") == "name" && !strings.HasPrefix(cur, "-"):
+		candidates = cachedBots()
+	case strings.TrimLeft(prev, "-") == "profile" && !strings.HasPrefix(cur, "-"):
+		candidates = profileNames()
+	case strings.HasPrefix(cur, "-"):
+		candidates = flagNames(fs)
+	case leaf == nil:
+		for _, c := range cmds {
+			if !c.hidden {
+				candidates = append(candidates, c.name)
+			}
+		}
+	case leaf.name == "clone":
+		candidates = cachedBots()
+	case leaf.name == "use" || leaf.name == "remove":
+		candidates = profileNames()
+	}
+	var res []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, cur) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// botCache is the content of the bot name cache file
+type botCache struct {
+	Updated time.Time `json:"updated"`
+	AppId   string    `json:"appId"`
+	Bots    []string  `json:"bots"`
+}
+
+func botCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "pbcli", "bots.json")
+}
+
+// cachedBots returns the bot names of the application, refreshing the cache when it is stale.
+// Errors are ignored, completion simply offers no bots.
+func cachedBots() []string {
+	path := botCachePath()
+	var cache botCache
+	if data, err := os.ReadFile(path); err == nil {
+		if json.Unmarshal(data, &cache) == nil && cache.AppId == cfg.AppId && time.Since(cache.Updated) < botCacheTTL {
+			return cache.Bots
+		}
+	}
+	*quiet = true
+	c, err := newClient()
+	if err != nil {
+		return cache.Bots
+	}
+	bots, err := c.List()
+	if err != nil {
+		return cache.Bots
+	}
+	cache = botCache{Updated: time.Now(), AppId: cfg.AppId}
+	for _, b := range bots {
+		cache.Bots = append(cache.Bots, b.Name)
+	}
+	if path != "" && os.MkdirAll(filepath.Dir(path), 0700) == nil {
+		if data, err := json.Marshal(cache); err == nil {
+			os.WriteFile(path, data, 0600)
+		}
+	}
+	return cache.Bots
+}
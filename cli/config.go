@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// config holds the settings pbcli uses to connect to pandorabots.
+// Values are read from the configuration file (the top level settings overridden
+// by the active profile), then from the PB_* environment variables and finally
+// from the command line flags, each overriding the former.
+type config struct {
+	AppId   string `json:"appId,omitempty"`
+	UserKey string `json:"userKey,omitempty"`
+	Bot     string `json:"bot,omitempty"` // Default bot name for commands that need one
+	Url     string `json:"url,omitempty"`
+	// Credentials is keyring when the user key is kept in the OS keyring instead of the file
+	Credentials string `json:"credentials,omitempty"`
+}
+
+// credentialsKeyring is the Credentials setting of the user keys kept in the OS keyring
+const credentialsKeyring = "keyring"
+
+// keyringService is the name pbcli stores the user keys under in the OS keyring
+const keyringService = "pbcli"
+
+// credentialStore returns the store of the user key of the configuration, nil for the configuration itself
+func (c config) credentialStore() pb.CredentialStore {
+	if c.Credentials == credentialsKeyring {
+		return pb.NewKeyringStore(keyringService)
+	}
+	return nil
+}
+
+// merge overrides the settings with the ones set in o
+func (c *config) merge(o config) {
+	for dst, src := range map[*string]string{&c.AppId: o.AppId, &c.UserKey: o.UserKey, &c.Bot: o.Bot, &c.Url: o.Url, &c.Credentials: o.Credentials} {
+		if src != "" {
+			*dst = src
+		}
+	}
+}
+
+// configFile is the content of the configuration file
+type configFile struct {
+	config
+	Profile  string            `json:"profile,omitempty"` // The profile used when none is specified
+	Profiles map[string]config `json:"profiles,omitempty"`
+}
+
+var (
+	cfg           config     // The resolved configuration
+	cfgFile       configFile // The configuration file as read
+	cfgPath       string     // The path of the configuration file
+	activeProfile string     // The name of the profile in use, if any
+)
+
+// defaultConfigPath returns ~/.config/pbcli/config.yaml (honoring XDG_CONFIG_HOME)
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "pbcli", "config.yaml")
+}
+
+// loadConfig resolves the configuration from the file, environment and global flags
+func loadConfig() error {
+	cfgPath = *configPath
+	explicit := cfgPath != ""
+	if !explicit {
+		cfgPath = os.Getenv("PB_CONFIG")
+		explicit = cfgPath != ""
+	}
+	if !explicit {
+		cfgPath = defaultConfigPath()
+	}
+	if cfgPath != "" {
+		data, err := os.ReadFile(cfgPath)
+		switch {
+		case err == nil:
+			if err = yamlUnmarshal(data, &cfgFile); err != nil {
+				return usagef("Invalid configuration file [%s] - %v", cfgPath, err)
+			}
+		case !os.IsNotExist(err) || explicit:
+			return err
+		}
+	}
+	cfg = cfgFile.config
+	env := config{
+		AppId:   os.Getenv("PB_APP_ID"),
+		UserKey: os.Getenv("PB_USER_KEY"),
+		Bot:     os.Getenv("PB_BOT"),
+		Url:     os.Getenv("PB_URL"),
+	}
+	activeProfile = *profileName
+	if activeProfile == "" {
+		activeProfile = os.Getenv("PB_PROFILE")
+	}
+	// A profile chosen with -profile or PB_PROFILE overrides the environment,
+	// so a stray PB_BOT does not redirect the commands to another bot
+	chosen := activeProfile != ""
+	if chosen {
+		cfg.merge(env)
+	} else {
+		activeProfile = cfgFile.Profile
+	}
+	if activeProfile != "" {
+		p, ok := cfgFile.Profiles[activeProfile]
+		if !ok {
+			return usagef("Profile [%s] is not defined in %s", activeProfile, cfgPath)
+		}
+		cfg.merge(p)
+		if p.Credentials == credentialsKeyring {
+			// The user key of the profile is in the keyring
+			cfg.UserKey = p.UserKey
+		}
+	}
+	if !chosen {
+		cfg.merge(env)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "appId":
+			cfg.AppId = *appId
+		case "userKey":
+			cfg.UserKey = *userKey
+		case "url":
+			cfg.Url = *rawurl
+		}
+	})
+	return nil
+}
+
+// saveConfig writes the configuration file
+func saveConfig() error {
+	if cfgPath == "" {
+		return usagef("Unable to determine the configuration file path, use -config")
+	}
+	data, err := yamlMarshal(cfgFile)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(cfgPath), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(cfgPath, data, 0600)
+}
+
+// defaultBotSource describes where the default bot of the configuration comes from
+func defaultBotSource() string {
+	switch {
+	case os.Getenv("PB_BOT") != "" && cfg.Bot == os.Getenv("PB_BOT"):
+		return "PB_BOT"
+	case activeProfile != "" && cfgFile.Profiles[activeProfile].Bot != "":
+		return "profile " + activeProfile
+	}
+	return cfgPath
+}
+
+// profileNames returns the sorted names of the configured profiles
+func profileNames() []string {
+	names := make([]string, 0, len(cfgFile.Profiles))
+	for name := range cfgFile.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// conversationConfig is the configuration of the conversations of the chat adapters
+type conversationConfig struct {
+	policy      pb.SessionPolicy
+	limiter     *pb.RateLimiter
+	fallback    *pb.Fallback
+	webhooks    *pb.Webhooks
+	escalator   *pb.Escalator
+	classifier  pb.Classifier
+	middleware  []pb.Middleware
+	transcripts pb.TranscriptStore // Set by the commands keeping transcripts
+}
+
+// conversationFlags adds the flags of the conversations of the chat
+// adapters, and returns the configuration they set up. Close the
+// configuration to post the queued webhook events.
+func conversationFlags(fs *flag.FlagSet) func(c *pb.Client) (*conversationConfig, error) {
+	policy := sessionFlags(fs)
+	limits := rateLimitFlags(fs)
+	fallbackReply := fallbackFlag(fs)
+	defaults := defaultResponsesFlag(fs)
+	webhooks := webhookFlags(fs, defaults)
+	escalator := handoffFlags(fs, defaults)
+	classifier := classifierFlag(fs)
+	middleware := middlewareFlags(fs)
+	return func(c *pb.Client) (*conversationConfig, error) {
+		cc := &conversationConfig{policy: *policy, limiter: limiter(limits), fallback: fallback(*fallbackReply)}
+		var err error
+		if cc.webhooks, err = webhooks(); err != nil {
+			return nil, err
+		}
+		if cc.escalator, err = escalator(); err != nil {
+			return nil, err
+		}
+		if cc.classifier, err = classifier(); err != nil {
+			return nil, err
+		}
+		if cc.middleware, err = middleware(c); err != nil {
+			return nil, err
+		}
+		return cc, nil
+	}
+}
+
+// configureConversation applies the configuration to a conversation of an adapter
+func (cc *conversationConfig) configureConversation(cv *pb.Conversation) {
+	cv.Policy = cc.policy
+	cv.Limiter = cc.limiter
+	cv.Fallback = cc.fallback
+	cv.Webhooks = cc.webhooks
+	cv.Escalator = cc.escalator
+	cv.Classifier = cc.classifier
+	cv.Middleware = cc.middleware
+	cv.Transcripts = cc.transcripts
+}
+
+// Close posts the queued webhook events
+func (cc *conversationConfig) Close() {
+	cc.webhooks.Close()
+}
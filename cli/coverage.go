@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/demisto/pb-go/aiml"
+)
+
+func coverageCmd() *command {
+	cmd := newCommand("coverage", "PATH...", "Match a corpus of user inputs against local AIML files and report what the bot does not cover")
+	corpus := cmd.fs.String("corpus", "", "The file with the user inputs, one per line.")
+	categories := cmd.fs.Bool("categories", false, "List the categories with the number of inputs they matched instead of the inputs no category covers.")
+	cmd.run = func(args []string) error {
+		if *corpus == "" {
+			return usagef("You must specify the corpus file with -corpus")
+		}
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		files, err := expandFiles(args)
+		if err != nil {
+			return err
+		}
+		m, err := aiml.MatcherFiles(files)
+		if err != nil {
+			return err
+		}
+		inputs, err := readInputs(*corpus)
+		if err != nil {
+			return err
+		}
+		utterances := make([]string, len(inputs))
+		for i, in := range inputs {
+			utterances[i] = in.Input
+		}
+		r := m.Coverage(utterances)
+		err = printResult(r, func(w io.Writer) {
+			if *categories {
+				for _, c := range r.Categories {
+					row(w, fmt.Sprintf("%s:%d", c.File, c.Line), c.Pattern, c.Hits)
+				}
+				return
+			}
+			for _, u := range r.Unmatched {
+				row(w, u.Count, u.Input)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		unused := 0
+		for _, c := range r.Categories {
+			if c.Hits == 0 {
+				unused++
+			}
+		}
+		percent := 0
+		if r.Utterances > 0 {
+			percent = r.Matched * 100 / r.Utterances
+		}
+		success("%d of %d inputs covered (%d%%), %d categories never matched.", r.Matched, r.Utterances, percent, unused)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// csvCmds returns the commands importing and exporting the sets or maps as CSV
+func csvCmds(kind string) []*command {
+	importFn, exportFn := (*pb.Client).ImportSetCSV, (*pb.Client).ExportSetCSV
+	columns := "a phrase per row"
+	if kind == "map" {
+		importFn, exportFn = (*pb.Client).ImportMapCSV, (*pb.Client).ExportMapCSV
+		columns = "a key and a value per row"
+	}
+
+	imp := newCommand("import", "FILE", "Upload a "+kind+" of a bot from a CSV file with "+columns)
+	impName := nameFlag(imp.fs)
+	as := imp.fs.String("as", "", "The "+kind+" name. Defaults to the file name without the extension.")
+	imp.run = func(args []string) error {
+		if err := requireName(impName); err != nil {
+			return err
+		}
+		if len(args) != 1 {
+			return usagef("You must specify the CSV file to import")
+		}
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		name := *as
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		if err = importFn(c, *impName, name, f); err != nil {
+			return err
+		}
+		success("%s%s %s successfully imported.", strings.ToUpper(kind[:1]), kind[1:], name)
+		return nil
+	}
+
+	exp := newCommand("export", strings.ToUpper(kind), "Write a "+kind+" of a bot as a CSV file with "+columns)
+	expName := nameFlag(exp.fs)
+	out := exp.fs.String("out", "", "Output file. If not specified will write to standard output.")
+	exp.run = func(args []string) error {
+		if err := requireName(expName); err != nil {
+			return err
+		}
+		if len(args) != 1 {
+			return usagef("You must specify the %s to export", kind)
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		var w io.Writer = os.Stdout
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		return exportFn(c, *expName, args[0], w)
+	}
+	return []*command{imp, exp}
+}
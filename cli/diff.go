@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	pb "github.com/demisto/pb-go"
+)
+
+func diffCmd() *command {
+	cmd := newCommand("diff", "BOT DIR|OTHER-BOT", "Show the differences between a bot and a local directory or another bot")
+	context := cmd.fs.Int("context", 3, "Number of context lines in the unified diffs.")
+	stat := cmd.fs.Bool("stat", false, "Only list the added, removed and changed files.")
+	cmd.run = func(args []string) error {
+		if len(args) != 2 {
+			return usagef("You must specify the bot and the directory or bot to compare with")
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		var d *pb.BotDiff
+		if fi, err := os.Stat(args[1]); err == nil && fi.IsDir() {
+			d, err = c.DiffDir(args[0], args[1])
+		} else {
+			d, err = c.DiffBots(args[0], args[1])
+		}
+		if err != nil {
+			return err
+		}
+		type fileView struct {
+			File   string        `json:"file"`
+			Status pb.DiffStatus `json:"status"`
+			Diff   string        `json:"diff,omitempty"`
+		}
+		view := struct {
+			From  string     `json:"from"`
+			To    string     `json:"to"`
+			Files []fileView `json:"files"`
+		}{From: d.From, To: d.To, Files: make([]fileView, 0)}
+		for _, f := range d.Files {
+			fv := fileView{File: f.File, Status: f.Status}
+			if !*stat {
+				fv.Diff = f.Unified(*context)
+			}
+			view.Files = append(view.Files, fv)
+		}
+		err = printResult(view, func(w io.Writer) {
+			if len(d.Files) == 0 {
+				fmt.Fprintf(w, "No differences between %s and %s.\n", d.From, d.To)
+			}
+			for _, f := range view.Files {
+				row(w, f.Status, f.File)
+			}
+		})
+		if err != nil || *output != outputTable {
+			return err
+		}
+		// The diffs are printed as is, outside of the aligned table
+		for _, f := range view.Files {
+			if f.Diff != "" {
+				fmt.Printf("\n%s", f.Diff)
+			}
+		}
+		return nil
+	}
+	return cmd
+}
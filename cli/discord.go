@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/demisto/pb-go/integrations/discord"
+)
+
+// guildFlags collects the -guild GUILD=BOT flags
+type guildFlags map[string]string
+
+func (g guildFlags) String() string {
+	var parts []string
+	for guild, bot := range g {
+		parts = append(parts, guild+"="+bot)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (g guildFlags) Set(s string) error {
+	guild, bot, ok := strings.Cut(s, "=")
+	if !ok || guild == "" || bot == "" {
+		return errors.New("Guild must be in the form GUILD=BOT")
+	}
+	g[guild] = bot
+	return nil
+}
+
+func discordCmd() *command {
+	cmd := newCommand("discord", "", "Run a bot as a Discord bot answering mentions and direct messages")
+	name := nameFlag(cmd.fs)
+	guilds := guildFlags{}
+	cmd.fs.Var(guilds, "guild", "Answer in a guild with another bot, as GUILD_ID=BOT. Can be repeated.")
+	format := cmd.fs.String("format", "plain", "Format of the messages: plain, or markdown keeping the links, bold text and lists of the responses.")
+	typingSpeed := typingFlag(cmd.fs)
+	conversations := conversationFlags(cmd.fs)
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		token := os.Getenv("DISCORD_TOKEN")
+		if token == "" {
+			return usagef("You must set DISCORD_TOKEN to the token of the Discord bot user")
+		}
+		filters, err := formatFilters(*format)
+		if err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		b := discord.New(c, *name, token)
+		b.Guilds = guilds
+		b.Filters = filters
+		b.Typing = typing(*typingSpeed)
+		cc, err := conversations(c)
+		if err != nil {
+			return err
+		}
+		defer cc.Close()
+		b.Configure = cc.configureConversation
+		b.ErrorLog = log.New(logWriter{warnf}, "", 0)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		info("Connecting %s to Discord, press Ctrl+C to stop", *name)
+		return b.Run(ctx)
+	}
+	return cmd
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/demisto/pb-go/integrations/email"
+)
+
+func emailCmd() *command {
+	cmd := newCommand("email", "", "Answer emails with a bot, polling an IMAP mailbox or from an inbound email webhook")
+	name := nameFlag(cmd.fs)
+	imapAddr := cmd.fs.String("imap", "", "IMAP server to poll, as HOST:PORT (TLS).")
+	mailbox := cmd.fs.String("mailbox", "INBOX", "IMAP mailbox to poll.")
+	interval := cmd.fs.Duration("interval", time.Minute, "How often to poll the mailbox.")
+	webhook := cmd.fs.String("webhook", "", "Address to serve the inbound email webhook on, under /email. Requires $EMAIL_WEBHOOK_TOKEN.")
+	smtpAddr := cmd.fs.String("smtp", "", "SMTP submission server to reply with, as HOST:PORT.")
+	from := cmd.fs.String("from", "", "Address of the bot, the replies are sent from it.")
+	conversations := conversationFlags(cmd.fs)
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		if *smtpAddr == "" || *from == "" {
+			return usagef("You must specify -smtp and -from")
+		}
+		if (*imapAddr == "") == (*webhook == "") {
+			return usagef("You must specify either -imap or -webhook")
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		// The same account usually sends and receives the mails of the bot
+		user, password := os.Getenv("EMAIL_USER"), os.Getenv("EMAIL_PASSWORD")
+		g := email.NewGateway(c, *name, nil, email.SMTP{Addr: *smtpAddr, User: user, Password: password, From: *from})
+		g.ErrorLog = log.New(logWriter{warnf}, "", 0)
+		cc, err := conversations(c)
+		if err != nil {
+			return err
+		}
+		defer cc.Close()
+		cc.configureConversation(g.Conversation)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if *webhook != "" {
+			if g.WebhookToken = os.Getenv("EMAIL_WEBHOOK_TOKEN"); g.WebhookToken == "" {
+				return usagef("You must set EMAIL_WEBHOOK_TOKEN to serve the webhook")
+			}
+			mux := http.NewServeMux()
+			mux.Handle("/email", g)
+			srv := &http.Server{Addr: *webhook, Handler: mux}
+			go func() {
+				<-ctx.Done()
+				srv.Close()
+			}()
+			info("Answering emails with %s on http://%s/email", *name, *webhook)
+			if err = srv.ListenAndServe(); err == http.ErrServerClosed {
+				return nil
+			}
+			return err
+		}
+		info("Answering the emails of %s with %s every %v, press Ctrl+C to stop", *imapAddr, *name, *interval)
+		return g.Poll(ctx, email.IMAP{Addr: *imapAddr, User: user, Password: password, Mailbox: *mailbox, Interval: *interval})
+	}
+	return cmd
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// Exit codes of pbcli
+const (
+	exitOK       = 0 // The command succeeded
+	exitUsage    = 1 // Invalid command line or configuration
+	exitError    = 2 // Any other failure
+	exitAuth     = 3 // Missing or rejected credentials
+	exitNotFound = 4 // The bot or file does not exist
+	exitCompile  = 5 // The bot failed to compile or lint
+	exitNetwork  = 6 // Pandorabots could not be reached
+	exitFailed   = 7 // Some tests failed
+)
+
+// exitCode maps an error returned by a command to the process exit code
+func exitCode(err error) int {
+	var (
+		ue     usageError
+		ce     *pb.CompileError
+		tf     *testsFailed
+		lf     lintFailed
+		apiErr *pb.APIError
+		netErr net.Error
+	)
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &ue):
+		return exitUsage
+	case errors.Is(err, pb.ErrNoCred):
+		return exitAuth
+	case errors.As(err, &ce), errors.As(err, &lf):
+		return exitCompile
+	case errors.As(err, &tf):
+		return exitFailed
+	case errors.As(err, &apiErr):
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return exitAuth
+		case http.StatusNotFound:
+			return exitNotFound
+		}
+	case errors.As(err, &netErr):
+		return exitNetwork
+	}
+	return exitError
+}
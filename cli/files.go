@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// isBotFile returns true if the file has the extension of a personality file
+func isBotFile(path string) bool {
+	switch filepath.Ext(path) {
+	case ".aiml", ".set", ".map", ".substitution", ".properties", ".pdefaults":
+		return true
+	}
+	return false
+}
+
+// expandFiles resolves the paths given on the command line to the bot files to use.
+// Globs are expanded (for shells that do not) and directories are walked for
+// personality files. Files given explicitly are always included.
+func expandFiles(args []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+	for _, arg := range args {
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			m, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, usagef("Invalid pattern [%s] - %v", arg, err)
+			}
+			if len(m) == 0 {
+				return nil, usagef("No files match [%s]", arg)
+			}
+			matches = m
+		}
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				add(path)
+				continue
+			}
+			err = filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if fi.IsDir() && p != path && strings.HasPrefix(fi.Name(), ".") {
+					return filepath.SkipDir
+				}
+				if !fi.IsDir() && isBotFile(p) {
+					add(p)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// loadValues reads the values of the template placeholders from a YAML or JSON file
+func loadValues(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(data, &values)
+	} else {
+		err = yamlUnmarshal(data, &values)
+	}
+	if err != nil {
+		return nil, usagef("Invalid values file [%s] - %v", path, err)
+	}
+	return values, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// replyFilters are the reply filters selectable on the command line
+var replyFilters = map[string]pb.ReplyFilter{
+	"breaks":     pb.SplitBreaks,
+	"html":       pb.StripHTML,
+	"entities":   pb.DecodeEntities,
+	"whitespace": pb.CollapseWhitespace,
+	"markdown":   pb.Markdown,
+	"mrkdwn":     pb.SlackMarkdown,
+}
+
+// filterNames is the order the filters are listed and applied in when all are selected
+var filterNames = []string{"breaks", "html", "entities", "whitespace"}
+
+// parseFilters converts a comma separated list of filter names to the client option.
+// The name "all" selects all the filters and split=N splits the responses longer
+// than N characters.
+func parseFilters(list string) (pb.OptionFunc, error) {
+	var filters []pb.ReplyFilter
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "all" {
+			for _, n := range filterNames {
+				filters = append(filters, replyFilters[n])
+			}
+			continue
+		}
+		if limit, ok := strings.CutPrefix(name, "split="); ok {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n < 1 {
+				return nil, usagef("Split length [%s] is not valid", limit)
+			}
+			filters = append(filters, pb.SplitLength(n))
+			continue
+		}
+		f, ok := replyFilters[name]
+		if !ok {
+			return nil, usagef("Filter [%s] is not recognized - use %s, markdown, mrkdwn, all or split=N", name, strings.Join(filterNames, ", "))
+		}
+		filters = append(filters, f)
+	}
+	return pb.SetReplyFilters(filters...), nil
+}
+
+// formatFilters returns the filters of the adapters formatting the responses
+// as plain text, the default, or converting their HTML to markdown or mrkdwn
+func formatFilters(format string) ([]pb.ReplyFilter, error) {
+	switch format {
+	case "", "plain":
+		return nil, nil
+	case "markdown":
+		return []pb.ReplyFilter{pb.SplitBreaks, pb.Markdown}, nil
+	case "mrkdwn":
+		return []pb.ReplyFilter{pb.SplitBreaks, pb.SlackMarkdown}, nil
+	}
+	return nil, usagef("Format [%s] is not recognized - use plain, markdown or mrkdwn", format)
+}
+
+// inputFilters are the input filters selectable on the command line
+var inputFilters = map[string]pb.InputFilter{
+	"space":       pb.NormalizeSpace,
+	"nfc":         pb.NormalizeNFC,
+	"emoji":       pb.NameEmoji,
+	"strip-emoji": pb.StripEmoji,
+}
+
+// inputFilterNames is the order the input filters are applied in when all are selected
+var inputFilterNames = []string{"nfc", "emoji", "space"}
+
+// newScrubber returns the scrubber of the -scrub and -mask flags, nil when neither is set
+func newScrubber() (*pb.Scrubber, error) {
+	if !*scrub && *mask == "" {
+		return nil, nil
+	}
+	s := &pb.Scrubber{}
+	if *scrub {
+		s.Rules = pb.PIIRules
+	}
+	if *mask != "" {
+		data, err := os.ReadFile(*mask)
+		if err != nil {
+			return nil, err
+		}
+		s.Words = strings.Split(string(data), "\n")
+	}
+	return s, nil
+}
+
+// parseInputFilters converts a comma separated list of input filter names and
+// the scrubber, if any, to the client option
+func parseInputFilters(list string, s *pb.Scrubber) (pb.OptionFunc, error) {
+	var filters []pb.InputFilter
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		switch f, ok := inputFilters[name]; {
+		case name == "":
+		case name == "all":
+			for _, n := range inputFilterNames {
+				filters = append(filters, inputFilters[n])
+			}
+		case ok:
+			filters = append(filters, f)
+		default:
+			return nil, usagef("Input filter [%s] is not recognized - use space, nfc, emoji, strip-emoji or all", name)
+		}
+	}
+	if s != nil {
+		filters = append(filters, s.Scrub)
+	}
+	return pb.SetInputFilters(filters...), nil
+}
+
+// calloutFlags collects the -callout SERVICE=URL flags, the empty service answers the others
+type calloutFlags map[string]string
+
+func (f calloutFlags) String() string {
+	var parts []string
+	for service, url := range f {
+		parts = append(parts, service+"="+url)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (f calloutFlags) Set(s string) error {
+	service, url, ok := strings.Cut(s, "=")
+	if !ok || strings.Contains(service, "/") {
+		service, url = "", s
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return errors.New("Callout must be in the form SERVICE=URL or URL")
+	}
+	f[service] = url
+	return nil
+}
+
+// newCallouts returns the callouts of the flags, calling the URLs with the
+// query in the q parameter and the service in the service parameter.
+// A bearer token is read from PB_CALLOUT_TOKEN.
+func newCallouts(f calloutFlags) *pb.Callouts {
+	cs := &pb.Callouts{Services: make(map[string]pb.Callout), ErrorLog: log.New(logWriter{warnf}, "", 0)}
+	header := http.Header{}
+	if token := os.Getenv("PB_CALLOUT_TOKEN"); token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	for service, url := range f {
+		h := &pb.HTTPCallout{Url: url, ServiceParam: "service", Header: header}
+		cs.Services[service] = h.Call
+	}
+	return cs
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// fleetBots returns the bots of the -bots flag, or all the bots of the application with -all
+func fleetBots(c *pb.Client, bots string, all bool) ([]string, error) {
+	if all {
+		list, err := c.List()
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, len(list))
+		for i, b := range list {
+			names[i] = b.Name
+		}
+		return names, nil
+	}
+	var names []string
+	for _, b := range strings.Split(bots, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			names = append(names, b)
+		}
+	}
+	return names, nil
+}
+
+// printFleet prints the per bot results of a fleet operation, with the upload
+// column for broadcasts. An error is returned if any bot failed.
+func printFleet(results []pb.FleetResult, upload bool) error {
+	type fleetView struct {
+		Bot      string              `json:"bot"`
+		Uploaded *bool               `json:"uploaded,omitempty"`
+		Compiled bool                `json:"compiled"`
+		Messages []pb.CompileMessage `json:"messages,omitempty"`
+		Error    string              `json:"error,omitempty"`
+	}
+	views := make([]fleetView, len(results))
+	failed := 0
+	for i, r := range results {
+		views[i] = fleetView{Bot: r.Bot, Compiled: r.Compiled, Messages: r.CompileMessages()}
+		if upload {
+			uploaded := r.Uploaded
+			views[i].Uploaded = &uploaded
+		}
+		if r.Err != nil {
+			views[i].Error = r.Err.Error()
+			failed++
+		}
+	}
+	err := printResult(views, func(w io.Writer) {
+		for _, r := range results {
+			var ce *pb.CompileError
+			switch {
+			case r.Err == nil:
+				row(w, "OK", r.Bot)
+			case errors.As(r.Err, &ce):
+				row(w, "BROKEN", r.Bot, r.Err)
+			default:
+				row(w, "FAILED", r.Bot, r.Err)
+			}
+		}
+		fmt.Fprintf(w, "%d ok, %d failed\n", len(results)-failed, failed)
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d bots failed", failed, len(results))
+	}
+	return nil
+}
+
+func fileBroadcastCmd() *command {
+	cmd := newCommand("broadcast", "FILE", "Upload a shared personality file to many bots and verify them")
+	bots := cmd.fs.String("bots", "", "Comma separated names of the bots to upload the file to.")
+	all := cmd.fs.Bool("all", false, "Upload the file to all the bots of the application.")
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			return usagef("You must specify the file to upload")
+		}
+		if *bots == "" && !*all {
+			return usagef("You must specify the bots with -bots or -all")
+		}
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		names, err := fleetBots(c, *bots, *all)
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return usagef("No bots to upload the file to")
+		}
+		results, err := c.BroadcastUpload(names, filepath.Base(args[0]), data)
+		if results == nil {
+			return err
+		}
+		return printFleet(results, true)
+	}
+	return cmd
+}
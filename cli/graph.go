@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/demisto/pb-go/aiml"
+)
+
+func graphCmd() *command {
+	cmd := newCommand("graph", "PATH...", "Export the srai, that and topic links of local AIML files as a GraphViz graph")
+	orphans := cmd.fs.Bool("orphans", false, "Only list the unreachable categories and the srai reaching no category.")
+	cmd.run = func(args []string) error {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		files, err := expandFiles(args)
+		if err != nil {
+			return err
+		}
+		g, err := aiml.GraphFiles(files)
+		if err != nil {
+			return err
+		}
+		if !*orphans {
+			if *output == outputTable {
+				return g.WriteDOT(os.Stdout)
+			}
+			return printResult(g, nil)
+		}
+		nodes, edges := g.Orphans(), g.Unresolved()
+		if nodes == nil {
+			nodes = []aiml.GraphNode{}
+		}
+		if edges == nil {
+			edges = []aiml.GraphEdge{}
+		}
+		err = printResult(map[string]interface{}{"orphans": nodes, "unresolved": edges}, func(w io.Writer) {
+			for _, n := range nodes {
+				if n.Kind == "topic" {
+					row(w, "topic", n.Topic, "topic is never set")
+					continue
+				}
+				reason := "that is never produced"
+				if n.That == "" {
+					reason = "topic is never set"
+				}
+				row(w, fmt.Sprintf("%s:%d", n.File, n.Line), n.Pattern, reason)
+			}
+			for _, e := range edges {
+				row(w, e.From, e.Text, "srai only reaches the default category")
+			}
+		})
+		if err != nil {
+			return err
+		}
+		success("%d unreachable categories and topics, %d unresolved srai.", len(nodes), len(edges))
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+)
+
+func grepCmd() *command {
+	cmd := newCommand("grep", "QUERY", "Search the files of a bot for the lines containing a text, e.g. to find where a response comes from")
+	name := nameFlag(cmd.fs)
+	regex := cmd.fs.Bool("E", false, "The query is a regular expression, matched case sensitively unless it starts with (?i).")
+	kind := cmd.fs.String("kind", "", "Only search this part of the bot: pattern, that, topic, template, or a file kind like set.")
+	cache := cmd.fs.String("cache", "", "Keep the downloaded files in this directory, or s3://BUCKET/PREFIX, and only download them again when the bot changed.")
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		if len(args) != 1 {
+			return usagef("You must specify the text to search for")
+		}
+		query := regexp.QuoteMeta(args[0])
+		if *regex {
+			query = args[0]
+		} else {
+			query = "(?i)" + query
+		}
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return usagef("Invalid regular expression [%s] - %v", args[0], err)
+		}
+		var options []pb.OptionFunc
+		if *cache != "" {
+			options = append(options, pb.SetFileCache(pb.NewStorageFileCache(openStorage(*cache))))
+		}
+		c, err := newClient(options...)
+		if err != nil {
+			return err
+		}
+		matches, err := c.SearchBotRegexp(*name, re)
+		if err != nil {
+			return err
+		}
+		if *kind != "" {
+			filtered := []pb.SearchMatch{}
+			for _, m := range matches {
+				if strings.EqualFold(m.Kind, *kind) {
+					filtered = append(filtered, m)
+				}
+			}
+			matches = filtered
+		}
+		err = printResult(matches, func(w io.Writer) {
+			for _, m := range matches {
+				row(w, fmt.Sprintf("%s:%d", m.File, m.Line), m.Kind, m.Pattern, m.Text)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			warnf("No match found in %s", *name)
+		}
+		return nil
+	}
+	return cmd
+}
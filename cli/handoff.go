@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// handoffFlags adds the flags escalating the conversations of the chat
+// adapters to human agents, and returns the escalator they configure
+func handoffFlags(fs *flag.FlagSet, defaults *string) func() (*pb.Escalator, error) {
+	slack := fs.String("handoff", "", "Slack incoming webhook URL to escalate the conversations to a human agent to, pausing the bot for them.")
+	phrases := fs.String("handoff-phrases", "agent,human,operator", "Comma separated words of the inputs escalated to a human agent.")
+	after := fs.Int("handoff-after", 0, "Escalate after this many -default-responses in a row. Zero to not escalate on the default responses.")
+	reply := fs.String("handoff-reply", pb.DefaultHandoffReply, "Reply to the users escalated to a human agent.")
+	release := fs.Duration("handoff-release", 30*time.Minute, "Resume the bot for the users escalated this long ago.")
+	return func() (*pb.Escalator, error) {
+		if *slack == "" {
+			return nil, nil
+		}
+		x := pb.NewEscalator(pb.NewSlackHandoff(*slack), splitList(*phrases)...)
+		x.MaxDefaults, x.Reply, x.ReleaseAfter = *after, *reply, *release
+		var err error
+		if x.DefaultResponses, err = loadDefaultResponses(*defaults); err != nil {
+			return nil, err
+		}
+		if x.MaxDefaults > 0 && len(x.DefaultResponses) == 0 {
+			return nil, usagef("You must specify the -default-responses of -handoff-after")
+		}
+		return x, nil
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/demisto/pb-go/aiml"
+	"github.com/demisto/pb-go/rivescript"
+)
+
+func importCmd() *command {
+	cmd := newCommand("import", "FILE|DIR...", "Convert RiveScript files to AIML and upload them to a bot")
+	name := nameFlag(cmd.fs)
+	out := cmd.fs.String("out", "", "Write the converted files to this directory instead of uploading them.")
+	cmd.run = func(args []string) error {
+		if len(args) == 0 {
+			return usagef("You must specify the RiveScript files to import")
+		}
+		if *out == "" {
+			if err := requireName(name); err != nil {
+				return err
+			}
+		}
+		res, err := rivescript.ConvertFiles(args)
+		if err != nil {
+			return err
+		}
+		issues := res.Issues
+		if issues == nil {
+			issues = []aiml.Issue{}
+		}
+		err = printResult(issues, func(w io.Writer) {
+			for _, i := range issues {
+				location := i.File
+				if i.Line > 0 {
+					location = fmt.Sprintf("%s:%d", i.File, i.Line)
+				}
+				row(w, location, i.Severity, i.Message)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if len(res.Files) == 0 {
+			return usagef("No RiveScript triggers found in the files")
+		}
+		if errs := aiml.Errors(res.Issues); errs > 0 {
+			warnf("%d constructs were not translated", errs)
+		}
+		files := make([]string, 0, len(res.Files))
+		for file := range res.Files {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		if *out != "" {
+			if err = os.MkdirAll(*out, 0755); err != nil {
+				return err
+			}
+			for _, file := range files {
+				if err = os.WriteFile(filepath.Join(*out, file), res.Files[file], 0644); err != nil {
+					return err
+				}
+				info("Wrote %s", filepath.Join(*out, file))
+			}
+			success("%d files converted.", len(files))
+			return nil
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		if err = res.Upload(c, *name); err != nil {
+			return printVerify(err)
+		}
+		success("%d files uploaded to %s and the bot verified.", len(files), *name)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// manifestFile is the name of the bot workspace manifest
+const manifestFile = "bot.yaml"
+
+// manifest describes a local bot workspace
+type manifest struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Language    pb.Language `json:"language,omitempty"`
+}
+
+// loadManifest reads the manifest of the workspace in dir. A missing manifest is empty.
+func loadManifest(dir string) (manifest, error) {
+	var m manifest
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	if err = yamlUnmarshal(data, &m); err != nil {
+		return m, usagef("Invalid manifest [%s] - %v", filepath.Join(dir, manifestFile), err)
+	}
+	return m, nil
+}
+
+// requireDirName defaults the bot name to the one in the manifest of dir
+func requireDirName(name *string, dir string) error {
+	if *name == "" {
+		m, err := loadManifest(dir)
+		if err != nil {
+			return err
+		}
+		if *name = m.Name; *name != "" {
+			statusf("Using bot %s of the manifest of %s", *name, dir)
+		}
+	}
+	return requireName(name)
+}
+
+const starterAIML = `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+  <category>
+    <pattern>HELLO</pattern>
+    <template>Hi! I am <bot name="name"/>. What can I do for you?</template>
+  </category>
+  <category>
+    <pattern>HI</pattern>
+    <template><srai>HELLO</srai></template>
+  </category>
+</aiml>
+`
+
+// udcAIML holds the ultimate default category, matching any input no other category matches
+const udcAIML = `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+  <category>
+    <pattern>*</pattern>
+    <template>I do not have an answer for that yet.</template>
+  </category>
+</aiml>
+`
+
+func initCmd() *command {
+	cmd := newCommand("init", "NAME", "Create a local bot workspace with starter files")
+	dir := cmd.fs.String("dir", "", "Directory to create the workspace in. Defaults to NAME.")
+	description := cmd.fs.String("description", "", "Description of the bot for the manifest.")
+	language := cmd.fs.String("language", string(pb.LanguageEnglish), "Language of the bot.")
+	create := cmd.fs.Bool("create", false, "Also create the bot on pandorabots and upload the starter files.")
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			return usagef("You must specify the bot name")
+		}
+		name := args[0]
+		if *dir == "" {
+			*dir = name
+		}
+		if !pb.Language(*language).Known() {
+			warnf("Language [%s] is not one of the known pandorabots languages", *language)
+		}
+		m := manifest{Name: name, Description: *description, Language: pb.Language(*language)}
+		mdata, err := yamlMarshal(m)
+		if err != nil {
+			return err
+		}
+		files := []struct {
+			path string
+			data string
+		}{
+			{manifestFile, string(mdata)},
+			{filepath.Join("aiml", "udc.aiml"), udcAIML},
+			{filepath.Join("aiml", "starter.aiml"), starterAIML},
+			{filepath.Join("aiml", "predicates.aiml"), pb.PredicateAIML},
+			{filepath.Join("sets", "colors.set"), "[]\n"},
+			{filepath.Join("maps", "capitals.map"), "[]\n"},
+			{name + ".properties", fmt.Sprintf(`[
+  ["name", %q],
+  ["language", %q],
+  ["version", "0.1"],
+  ["default-get", "unknown"]
+]
+`, name, *language)},
+		}
+		for _, f := range files {
+			path := filepath.Join(*dir, f.path)
+			if _, err := os.Stat(path); err == nil {
+				return usagef("File [%s] already exists", path)
+			}
+		}
+		for _, f := range files {
+			path := filepath.Join(*dir, f.path)
+			if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err = os.WriteFile(path, []byte(f.data), 0644); err != nil {
+				return err
+			}
+			info("Created %s", path)
+		}
+		if *create {
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			if err = c.CreateBot(name); err != nil {
+				return err
+			}
+			res, err := c.SyncDir(name, *dir, pb.SyncOptions{Verify: true})
+			if err != nil {
+				return err
+			}
+			if failed := res.Failed(); failed > 0 {
+				return fmt.Errorf("%d of %d uploads failed", failed, len(res.Actions))
+			}
+			if res.VerifyErr != nil {
+				return res.VerifyErr
+			}
+			success("Bot %s created from %s.", name, *dir)
+			return nil
+		}
+		success("Workspace %s is ready. Run \"pbcli bot create -name %s\" and \"pbcli sync %s\" to upload it.", *dir, name, *dir)
+		return nil
+	}
+	return cmd
+}
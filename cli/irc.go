@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/demisto/pb-go/integrations/irc"
+)
+
+func ircCmd() *command {
+	cmd := newCommand("irc", "", "Run a bot as an IRC client answering addressed and private messages")
+	name := nameFlag(cmd.fs)
+	server := cmd.fs.String("server", "", "IRC server, as HOST:PORT (TLS).")
+	insecure := cmd.fs.Bool("insecure", false, "Connect without TLS.")
+	nick := cmd.fs.String("nick", "", "Nick of the bot. Defaults to the bot name.")
+	channels := cmd.fs.String("channels", "", "Comma separated channels to join.")
+	typingSpeed := typingFlag(cmd.fs)
+	conversations := conversationFlags(cmd.fs)
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		if *server == "" {
+			return usagef("You must specify -server")
+		}
+		if *nick == "" {
+			*nick = *name
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		b := irc.New(c, *name, *server, *nick)
+		b.Typing = typing(*typingSpeed)
+		cc, err := conversations(c)
+		if err != nil {
+			return err
+		}
+		defer cc.Close()
+		b.Configure = cc.configureConversation
+		for _, ch := range strings.Split(*channels, ",") {
+			if ch = strings.TrimSpace(ch); ch != "" {
+				b.Channels = append(b.Channels, ch)
+			}
+		}
+		b.Insecure = *insecure
+		b.Password = os.Getenv("IRC_PASSWORD")
+		b.ErrorLog = log.New(logWriter{warnf}, "", 0)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		info("Connecting %s to %s as %s, press Ctrl+C to stop", *name, *server, *nick)
+		return b.Run(ctx)
+	}
+	return cmd
+}
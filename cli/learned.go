@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io"
+	"regexp"
+
+	pb "github.com/demisto/pb-go"
+)
+
+func learnedListCmd() *command {
+	cmd := newCommand("list", "", "List the categories a bot learned from its users")
+	name := nameFlag(cmd.fs)
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		learned, err := c.Learned(*name)
+		if err != nil {
+			return err
+		}
+		return printResult(learned, func(w io.Writer) {
+			row(w, "LINE", "PATTERN", "THAT", "TEMPLATE")
+			for _, l := range learned {
+				row(w, l.Line, l.Pattern, l.That, l.Template)
+			}
+		})
+	}
+	return cmd
+}
+
+func learnedPruneCmd() *command {
+	cmd := newCommand("prune", "", "Remove the learned categories matching regular expressions and verify the bot")
+	name := nameFlag(cmd.fs)
+	pattern := cmd.fs.String("pattern", "", "Remove the categories whose pattern matches this regular expression.")
+	template := cmd.fs.String("template", "", "Remove the categories whose template matches this regular expression.")
+	all := cmd.fs.Bool("all", false, "Remove all the learned categories.")
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		if *pattern == "" && *template == "" && !*all {
+			return usagef("You must specify -pattern, -template or -all")
+		}
+		var patternRe, templateRe *regexp.Regexp
+		var err error
+		if *pattern != "" {
+			if patternRe, err = regexp.Compile(*pattern); err != nil {
+				return usagef("Invalid regular expression [%s] - %v", *pattern, err)
+			}
+		}
+		if *template != "" {
+			if templateRe, err = regexp.Compile(*template); err != nil {
+				return usagef("Invalid regular expression [%s] - %v", *template, err)
+			}
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		removed, err := c.PruneLearned(*name, func(l pb.LearnedCategory) bool {
+			return *all || (patternRe == nil || patternRe.MatchString(l.Pattern)) && (templateRe == nil || templateRe.MatchString(l.Template))
+		})
+		if err != nil {
+			return printVerify(err)
+		}
+		success("%d learned categories removed from %s.", removed, *name)
+		return nil
+	}
+	return cmd
+}
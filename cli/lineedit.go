@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxHistory is the number of history entries kept in the history file
+const maxHistory = 500
+
+// lineEditor reads lines from the terminal with basic editing and history.
+// When standard input is not a terminal lines are read as is.
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	terminal bool
+	history  []string
+	histFile string // Optional file to persist the history in
+}
+
+func newLineEditor(histFile string) *lineEditor {
+	e := &lineEditor{
+		in:       bufio.NewReader(os.Stdin),
+		out:      os.Stdout,
+		terminal: isTerminal(os.Stdin.Fd()),
+		histFile: histFile,
+	}
+	if histFile != "" {
+		if data, err := os.ReadFile(histFile); err == nil {
+			for _, l := range strings.Split(string(data), "\n") {
+				if l != "" {
+					e.history = append(e.history, l)
+				}
+			}
+		}
+	}
+	return e
+}
+
+// addHistory records a line in the history and the history file
+func (e *lineEditor) addHistory(line string) {
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+	if e.histFile != "" {
+		os.WriteFile(e.histFile, []byte(strings.Join(e.history, "\n")+"\n"), 0600)
+	}
+}
+
+// readLine reads a single line without the trailing new line.
+// io.EOF is returned on end of input or Ctrl-D on an empty line.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	if !e.terminal {
+		fmt.Fprint(e.out, prompt)
+		line, err := e.in.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	restore, err := makeRaw(os.Stdin.Fd())
+	if err != nil {
+		e.terminal = false
+		return e.readLine(prompt)
+	}
+	defer restore()
+
+	var (
+		buf  []rune
+		pos  int
+		hist = len(e.history)
+	)
+	redraw := func() {
+		fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(buf))
+		if back := len(buf) - pos; back > 0 {
+			fmt.Fprintf(e.out, "\x1b[%dD", back)
+		}
+	}
+	setLine := func(s string) {
+		buf = []rune(s)
+		pos = len(buf)
+		redraw()
+	}
+	redraw()
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			line := string(buf)
+			e.addHistory(line)
+			return line, nil
+		case 3: // Ctrl-C discards the line
+			fmt.Fprint(e.out, "^C\r\n")
+			buf, pos = nil, 0
+			redraw()
+		case 4: // Ctrl-D
+			if len(buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+				redraw()
+			}
+		case 127, 8: // Backspace
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+				redraw()
+			}
+		case 1: // Ctrl-A
+			pos = 0
+			redraw()
+		case 5: // Ctrl-E
+			pos = len(buf)
+			redraw()
+		case 11: // Ctrl-K
+			buf = buf[:pos]
+			redraw()
+		case 21: // Ctrl-U
+			buf, pos = buf[pos:], 0
+			redraw()
+		case 27: // Escape sequences for the arrows, home, end and delete
+			switch e.readEscape() {
+			case "[A", "OA":
+				if hist > 0 {
+					hist--
+					setLine(e.history[hist])
+				}
+			case "[B", "OB":
+				if hist < len(e.history)-1 {
+					hist++
+					setLine(e.history[hist])
+				} else {
+					hist = len(e.history)
+					setLine("")
+				}
+			case "[C", "OC":
+				if pos < len(buf) {
+					pos++
+					redraw()
+				}
+			case "[D", "OD":
+				if pos > 0 {
+					pos--
+					redraw()
+				}
+			case "[H", "OH", "[1~":
+				pos = 0
+				redraw()
+			case "[F", "OF", "[4~":
+				pos = len(buf)
+				redraw()
+			case "[3~":
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+					redraw()
+				}
+			}
+		default:
+			if r >= 32 && r != utf8.RuneError {
+				buf = append(buf[:pos], append([]rune{r}, buf[pos:]...)...)
+				pos++
+				redraw()
+			}
+		}
+	}
+}
+
+// readSecret reads a line without echoing it, e.g. a password. The line is
+// not added to the history.
+func (e *lineEditor) readSecret(prompt string) (string, error) {
+	if !e.terminal {
+		fmt.Fprint(e.out, prompt)
+		line, err := e.in.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	restore, err := makeRaw(os.Stdin.Fd())
+	if err != nil {
+		e.terminal = false
+		return e.readSecret(prompt)
+	}
+	defer restore()
+	fmt.Fprint(e.out, prompt)
+	var buf []rune
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\r\n")
+			return string(buf), nil
+		case 3, 4: // Ctrl-C and Ctrl-D abort
+			fmt.Fprint(e.out, "\r\n")
+			return "", io.EOF
+		case 127, 8:
+			if len(buf) > 0 {
+				buf = buf[:len(buf)-1]
+			}
+		default:
+			if r >= ' ' {
+				buf = append(buf, r)
+			}
+		}
+	}
+}
+
+// readEscape reads the rest of an escape sequence after the ESC character
+func (e *lineEditor) readEscape() string {
+	var seq []byte
+	for len(seq) < 8 {
+		b, err := e.in.ReadByte()
+		if err != nil {
+			break
+		}
+		seq = append(seq, b)
+		// The sequence ends with a letter or ~ after the introducer
+		if len(seq) > 1 && (b == '~' || (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z')) {
+			break
+		}
+	}
+	return string(seq)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	pb "github.com/demisto/pb-go"
+	"github.com/demisto/pb-go/aiml"
+)
+
+// lintFailed is returned when the local files have problems or are not formatted
+type lintFailed string
+
+func (e lintFailed) Error() string {
+	return string(e)
+}
+
+// printIssues prints the lint issues in the format of the -output flag
+func printIssues(issues []aiml.Issue) error {
+	if annotationOutput() {
+		return printProblems(lintProblems(issues))
+	}
+	return printResult(issues, func(w io.Writer) {
+		for _, i := range issues {
+			location := i.File
+			if i.Line > 0 {
+				location = fmt.Sprintf("%s:%d", i.File, i.Line)
+			}
+			row(w, location, i.Severity, i.Message)
+		}
+	})
+}
+
+func lintCmd() *command {
+	cmd := newCommand("lint", "PATH...", "Check local bot files for problems without calling the API")
+	strict := cmd.fs.Bool("strict", false, "Fail on warnings too.")
+	cmd.run = func(args []string) error {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		files, err := expandFiles(args)
+		if err != nil {
+			return err
+		}
+		issues, err := aiml.LintFiles(files)
+		if err != nil {
+			return err
+		}
+		aiml.SortIssues(issues)
+		if issues == nil {
+			issues = []aiml.Issue{}
+		}
+		if err = printIssues(issues); err != nil {
+			return err
+		}
+		errs := aiml.Errors(issues)
+		if errs > 0 || *strict && len(issues) > 0 {
+			return lintFailed(fmt.Sprintf("Found %d errors and %d warnings", errs, len(issues)-errs))
+		}
+		success("%d files checked, %d warnings.", len(files), len(issues))
+		return nil
+	}
+	return cmd
+}
+
+func fmtCmd() *command {
+	cmd := newCommand("fmt", "PATH...", "Rewrite local AIML files in the canonical layout")
+	list := cmd.fs.Bool("l", false, "Only list the files whose formatting differs, failing if there are any.")
+	diff := cmd.fs.Bool("d", false, "Only print the formatting changes as unified diffs, failing if there are any.")
+	cmd.run = func(args []string) error {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		files, err := expandFiles(args)
+		if err != nil {
+			return err
+		}
+		changed := 0
+		for _, path := range files {
+			if filepath.Ext(path) != ".aiml" {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			formatted, err := aiml.Format(data)
+			if err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+			if bytes.Equal(data, formatted) {
+				continue
+			}
+			changed++
+			switch {
+			case *diff:
+				fmt.Print(pb.FileDiff{File: path, Status: pb.DiffChanged, Old: data, New: formatted}.Unified(3))
+			case *list:
+				fmt.Println(path)
+			default:
+				if err = os.WriteFile(path, formatted, 0644); err != nil {
+					return err
+				}
+				info("Formatted %s", path)
+			}
+		}
+		if (*list || *diff) && changed > 0 {
+			return lintFailed(fmt.Sprintf("%d files are not formatted", changed))
+		}
+		return nil
+	}
+	return cmd
+}
+
+func upgradeCmd() *command {
+	cmd := newCommand("upgrade", "PATH...", "Rewrite local AIML 1.x files as AIML 2.0, listing what needs manual attention")
+	diff := cmd.fs.Bool("d", false, "Only print the changes as unified diffs, without rewriting the files.")
+	cmd.run = func(args []string) error {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		files, err := expandFiles(args)
+		if err != nil {
+			return err
+		}
+		issues := []aiml.Issue{}
+		changed := 0
+		for _, path := range files {
+			if filepath.Ext(path) != ".aiml" {
+				continue
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			upgraded, found, err := aiml.Upgrade(path, data)
+			if err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+			issues = append(issues, found...)
+			if bytes.Equal(data, upgraded) {
+				continue
+			}
+			changed++
+			if *diff {
+				fmt.Print(pb.FileDiff{File: path, Status: pb.DiffChanged, Old: data, New: upgraded}.Unified(3))
+				continue
+			}
+			if err = os.WriteFile(path, upgraded, 0644); err != nil {
+				return err
+			}
+			info("Upgraded %s", path)
+		}
+		aiml.SortIssues(issues)
+		if err = printIssues(issues); err != nil {
+			return err
+		}
+		if errs := aiml.Errors(issues); errs > 0 {
+			return lintFailed(fmt.Sprintf("%d files changed, %d constructs need to be rewritten by hand", changed, errs))
+		}
+		success("%d files changed, %d warnings.", changed, len(issues))
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// credentialsEntry returns the settings of the profile, or the top level settings if name is empty
+func credentialsEntry(name string) (config, bool) {
+	if name == "" {
+		return cfgFile.config, true
+	}
+	p, ok := cfgFile.Profiles[name]
+	return p, ok
+}
+
+// setCredentialsEntry replaces the settings of the profile, or the top level settings if name is empty
+func setCredentialsEntry(name string, c config) {
+	if name == "" {
+		cfgFile.config = c
+		return
+	}
+	if cfgFile.Profiles == nil {
+		cfgFile.Profiles = make(map[string]config)
+	}
+	cfgFile.Profiles[name] = c
+}
+
+// profileArg returns the profile named by the arguments, else the active one
+func profileArg(args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		return activeProfile, nil
+	case 1:
+		return args[0], nil
+	}
+	return "", usagef("You must specify at most one profile name")
+}
+
+// profileLabel describes the settings of the profile in messages
+func profileLabel(name string) string {
+	if name == "" {
+		return "the default settings"
+	}
+	return "profile " + name
+}
+
+func loginCmd() *command {
+	cmd := newCommand("login", "[PROFILE]", "Prompt for the application ID and user key, check them and store them, by default in the OS keyring")
+	id := cmd.fs.String("appId", "", "The application ID. Prompted for if not given.")
+	key := cmd.fs.String("userKey", "", "The user key. Prompted for if not given, which keeps it out of the shell history.")
+	url := cmd.fs.String("url", "", "The pandorabots API URL, e.g. of an on-premises install.")
+	store := cmd.fs.String("store", credentialsKeyring, "Where to keep the user key: keyring for the OS keyring, or file for the configuration file.")
+	use := cmd.fs.Bool("use", false, "Make the profile the active one.")
+	cmd.run = func(args []string) error {
+		name, err := profileArg(args)
+		if err != nil {
+			return err
+		}
+		if *store != credentialsKeyring && *store != "file" {
+			return usagef("Credential store is not valid [%s] - it must be keyring or file", *store)
+		}
+		entry, _ := credentialsEntry(name)
+		e := newLineEditor("")
+		e.out = os.Stderr
+		appId := *id
+		if appId == "" {
+			prompt := "Application ID: "
+			if entry.AppId != "" {
+				prompt = fmt.Sprintf("Application ID [%s]: ", entry.AppId)
+			}
+			if appId, err = e.readLine(prompt); err != nil {
+				return usagef("Login cancelled")
+			}
+			if appId = strings.TrimSpace(appId); appId == "" {
+				appId = entry.AppId
+			}
+		}
+		userKey := *key
+		if userKey == "" {
+			if userKey, err = e.readSecret("User key: "); err != nil {
+				return usagef("Login cancelled")
+			}
+			userKey = strings.TrimSpace(userKey)
+		}
+		if appId == "" || userKey == "" {
+			return usagef("You must specify the application ID and user key")
+		}
+		options := []pb.OptionFunc{pb.SetCredentials(appId, userKey)}
+		if *url != "" {
+			options = append(options, pb.SetUrl(*url))
+			entry.Url = *url
+		}
+		c, err := newClient(options...)
+		if err != nil {
+			return err
+		}
+		// Checks the credentials
+		bots, err := c.List()
+		if err != nil {
+			return err
+		}
+		previous := entry
+		entry.AppId = appId
+		where := cfgPath
+		if *store == credentialsKeyring {
+			if err = pb.NewKeyringStore(keyringService).Set(appId, userKey); err != nil {
+				return fmt.Errorf("Unable to store the user key in the OS keyring - %v. Use -store file to keep it in the configuration file.", err)
+			}
+			entry.UserKey, entry.Credentials = "", credentialsKeyring
+			where = "the OS keyring"
+		} else {
+			entry.UserKey, entry.Credentials = userKey, ""
+		}
+		setCredentialsEntry(name, entry)
+		if *use && name != "" {
+			cfgFile.Profile = name
+		}
+		// The key of the application previously logged in stays in the keyring if another profile uses it
+		if previous.credentialStore() != nil && previous.AppId != appId && !keyringShared(previous.AppId) {
+			if err = previous.credentialStore().Delete(previous.AppId); err != nil {
+				return err
+			}
+		}
+		if err = saveConfig(); err != nil {
+			return err
+		}
+		success("Logged in to application %s with %d bots as %s, the user key is kept in %s.", appId, len(bots), profileLabel(name), where)
+		return nil
+	}
+	return cmd
+}
+
+func logoutCmd() *command {
+	cmd := newCommand("logout", "[PROFILE]", "Remove the stored application ID and user key of a profile, or of the default settings")
+	cmd.run = func(args []string) error {
+		name, err := profileArg(args)
+		if err != nil {
+			return err
+		}
+		entry, ok := credentialsEntry(name)
+		if !ok {
+			return usagef("Profile [%s] is not defined", name)
+		}
+		if entry.AppId == "" && entry.UserKey == "" {
+			warnf("Not logged in with %s", profileLabel(name))
+			return nil
+		}
+		cleared := entry
+		cleared.AppId, cleared.UserKey, cleared.Credentials = "", "", ""
+		setCredentialsEntry(name, cleared)
+		if store := entry.credentialStore(); store != nil && !keyringShared(entry.AppId) {
+			if err = store.Delete(entry.AppId); err != nil {
+				return err
+			}
+		}
+		if err = saveConfig(); err != nil {
+			return err
+		}
+		success("Logged out of application %s with %s.", entry.AppId, profileLabel(name))
+		return nil
+	}
+	return cmd
+}
+
+func whoamiCmd() *command {
+	cmd := newCommand("whoami", "", "Show the active profile, application and where the user key comes from")
+	cmd.run = func(args []string) error {
+		type whoamiView struct {
+			Profile string `json:"profile,omitempty"`
+			AppId   string `json:"appId"`
+			Url     string `json:"url"`
+			Bot     string `json:"bot,omitempty"`
+			UserKey string `json:"userKey"` // Where the user key comes from
+		}
+		v := whoamiView{Profile: activeProfile, AppId: cfg.AppId, Url: cfg.Url, Bot: cfg.Bot, UserKey: "none"}
+		if v.Url == "" {
+			v.Url = pb.DefaultURL
+		}
+		keyFlag := false
+		flag.Visit(func(f *flag.Flag) {
+			keyFlag = keyFlag || f.Name == "userKey"
+		})
+		switch {
+		case keyFlag:
+			v.UserKey = "-userKey flag"
+		case os.Getenv("PB_USER_KEY") != "" && cfg.UserKey == os.Getenv("PB_USER_KEY"):
+			v.UserKey = "PB_USER_KEY"
+		case cfg.UserKey != "":
+			v.UserKey = cfgPath
+		case cfg.credentialStore() != nil:
+			v.UserKey = "OS keyring"
+		}
+		if err := printResult(v, func(w io.Writer) {
+			profile := v.Profile
+			if profile == "" {
+				profile = "(none)"
+			}
+			row(w, "Profile:", profile)
+			row(w, "App ID:", v.AppId)
+			row(w, "URL:", v.Url)
+			row(w, "Default bot:", v.Bot)
+			row(w, "User key:", v.UserKey)
+		}); err != nil {
+			return err
+		}
+		if v.AppId == "" {
+			warnf("Not logged in, use pbcli login")
+		}
+		return nil
+	}
+	return cmd
+}
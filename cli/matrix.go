@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/demisto/pb-go/integrations/matrix"
+)
+
+func matrixCmd() *command {
+	cmd := newCommand("matrix", "", "Run a bot as a Matrix bot answering in its rooms")
+	name := nameFlag(cmd.fs)
+	homeserver := cmd.fs.String("homeserver", "", "Base URL of the homeserver, e.g. https://matrix.org.")
+	mention := cmd.fs.Bool("mention", false, "Answer only the messages mentioning the bot.")
+	noJoin := cmd.fs.Bool("no-join", false, "Do not accept the room invitations.")
+	typingSpeed := typingFlag(cmd.fs)
+	conversations := conversationFlags(cmd.fs)
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		if *homeserver == "" {
+			return usagef("You must specify -homeserver")
+		}
+		token := os.Getenv("MATRIX_ACCESS_TOKEN")
+		if token == "" {
+			return usagef("You must set MATRIX_ACCESS_TOKEN to the access token of the bot user")
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		b := matrix.New(c, *name, *homeserver, token)
+		b.RequireMention = *mention
+		b.AutoJoin = !*noJoin
+		b.Typing = typing(*typingSpeed)
+		cc, err := conversations(c)
+		if err != nil {
+			return err
+		}
+		defer cc.Close()
+		b.Configure = cc.configureConversation
+		b.ErrorLog = log.New(logWriter{warnf}, "", 0)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		info("Connecting %s to %s, press Ctrl+C to stop", *name, *homeserver)
+		return b.Run(ctx)
+	}
+	return cmd
+}
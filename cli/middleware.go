@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// middlewareFlags adds the flags of the middleware of the conversations of
+// the chat adapters, and returns the middleware they configure. The talks
+// are logged in verbose mode.
+func middlewareFlags(fs *flag.FlagSet) func(c *pb.Client) ([]pb.Middleware, error) {
+	translate := fs.String("translate", "", "URL of a LibreTranslate server translating the inputs into -bot-language and the responses back, with the API key $PB_TRANSLATE_KEY if set.")
+	botLanguage := fs.String("bot-language", "en", "Language of the bot for -translate.")
+	fallbackBots := fs.String("fallback-bots", "", "Comma separated bots answering in order when the bot is unavailable, within -budget.")
+	canary := fs.String("canary", "", "Bot receiving the inputs too, its responses are compared with the responses of the bot in verbose mode.")
+	budget := fs.Duration("budget", 0, "Latency budget of the talks with the bot, the -fallback-bots and the -canary, e.g. 3s. Zero for no limit.")
+	return func(c *pb.Client) ([]pb.Middleware, error) {
+		if *budget < 0 {
+			return nil, usagef("Budget cannot be negative")
+		}
+		var middleware []pb.Middleware
+		if (*verbose || *debug) && !*quiet {
+			middleware = append(middleware, pb.LogTalks(log.New(logWriter{verbosef}, "", 0)))
+		}
+		if *translate != "" {
+			if *botLanguage == "" {
+				return nil, usagef("You must set the -bot-language of -translate")
+			}
+			tr := pb.NewTranslation(pb.NewLibreTranslator(*translate, os.Getenv("PB_TRANSLATE_KEY")), *botLanguage)
+			tr.OnError = func(err error) {
+				warnf("%v", err)
+			}
+			middleware = append(middleware, tr.Middleware())
+		}
+		if bots := splitList(*fallbackBots); len(bots) > 0 || (*budget > 0 && *canary == "") {
+			chain := pb.NewFallbackChain(*budget)
+			for _, bot := range bots {
+				chain.Fallbacks = append(chain.Fallbacks, pb.NamedTalk{Name: bot, Talk: fanoutConversation(c, bot).Talk})
+			}
+			chain.OnReport = func(key string, calls []pb.BudgetCall) {
+				for _, call := range calls {
+					if call.Skipped || call.TimedOut {
+						warnf("Talk to %s of %s failed - %v", call.Name, key, call.Err)
+					}
+				}
+			}
+			middleware = append(middleware, chain.Middleware())
+		}
+		if *canary != "" {
+			middleware = append(middleware, pb.NewCanary(fanoutConversation(c, *canary).Talk, *budget, func(r pb.CanaryResult) {
+				switch {
+				case r.CanaryErr != nil:
+					verbosef("Canary %s of %s failed - %v", *canary, r.Key, r.CanaryErr)
+				case !r.Same():
+					verbosef("Canary %s of %s differs: %q -> %q instead of %q", *canary, r.Key, r.Input, r.CanaryReply.Responses, replyResponses(r.Reply))
+				}
+			}).Middleware())
+		}
+		return middleware, nil
+	}
+}
+
+// fanoutConversation returns a conversation with the bot for the fan-outs of the middleware
+func fanoutConversation(c *pb.Client, bot string) *pb.Conversation {
+	cv := pb.NewConversation(c, bot, nil)
+	cv.ClientName = pb.HashedClientName("pbcli-")
+	return cv
+}
+
+// replyResponses returns the responses of the reply, nil if there is none
+func replyResponses(r *pb.Reply) []string {
+	if r == nil {
+		return nil
+	}
+	return r.Responses
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// Supported values of the -output flag
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputJUnit = "junit" // Only supported by the test command
+	outputTAP   = "tap"   // Only supported by the test command
+	// GitHub Actions annotations and SARIF logs, only supported by the lint, upgrade and verify commands
+	outputGitHub = "github"
+	outputSARIF  = "sarif"
+	outputCSV    = "csv" // Only supported by the talk batch mode
+)
+
+// validateOutput checks the -output flag
+func validateOutput() error {
+	switch *output {
+	case outputTable, outputJSON, outputYAML, outputJUnit, outputTAP, outputGitHub, outputSARIF, outputCSV:
+		return nil
+	}
+	return usagef("Invalid output format [%s] - must be one of json/yaml/table/junit/tap/github/sarif/csv", *output)
+}
+
+// printResult writes v to standard output in the requested format.
+// For the table format the table func is used to write the rows.
+func printResult(v interface{}, table func(w io.Writer)) error {
+	return writeResult(os.Stdout, v, table)
+}
+
+// writeResult writes v to out in the requested format, like printResult
+func writeResult(out io.Writer, v interface{}, table func(w io.Writer)) error {
+	switch *output {
+	case outputJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		data, err := yamlMarshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	case outputJUnit, outputTAP, outputGitHub, outputSARIF, outputCSV:
+		return usagef("Output format [%s] is not supported by this command", *output)
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+// row writes a tab separated table row
+func row(w io.Writer, cols ...interface{}) {
+	for i, c := range cols {
+		if i > 0 {
+			fmt.Fprint(w, "\t")
+		}
+		fmt.Fprint(w, c)
+	}
+	fmt.Fprintln(w)
+}
@@ -1,163 +1,282 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	pb "github.com/demisto/pb-go"
 )
 
 var (
-	appId, userKey, name, out, file, input, cmd *string
-	debug                                       *bool
+	appId, userKey, rawurl, configPath, output *string
+	profileName, normalize, mask, failover     *string
+	templateValues, progressMode               *string
+	encrypt, anonymize                         *bool
+	debug, quiet, verbose, noColor, scrub      *bool
+	timeout, cacheTTL, hedge                   *time.Duration
+	retries, maxInput, cacheSize, parallel     *int
+	callouts                                   = calloutFlags{}
 )
 
 func init() {
-	appId = flag.String("appId", "", "Application ID as received from pandoranbots.")
-	userKey = flag.String("userKey", "", "User key as received from pandoranbots.")
-	name = flag.String("name", "", "The bot name to use.")
-	out = flag.String("out", "", "Output file. If not specified will write to standard output.")
-	file = flag.String("file", "", "Input file for uploads or file name for downloads.")
-	input = flag.String("input", "", "Input to talk.")
-	cmd = flag.String("cmd", "", "The command to execute. Can be one of the following: list/createBot/deleteBot/listFiles/download/upload/downloadBot/deleteFile/verify/talk")
-	debug = flag.Bool("debug", false, "Debug output")
-}
+	// Added here since they refer to the command list
+	commands = append(commands, completionCmd(), completeCmd())
 
-func main() {
-	flag.Parse()
-	c, err := pb.New(pb.SetErrorLog(log.New(os.Stderr, "", log.Lshortfile)), pb.SetCredentials(*appId, *userKey))
-	if err != nil {
-		fmt.Printf("%v\n", err)
-		os.Exit(1)
-	}
-	if *debug {
-		pb.SetTraceLog(log.New(os.Stderr, "TRACE: ", log.Lshortfile))(c)
-	}
-	if strings.ToLower(*cmd) != "list" && *name == "" {
-		fmt.Println("You must specify the bot name")
-		os.Exit(1)
+	appId = flag.String("appId", "", "Application ID as received from pandoranbots. Defaults to PB_APP_ID.")
+	userKey = flag.String("userKey", "", "User key as received from pandoranbots. Defaults to PB_USER_KEY.")
+	rawurl = flag.String("url", "", "The pandorabots API URL. Defaults to PB_URL or "+pb.DefaultURL+".")
+	configPath = flag.String("config", "", "Configuration file. Defaults to PB_CONFIG or ~/.config/pbcli/config.yaml.")
+	profileName = flag.String("profile", "", "The configuration profile to use. Defaults to PB_PROFILE or the profile set in the configuration file.")
+	timeout = flag.Duration("timeout", time.Minute, "Time limit of each API request. Zero for no limit.")
+	retries = flag.Int("retries", 2, "How many times to retry requests that failed on network errors or server overload. The talk requests are only retried if the bot did not receive them.")
+	output = flag.String("output", outputTable, "Output format of command results. Can be one of json/yaml/table, junit/tap for the test command, github/sarif for the lint, upgrade and verify problems, or csv for the talk batch mode.")
+	debug = flag.Bool("debug", false, "Debug output including the HTTP requests and responses.")
+	verbose = flag.Bool("verbose", false, "Print the API calls made and the details of failures.")
+	quiet = flag.Bool("quiet", false, "Only print command results and errors, for script usage.")
+	normalize = flag.String("normalize", "", "Comma separated input filters applied before talking: space, nfc, emoji (to names), strip-emoji, or all.")
+	mask = flag.String("mask", "", "File of words, one per line, masked with asterisks in the inputs and transcripts, e.g. profanity.")
+	scrub = flag.Bool("scrub", false, "Redact the email addresses, phone, card and social security numbers and IP addresses from the inputs and transcripts.")
+	maxInput = flag.Int("max-input", 0, "Split the inputs longer than this many characters into several talk requests within the session. Zero for no limit.")
+	flag.Var(callouts, "callout", "Replace the <callout service=\"SERVICE\">query</callout> elements of the responses with the result of an HTTP GET of the query, as SERVICE=URL, or URL for all services. Can be repeated.")
+	parallel = flag.Int("parallel", pb.DefaultParallelism, "How many bots or files the fleet commands process at once.")
+	failover = flag.String("failover", "", "Comma separated API URLs to fail over to when the -url fails, e.g. mirrors or an on-premises install.")
+	hedge = flag.Duration("hedge", 0, "Send a talk request again when it is not answered within this time, e.g. 800ms, using the first reply. Zero to not hedge.")
+	cacheTTL = flag.Duration("cache", 0, "Answer the repeated inputs of stateless bots, like FAQ bots, from a cache for this long, e.g. 10m. Zero for no cache.")
+	cacheSize = flag.Int("cache-size", 1000, "The maximum number of replies in the -cache.")
+	templateValues = flag.String("values", "", "YAML or JSON file of the values of the {{placeholders}} of templated bot files, rendered on upload.")
+	progressMode = flag.String("progress", "text", "How sync, clone, restore, backup and bench report their progress: text, or json for a line of JSON per event on standard error, for CI systems.")
+	encrypt = flag.Bool("encrypt", false, "Encrypt the backups, transcripts and session files written to disk with the AES-256 key in "+pb.EncryptionKeyEnv+", base64 or hex encoded.")
+	anonymize = flag.Bool("anonymize", false, "Send pseudonyms of the client names to pandorabots and the transcript store instead of the names, keyed by the secret salt in "+pb.AnonymizeSaltEnv+". The mapping back to the names is kept in the pseudonyms directory next to the configuration file, encrypted with -encrypt.")
+	noColor = flag.Bool("no-color", false, "Disable colored output. Also disabled by the NO_COLOR environment variable.")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: pbcli [global flags] <command> [flags] [args]\n\nCommands:\n")
+		printCommands(commands, "  ")
+		fmt.Fprintf(os.Stderr, "\nGlobal flags:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nUse \"pbcli <command> -h\" for more information about a command.\n")
+		fmt.Fprintf(os.Stderr, "\nExit codes: 0 success, 1 usage, 2 error, 3 authentication, 4 not found, 5 compile failure, 6 network, 7 test failures.\n")
 	}
-	switch strings.ToLower(*cmd) {
-	case "list":
-		res, err := c.List()
-		if err != nil {
-			fmt.Printf("%v\n", err)
-			os.Exit(2)
-		}
-		for _, s := range res {
-			fmt.Println(s)
+}
+
+// command is a single pbcli sub command with its own flag set.
+// A command either has sub commands or a run function.
+type command struct {
+	name    string        // The name used on the command line
+	args    string        // Synopsis of the positional arguments
+	summary string        // One line description
+	fs      *flag.FlagSet // The flags of the command
+	subs    []*command    // Sub commands
+	hidden  bool          // Hidden commands are not listed in the help
+	run     func(args []string) error
+}
+
+// newCommand creates a leaf command with an empty flag set
+func newCommand(name, args, summary string) *command {
+	cmd := &command{name: name, args: args, summary: summary}
+	cmd.fs = flag.NewFlagSet(name, flag.ContinueOnError)
+	return cmd
+}
+
+// newGroup creates a command that only holds sub commands
+func newGroup(name, summary string, subs ...*command) *command {
+	return &command{name: name, summary: summary, subs: subs}
+}
+
+// usageError is returned when the command line is invalid
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+func usagef(format string, args ...interface{}) error {
+	return usageError(fmt.Sprintf(format, args...))
+}
+
+func printCommands(cmds []*command, indent string) {
+	for _, c := range cmds {
+		if !c.hidden {
+			fmt.Fprintf(os.Stderr, "%s%-12s %s\n", indent, c.name, c.summary)
 		}
-	case "createbot":
-		err = c.CreateBot(*name)
-		if err != nil {
-			fmt.Printf("%v\n", err)
-		} else {
-			fmt.Println("Bot successfully created.")
+	}
+}
+
+func findCommand(cmds []*command, name string) *command {
+	for _, c := range cmds {
+		if strings.EqualFold(c.name, name) {
+			return c
 		}
-	case "deletebot":
-		err = c.DeleteBot(*name)
-		if err != nil {
-			fmt.Printf("%v\n", err)
+	}
+	return nil
+}
+
+// dispatch finds the command matching the arguments and runs it
+func dispatch(cmds []*command, path string, args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		if path == "pbcli" {
+			flag.Usage()
 		} else {
-			fmt.Println("Bot successfully deleted.")
+			fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags] [args]\n\nCommands:\n", path)
+			printCommands(cmds, "  ")
 		}
-	case "upload":
-		if *file == "" {
-			fmt.Println("You must specify the file name to upload")
-			os.Exit(1)
+		if len(args) == 0 {
+			return usagef("No command specified")
 		}
-		err = c.UploadFileFromPath(*name, *file)
-		if err != nil {
-			fmt.Printf("%v\n", err)
-		} else {
-			fmt.Println("File successfully uploaded.")
+		return nil
+	}
+	cmd := findCommand(cmds, args[0])
+	if cmd == nil {
+		return usagef("Command [%s] was not recognized", args[0])
+	}
+	path += " " + cmd.name
+	if len(cmd.subs) > 0 {
+		return dispatch(cmd.subs, path, args[1:])
+	}
+	cmd.fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s\n\n%s\n\nFlags:\n", strings.TrimSpace(path+" [flags] "+cmd.args), cmd.summary)
+		cmd.fs.PrintDefaults()
+	}
+	if err := cmd.fs.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return nil
 		}
-	case "download":
-		if *out == "" {
-			if *file == "" {
-				fmt.Println("You must specify the file name to download")
-				os.Exit(1)
-			}
-			err = c.GetFile(*name, *file, os.Stdout)
-			if err != nil {
-				fmt.Printf("%v\n", err)
-			}
-		} else {
-			err = c.GetFileToPath(*name, *out)
-			if err != nil {
-				fmt.Printf("%v\n", err)
-			} else {
-				fmt.Println("File successfully downloaded.")
+		return usageError(err.Error())
+	}
+	return cmd.run(cmd.fs.Args())
+}
+
+// newEncryptor returns the encryptor of the -encrypt flag, nil if it is not set
+func newEncryptor() (*pb.Encryptor, error) {
+	if !*encrypt {
+		return nil, nil
+	}
+	key, err := pb.KeyFromEnv(pb.EncryptionKeyEnv)()
+	if err != nil {
+		return nil, usagef("%v", err)
+	}
+	return pb.NewEncryptor(func() ([]byte, error) { return key, nil }), nil
+}
+
+// anonymizer is the anonymizer of the -anonymize flag, created once
+var anonymizer *pb.Anonymizer
+
+// newAnonymizer returns the anonymizer of the -anonymize flag, nil if it is not set
+func newAnonymizer() (*pb.Anonymizer, error) {
+	if !*anonymize || anonymizer != nil {
+		return anonymizer, nil
+	}
+	salt := strings.TrimSpace(os.Getenv(pb.AnonymizeSaltEnv))
+	if salt == "" {
+		return nil, usagef("You must set the anonymization salt in %s", pb.AnonymizeSaltEnv)
+	}
+	base := defaultConfigPath()
+	if base == "" {
+		return nil, fmt.Errorf("Unable to determine the pseudonyms directory")
+	}
+	e, err := newEncryptor()
+	if err != nil {
+		return nil, err
+	}
+	a, err := pb.NewAnonymizer([]byte(salt), pb.NewDirStorage(filepath.Dir(base)), e)
+	if err != nil {
+		return nil, usagef("%v", err)
+	}
+	anonymizer = a
+	return a, nil
+}
+
+// newClient creates the pandorabots client from the global flags and the extra options
+func newClient(extra ...pb.OptionFunc) (*pb.Client, error) {
+	options := []pb.OptionFunc{
+		pb.SetCredentials(cfg.AppId, cfg.UserKey),
+		pb.SetUrl(cfg.Url),
+		pb.SetErrorLog(log.New(logWriter{verbosef}, "", 0)),
+		pb.SetOnRequestEnd(logRequest),
+		pb.SetTimeout(*timeout),
+		pb.SetRetries(*retries, 500*time.Millisecond),
+		pb.SetMaxInputLength(*maxInput),
+		pb.SetHedging(*hedge),
+		pb.SetParallelism(*parallel),
+	}
+	switch *progressMode {
+	case "text":
+	case "json":
+		options = append(options, pb.SetProgress(pb.JSONProgress(os.Stderr)))
+	default:
+		return nil, usagef("Progress mode is not valid [%s] - it must be text or json", *progressMode)
+	}
+	if store := cfg.credentialStore(); store != nil {
+		options = append(options, pb.SetCredentialStore(store))
+	}
+	if *debug {
+		options = append(options, pb.SetTraceLog(log.New(logWriter{verbosef}, "TRACE: ", 0)))
+	}
+	if *failover != "" {
+		var urls []string
+		for _, u := range strings.Split(*failover, ",") {
+			if u = strings.TrimSpace(u); u != "" {
+				urls = append(urls, u)
 			}
 		}
-	case "deletefile":
-		if *file == "" {
-			fmt.Println("You must specify the file name to delete")
-			os.Exit(1)
-		}
-		err = c.DeleteFile(*name, *file)
-		if err != nil {
-			fmt.Printf("%v\n", err)
-		} else {
-			fmt.Println("File successfully deleted.")
-		}
-	case "listfiles":
-		res, err := c.ListFiles(*name)
+		options = append(options, pb.SetEndpoints(0, urls...))
+	}
+	if *cacheTTL > 0 {
+		options = append(options, pb.SetReplyCache(pb.NewReplyCache(*cacheTTL, *cacheSize)))
+	}
+	if *templateValues != "" {
+		v, err := loadValues(*templateValues)
 		if err != nil {
-			fmt.Printf("%v\n", err)
-		} else {
-			fmt.Printf("%v\n", res)
-		}
-	case "downloadbot":
-		if *out == "" {
-			err = c.DownloadFiles(*name, os.Stdout)
-			if err != nil {
-				fmt.Printf("%v\n", err)
-			}
-		} else {
-			err = c.DownloadFilesToPath(*name, *out)
-			if err != nil {
-				fmt.Printf("%v\n", err)
-			} else {
-				fmt.Println("Bot files successfully downloaded.")
-			}
+			return nil, err
 		}
-	case "verify":
-		err = c.Verify(*name)
+		options = append(options, pb.SetTemplateValues(v))
+	}
+	if len(callouts) > 0 {
+		options = append(options, pb.SetCallouts(newCallouts(callouts)))
+	}
+	e, err := newEncryptor()
+	if err != nil {
+		return nil, err
+	}
+	if e != nil {
+		options = append(options, pb.SetEncryption(e))
+	}
+	a, err := newAnonymizer()
+	if err != nil {
+		return nil, err
+	}
+	if a != nil {
+		options = append(options, pb.SetAnonymizer(a))
+	}
+	s, err := newScrubber()
+	if err != nil {
+		return nil, err
+	}
+	if *normalize != "" || s != nil {
+		option, err := parseInputFilters(*normalize, s)
 		if err != nil {
-			fmt.Printf("%v\n", err)
-		} else {
-			fmt.Println("Bot verified.")
-		}
-	case "talk":
-		// While we are not quiting, let's talk
-		if *input == "" {
-			var sessionId int
-			r := bufio.NewReader(os.Stdin)
-			for line, err := r.ReadString('\n'); strings.ToLower(line) != "exit\n" && err == nil; line, err = r.ReadString('\n') {
-				res, err := c.Talk(*name, line, "", sessionId, false)
-				if err != nil {
-					fmt.Printf("%v\n", err)
-					os.Exit(2)
-				}
-				sessionId = res.SessionId
-				for _, s := range res.Responses {
-					fmt.Println(s)
-				}
-			}
-		} else {
-			res, err := c.Talk(*name, *input, "", 0, false)
-			if err != nil {
-				fmt.Printf("%v\n", err)
-			} else {
-				fmt.Printf("%v", res)
-			}
+			return nil, err
 		}
-	default:
-		fmt.Printf("Command [%s] was not recognized\n", *cmd)
+		options = append(options, option)
+	}
+	return pb.New(append(options, extra...)...)
+}
+
+func main() {
+	flag.Parse()
+	err := validateOutput()
+	if err == nil {
+		err = loadConfig()
+	}
+	if err == nil {
+		err = dispatch(commands, "pbcli", flag.Args())
+	}
+	if err != nil {
+		errorf("%v", err)
 	}
+	os.Exit(exitCode(err))
 }
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"io"
+
+	pb "github.com/demisto/pb-go"
+)
+
+func profileAddCmd() *command {
+	cmd := newCommand("add", "NAME", "Add or update a credentials profile")
+	var p config
+	cmd.fs.StringVar(&p.AppId, "appId", "", "Application ID of the profile.")
+	cmd.fs.StringVar(&p.UserKey, "userKey", "", "User key of the profile.")
+	cmd.fs.StringVar(&p.Bot, "bot", "", "Default bot name of the profile.")
+	cmd.fs.StringVar(&p.Url, "url", "", "The pandorabots API URL of the profile.")
+	use := cmd.fs.Bool("use", false, "Make the profile the active one.")
+	keyring := cmd.fs.Bool("keyring", false, "Keep the user key in the OS keyring instead of the configuration file.")
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			return usagef("You must specify the profile name")
+		}
+		if cfgFile.Profiles == nil {
+			cfgFile.Profiles = make(map[string]config)
+		}
+		existing, update := cfgFile.Profiles[args[0]]
+		if !update && (p.AppId == "" || p.UserKey == "") {
+			return usagef("You must specify the application ID and user key of a new profile")
+		}
+		existing.merge(p)
+		if *keyring && existing.UserKey != "" {
+			if err := pb.NewKeyringStore(keyringService).Set(existing.AppId, existing.UserKey); err != nil {
+				return err
+			}
+			existing.UserKey, existing.Credentials = "", credentialsKeyring
+		}
+		cfgFile.Profiles[args[0]] = existing
+		if *use {
+			cfgFile.Profile = args[0]
+		}
+		if err := saveConfig(); err != nil {
+			return err
+		}
+		if update {
+			success("Profile %s updated.", args[0])
+		} else {
+			success("Profile %s added.", args[0])
+		}
+		return nil
+	}
+	return cmd
+}
+
+func profileListCmd() *command {
+	cmd := newCommand("list", "", "List the credentials profiles")
+	cmd.run = func(args []string) error {
+		type profileView struct {
+			Name   string `json:"name"`
+			Active bool   `json:"active"`
+			AppId  string `json:"appId"`
+			Bot    string `json:"bot,omitempty"`
+			Url    string `json:"url,omitempty"`
+			// Credentials is where the user key is kept, file or keyring
+			Credentials string `json:"credentials"`
+		}
+		views := make([]profileView, 0)
+		for _, name := range profileNames() {
+			p := cfgFile.Profiles[name]
+			credentials := p.Credentials
+			if credentials == "" {
+				credentials = "file"
+			}
+			views = append(views, profileView{name, name == activeProfile, p.AppId, p.Bot, p.Url, credentials})
+		}
+		return printResult(views, func(w io.Writer) {
+			row(w, "", "NAME", "APP ID", "BOT", "URL", "KEY")
+			for _, v := range views {
+				active := ""
+				if v.Active {
+					active = "*"
+				}
+				row(w, active, v.Name, v.AppId, v.Bot, v.Url, v.Credentials)
+			}
+		})
+	}
+	return cmd
+}
+
+// keyringShared reports whether the top level settings or a profile keep the user key of the application in the OS keyring
+func keyringShared(appId string) bool {
+	if cfgFile.AppId == appId && cfgFile.Credentials == credentialsKeyring {
+		return true
+	}
+	for _, p := range cfgFile.Profiles {
+		if p.AppId == appId && p.Credentials == credentialsKeyring {
+			return true
+		}
+	}
+	return false
+}
+
+func profileUseCmd() *command {
+	cmd := newCommand("use", "NAME", "Make a profile the default one")
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			return usagef("You must specify the profile name")
+		}
+		if _, ok := cfgFile.Profiles[args[0]]; !ok {
+			return usagef("Profile [%s] is not defined", args[0])
+		}
+		cfgFile.Profile = args[0]
+		if err := saveConfig(); err != nil {
+			return err
+		}
+		success("Using profile %s.", args[0])
+		return nil
+	}
+	return cmd
+}
+
+func profileRemoveCmd() *command {
+	cmd := newCommand("remove", "NAME", "Remove a credentials profile")
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			return usagef("You must specify the profile name")
+		}
+		p, ok := cfgFile.Profiles[args[0]]
+		if !ok {
+			return usagef("Profile [%s] is not defined", args[0])
+		}
+		delete(cfgFile.Profiles, args[0])
+		if store := p.credentialStore(); store != nil && !keyringShared(p.AppId) {
+			if err := store.Delete(p.AppId); err != nil {
+				return err
+			}
+		}
+		if cfgFile.Profile == args[0] {
+			cfgFile.Profile = ""
+		}
+		if err := saveConfig(); err != nil {
+			return err
+		}
+		success("Profile %s removed.", args[0])
+		return nil
+	}
+	return cmd
+}
+
+func profileBotCmd() *command {
+	cmd := newCommand("bot", "[BOT]", "Show or set the default bot of the active profile, or of the default settings without profile")
+	unset := cmd.fs.Bool("unset", false, "Remove the default bot.")
+	cmd.run = func(args []string) error {
+		entry, ok := credentialsEntry(activeProfile)
+		if !ok {
+			return usagef("Profile [%s] is not defined", activeProfile)
+		}
+		switch {
+		case *unset:
+			entry.Bot = ""
+		case len(args) == 0:
+			if entry.Bot == "" {
+				warnf("No default bot for %s", profileLabel(activeProfile))
+				return nil
+			}
+			info("%s", entry.Bot)
+			return nil
+		case len(args) == 1:
+			c, err := newClient()
+			if err != nil {
+				return err
+			}
+			bots, err := c.List()
+			if err != nil {
+				return err
+			}
+			exists := false
+			for _, b := range bots {
+				exists = exists || b.Name == args[0]
+			}
+			if !exists {
+				return usagef("Bot [%s] does not exist", args[0])
+			}
+			entry.Bot = args[0]
+		default:
+			return usagef("You must specify at most one bot name")
+		}
+		setCredentialsEntry(activeProfile, entry)
+		if err := saveConfig(); err != nil {
+			return err
+		}
+		if entry.Bot == "" {
+			success("Default bot of %s removed.", profileLabel(activeProfile))
+		} else {
+			success("Default bot of %s set to %s.", profileLabel(activeProfile), entry.Bot)
+		}
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	pb "github.com/demisto/pb-go"
+)
+
+func promoteCmd() *command {
+	cmd := newCommand("promote", "SRC DST", "Release the files of a bot to another, e.g. staging to production, rolling back if it does not compile")
+	del := cmd.fs.Bool("delete", false, "Delete the files of the destination bot that the source bot does not have.")
+	dryRun := cmd.fs.Bool("dry-run", false, "Only print the files which would change without changing the destination bot.")
+	backup := cmd.fs.String("backup", "", "The backup file of the destination bot, or s3://BUCKET/KEY. Defaults to the backups directory next to the configuration file.")
+	force := cmd.fs.Bool("force", false, "Delete files without asking for confirmation.")
+	cmd.run = func(args []string) error {
+		if len(args) != 2 {
+			return usagef("You must specify the source and destination bot names")
+		}
+		src, dst := args[0], args[1]
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		opts := pb.PromoteOptions{Delete: *del, DryRun: *dryRun, Progress: progress}
+		if *del && !*dryRun && !*force {
+			plan, err := c.Promote(src, dst, pb.PromoteOptions{Delete: true, DryRun: true})
+			if err != nil {
+				return err
+			}
+			if deletes := countStatus(plan.Diff, pb.DiffRemoved); deletes > 0 {
+				if err = confirm(fmt.Sprintf("delete %d files of %s that %s does not have", deletes, dst, src), dst); err != nil {
+					return err
+				}
+			}
+		}
+		location := ""
+		if !*dryRun {
+			if location = *backup; location == "" {
+				if location, err = defaultBackupPath(dst); err != nil {
+					return err
+				}
+			}
+			if s, key, ok := s3Object(location); ok {
+				opts.Storage, opts.BackupKey = s, key
+			} else {
+				opts.Storage, opts.BackupKey = pb.NewDirStorage(filepath.Dir(location)), filepath.Base(location)
+			}
+		}
+		res, err := c.Promote(src, dst, opts)
+		if res == nil {
+			return err
+		}
+		if perr := printPromoteResult(res, location, err); perr != nil {
+			return perr
+		}
+		if err != nil {
+			if res.VerifyErr != nil && *output == outputTable {
+				printVerify(res.VerifyErr)
+			}
+			if res.RolledBack {
+				warnf("Bot %s rolled back to the backup %s.", dst, location)
+			}
+			return err
+		}
+		switch {
+		case *dryRun:
+		case len(res.Diff.Files) == 0:
+			info("Bot %s is up to date with %s.", dst, src)
+		default:
+			success("Bot %s successfully promoted to %s, the previous files are backed up to %s.", src, dst, location)
+		}
+		return nil
+	}
+	return cmd
+}
+
+func printPromoteResult(res *pb.PromoteResult, backup string, err error) error {
+	type fileView struct {
+		File   string        `json:"file"`
+		Status pb.DiffStatus `json:"status"`
+	}
+	view := struct {
+		Src        string              `json:"src"`
+		Dst        string              `json:"dst"`
+		Files      []fileView          `json:"files"`
+		Backup     string              `json:"backup,omitempty"`
+		Promoted   bool                `json:"promoted"`
+		RolledBack bool                `json:"rolledBack"`
+		Error      string              `json:"error,omitempty"`
+		Messages   []pb.CompileMessage `json:"messages,omitempty"` // The compile messages if the promoted bot does not compile
+	}{Src: res.Src, Dst: res.Dst, Files: make([]fileView, 0), Promoted: res.Promoted, RolledBack: res.RolledBack}
+	for _, f := range res.Diff.Files {
+		view.Files = append(view.Files, fileView{f.File, f.Status})
+	}
+	if len(res.Diff.Files) > 0 {
+		view.Backup = backup
+	}
+	if err != nil {
+		view.Error = err.Error()
+	}
+	var ce *pb.CompileError
+	if errors.As(res.VerifyErr, &ce) {
+		view.Messages = ce.Messages
+	}
+	return printResult(view, func(w io.Writer) {
+		for _, f := range view.Files {
+			row(w, f.Status, f.File)
+		}
+	})
+}
+
+func countStatus(d *pb.BotDiff, status pb.DiffStatus) int {
+	n := 0
+	for _, f := range d.Files {
+		if f.Status == status {
+			n++
+		}
+	}
+	return n
+}
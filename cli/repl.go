@@ -0,0 +1,262 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// talkSession is the state persisted in the session file between runs
+type talkSession struct {
+	Bot        string `json:"bot"`
+	SessionId  int    `json:"sessionid"`
+	ClientName string `json:"client_name,omitempty"`
+}
+
+// loadSession reads the session file. A missing file is an empty session.
+func loadSession(path string) (talkSession, error) {
+	var s talkSession
+	e, err := newEncryptor()
+	if err != nil {
+		return s, err
+	}
+	data, err := e.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(data, &s)
+	return s, err
+}
+
+// resumeSession loads the session of bot from the session file, starting a new one
+// with a unique client name if the file is missing or belongs to another bot.
+func resumeSession(path, bot string) (talkSession, error) {
+	s, err := loadSession(path)
+	if err != nil {
+		return s, err
+	}
+	if s.Bot != bot {
+		s = talkSession{Bot: bot, ClientName: newClientName()}
+	}
+	return s, nil
+}
+
+// newClientName generates a client name unique to a conversation
+func newClientName() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "pbcli-" + hex.EncodeToString(b)
+}
+
+// saveSession writes the session file, encrypted with the -encrypt flag
+func saveSession(path string, s talkSession) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	e, err := newEncryptor()
+	if err != nil {
+		return err
+	}
+	return e.WriteFile(path, data, 0600)
+}
+
+// repl is the interactive talk loop
+type repl struct {
+	c           *pb.Client
+	bot         string
+	sessionId   int
+	clientName  string
+	that, topic string
+	reset       bool // Reset the bot memory on the next input
+	trace       bool // Request and show the matching trace
+	sessionFile string
+
+	transcriptFile string
+	transcript     *pb.Transcript
+}
+
+const replHelp = `Commands:
+  /reset           Reset the conversation with the bot
+  /trace           Toggle the matching trace
+  /topic [name]    Show or set the topic sent with the input
+  /session         Show the current session ID
+  /set NAME VALUE  Set a predicate, the bot needs the categories of aiml/predicates.aiml
+  /get NAME        Show a predicate
+  /help            Show this help
+  /exit            Leave (also "exit" or Ctrl-D)`
+
+func (r *repl) run() error {
+	if r.sessionFile != "" {
+		s, err := resumeSession(r.sessionFile, r.bot)
+		if err != nil {
+			return err
+		}
+		r.sessionId, r.clientName = s.SessionId, s.ClientName
+		if r.sessionId != 0 {
+			info("Resuming session %d", r.sessionId)
+		}
+	}
+	if r.transcriptFile != "" {
+		t, err := loadTranscript(r.transcriptFile, r.bot)
+		if err != nil {
+			return err
+		}
+		r.transcript = t
+		r.transcript.ClientName = r.clientName
+	}
+	histFile := ""
+	if path := defaultConfigPath(); path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err == nil {
+			histFile = filepath.Join(filepath.Dir(path), "history")
+		}
+	}
+	editor := newLineEditor(histFile)
+	info("Talking with %s. Type /help for commands.", r.bot)
+	for {
+		line, err := editor.readLine(r.bot + "> ")
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.ToLower(line) == "exit" || line == "/exit" || line == "/quit":
+			return nil
+		case strings.HasPrefix(line, "/"):
+			r.command(line)
+			continue
+		}
+		if err = r.talk(line); err != nil {
+			return err
+		}
+	}
+}
+
+// command handles a slash command
+func (r *repl) command(line string) {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case "/reset":
+		r.reset, r.that = true, ""
+		fmt.Println("The conversation will be reset with the next input.")
+	case "/trace":
+		r.trace = !r.trace
+		fmt.Printf("Trace is %s.\n", map[bool]string{true: "on", false: "off"}[r.trace])
+	case "/topic":
+		if len(fields) > 1 {
+			r.topic = strings.Join(fields[1:], " ")
+		}
+		fmt.Printf("Topic: %s\n", r.topic)
+	case "/session":
+		fmt.Printf("Session: %d\n", r.sessionId)
+	case "/set", "/get":
+		var input string
+		var err error
+		if fields[0] == "/set" && len(fields) > 2 {
+			input, err = pb.SetPredicateInput(fields[1], strings.Join(fields[2:], " "))
+		} else if fields[0] == "/get" && len(fields) == 2 {
+			input, err = pb.GetPredicateInput(fields[1])
+		} else {
+			fmt.Println(replHelp)
+			return
+		}
+		if err == nil {
+			err = r.predicate(input)
+		}
+		if err != nil {
+			errorf("%v", err)
+		}
+	case "/help":
+		fmt.Println(replHelp)
+	default:
+		fmt.Printf("Unknown command [%s]. Type /help for commands.\n", fields[0])
+	}
+}
+
+// predicate sends a predicate input within the session, printing the value it returned
+func (r *repl) predicate(input string) error {
+	if r.clientName == "" {
+		// The predicates are kept per client name
+		r.clientName = newClientName()
+	}
+	res, err := r.c.Talk(r.bot, input, r.clientName, r.sessionId, false)
+	if err != nil {
+		return err
+	}
+	r.sessionId = res.SessionId
+	if len(res.Responses) > 0 {
+		fmt.Println(strings.Join(res.Responses, " "))
+	}
+	return nil
+}
+
+func (r *repl) talk(input string) error {
+	res, err := r.c.TalkDebug(r.bot, input, r.clientName, r.sessionId, false, r.that, r.topic, false, r.reset, r.trace, false)
+	if err != nil {
+		return err
+	}
+	r.reset = false
+	r.sessionId = res.SessionId
+	for _, s := range res.Responses {
+		fmt.Println(s)
+	}
+	for _, c := range res.Commands {
+		fmt.Printf("[oob] %s\n", c)
+	}
+	if len(res.Responses) > 0 {
+		r.that = res.Responses[len(res.Responses)-1]
+	}
+	if r.trace && len(res.Trace) > 0 {
+		fmt.Printf("Trace: %s\n", string(res.Trace))
+	}
+	if r.transcript != nil {
+		r.transcript.Record(input, res)
+		if err = r.transcript.WriteToPath(r.transcriptFile); err != nil {
+			return err
+		}
+	}
+	if *debug {
+		fmt.Printf("[that: %s | topic: %s | session: %d]\n", r.that, r.topic, r.sessionId)
+	}
+	if r.sessionFile != "" {
+		return saveSession(r.sessionFile, talkSession{Bot: r.bot, SessionId: r.sessionId, ClientName: r.clientName})
+	}
+	return nil
+}
+
+// setPredicates sets the predicates of the client name within the session, returning the session ID
+func setPredicates(c *pb.Client, bot, clientName string, sessionId int, predicates paramFlags) (int, error) {
+	names := make([]string, 0, len(predicates))
+	for name := range predicates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		input, err := pb.SetPredicateInput(name, fmt.Sprint(predicates[name]))
+		if err != nil {
+			return sessionId, err
+		}
+		res, err := c.Talk(bot, input, clientName, sessionId, false)
+		if err != nil {
+			return sessionId, err
+		}
+		sessionId = res.SessionId
+	}
+	return sessionId, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// loadTranscript reads the transcript file, returning a new transcript of bot
+// if the file does not exist or was recorded with another bot
+func loadTranscript(path, bot string) (*pb.Transcript, error) {
+	s, err := newScrubber()
+	if err != nil {
+		return nil, err
+	}
+	e, err := newEncryptor()
+	if err != nil {
+		return nil, err
+	}
+	t, err := pb.ReadEncryptedTranscript(path, e)
+	if os.IsNotExist(err) {
+		t, err = &pb.Transcript{Bot: bot, Encryptor: e}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.Bot != bot {
+		warnf("Transcript %s was recorded with %s, starting a new one", path, t.Bot)
+		t = &pb.Transcript{Bot: bot, Encryptor: e}
+	}
+	if s != nil {
+		t.Scrub = s.Scrub
+	}
+	return t, nil
+}
+
+func replayCmd() *command {
+	cmd := newCommand("replay", "TRANSCRIPT...", "Replay recorded conversations and show the responses that changed")
+	name := nameFlag(cmd.fs)
+	cmd.run = func(args []string) error {
+		if len(args) == 0 {
+			return usagef("You must specify the transcript files to replay")
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		e, err := newEncryptor()
+		if err != nil {
+			return err
+		}
+		type replayView struct {
+			Transcript string `json:"transcript"`
+			*pb.ReplayResult
+		}
+		var results []replayView
+		failed, total := 0, 0
+		for _, path := range args {
+			t, err := pb.ReadEncryptedTranscript(path, e)
+			if err != nil {
+				return err
+			}
+			res, err := c.Replay(*name, t)
+			if err != nil {
+				return err
+			}
+			failed += res.Mismatches
+			total += len(res.Turns)
+			results = append(results, replayView{path, res})
+		}
+		err = printResult(results, func(w io.Writer) {
+			for _, res := range results {
+				fmt.Fprintf(w, "Transcript %s (bot %s)\n", res.Transcript, res.Bot)
+				for _, t := range res.Turns {
+					status := "  SAME"
+					if !t.Match {
+						status = "  DIFF"
+					}
+					row(w, status, t.Input)
+				}
+				fmt.Fprintf(w, "%d of %d responses changed in %v\n", res.Mismatches, len(res.Turns), res.Elapsed.Round(time.Millisecond))
+			}
+		})
+		if err == nil && *output == outputTable {
+			// The diffs are printed as is, outside of the aligned table
+			for _, res := range results {
+				for _, t := range res.Turns {
+					if !t.Match {
+						fmt.Printf("\n%s: %s\n%s", res.Transcript, t.Input, t.Diff())
+					}
+				}
+			}
+		}
+		if err == nil && failed > 0 {
+			err = &testsFailed{failed: failed, total: total, what: "replayed responses"}
+		}
+		return err
+	}
+	return cmd
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+	"github.com/demisto/pb-go/integrations/alexa"
+	"github.com/demisto/pb-go/integrations/botframework"
+	"github.com/demisto/pb-go/integrations/dialogflow"
+	"github.com/demisto/pb-go/integrations/messenger"
+)
+
+// routeFlags collects the -route PATH=BOT flags
+type routeFlags map[string]string
+
+func (r routeFlags) String() string {
+	var parts []string
+	for _, path := range sortedRoutes(r) {
+		parts = append(parts, path+"="+r[path])
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r routeFlags) Set(s string) error {
+	path, bot, ok := strings.Cut(s, "=")
+	if !ok || path == "" || bot == "" {
+		return errors.New("Route must be in the form PATH=BOT")
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	r[path] = bot
+	return nil
+}
+
+func sortedRoutes(r routeFlags) []string {
+	paths := make([]string, 0, len(r))
+	for path := range r {
+		paths = append(paths, path)
+	}
+	// Longer paths first so they are matched before their prefixes
+	sort.Slice(paths, func(i, j int) bool {
+		if len(paths[i]) != len(paths[j]) {
+			return len(paths[i]) > len(paths[j])
+		}
+		return paths[i] < paths[j]
+	})
+	return paths
+}
+
+// talkRequest is the body of a gateway talk request
+type talkRequest struct {
+	Input      string `json:"input"`
+	ClientName string `json:"clientName,omitempty"`
+	SessionId  int    `json:"sessionId,omitempty"`
+}
+
+// gateway relays chat requests from web clients to the bots
+type gateway struct {
+	c        *pb.Client
+	routes   routeFlags
+	origins  []string
+	user     string
+	pass     string
+	limiter  *pb.RateLimiter
+	fallback *pb.Fallback
+	aliases  *pb.Aliases                 // Resolves the routes to @ALIAS
+	splits   map[string]*pb.TrafficSplit // The weighted routes, by the bots of the route
+	store    pb.TranscriptStore          // Keeps the conversations, nil to not keep them
+	webhooks *pb.Webhooks                // Receive the events of the conversations, nil to not post them
+}
+
+func (g *gateway) allowedOrigin(origin string) bool {
+	for _, o := range g.origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *gateway) bot(path string) string {
+	for _, route := range sortedRoutes(g.routes) {
+		if path == route || strings.HasPrefix(path, strings.TrimSuffix(route, "/")+"/") {
+			return g.routes[route]
+		}
+	}
+	return ""
+}
+
+// clientKey returns the key the requests of the client are throttled by, the
+// remote address for the new clients which do not have a client name yet
+func (g *gateway) clientKey(r *http.Request, clientName string) string {
+	if clientName != "" {
+		return clientName
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" && g.allowedOrigin(origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.Header().Add("Vary", "Origin")
+	}
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if g.user != "" {
+		user, pass, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(user), []byte(g.user)) != 1 || subtle.ConstantTimeCompare([]byte(pass), []byte(g.pass)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="pbcli"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	bot := g.bot(r.URL.Path)
+	if bot == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST, OPTIONS")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req talkRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		req.Input, req.ClientName = r.FormValue("input"), r.FormValue("clientName")
+		if sessionId := r.FormValue("sessionId"); sessionId != "" {
+			var err error
+			if req.SessionId, err = strconv.Atoi(sessionId); err != nil {
+				http.Error(w, "Invalid session ID", http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if req.Input == "" {
+		http.Error(w, "Missing input", http.StatusBadRequest)
+		return
+	}
+	if g.limiter != nil && !g.limiter.Allow(bot+"/"+g.clientKey(r, req.ClientName)) {
+		http.Error(w, g.limiter.Reply, http.StatusTooManyRequests)
+		return
+	}
+	split := g.splits[bot]
+	if split != nil {
+		bot = split.Pick(g.clientKey(r, req.ClientName))
+	}
+	if alias, ok := strings.CutPrefix(bot, "@"); ok {
+		var err error
+		if bot, err = g.aliases.Resolve(alias); err != nil {
+			verbosef("Unable to resolve alias %s - %v", alias, err)
+			http.Error(w, "Bot is not available", http.StatusBadGateway)
+			return
+		}
+	}
+	res, err := g.c.Talk(bot, req.Input, req.ClientName, req.SessionId, false)
+	if split != nil {
+		split.Record(bot, g.c.Anonymized(req.ClientName), req.Input, res, err)
+	}
+	g.webhooks.Observe(bot, g.c.Anonymized(req.ClientName), req.Input, req.SessionId == 0, res, err)
+	if reply, ok := g.fallback.Answer(err); ok {
+		if res != nil {
+			reply.Info.Attempts, reply.Info.Latency = res.Info.Attempts, res.Info.Latency
+		}
+		res, err = reply, nil
+	}
+	if err != nil {
+		verbosef("Talk to %s failed - %v", bot, err)
+		http.Error(w, "Bot is not available", http.StatusBadGateway)
+		return
+	}
+	if g.store != nil && !res.Info.Fallback {
+		if err = g.store.Append(pb.NewTranscriptEntry(bot, req.ClientName, req.Input, res)); err != nil {
+			verbosef("Unable to store the transcript of %s - %v", req.ClientName, err)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// checkEndpoints checks the health of the API endpoints at the interval, reporting the changes
+func checkEndpoints(c *pb.Client, interval time.Duration) {
+	healthy := make(map[string]bool)
+	for {
+		for _, e := range c.CheckEndpoints() {
+			if was, ok := healthy[e.Url]; (!ok || was) && !e.Healthy {
+				warnf("Endpoint %s is failing", e.Url)
+			} else if ok && !was && e.Healthy {
+				info("Endpoint %s recovered", e.Url)
+			}
+			healthy[e.Url] = e.Healthy
+			verbosef("Endpoint %s healthy %v latency %v", e.Url, e.Healthy, e.Latency)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func serveCmd() *command {
+	cmd := newCommand("serve", "", "Serve the bots as an HTTP chat gateway")
+	name := nameFlag(cmd.fs)
+	addr := cmd.fs.String("addr", ":8080", "Address to listen on.")
+	cert := cmd.fs.String("tls-cert", "", "TLS certificate file. Serves HTTPS when specified together with -tls-key.")
+	key := cmd.fs.String("tls-key", "", "TLS private key file.")
+	origins := cmd.fs.String("origins", "", "Comma separated origins allowed to call the gateway from browsers (CORS), or * for any.")
+	auth := cmd.fs.String("auth", "", "Require HTTP basic authentication with USER:PASSWORD.")
+	routes := routeFlags{}
+	cmd.fs.Var(routes, "route", "Serve a bot under a path, as PATH=BOT, or PATH=@ALIAS for the live bot of a blue/green deployment, or PATH=BOT:WEIGHT,BOT:WEIGHT to split the users between bots, e.g. /talk=mybot:90,mybot-next:10. Can be repeated. Defaults to the -name bot under /talk.")
+	aliasLocation := aliasesFlag(cmd.fs)
+	transcripts := transcriptsFlag(cmd.fs)
+	transcriptsTTL := cmd.fs.Duration("transcripts-ttl", 0, "Delete the conversations of the Redis -transcripts store inactive this long, like 720h. Zero keeps them.")
+	splitLog := cmd.fs.String("split-log", "", "Append the inputs and responses of the split routes to this file as JSON lines, with the bot of each arm. Logged in verbose mode if not given.")
+	skills := routeFlags{}
+	cmd.fs.Var(skills, "alexa", "Serve a bot as an Alexa skill endpoint under a path, as PATH=BOT. Can be repeated.")
+	agents := routeFlags{}
+	cmd.fs.Var(agents, "dialogflow", "Serve a bot as a Dialogflow fulfillment webhook under a path, as PATH=BOT. Can be repeated. Honors -auth.")
+	activities := routeFlags{}
+	cmd.fs.Var(activities, "botframework", "Serve a bot as a Microsoft Bot Framework messaging endpoint under a path, as PATH=BOT. Can be repeated.")
+	appId := cmd.fs.String("botframework-app-id", os.Getenv("MICROSOFT_APP_ID"), "Microsoft app ID of the Bot Framework registration. Defaults to $MICROSOFT_APP_ID, empty for the emulator.")
+	appPassword := cmd.fs.String("botframework-app-password", os.Getenv("MICROSOFT_APP_PASSWORD"), "Microsoft app password of the Bot Framework registration. Defaults to $MICROSOFT_APP_PASSWORD.")
+	pages := routeFlags{}
+	cmd.fs.Var(pages, "messenger", "Serve a bot as a Facebook Messenger webhook under a path, as PATH=BOT. Can be repeated. Requires $MESSENGER_PAGE_TOKEN, $MESSENGER_APP_SECRET and $MESSENGER_VERIFY_TOKEN.")
+	activityFormat := cmd.fs.String("botframework-format", "plain", "Format of the Bot Framework replies: plain, or markdown keeping the links, bold text and lists of the responses.")
+	typingSpeed := typingFlag(cmd.fs)
+	conversations := conversationFlags(cmd.fs)
+	healthInterval := cmd.fs.Duration("health-interval", 30*time.Second, "How often to check the health of the -failover endpoints.")
+	skipVerify := cmd.fs.Bool("skip-verify", false, "Do not verify the signatures of the Alexa and Messenger requests, for testing only.")
+	cmd.run = func(args []string) error {
+		if len(routes) == 0 && len(skills) == 0 && len(agents) == 0 && len(activities) == 0 && len(pages) == 0 {
+			if err := requireName(name); err != nil {
+				return err
+			}
+			routes["/talk"] = *name
+		}
+		if *failover != "" && *healthInterval <= 0 {
+			return usagef("Health interval must be positive")
+		}
+		if (*cert == "") != (*key == "") {
+			return usagef("You must specify both -tls-cert and -tls-key")
+		}
+		activityFilters, err := formatFilters(*activityFormat)
+		if err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		cc, err := conversations(c)
+		if err != nil {
+			return err
+		}
+		defer cc.Close()
+		g := &gateway{c: c, routes: routes, limiter: cc.limiter, fallback: cc.fallback, webhooks: cc.webhooks}
+		if *transcripts != "" {
+			s, closeStore, err := openTranscriptStore(*transcripts, *transcriptsTTL)
+			if err != nil {
+				return err
+			}
+			defer closeStore()
+			if g.store, err = anonymizeTranscripts(s); err != nil {
+				return err
+			}
+			cc.transcripts = g.store
+		}
+		var onReply func(e pb.SplitEvent)
+		if *splitLog != "" {
+			f, err := os.OpenFile(*splitLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			onReply = pb.JSONSplitLog(f)
+		} else {
+			onReply = func(e pb.SplitEvent) {
+				verbosef("Split %s %s: %q -> %q %v", e.Bot, e.ClientName, e.Input, e.Responses, e.Latency)
+			}
+		}
+		g.splits = make(map[string]*pb.TrafficSplit)
+		for _, bot := range routes {
+			targets := []string{bot}
+			// The weights make a split, even with a single arm like mybot:100
+			if strings.Contains(bot, ":") {
+				split, err := pb.ParseTrafficSplit(bot)
+				if err != nil {
+					return usagef("%v", err)
+				}
+				split.OnReply = onReply
+				g.splits[bot] = split
+				targets = targets[:0]
+				for _, a := range split.Arms {
+					targets = append(targets, a.Bot)
+				}
+			}
+			for _, target := range targets {
+				if strings.HasPrefix(target, "@") && g.aliases == nil {
+					if g.aliases, err = openAliases(*aliasLocation); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if *origins != "" {
+			for _, o := range strings.Split(*origins, ",") {
+				g.origins = append(g.origins, strings.TrimSpace(o))
+			}
+		}
+		if *auth != "" {
+			var ok bool
+			if g.user, g.pass, ok = strings.Cut(*auth, ":"); !ok || g.user == "" {
+				return usagef("Auth must be in the form USER:PASSWORD")
+			}
+		}
+		for _, path := range sortedRoutes(routes) {
+			info("Serving %s on %s", routes[path], path)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/", g)
+		for _, path := range sortedRoutes(skills) {
+			h := alexa.NewHandler(c, skills[path], nil)
+			cc.configureConversation(h.Conversation)
+			h.ErrorLog = log.New(logWriter{verbosef}, "", 0)
+			if *skipVerify {
+				h.Verifier = nil
+			}
+			mux.Handle(path, h)
+			info("Serving %s as an Alexa skill on %s", skills[path], path)
+		}
+		for _, path := range sortedRoutes(agents) {
+			h := dialogflow.NewHandler(c, agents[path], nil)
+			cc.configureConversation(h.Conversation)
+			h.User, h.Password = g.user, g.pass
+			h.ErrorLog = log.New(logWriter{verbosef}, "", 0)
+			mux.Handle(path, h)
+			info("Serving %s as a Dialogflow webhook on %s", agents[path], path)
+		}
+		if len(activities) > 0 && *appId == "" {
+			warnf("No -botframework-app-id, the Bot Framework requests are not authenticated")
+		}
+		for _, path := range sortedRoutes(activities) {
+			h := botframework.NewHandler(c, activities[path], nil, *appId, *appPassword)
+			cc.configureConversation(h.Conversation)
+			h.Filters = activityFilters
+			if activityFilters != nil {
+				h.TextFormat = *activityFormat
+			}
+			h.Typing = typing(*typingSpeed)
+			h.ErrorLog = log.New(logWriter{verbosef}, "", 0)
+			mux.Handle(path, h)
+			info("Serving %s as a Bot Framework endpoint on %s", activities[path], path)
+		}
+		for _, path := range sortedRoutes(pages) {
+			h := messenger.NewHandler(c, pages[path], nil, os.Getenv("MESSENGER_PAGE_TOKEN"), os.Getenv("MESSENGER_APP_SECRET"), os.Getenv("MESSENGER_VERIFY_TOKEN"))
+			if h.PageToken == "" || h.VerifyToken == "" || (h.AppSecret == "" && !*skipVerify) {
+				return usagef("You must set MESSENGER_PAGE_TOKEN, MESSENGER_APP_SECRET and MESSENGER_VERIFY_TOKEN to serve Messenger")
+			}
+			h.Typing = typing(*typingSpeed)
+			cc.configureConversation(h.Conversation)
+			if *skipVerify {
+				h.AppSecret = ""
+			}
+			h.ErrorLog = log.New(logWriter{verbosef}, "", 0)
+			mux.Handle(path, h)
+			info("Serving %s as a Messenger webhook on %s", pages[path], path)
+		}
+		if *failover != "" {
+			go checkEndpoints(c, *healthInterval)
+		}
+		srv := &http.Server{Addr: *addr, Handler: mux}
+		if *cert != "" {
+			info("Listening on https://%s", *addr)
+			return srv.ListenAndServeTLS(*cert, *key)
+		}
+		info("Listening on http://%s", *addr)
+		return srv.ListenAndServe()
+	}
+	return cmd
+}
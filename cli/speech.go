@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// transcribeFile returns the text spoken in the audio file, transcribed by the endpoint
+func transcribeFile(path, url, formField string) (string, error) {
+	if url == "" {
+		return "", usagef("You must specify the speech to text endpoint with -stt or PB_STT_URL")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	t := &pb.HTTPTranscriber{
+		Url:         url,
+		ContentType: mime.TypeByExtension(filepath.Ext(path)),
+		FormField:   formField,
+		FileName:    filepath.Base(path),
+		Header:      http.Header{},
+	}
+	if token := os.Getenv("PB_STT_TOKEN"); token != "" {
+		t.Header.Set("Authorization", "Bearer "+token)
+	}
+	text, err := t.Transcribe(f)
+	if err != nil {
+		return "", err
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return "", pb.ErrNoSpeech
+	}
+	return text, nil
+}
+
+// paramFlags collects the -tts-param KEY=VALUE flags
+type paramFlags map[string]interface{}
+
+func (p paramFlags) String() string {
+	var parts []string
+	for k, v := range p {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (p paramFlags) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return errors.New("Parameter must be in the form KEY=VALUE")
+	}
+	p[k] = v
+	return nil
+}
+
+// speakFile writes the audio of the responses, synthesized by the endpoint, to the file
+func speakFile(path, url, textField string, params paramFlags, responses []string) error {
+	t := &pb.HTTPSynthesizer{
+		Url:       url,
+		TextField: textField,
+		PlainText: textField != "",
+		Params:    params,
+		Header:    http.Header{},
+	}
+	if token := os.Getenv("PB_TTS_TOKEN"); token != "" {
+		t.Header.Set("Authorization", "Bearer "+token)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = pb.SpeakResponses(t, pb.SSML{}, responses, f); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
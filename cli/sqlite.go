@@ -0,0 +1,12 @@
+//go:build sqlite
+
+package main
+
+import (
+	// The pure Go SQLite driver, so pbcli still cross-compiles without cgo
+	_ "modernc.org/sqlite"
+)
+
+func init() {
+	sqliteDriver = "sqlite"
+}
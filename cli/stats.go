@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+func statsCmd() *command {
+	cmd := newCommand("stats", "", "Print the statistics of a bot - files, categories, sets and maps sizes")
+	name := nameFlag(cmd.fs)
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		stats, err := c.Stats(*name)
+		if err != nil {
+			return err
+		}
+		return printResult(stats, func(w io.Writer) {
+			row(w, "Bot:", stats.Bot)
+			row(w, "Language:", stats.Language.Name())
+			row(w, "Files:", len(stats.Files))
+			row(w, "Categories:", stats.Categories)
+			row(w, "Set entries:", stats.SetEntries)
+			row(w, "Map entries:", stats.MapEntries)
+			row(w, "Total size:", fmt.Sprintf("%d bytes", stats.TotalSize))
+			row(w, "Last modified:", stats.LastModified.Format("2006-01-02 15:04:05"))
+			fmt.Fprintln(w)
+			row(w, "NAME", "KIND", "ITEMS", "SIZE", "MODIFIED")
+			for _, f := range stats.Files {
+				row(w, f.Name, f.Kind, f.Items, f.Size, f.Modified.Format("2006-01-02 15:04:05"))
+			}
+		})
+	}
+	return cmd
+}
+
+func reportCmd() *command {
+	cmd := newCommand("report", "", "Generate a shareable report of a bot")
+	name := nameFlag(cmd.fs)
+	format := cmd.fs.String("format", "html", "Report format, html or markdown.")
+	out := cmd.fs.String("out", "", "Output file. If not specified will write to standard output.")
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		if *format != "html" && *format != "markdown" {
+			return usagef("Invalid format [%s] - must be html or markdown", *format)
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		stats, err := c.Stats(*name)
+		if err != nil {
+			return err
+		}
+		w := io.Writer(os.Stdout)
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		if *format == "markdown" {
+			err = stats.WriteMarkdown(w)
+		} else {
+			err = stats.WriteHTML(w)
+		}
+		if err == nil && *out != "" {
+			success("Report written to %s.", *out)
+		}
+		return err
+	}
+	return cmd
+}
+
+func docsCmd() *command {
+	cmd := newCommand("docs", "", "Generate the documentation of a bot for non-developers - topics, sample patterns, properties, sets and files")
+	name := nameFlag(cmd.fs)
+	format := cmd.fs.String("format", "html", "Documentation format, html or markdown.")
+	out := cmd.fs.String("out", "", "Output file. If not specified will write to standard output.")
+	cmd.run = func(args []string) error {
+		if err := requireName(name); err != nil {
+			return err
+		}
+		if *format != "html" && *format != "markdown" {
+			return usagef("Invalid format [%s] - must be html or markdown", *format)
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		docs, err := c.Docs(*name)
+		if err != nil {
+			return err
+		}
+		w := io.Writer(os.Stdout)
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		if *format == "markdown" {
+			err = docs.WriteMarkdown(w)
+		} else {
+			err = docs.WriteHTML(w)
+		}
+		if err == nil && *out != "" {
+			success("Documentation written to %s.", *out)
+		}
+		return err
+	}
+	return cmd
+}
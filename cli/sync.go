@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	pb "github.com/demisto/pb-go"
+)
+
+func syncCmd() *command {
+	cmd := newCommand("sync", "DIR", "Upload the personality files of a local directory to a bot")
+	name := nameFlag(cmd.fs)
+	del := cmd.fs.Bool("delete", false, "Delete bot files that do not exist in the directory.")
+	dryRun := cmd.fs.Bool("dry-run", false, "Only print the plan without changing the bot.")
+	verify := cmd.fs.Bool("verify", false, "Verify the bot after syncing.")
+	force := cmd.fs.Bool("force", false, "Delete files without asking for confirmation.")
+	backup := cmd.fs.Bool("backup", true, "Back up the bot files to the pbcli backups directory before deleting files.")
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			return usagef("You must specify the directory to sync")
+		}
+		if err := requireDirName(name, args[0]); err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		opts := pb.SyncOptions{Delete: *del, DryRun: *dryRun, Verify: *verify}
+		res, err := c.PlanSync(*name, args[0], opts)
+		if err != nil {
+			return err
+		}
+		if !*dryRun {
+			if deletes := countOp(res, pb.SyncDelete); deletes > 0 {
+				if !*force {
+					if err = confirm(fmt.Sprintf("delete %d files of %s that do not exist locally", deletes, *name), *name); err != nil {
+						return err
+					}
+				}
+				if *backup {
+					path, err := backupBeforeDelete(c, *name)
+					if err != nil {
+						return fmt.Errorf("Backup failed, the bot was not synced - %v", err)
+					}
+					info("Bot %s backed up to %s", *name, path)
+				}
+			}
+			c.ApplySync(*name, res, opts)
+		}
+		if err = printSyncResult(res, *dryRun); err != nil {
+			return err
+		}
+		if failed := res.Failed(); failed > 0 {
+			return fmt.Errorf("%d of %d actions failed", failed, len(res.Actions))
+		}
+		if res.VerifyErr != nil {
+			return res.VerifyErr
+		}
+		return nil
+	}
+	return cmd
+}
+
+// syncActionView is the printable form of a sync action
+type syncActionView struct {
+	Op    pb.SyncOp `json:"op"`
+	File  string    `json:"file"`
+	Error string    `json:"error,omitempty"`
+}
+
+func printSyncResult(res *pb.SyncResult, dryRun bool) error {
+	view := struct {
+		DryRun    bool             `json:"dryRun"`
+		Actions   []syncActionView `json:"actions"`
+		Unchanged int              `json:"unchanged"`
+		Verified  bool             `json:"verified"`
+	}{DryRun: dryRun, Actions: make([]syncActionView, 0), Unchanged: res.Unchanged, Verified: res.Verified}
+	counts := make(map[pb.SyncOp]int)
+	for _, a := range res.Actions {
+		v := syncActionView{Op: a.Op, File: a.File}
+		if a.Err != nil {
+			v.Error = a.Err.Error()
+		} else {
+			counts[a.Op]++
+		}
+		view.Actions = append(view.Actions, v)
+	}
+	return printResult(view, func(w io.Writer) {
+		if len(res.Actions) == 0 {
+			fmt.Fprintln(w, "Bot is up to date.")
+		}
+		for _, a := range view.Actions {
+			if a.Error != "" {
+				row(w, a.Op, a.File, "FAILED: "+a.Error)
+			} else {
+				row(w, a.Op, a.File)
+			}
+		}
+		if dryRun {
+			fmt.Fprintf(w, "Dry run: %d to upload, %d to delete, %d unchanged\n", countOp(res, pb.SyncUpload), countOp(res, pb.SyncDelete), res.Unchanged)
+			return
+		}
+		fmt.Fprintf(w, "%d uploaded, %d deleted, %d unchanged, %d failed\n", counts[pb.SyncUpload], counts[pb.SyncDelete], res.Unchanged, res.Failed())
+		if res.Verified {
+			fmt.Fprintln(w, "Bot verified.")
+		}
+	})
+}
+
+func countOp(res *pb.SyncResult, op pb.SyncOp) int {
+	n := 0
+	for _, a := range res.Actions {
+		if a.Op == op {
+			n++
+		}
+	}
+	return n
+}
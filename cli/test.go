@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// testsFailed is returned when some suite cases or replayed turns failed
+type testsFailed struct {
+	failed, total int
+	what          string // What failed, defaults to tests
+}
+
+func (e *testsFailed) Error() string {
+	what := e.what
+	if what == "" {
+		what = "tests"
+	}
+	return fmt.Sprintf("%d of %d %s failed", e.failed, e.total, what)
+}
+
+// loadSuite reads a suite from a YAML or JSON file
+func loadSuite(path string) (*pb.Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	suite := &pb.Suite{}
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(data, suite)
+	} else {
+		err = yamlUnmarshal(data, suite)
+	}
+	if err != nil {
+		return nil, usagef("Invalid suite file [%s] - %v", path, err)
+	}
+	if suite.Name == "" {
+		suite.Name = filepath.Base(path)
+	}
+	return suite, nil
+}
+
+func testCmd() *command {
+	cmd := newCommand("test", "SUITE...", "Run conversation test suites (YAML or JSON) against a bot")
+	name := nameFlag(cmd.fs)
+	junit := cmd.fs.String("junit", "", "Also write the results to this file as a JUnit XML report, e.g. for Jenkins or GitLab.")
+	tap := cmd.fs.String("tap", "", "Also write the results to this file in the Test Anything Protocol.")
+	cmd.run = func(args []string) error {
+		if len(args) == 0 {
+			return usagef("You must specify the suite files to run")
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		var results []*pb.SuiteResult
+		failed, total := 0, 0
+		for _, path := range args {
+			suite, err := loadSuite(path)
+			if err != nil {
+				return err
+			}
+			bot := *name
+			if bot == "" && suite.Bot == "" && cfg.Bot != "" {
+				bot = cfg.Bot
+				statusf("Using bot %s, the default bot of %s, for %s", bot, defaultBotSource(), path)
+			}
+			res, err := c.RunSuite(bot, suite)
+			if err != nil {
+				return usageError(err.Error())
+			}
+			failed += res.Failed
+			total += len(res.Cases)
+			results = append(results, res)
+		}
+		if err = printSuiteResults(results); err != nil {
+			return err
+		}
+		for path, write := range map[string]func(io.Writer, []*pb.SuiteResult) error{*junit: writeJUnit, *tap: writeTAP} {
+			if path == "" {
+				continue
+			}
+			if err = writeReport(path, write, results); err != nil {
+				return err
+			}
+		}
+		if failed > 0 {
+			return &testsFailed{failed: failed, total: total}
+		}
+		return nil
+	}
+	return cmd
+}
+
+func printSuiteResults(results []*pb.SuiteResult) error {
+	switch *output {
+	case outputJUnit:
+		return writeJUnit(os.Stdout, results)
+	case outputTAP:
+		return writeTAP(os.Stdout, results)
+	}
+	return printResult(results, func(w io.Writer) {
+		for _, res := range results {
+			fmt.Fprintf(w, "Suite %s (bot %s)\n", res.Name, res.Bot)
+			for _, cr := range res.Cases {
+				if cr.Passed {
+					row(w, "  PASS", cr.Name, cr.Elapsed.Round(time.Millisecond))
+				} else {
+					row(w, "  FAIL", cr.Name, cr.Elapsed.Round(time.Millisecond), cr.Failure())
+				}
+			}
+			fmt.Fprintf(w, "%d passed, %d failed in %v\n", res.Passed, res.Failed, res.Elapsed.Round(time.Millisecond))
+		}
+	})
+}
+
+// JUnit XML report types
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Errors    int         `xml:"errors,attr"`
+	Time      float64     `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+// writeJUnit writes the results as a JUnit XML report
+func writeJUnit(w io.Writer, results []*pb.SuiteResult) error {
+	var report junitSuites
+	for _, res := range results {
+		js := junitSuite{
+			Name:      res.Name,
+			Tests:     len(res.Cases),
+			Time:      res.Elapsed.Seconds(),
+			Timestamp: res.Started.Format("2006-01-02T15:04:05"),
+		}
+		for _, cr := range res.Cases {
+			jc := junitCase{Name: cr.Name, ClassName: res.Bot, Time: cr.Elapsed.Seconds()}
+			switch {
+			case cr.Error != "":
+				jc.Error = &junitFailure{Message: cr.Error, Text: cr.Error}
+				js.Errors++
+			case !cr.Passed:
+				jc.Failure = &junitFailure{Message: cr.Failure(), Text: cr.Failure()}
+				js.Failures++
+			}
+			js.Cases = append(js.Cases, jc)
+		}
+		report.Suites = append(report.Suites, js)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeTAP writes the results in the Test Anything Protocol version 13, each
+// case a test point with the failure as YAML diagnostics
+func writeTAP(w io.Writer, results []*pb.SuiteResult) error {
+	total := 0
+	for _, res := range results {
+		total += len(res.Cases)
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "TAP version 13\n1..%d\n", total)
+	n := 0
+	for _, res := range results {
+		fmt.Fprintf(&buf, "# Suite %s (bot %s)\n", res.Name, res.Bot)
+		for _, cr := range res.Cases {
+			n++
+			if cr.Passed {
+				fmt.Fprintf(&buf, "ok %d - %s\n", n, cr.Name)
+				continue
+			}
+			severity := "fail"
+			if cr.Error != "" {
+				severity = "error"
+			}
+			fmt.Fprintf(&buf, "not ok %d - %s\n  ---\n  message: %s\n  severity: %s\n  suite: %s\n  bot: %s\n  duration_ms: %d\n  ...\n",
+				n, cr.Name, strconv.Quote(cr.Failure()), severity, strconv.Quote(res.Name), strconv.Quote(res.Bot), cr.Elapsed.Milliseconds())
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeReport writes the results to the file in path with the report writer
+func writeReport(path string, write func(io.Writer, []*pb.SuiteResult) error, results []*pb.SuiteResult) error {
+	var buf bytes.Buffer
+	if err := write(&buf, results); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// transcriptsFlag adds the flag of the store the conversations are kept in
+func transcriptsFlag(fs *flag.FlagSet) *string {
+	return fs.String("transcripts", os.Getenv("PB_TRANSCRIPTS"), "Store the conversations are kept in: a Redis URL like redis://:PASSWORD@localhost:6379/0, or sqlite:PATH for a SQLite database when pbcli is built with -tags sqlite. Defaults to $PB_TRANSCRIPTS.")
+}
+
+// sqliteDriver is the database/sql driver of the sqlite: transcript stores,
+// registered when pbcli is built with the sqlite tag
+var sqliteDriver string
+
+// openTranscriptStore opens the store of the -transcripts flag, expiring
+// the conversations inactive for ttl if not zero, and returns its close function
+func openTranscriptStore(location string, ttl time.Duration) (pb.TranscriptStore, func() error, error) {
+	if path, ok := strings.CutPrefix(location, "sqlite:"); ok {
+		if sqliteDriver == "" {
+			return nil, nil, usagef("Transcript store is not supported [%s] - pbcli must be built with -tags sqlite for the SQLite stores", location)
+		}
+		if ttl > 0 {
+			return nil, nil, usagef("The SQLite transcript stores do not expire the conversations, -transcripts-ttl needs a Redis store")
+		}
+		db, err := sql.Open(sqliteDriver, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		store, err := pb.NewSQLTranscriptStore(db)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return store, db.Close, nil
+	}
+	if !strings.HasPrefix(location, "redis://") && !strings.HasPrefix(location, "rediss://") {
+		return nil, nil, usagef("Transcript store is not valid [%s] - it must be a redis:// or rediss:// URL, or sqlite:PATH", location)
+	}
+	store, err := pb.NewRedisTranscriptStore(location)
+	if err != nil {
+		return nil, nil, err
+	}
+	store.TTL = ttl
+	return store, store.Close, nil
+}
+
+// anonymizeTranscripts stores the pseudonyms of the client names in the store with the -anonymize flag
+func anonymizeTranscripts(store pb.TranscriptStore) (pb.TranscriptStore, error) {
+	a, err := newAnonymizer()
+	if err != nil || a == nil {
+		return store, err
+	}
+	return a.Transcripts(store), nil
+}
+
+func transcriptsCmd() *command {
+	cmd := newCommand("transcripts", "", "Query the conversations kept in the transcript store of pbcli serve")
+	store := transcriptsFlag(cmd.fs)
+	bot := cmd.fs.String("name", "", "Only the conversations with this bot.")
+	clientName := cmd.fs.String("client", "", "Only the conversations of this client name.")
+	sessionId := cmd.fs.Int("session", 0, "Only the turns of this session.")
+	since := cmd.fs.Duration("since", 0, "Only the turns of this last period, like 24h.")
+	limit := cmd.fs.Int("limit", 100, "The number of most recent turns to show, zero for all.")
+	format := cmd.fs.String("format", "", "Export the turns for analytics tools instead of showing them, as jsonl, csv or parquet.")
+	out := cmd.fs.String("out", "", "Export file. If not specified will write to standard output.")
+	cmd.run = func(args []string) error {
+		if *format != "" && *format != pb.ExportJSONLines && *format != pb.ExportCSV && *format != pb.ExportParquet {
+			return usagef("Invalid format [%s] - must be jsonl, csv or parquet", *format)
+		}
+		if *store == "" {
+			return usagef("You must specify the transcript store with -transcripts or PB_TRANSCRIPTS")
+		}
+		s, closeStore, err := openTranscriptStore(*store, 0)
+		if err != nil {
+			return err
+		}
+		defer closeStore()
+		store, err := anonymizeTranscripts(s)
+		if err != nil {
+			return err
+		}
+		q := pb.TranscriptQuery{Bot: *bot, ClientName: *clientName, SessionId: *sessionId, Limit: *limit}
+		if *since > 0 {
+			q.Since = time.Now().Add(-*since)
+		}
+		entries, err := store.Query(q)
+		if err != nil {
+			return err
+		}
+		if *format != "" {
+			w := io.Writer(os.Stdout)
+			if *out != "" {
+				f, err := os.Create(*out)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			err = pb.ExportTranscripts(w, *format, entries)
+			if err == nil && *out != "" {
+				success("%d turns exported to %s.", len(entries), *out)
+			}
+			return err
+		}
+		return printResult(entries, func(w io.Writer) {
+			if len(entries) == 0 {
+				fmt.Fprintln(w, "No conversations found.")
+				return
+			}
+			row(w, "TIME", "BOT", "CLIENT", "SESSION", "TURN", "INPUT", "RESPONSES")
+			for _, e := range entries {
+				row(w, e.Time.Local().Format("2006-01-02 15:04:05"), e.Bot, e.ClientName, e.SessionId, e.Turn, e.Input, strings.Join(e.Responses, " "))
+			}
+		})
+	}
+	return cmd
+}
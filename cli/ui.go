@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// ANSI colors used for messages
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorGray   = "\x1b[90m"
+)
+
+// useColor returns true if messages written to f should be colored
+func useColor(f *os.File) bool {
+	return !*noColor && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb" && isTerminal(f.Fd())
+}
+
+// printColored writes the message to f followed by a new line, colored if f is a terminal
+func printColored(f *os.File, color, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if useColor(f) {
+		msg = color + msg + colorReset
+	}
+	fmt.Fprintln(f, msg)
+}
+
+// info prints an informational message unless running in quiet mode
+func info(format string, args ...interface{}) {
+	if !*quiet {
+		fmt.Printf(format+"\n", args...)
+	}
+}
+
+// statusf prints progress and summaries to standard error, keeping standard
+// output parseable, unless running in quiet mode
+func statusf(format string, args ...interface{}) {
+	if !*quiet {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// success prints a message about a completed operation unless running in quiet mode
+func success(format string, args ...interface{}) {
+	if !*quiet {
+		printColored(os.Stdout, colorGreen, format, args...)
+	}
+}
+
+// warnf prints a warning to standard error unless running in quiet mode
+func warnf(format string, args ...interface{}) {
+	if !*quiet {
+		printColored(os.Stderr, colorYellow, format, args...)
+	}
+}
+
+// errorf prints an error to standard error
+func errorf(format string, args ...interface{}) {
+	printColored(os.Stderr, colorRed, format, args...)
+}
+
+// verbosef prints details to standard error when running in verbose or debug mode
+func verbosef(format string, args ...interface{}) {
+	if (*verbose || *debug) && !*quiet {
+		printColored(os.Stderr, colorGray, format, args...)
+	}
+}
+
+// confirm asks the user to type answer to go ahead with a destructive operation.
+// Without a terminal to ask on the operation is refused.
+func confirm(prompt, answer string) error {
+	if !isTerminal(os.Stdin.Fd()) {
+		return usagef("Refusing to %s without confirmation, use -force", prompt)
+	}
+	printColored(os.Stderr, colorYellow, "This will %s. Type %q to confirm:", prompt, answer)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(line) != answer {
+		return usagef("Cancelled")
+	}
+	return nil
+}
+
+// logWriter adapts the library loggers to the pbcli messages
+type logWriter struct {
+	print func(format string, args ...interface{})
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w.print("%s", strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+// logRequest prints the outcome and timing of each API call in verbose mode
+func logRequest(ri pb.RequestInfo) {
+	attempt := ""
+	if ri.Attempt > 1 {
+		attempt = fmt.Sprintf(" (attempt %d)", ri.Attempt)
+	}
+	if ri.Err != nil && ri.StatusCode == 0 {
+		verbosef("%s %s failed after %v%s - %v", ri.Method, ri.Endpoint, ri.Elapsed, attempt, ri.Err)
+		return
+	}
+	verbosef("%s %s %d %v%s", ri.Method, ri.Endpoint, ri.StatusCode, ri.Elapsed, attempt)
+}
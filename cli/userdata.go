@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// openUserDataStores opens the transcript store of the user data commands
+func openUserDataStores(location string) (pb.UserDataStores, func() error, error) {
+	if location == "" {
+		return pb.UserDataStores{}, nil, usagef("You must specify the transcript store with -transcripts or PB_TRANSCRIPTS")
+	}
+	s, closeStore, err := openTranscriptStore(location, 0)
+	if err != nil {
+		return pb.UserDataStores{}, nil, err
+	}
+	store, err := anonymizeTranscripts(s)
+	if err != nil {
+		closeStore()
+		return pb.UserDataStores{}, nil, err
+	}
+	return pb.UserDataStores{Transcripts: []pb.TranscriptStore{store}}, closeStore, nil
+}
+
+func userDataExportCmd() *command {
+	cmd := newCommand("export", "", "Export the data kept about the end user of a client name as JSON, for data access requests")
+	store := transcriptsFlag(cmd.fs)
+	clientName := cmd.fs.String("client", "", "The client name of the end user.")
+	out := cmd.fs.String("out", "", "Output file. If not specified will write to standard output.")
+	cmd.run = func(args []string) error {
+		if *clientName == "" {
+			return usagef("You must specify the client name with -client")
+		}
+		u, closeStores, err := openUserDataStores(*store)
+		if err != nil {
+			return err
+		}
+		defer closeStores()
+		data, err := u.ExportUserData(*clientName)
+		if err != nil {
+			return err
+		}
+		w := io.Writer(os.Stdout)
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(data); err == nil && *out != "" {
+			success("%d turns of %s exported to %s.", len(data.Transcripts), *clientName, *out)
+		}
+		return err
+	}
+	return cmd
+}
+
+func userDataDeleteCmd() *command {
+	cmd := newCommand("delete", "", "Delete the data kept about the end user of a client name, for erasure requests")
+	store := transcriptsFlag(cmd.fs)
+	clientName := cmd.fs.String("client", "", "The client name of the end user.")
+	force := cmd.fs.Bool("force", false, "Delete the data without asking for confirmation.")
+	cmd.run = func(args []string) error {
+		if *clientName == "" {
+			return usagef("You must specify the client name with -client")
+		}
+		u, closeStores, err := openUserDataStores(*store)
+		if err != nil {
+			return err
+		}
+		defer closeStores()
+		if !*force {
+			if err = confirm(fmt.Sprintf("delete the conversations of %s", *clientName), *clientName); err != nil {
+				return err
+			}
+		}
+		res, err := u.DeleteUserData(*clientName)
+		if err != nil {
+			return err
+		}
+		return printResult(res, func(w io.Writer) {
+			fmt.Fprintf(w, "Deleted %d turns of %s.\n", res.Transcripts, res.ClientName)
+		})
+	}
+	return cmd
+}
+
+func userDataRevealCmd() *command {
+	cmd := newCommand("reveal", "PSEUDONYM", "Reveal the client name of a pseudonym of -anonymize, from the local mapping")
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			return usagef("You must specify the pseudonym")
+		}
+		a, err := newAnonymizer()
+		if err != nil {
+			return err
+		}
+		if a == nil {
+			return usagef("Pseudonyms are only revealed with -anonymize")
+		}
+		clientName, err := a.Reveal(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(clientName)
+		return nil
+	}
+	return cmd
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// printVerify prints the outcome of a verification, listing the compile messages
+// if the bot does not compile. The verification error is returned.
+func printVerify(err error) error {
+	return printVerifyDir(err, "")
+}
+
+// printVerifyDir is printVerify locating the files of the compile messages
+// under dir in the annotations, if dir is not empty
+func printVerifyDir(err error, dir string) error {
+	var ce *pb.CompileError
+	if err != nil && !errors.As(err, &ce) {
+		return err
+	}
+	if annotationOutput() {
+		var messages []pb.CompileMessage
+		if ce != nil {
+			messages = ce.Messages
+		}
+		if perr := printProblems(compileProblems(messages, dir)); perr != nil {
+			return perr
+		}
+		return err
+	}
+	view := struct {
+		Compiled bool                `json:"compiled"`
+		Messages []pb.CompileMessage `json:"messages"`
+	}{Compiled: err == nil, Messages: []pb.CompileMessage{}}
+	if ce != nil {
+		view.Messages = append(view.Messages, ce.Messages...)
+	}
+	if *output != outputTable {
+		if perr := printResult(view, nil); perr != nil {
+			return perr
+		}
+		return err
+	}
+	if err == nil {
+		success("Bot verified.")
+		return nil
+	}
+	if len(view.Messages) > 0 {
+		perr := printResult(view, func(w io.Writer) {
+			row(w, "FILE", "LINE", "MESSAGE")
+			for _, m := range view.Messages {
+				line := ""
+				if m.Line > 0 {
+					line = fmt.Sprint(m.Line)
+				}
+				row(w, m.File, line, m.Message)
+			}
+		})
+		if perr != nil {
+			return perr
+		}
+	}
+	return err
+}
+
+// botState returns the modification state of the bot files, to detect changes
+func botState(c *pb.Client, name string) (map[string]fileState, error) {
+	files, err := c.ListFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	state := make(map[string]fileState)
+	add := func(kind string, list []pb.BotFile) {
+		for _, f := range list {
+			state[kind+"/"+f.Name] = fileState{f.Size, f.Modified.Time}
+		}
+	}
+	add("file", files.Files)
+	add("set", files.Sets)
+	add("map", files.Maps)
+	add("substitution", files.Substitutions)
+	add("properties", files.Properties)
+	add("pdefaults", files.Pdefaults)
+	return state, nil
+}
+
+// watchVerify verifies the bot now and again whenever its files change
+func watchVerify(c *pb.Client, name string, interval time.Duration) error {
+	prev, err := botState(c, name)
+	if err != nil {
+		return err
+	}
+	verify := func() {
+		stamp := time.Now().Format("15:04:05")
+		if err := c.Verify(name); err != nil {
+			errorf("%s verification failed", stamp)
+			printCompileError(err)
+		} else {
+			success("%s Bot verified.", stamp)
+		}
+	}
+	verify()
+	info("Watching %s for changes. Press Ctrl-C to stop.", name)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+		cur, err := botState(c, name)
+		if err != nil {
+			warnf("%v", err)
+			continue
+		}
+		if changed, removed := changes(prev, cur); len(changed) > 0 || len(removed) > 0 {
+			verify()
+		}
+		prev = cur
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// fileState is what the watcher compares to detect changes
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshot returns the state of the bot files under dir
+func snapshot(dir string) (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isBotFile(path) {
+			files[path] = fileState{info.Size(), info.ModTime()}
+		}
+		return nil
+	})
+	return files, err
+}
+
+// changes returns the paths that were added or modified and the ones that were removed
+func changes(prev, cur map[string]fileState) (changed, removed []string) {
+	for path, s := range cur {
+		if p, ok := prev[path]; !ok || p != s {
+			changed = append(changed, path)
+		}
+	}
+	for path := range prev {
+		if _, ok := cur[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return changed, removed
+}
+
+func watchCmd() *command {
+	cmd := newCommand("watch", "DIR", "Upload changed files of a local directory and re-verify the bot as you edit")
+	name := nameFlag(cmd.fs)
+	interval := cmd.fs.Duration("interval", time.Second, "How often to check the directory for changes.")
+	del := cmd.fs.Bool("delete", false, "Delete bot files when they are removed locally.")
+	initial := cmd.fs.Bool("sync", true, "Sync the directory to the bot before watching.")
+	cmd.run = func(args []string) error {
+		if len(args) != 1 {
+			return usagef("You must specify the directory to watch")
+		}
+		if err := requireDirName(name, args[0]); err != nil {
+			return err
+		}
+		dir := args[0]
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		if *initial {
+			res, err := c.SyncDir(*name, dir, pb.SyncOptions{Delete: *del, Verify: true})
+			if err != nil {
+				return err
+			}
+			if err = printSyncResult(res, false); err != nil {
+				return err
+			}
+			if res.VerifyErr != nil {
+				printCompileError(res.VerifyErr)
+			}
+		}
+		prev, err := snapshot(dir)
+		if err != nil {
+			return err
+		}
+		info("Watching %s for changes. Press Ctrl-C to stop.", dir)
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt)
+		ticker := time.NewTicker(*interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return nil
+			case <-ticker.C:
+			}
+			cur, err := snapshot(dir)
+			if err != nil {
+				warnf("%v", err)
+				continue
+			}
+			changed, removed := changes(prev, cur)
+			prev = cur
+			if !*del {
+				removed = nil
+			}
+			if len(changed) == 0 && len(removed) == 0 {
+				continue
+			}
+			stamp := time.Now().Format("15:04:05")
+			failed := false
+			for _, path := range changed {
+				if err := c.UploadFileFromPath(*name, path); err != nil {
+					errorf("%s upload %s FAILED: %v", stamp, filepath.Base(path), err)
+					failed = true
+				} else {
+					fmt.Printf("%s upload %s\n", stamp, filepath.Base(path))
+				}
+			}
+			for _, path := range removed {
+				if err := c.DeleteFile(*name, filepath.Base(path)); err != nil {
+					errorf("%s delete %s FAILED: %v", stamp, filepath.Base(path), err)
+					failed = true
+				} else {
+					fmt.Printf("%s delete %s\n", stamp, filepath.Base(path))
+				}
+			}
+			if failed {
+				continue
+			}
+			if err := c.Verify(*name); err != nil {
+				printCompileError(err)
+			} else {
+				success("%s Bot verified.", stamp)
+			}
+		}
+	}
+	return cmd
+}
+
+// printCompileError prints a verification failure with a line per compile message
+func printCompileError(err error) {
+	var ce *pb.CompileError
+	if !errors.As(err, &ce) || len(ce.Messages) == 0 {
+		errorf("%v", err)
+		return
+	}
+	errorf("Bot does not compile:")
+	for _, m := range ce.Messages {
+		errorf("  %s", m)
+	}
+}
+
+func monitorCmd() *command {
+	cmd := newCommand("monitor", "", "Report the files added, modified or removed on the bots, e.g. edited in the web UI")
+	name := cmd.fs.String("name", "", "The bot to monitor. Defaults to all the bots of the application.")
+	interval := cmd.fs.Duration("interval", time.Minute, "How often to list the bot files.")
+	webhook := cmd.fs.String("webhook", "", "URL to post the changes to as JSON.")
+	cmd.run = func(args []string) error {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		var w *pb.ChangeWatcher
+		if *name != "" {
+			w = pb.NewChangeWatcher(c, *name)
+		} else {
+			w = pb.NewChangeWatcher(c)
+		}
+		w.Interval = *interval
+		w.Webhook = *webhook
+		w.OnChange = func(changes []pb.FileChange) {
+			stamp := time.Now().Format("15:04:05")
+			for _, ch := range changes {
+				fmt.Printf("%s %s %s %s\n", stamp, ch.Bot, ch.Change, ch.File)
+			}
+		}
+		w.OnError = func(err error) {
+			warnf("%v", err)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		info("Monitoring the bot files every %v, press Ctrl+C to stop", *interval)
+		return w.Run(ctx)
+	}
+	return cmd
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// webhookEvents are the events of the -webhook-events flag
+var webhookEvents = []string{pb.EventStarted, pb.EventDefault, pb.EventError, pb.EventKeyword}
+
+// splitList splits a comma separated list, dropping the empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// defaultResponsesFlag adds the flag of the default responses of the bot, shared by the -notify and -handoff flags
+func defaultResponsesFlag(fs *flag.FlagSet) *string {
+	return fs.String("default-responses", "", "File of the default responses of the bot, one per line, posting a "+pb.EventDefault+" event and counted by -handoff-after.")
+}
+
+// loadDefaultResponses reads the file of the -default-responses flag
+func loadDefaultResponses(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var responses []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			responses = append(responses, line)
+		}
+	}
+	return responses, nil
+}
+
+// webhookFlags adds the flags posting the events of the conversations of the
+// chat adapters to webhooks, and returns the webhooks they configure
+func webhookFlags(fs *flag.FlagSet, defaults *string) func() (*pb.Webhooks, error) {
+	urls := fs.String("notify", "", "Comma separated webhook URLs to post the events of the conversations to as JSON, signed with $PB_WEBHOOK_SECRET if set.")
+	events := fs.String("notify-events", "", "Comma separated events posted to the -notify webhooks: "+strings.Join(webhookEvents, ", ")+". Empty for all.")
+	keywords := fs.String("keywords", "", "Comma separated words of the inputs posting an "+pb.EventKeyword+" event, e.g. cancel,lawyer.")
+	return func() (*pb.Webhooks, error) {
+		if *urls == "" {
+			return nil, nil
+		}
+		selected := splitList(*events)
+		for _, e := range selected {
+			valid := false
+			for _, known := range webhookEvents {
+				valid = valid || e == known
+			}
+			if !valid {
+				return nil, usagef("Webhook event is not valid [%s] - must be one of %s", e, strings.Join(webhookEvents, ", "))
+			}
+		}
+		w := pb.NewWebhooks()
+		for _, u := range splitList(*urls) {
+			w.Hooks = append(w.Hooks, pb.Webhook{Url: u, Secret: os.Getenv("PB_WEBHOOK_SECRET"), Events: selected})
+		}
+		w.Keywords = splitList(*keywords)
+		var err error
+		if w.DefaultResponses, err = loadDefaultResponses(*defaults); err != nil {
+			return nil, err
+		}
+		w.OnError = func(err error) {
+			warnf("%v", err)
+		}
+		return w, nil
+	}
+}
@@ -0,0 +1,623 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A small decoder for the subset of YAML used by the pbcli files: block maps,
+// block lists, nested on the item lines too like "- - x", scalars, simple flow
+// lists and literal/folded block scalars.
+// Values are decoded into generic values and then assigned to the target using
+// the json struct tags, converting scalars to the type of the field.
+
+type yamlLine struct {
+	num    int    // 1 based line number
+	indent int    // number of leading spaces
+	text   string // the line without indentation and comments
+	raw    string // the original line
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// yamlUnmarshal decodes YAML data into v which must be a pointer
+func yamlUnmarshal(data []byte, v interface{}) error {
+	val, err := yamlDecode(data)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("yaml: target must be a non nil pointer")
+	}
+	return yamlAssign(rv.Elem(), val, "")
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// yamlAssign assigns the generic value val to dst
+func yamlAssign(dst reflect.Value, val interface{}, path string) error {
+	if val == nil {
+		return nil
+	}
+	if n, ok := val.(yamlNumber); ok {
+		if dst.Kind() == reflect.String {
+			// Keep the text, like 007 or 1e3, not the number it reads as
+			dst.SetString(n.text)
+			return nil
+		}
+		val = n.val
+	}
+	mismatch := func() error {
+		return fmt.Errorf("yaml: cannot assign %v to %s (%s)", val, strings.TrimPrefix(path, "."), dst.Type())
+	}
+	if dst.Type() == durationType {
+		d, err := time.ParseDuration(fmt.Sprint(val))
+		if err != nil {
+			return mismatch()
+		}
+		dst.SetInt(int64(d))
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return yamlAssign(dst.Elem(), val, path)
+	case reflect.Interface:
+		dst.Set(reflect.ValueOf(yamlValue(val)))
+	case reflect.String:
+		switch val.(type) {
+		case map[string]interface{}, []interface{}:
+			return mismatch()
+		}
+		dst.SetString(fmt.Sprint(val))
+	case reflect.Bool:
+		b, ok := val.(bool)
+		if !ok {
+			return mismatch()
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := val.(int64)
+		if !ok {
+			return mismatch()
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, ok := val.(int64)
+		if !ok || i < 0 {
+			return mismatch()
+		}
+		dst.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		switch n := val.(type) {
+		case int64:
+			dst.SetFloat(float64(n))
+		case float64:
+			dst.SetFloat(n)
+		default:
+			return mismatch()
+		}
+	case reflect.Slice:
+		list, ok := val.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		s := reflect.MakeSlice(dst.Type(), len(list), len(list))
+		for i, item := range list {
+			if err := yamlAssign(s.Index(i), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(s)
+	case reflect.Map:
+		m, ok := val.(map[string]interface{})
+		if !ok || dst.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		for k, item := range m {
+			ev := reflect.New(dst.Type().Elem()).Elem()
+			if err := yamlAssign(ev, item, path+"."+k); err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), ev)
+		}
+	case reflect.Struct:
+		m, ok := val.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		t := dst.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			// Embedded structs share the keys of the parent like with encoding/json, even if their type is unexported
+			if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+				if err := yamlAssign(dst.Field(i), val, path); err != nil {
+					return err
+				}
+				continue
+			}
+			if f.PkgPath != "" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			item, ok := m[name]
+			if !ok {
+				continue
+			}
+			if err := yamlAssign(dst.Field(i), item, path+"."+name); err != nil {
+				return err
+			}
+		}
+	default:
+		return mismatch()
+	}
+	return nil
+}
+
+// yamlNumber is an unquoted number, keeping its text for the string fields
+type yamlNumber struct {
+	text string
+	val  interface{} // int64 or float64
+}
+
+func (n yamlNumber) String() string {
+	return n.text
+}
+
+// yamlValue returns the generic value with the numbers in place of their yamlNumber
+func yamlValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case yamlNumber:
+		return v.val
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = yamlValue(item)
+		}
+		return list
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[k] = yamlValue(item)
+		}
+		return m
+	}
+	return val
+}
+
+// yamlDecode decodes YAML data into maps, slices and scalars
+func yamlDecode(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{
+			num:    i + 1,
+			indent: len(raw) - len(trimmed),
+			text:   strings.TrimSpace(stripComment(trimmed)),
+			raw:    raw,
+		})
+	}
+	p.skip()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	val, err := p.parseNode(p.lines[p.pos].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.skip(); p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return val, nil
+}
+
+// stripComment removes a trailing comment that is not part of a quoted string
+func stripComment(s string) string {
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || s[i-1] == ' '):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// skip moves past blank lines, comments and document markers
+func (p *yamlParser) skip() {
+	for p.pos < len(p.lines) && (p.lines[p.pos].text == "" || p.lines[p.pos].text == "---") {
+		p.pos++
+	}
+}
+
+func isListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) parseNode(indent int) (interface{}, error) {
+	p.skip()
+	if p.pos >= len(p.lines) || p.lines[p.pos].indent < indent {
+		return nil, nil
+	}
+	l := p.lines[p.pos]
+	if isListItem(l.text) {
+		return p.parseList(l.indent)
+	}
+	if _, _, ok := splitKey(l.text); ok {
+		return p.parseMap(l.indent)
+	}
+	p.pos++
+	return parseScalar(l.text)
+}
+
+func (p *yamlParser) parseList(indent int) ([]interface{}, error) {
+	list := make([]interface{}, 0)
+	for p.skip(); p.pos < len(p.lines); p.skip() {
+		l := p.lines[p.pos]
+		if l.indent != indent || !isListItem(l.text) {
+			break
+		}
+		text := strings.TrimSpace(l.text[1:])
+		if text == "" {
+			p.pos++
+			item, err := p.parseNode(indent + 1)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+			continue
+		}
+		col := indent + 1 + len(l.text[1:]) - len(strings.TrimLeft(l.text[1:], " "))
+		if isListItem(text) {
+			// A nested list starting on the item line, like "- - x"
+			p.lines[p.pos] = yamlLine{num: l.num, indent: col, text: text, raw: l.raw}
+			item, err := p.parseList(col)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+			continue
+		}
+		if _, _, ok := splitKey(text); ok {
+			// A map starting on the item line - re-read it at the column of the key
+			p.lines[p.pos] = yamlLine{num: l.num, indent: col, text: text, raw: l.raw}
+			item, err := p.parseMap(col)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+			continue
+		}
+		p.pos++
+		item, err := parseScalar(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", l.num, err)
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+func (p *yamlParser) parseMap(indent int) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for p.skip(); p.pos < len(p.lines); p.skip() {
+		l := p.lines[p.pos]
+		if l.indent < indent || (l.indent == indent && isListItem(l.text)) {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", l.num)
+		}
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", l.num)
+		}
+		p.pos++
+		switch {
+		case rest == "":
+			p.skip()
+			if p.pos < len(p.lines) && (p.lines[p.pos].indent > indent || (p.lines[p.pos].indent == indent && isListItem(p.lines[p.pos].text))) {
+				val, err := p.parseNode(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				m[key] = val
+			} else {
+				m[key] = nil
+			}
+		case rest == "|" || rest == "|-" || rest == ">" || rest == ">-":
+			m[key] = p.parseBlockScalar(indent, rest)
+		default:
+			val, err := parseScalar(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", l.num, err)
+			}
+			m[key] = val
+		}
+	}
+	return m, nil
+}
+
+// parseBlockScalar reads the lines of a literal (|) or folded (>) scalar
+func (p *yamlParser) parseBlockScalar(indent int, style string) string {
+	var lines []string
+	blockIndent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		l := p.lines[p.pos]
+		if strings.TrimSpace(l.raw) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if l.indent <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = l.indent
+		}
+		if l.indent < blockIndent {
+			break
+		}
+		lines = append(lines, l.raw[blockIndent:])
+	}
+	// Trailing blank lines belong to whatever follows
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	sep := "\n"
+	if style[0] == '>' {
+		sep = " "
+	}
+	s := strings.Join(lines, sep)
+	if !strings.HasSuffix(style, "-") {
+		s += "\n"
+	}
+	return s
+}
+
+// splitKey splits a "key: value" line. The key may be quoted.
+func splitKey(text string) (string, string, bool) {
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") {
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 {
+			return "", "", false
+		}
+		rest := text[end+2:]
+		if !strings.HasPrefix(rest, ":") || (len(rest) > 1 && rest[1] != ' ') {
+			return "", "", false
+		}
+		return text[1 : end+1], strings.TrimSpace(rest[1:]), true
+	}
+	if strings.HasSuffix(text, ":") {
+		return text[:len(text)-1], "", true
+	}
+	if i := strings.Index(text, ": "); i > 0 {
+		return text[:i], strings.TrimSpace(text[i+2:]), true
+	}
+	return "", "", false
+}
+
+func parseScalar(s string) (interface{}, error) {
+	switch {
+	case s == "~" || s == "null":
+		return nil, nil
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case s == "{}":
+		return map[string]interface{}{}, nil
+	case strings.HasPrefix(s, "\""):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return nil, fmt.Errorf("unterminated string %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]"):
+		list := make([]interface{}, 0)
+		inner := strings.TrimSpace(s[1 : len(s)-1])
+		if inner == "" {
+			return list, nil
+		}
+		for _, item := range splitFlow(inner) {
+			v, err := parseScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return yamlNumber{s, i}, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return yamlNumber{s, f}, nil
+	}
+	return s, nil
+}
+
+// splitFlow splits the items of a flow list on the commas outside of the
+// quoted strings and the nested lists
+func splitFlow(s string) []string {
+	var items []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(items, s[start:])
+}
+
+// yamlMarshal encodes v as YAML. The value is first encoded as JSON so the json
+// struct tags and marshalers apply, and the field order is preserved.
+func yamlMarshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := readJSONNode(dec)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writeYAMLNode(&buf, node, 0, false)
+	return buf.Bytes(), nil
+}
+
+// jsonField is a key/value pair of an ordered JSON object
+type jsonField struct {
+	key string
+	val interface{}
+}
+
+// readJSONNode reads a JSON value keeping the order of object keys
+func readJSONNode(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		obj := make([]jsonField, 0)
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			val, err := readJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, jsonField{key.(string), val})
+		}
+		_, err = dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := make([]interface{}, 0)
+		for dec.More() {
+			val, err := readJSONNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, val)
+		}
+		_, err = dec.Token()
+		return list, err
+	}
+	return tok, nil
+}
+
+// writeYAMLNode writes node at the given indentation. inline is set when the
+// node follows a "- " list marker on the same line.
+func writeYAMLNode(buf *bytes.Buffer, node interface{}, indent int, inline bool) {
+	pad := strings.Repeat("  ", indent)
+	switch n := node.(type) {
+	case []jsonField:
+		if len(n) == 0 {
+			buf.WriteString("{}\n")
+			return
+		}
+		for i, f := range n {
+			if i > 0 || !inline {
+				buf.WriteString(pad)
+			}
+			buf.WriteString(yamlScalar(f.key) + ":")
+			switch v := f.val.(type) {
+			case []jsonField:
+				if len(v) == 0 {
+					buf.WriteString(" {}\n")
+					continue
+				}
+				buf.WriteString("\n")
+				writeYAMLNode(buf, v, indent+1, false)
+			case []interface{}:
+				if len(v) == 0 {
+					buf.WriteString(" []\n")
+					continue
+				}
+				buf.WriteString("\n")
+				writeYAMLNode(buf, v, indent, false)
+			default:
+				buf.WriteString(" ")
+				writeYAMLNode(buf, v, indent+1, true)
+			}
+		}
+	case []interface{}:
+		if len(n) == 0 {
+			buf.WriteString("[]\n")
+			return
+		}
+		for i, item := range n {
+			if i > 0 || !inline {
+				buf.WriteString(pad)
+			}
+			buf.WriteString("- ")
+			writeYAMLNode(buf, item, indent+1, true)
+		}
+	case nil:
+		buf.WriteString("null\n")
+	case string:
+		buf.WriteString(yamlScalar(n) + "\n")
+	default:
+		fmt.Fprintf(buf, "%v\n", n)
+	}
+}
+
+// yamlScalar quotes strings that would otherwise be read back as something else
+func yamlScalar(s string) string {
+	if s == "" || strings.ContainsAny(s, ":#\n\"'{}[],&*!|>%@`") || strings.TrimSpace(s) != s || strings.HasPrefix(s, "- ") {
+		return strconv.Quote(s)
+	}
+	if v, _ := parseScalar(s); v != s {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,99 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CompileMessage is a single problem reported by the bot compiler
+type CompileMessage struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (m CompileMessage) String() string {
+	switch {
+	case m.File != "" && m.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", m.File, m.Line, m.Message)
+	case m.File != "":
+		return m.File + ": " + m.Message
+	}
+	return m.Message
+}
+
+// CompileError is returned by Verify when the bot does not compile.
+// It wraps the APIError holding the raw response.
+type CompileError struct {
+	Messages []CompileMessage
+	Err      *APIError
+}
+
+func (e *CompileError) Error() string {
+	if len(e.Messages) == 0 {
+		return "Bot does not compile - " + e.Err.Error()
+	}
+	msgs := make([]string, len(e.Messages))
+	for i, m := range e.Messages {
+		msgs[i] = m.String()
+	}
+	return "Bot does not compile - " + strings.Join(msgs, "; ")
+}
+
+func (e *CompileError) Unwrap() error {
+	return e.Err
+}
+
+// compileMessage is the tolerant form of the messages sent by pandorabots
+type compileMessage struct {
+	File     string          `json:"file"`
+	Filename string          `json:"filename"`
+	Line     json.RawMessage `json:"line"`
+	Message  string          `json:"message"`
+	Error    string          `json:"error"`
+}
+
+// parseCompileError extracts the compile messages from a verify response
+func parseCompileError(apiErr *APIError) *CompileError {
+	ce := &CompileError{Err: apiErr}
+	var body struct {
+		Message string           `json:"message"`
+		Errors  []compileMessage `json:"errors"`
+	}
+	if json.Unmarshal(apiErr.Body, &body) != nil {
+		if text := strings.TrimSpace(string(apiErr.Body)); text != "" {
+			ce.Messages = append(ce.Messages, CompileMessage{Message: text})
+		}
+		return ce
+	}
+	for _, m := range body.Errors {
+		cm := CompileMessage{File: m.File, Message: m.Message}
+		if cm.File == "" {
+			cm.File = m.Filename
+		}
+		if cm.Message == "" {
+			cm.Message = m.Error
+		}
+		cm.Line, _ = strconv.Atoi(strings.Trim(string(m.Line), `"`))
+		ce.Messages = append(ce.Messages, cm)
+	}
+	if len(ce.Messages) == 0 && body.Message != "" {
+		ce.Messages = append(ce.Messages, CompileMessage{Message: body.Message})
+	}
+	return ce
+}
+
+// verifyError converts the bad request answered when the bot does not compile to a CompileError
+func verifyError(err error) error {
+	if apiErr, ok := err.(*APIError); ok && apiErr.StatusCode == http.StatusBadRequest {
+		return parseCompileError(apiErr)
+	}
+	return err
+}
@@ -0,0 +1,338 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Session is the state of the conversation of a user with a bot
+type Session struct {
+	ClientName string    `json:"clientName"` // The pandorabots client name, which keeps the bot memory of the user
+	SessionId  int       `json:"sessionId"`  // The pandorabots session, zero to start a new one
+	Turns      int       `json:"turns"`      // The number of inputs sent in the session
+	Started    time.Time `json:"started"`
+	LastActive time.Time `json:"lastActive"`
+	Tags       []string  `json:"tags,omitempty"` // The tags of the Classifier of the Conversation
+	Bot        string    `json:"bot,omitempty"`  // The bot the Classifier switched the session to, empty for the bot of the Conversation
+}
+
+// HasTag reports whether the Classifier tagged the session with the tag
+func (s *Session) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionStore keeps the sessions of a Conversation by user key.
+// Implementations must be safe for concurrent use.
+type SessionStore interface {
+	// Get returns the session of the key, or nil if there is none
+	Get(key string) (*Session, error)
+	// Put stores the session of the key
+	Put(key string, s *Session) error
+	// Delete removes the session of the key, if any
+	Delete(key string) error
+}
+
+// MemorySessionStore is a SessionStore keeping the sessions in memory
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewMemorySessionStore creates an empty in memory session store
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]Session)}
+}
+
+func (m *MemorySessionStore) Get(key string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[key]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (m *MemorySessionStore) Put(key string, s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[key] = *s
+	return nil
+}
+
+func (m *MemorySessionStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, key)
+	return nil
+}
+
+// Keys returns the keys of the sessions, in no particular order
+func (m *MemorySessionStore) Keys() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.sessions))
+	for key := range m.sessions {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// SessionPolicy expires the stale sessions of a Conversation, so the next
+// input of the user starts a new pandorabots session
+type SessionPolicy struct {
+	IdleTimeout time.Duration // Expire the sessions inactive this long, zero to keep them
+	MaxTurns    int           // Expire the sessions after this many inputs, zero for no limit
+	// Reset clears the bot memory of the client name with the first input of
+	// the new session, so nothing of the stale conversation carries over
+	Reset bool
+}
+
+// Expired reports whether the session is stale at the time
+func (p SessionPolicy) Expired(s *Session, now time.Time) bool {
+	return (p.IdleTimeout > 0 && now.Sub(s.LastActive) > p.IdleTimeout) || (p.MaxTurns > 0 && s.Turns >= p.MaxTurns)
+}
+
+// Conversation talks with a bot on behalf of the users of a channel, keeping a
+// pandorabots session per user key (e.g. the user ID of a chat platform).
+// It is the building block of the channel adapters.
+type Conversation struct {
+	Client *Client
+	Bot    string
+	Store  SessionStore
+	// ClientName returns the pandorabots client name of a user key.
+	// Defaults to the key itself.
+	ClientName func(key string) string
+	// InputFilters normalize the inputs of the channel before they are sent,
+	// before the input filters of the Client
+	InputFilters []InputFilter
+	// Policy expires the stale sessions. The zero policy keeps the sessions until Reset.
+	Policy SessionPolicy
+	// Limiter throttles the inputs of each user, nil for no limit. The throttled
+	// inputs are not sent to the bot: the first is answered with the reply of
+	// the limiter and the next ones with no responses.
+	Limiter *RateLimiter
+	// Fallback answers the users when the bot is unavailable, nil to return the errors
+	Fallback *Fallback
+	// Selector can override or augment the replies of the bot, e.g. for the
+	// users of a feature flag, nil to send the replies of the bot
+	Selector ReplySelector
+	// Transcripts keeps the inputs and the replies sent to the users, nil to not keep them
+	Transcripts TranscriptStore
+	// Webhooks receive the events of the conversations, like the sessions
+	// started and the default responses, nil to not post them
+	Webhooks *Webhooks
+	// Escalator hands the conversations over to human agents when the users
+	// ask for one or the bot has no answer, nil to always answer with the bot
+	Escalator *Escalator
+	// Classifier detects the sentiment or the keywords of the inputs to tag
+	// the sessions, escalate them or switch them to another bot, nil for none
+	Classifier Classifier
+	// Middleware wraps the talks, the first being the outermost, see Use
+	Middleware []Middleware
+
+	locks keyLocks // Serializes the inputs of each key
+}
+
+// keyLocks serializes the calls of each key. The lock of a key is dropped
+// once no call holds or waits for it, so the locks do not grow with the users.
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	sync.Mutex
+	refs int // The calls holding or waiting for the lock
+}
+
+// lock locks the key and returns the func unlocking it
+func (l *keyLocks) lock(key string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*keyLock)
+	}
+	k := l.locks[key]
+	if k == nil {
+		k = &keyLock{}
+		l.locks[key] = k
+	}
+	k.refs++
+	l.mu.Unlock()
+	k.Lock()
+	return func() {
+		k.Unlock()
+		l.mu.Lock()
+		if k.refs--; k.refs == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// HashedClientName returns a Conversation.ClientName deriving short client names
+// from the hash of the keys, for channels with long or sensitive user IDs
+func HashedClientName(prefix string) func(key string) string {
+	return func(key string) string {
+		sum := sha256.Sum256([]byte(key))
+		return prefix + hex.EncodeToString(sum[:12])
+	}
+}
+
+// NewConversation creates a conversation with the bot. If store is nil the
+// sessions are kept in memory.
+func NewConversation(c *Client, bot string, store SessionStore) *Conversation {
+	if store == nil {
+		store = NewMemorySessionStore()
+	}
+	return &Conversation{Client: c, Bot: bot, Store: store}
+}
+
+// botOf returns the bot answering the session
+func (cv *Conversation) botOf(s *Session) string {
+	if s.Bot != "" {
+		return s.Bot
+	}
+	return cv.Bot
+}
+
+// session returns the session of the key, starting a new one if needed
+func (cv *Conversation) session(key string) (*Session, error) {
+	s, err := cv.Store.Get(key)
+	if err != nil || s != nil {
+		return s, err
+	}
+	clientName := key
+	if cv.ClientName != nil {
+		clientName = cv.ClientName(key)
+	}
+	now := time.Now()
+	return &Session{ClientName: clientName, Started: now, LastActive: now}, nil
+}
+
+// Talk sends the input of the user with the key to the bot within the user
+// session, through the Middleware of the conversation. The Fallback answers
+// the errors of the middleware too, like an exhausted latency budget.
+func (cv *Conversation) Talk(key, input string) (*Reply, error) {
+	reply, err := Chain(cv.talk, cv.Middleware...)(key, input)
+	if fb, ok := cv.Fallback.Answer(err); ok {
+		return fb, nil
+	}
+	return reply, err
+}
+
+// talk sends the input of the user to the bot, after the middleware
+func (cv *Conversation) talk(key, input string) (*Reply, error) {
+	if cv.Limiter != nil {
+		if ok, notify := cv.Limiter.take(key, time.Now()); !ok {
+			reply := &Reply{Responses: []string{}, Info: ReplyInfo{Throttled: true}}
+			if notify {
+				reply.Responses = append(reply.Responses, cv.Limiter.reply())
+			}
+			return reply, nil
+		}
+	}
+	defer cv.locks.lock(key)()
+	s, err := cv.session(key)
+	if err != nil {
+		return nil, err
+	}
+	if cv.Escalator != nil {
+		if cv.Escalator.Paused(key) != nil {
+			return handedOff(s, cv.Escalator.PausedReply), nil
+		}
+		if cv.Escalator.asked(input) {
+			if response := cv.escalate(key, EscalationPhrase, input, s); response != "" {
+				return handedOff(s, response), nil
+			}
+		}
+	}
+	reset := false
+	if now := time.Now(); s.Turns > 0 && cv.Policy.Expired(s, now) {
+		s = &Session{ClientName: s.ClientName, Started: now, LastActive: now}
+		reset = cv.Policy.Reset
+	}
+	var tags []string
+	if cv.Classifier != nil {
+		c, err := cv.classify(key, input, s)
+		if err != nil {
+			cv.Client.errorf("Unable to classify the input of [%s] - %v\n", s.ClientName, err)
+		} else if c != nil {
+			tags = c.Tags
+			if c.Escalate && cv.Escalator != nil {
+				if response := cv.escalate(key, EscalationClassifier, input, s); response != "" {
+					if err = cv.Store.Put(key, s); err != nil {
+						return nil, err
+					}
+					reply := handedOff(s, response)
+					reply.Info.Tags = tags
+					return reply, nil
+				}
+			}
+		}
+	}
+	bot := cv.botOf(s)
+	started := s.Turns == 0
+	reply, err := cv.Client.TalkDebug(bot, FilterInput(input, cv.InputFilters...), s.ClientName, s.SessionId, false, "", "", false, reset, false, false)
+	if err != nil {
+		cv.Webhooks.Observe(bot, cv.Client.Anonymized(s.ClientName), input, started, nil, err)
+		if fb, ok := cv.Fallback.Answer(err); ok {
+			if reply != nil {
+				fb.Info.Attempts, fb.Info.Latency = reply.Info.Attempts, reply.Info.Latency
+			}
+			return fb, nil
+		}
+		return nil, err
+	}
+	reply.Info.Tags = tags
+	s.SessionId = reply.SessionId
+	s.Turns++
+	s.LastActive = time.Now()
+	if err = cv.Store.Put(key, s); err != nil {
+		return reply, err
+	}
+	if cv.Selector != nil {
+		if reply, err = cv.selectReply(ReplyContext{Bot: bot, Key: key, Input: input, Session: *s}, reply); err != nil {
+			return reply, err
+		}
+	}
+	if cv.Transcripts != nil {
+		if err = cv.Transcripts.Append(NewTranscriptEntry(bot, s.ClientName, input, reply)); err != nil {
+			cv.Client.errorf("Unable to store the transcript of [%s] - %v\n", s.ClientName, err)
+		}
+	}
+	if cv.Escalator != nil && cv.Escalator.answered(key, reply) {
+		if response := cv.escalate(key, EscalationDefaults, input, s); response != "" {
+			reply.Responses = append(append([]string{}, reply.Responses...), response)
+			reply.Info.HandedOff = true
+		}
+	}
+	cv.Webhooks.Observe(bot, cv.Client.Anonymized(s.ClientName), input, started, reply, nil)
+	return reply, nil
+}
+
+// handedOff returns the reply to the users escalated to a human agent, with the response if any
+func handedOff(s *Session, response string) *Reply {
+	reply := &Reply{SessionId: s.SessionId, Responses: []string{}, Info: ReplyInfo{HandedOff: true}}
+	if response != "" {
+		reply.Responses = append(reply.Responses, response)
+	}
+	return reply
+}
+
+// Reset ends the session of the key so the next input starts a new pandorabots
+// session. The bot memory of the client name is kept.
+func (cv *Conversation) Reset(key string) error {
+	return cv.Store.Delete(key)
+}
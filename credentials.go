@@ -0,0 +1,78 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrCredentialNotFound is returned by a CredentialStore without a user key for the application
+	ErrCredentialNotFound = errors.New("No user key stored for the application")
+	// ErrKeyringUnsupported is returned by KeyringStore on the systems without a supported keyring
+	ErrKeyringUnsupported = errors.New("The OS keyring is not supported on this system")
+)
+
+// CredentialStore keeps the user keys of the applications, so they do not
+// need to be written in plain text configuration files
+type CredentialStore interface {
+	// Get returns the user key of the application, or ErrCredentialNotFound
+	Get(appId string) (string, error)
+	// Set stores the user key of the application, replacing the previous one if any
+	Set(appId, userKey string) error
+	// Delete removes the user key of the application, if any
+	Delete(appId string) error
+}
+
+// SetCredentialStore takes the user key from the store when SetCredentials
+// gives the application ID without a user key
+func SetCredentialStore(s CredentialStore) OptionFunc {
+	return func(c *Client) error {
+		c.credentials = s
+		return nil
+	}
+}
+
+// storedUserKey sets the user key from the credential store of the client, if needed
+func (c *Client) storedUserKey() error {
+	if c.credentials == nil || c.appId == "" || c.userKey != "" {
+		return nil
+	}
+	key, err := c.credentials.Get(c.appId)
+	if err == ErrCredentialNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to get the user key of [%s] from the credential store - %v", c.appId, err)
+	}
+	c.userKey = key
+	return nil
+}
+
+// KeyringStore is a CredentialStore keeping the user keys in the keyring of
+// the OS: the macOS keychain, the Windows credential manager or, on Linux and
+// the BSDs, the Secret Service of the desktop, like GNOME Keyring or KWallet,
+// through the secret-tool command. Other systems fail with ErrKeyringUnsupported.
+type KeyringStore struct {
+	Service string // The name the keys are stored under, with the application ID as the account
+}
+
+// NewKeyringStore creates a store keeping the user keys in the OS keyring under the service name
+func NewKeyringStore(service string) *KeyringStore {
+	return &KeyringStore{Service: service}
+}
+
+func (s *KeyringStore) Get(appId string) (string, error) {
+	return keyringGet(s.Service, appId)
+}
+
+func (s *KeyringStore) Set(appId, userKey string) error {
+	return keyringSet(s.Service, appId, userKey)
+}
+
+func (s *KeyringStore) Delete(appId string) error {
+	return keyringDelete(s.Service, appId)
+}
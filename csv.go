@@ -0,0 +1,136 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// readCSV reads the rows of a CSV file, as saved by spreadsheets, trimming
+// the cells and skipping the empty rows. Each row must have the number of
+// columns given, or at least one when zero.
+func readCSV(r io.Reader, columns int) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	cr := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	var rows [][]string
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+		if strings.Join(row, "") == "" {
+			continue
+		}
+		if line, _ := cr.FieldPos(0); columns > 0 && len(row) != columns {
+			return nil, fmt.Errorf("Line %d must have %d columns but has %d", line, columns, len(row))
+		}
+		rows = append(rows, row)
+	}
+}
+
+// writeList writes the entries in the JSON list format of the sets and maps, an entry per line
+func writeList(entries [][]string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("[\n")
+	for i, e := range entries {
+		data, _ := json.Marshal(e)
+		buf.WriteString("  ")
+		buf.Write(data)
+		if i < len(entries)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("]\n")
+	return buf.Bytes()
+}
+
+// readList downloads a set or map file of the bot
+func (c *Client) readList(bot, filename string) ([][]string, error) {
+	var buf bytes.Buffer
+	if err := c.GetFile(bot, filename, &buf); err != nil {
+		return nil, err
+	}
+	var entries [][]string
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		return nil, fmt.Errorf("File is not a JSON list [%s] - %v", filename, err)
+	}
+	return entries, nil
+}
+
+// ImportSetCSV uploads the set of the bot from a CSV file with a phrase per
+// row, in the first column, so large sets can be kept in spreadsheets. The
+// set name may have the .set extension. The file has no header row.
+func (c *Client) ImportSetCSV(bot, set string, r io.Reader) error {
+	rows, err := readCSV(r, 0)
+	if err != nil {
+		return err
+	}
+	var entries [][]string
+	for _, row := range rows {
+		if words := strings.Fields(row[0]); len(words) > 0 {
+			entries = append(entries, words)
+		}
+	}
+	return c.UploadFile(bot, strings.TrimSuffix(set, ".set")+".set", bytes.NewReader(writeList(entries)))
+}
+
+// ExportSetCSV writes the set of the bot as a CSV file with a phrase per row, as read by ImportSetCSV
+func (c *Client) ExportSetCSV(bot, set string, w io.Writer) error {
+	entries, err := c.readList(bot, strings.TrimSuffix(set, ".set")+".set")
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	for _, e := range entries {
+		cw.Write([]string{strings.Join(e, " ")})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportMapCSV uploads the map of the bot from a CSV file with a key and a
+// value per row. The map name may have the .map extension. The file has no
+// header row.
+func (c *Client) ImportMapCSV(bot, name string, r io.Reader) error {
+	rows, err := readCSV(r, 2)
+	if err != nil {
+		return err
+	}
+	return c.UploadFile(bot, strings.TrimSuffix(name, ".map")+".map", bytes.NewReader(writeList(rows)))
+}
+
+// ExportMapCSV writes the map of the bot as a CSV file with a key and a value per row, as read by ImportMapCSV
+func (c *Client) ExportMapCSV(bot, name string, w io.Writer) error {
+	entries, err := c.readList(bot, strings.TrimSuffix(name, ".map")+".map")
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	for _, e := range entries {
+		if len(e) != 2 {
+			return fmt.Errorf("Map entry must have a key and a value [%s]", strings.Join(e, ", "))
+		}
+		cw.Write(e)
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,271 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DiffStatus is the kind of difference of a file
+type DiffStatus string
+
+const (
+	DiffAdded   DiffStatus = "added"   // The file exists only on the new side
+	DiffRemoved DiffStatus = "removed" // The file exists only on the old side
+	DiffChanged DiffStatus = "changed" // The file exists on both sides with different content
+)
+
+// FileDiff is the difference of a single file
+type FileDiff struct {
+	File   string     `json:"file"`
+	Status DiffStatus `json:"status"`
+	Old    []byte     `json:"-"` // The old content, nil if the file was added
+	New    []byte     `json:"-"` // The new content, nil if the file was removed
+}
+
+// BotDiff is the list of differences between two sets of bot files
+type BotDiff struct {
+	From  string     `json:"from"` // Description of the old side, e.g. the bot name
+	To    string     `json:"to"`   // Description of the new side, e.g. the directory
+	Files []FileDiff `json:"files"`
+}
+
+// diffContents compares two sets of files by the resource they are uploaded to
+func diffContents(from, to string, old, new map[string][]byte) *BotDiff {
+	type entry struct {
+		name string
+		data []byte
+	}
+	byKey := func(m map[string][]byte) map[string]entry {
+		res := make(map[string]entry)
+		for name, data := range m {
+			res[fileKey(name)] = entry{name, data}
+		}
+		return res
+	}
+	oldByKey, newByKey := byKey(old), byKey(new)
+	d := &BotDiff{From: from, To: to, Files: make([]FileDiff, 0)}
+	for key, o := range oldByKey {
+		n, ok := newByKey[key]
+		switch {
+		case !ok:
+			d.Files = append(d.Files, FileDiff{File: o.name, Status: DiffRemoved, Old: o.data})
+		case !bytes.Equal(o.data, n.data):
+			d.Files = append(d.Files, FileDiff{File: n.name, Status: DiffChanged, Old: o.data, New: n.data})
+		}
+	}
+	for key, n := range newByKey {
+		if _, ok := oldByKey[key]; !ok {
+			d.Files = append(d.Files, FileDiff{File: n.name, Status: DiffAdded, New: n.data})
+		}
+	}
+	sort.Slice(d.Files, func(i, j int) bool { return d.Files[i].File < d.Files[j].File })
+	return d
+}
+
+// DiffBots compares the files of two bots. The from bot is the old side.
+func (c *Client) DiffBots(from, to string) (*BotDiff, error) {
+	old, err := c.fileContents(from)
+	if err != nil {
+		return nil, err
+	}
+	new, err := c.fileContents(to)
+	if err != nil {
+		return nil, err
+	}
+	return diffContents(from, to, old, new), nil
+}
+
+// DiffDir compares the files of the bot (the old side) with the personality
+// files found in the local directory, as SyncDir would upload them.
+func (c *Client) DiffDir(name, dir string) (*BotDiff, error) {
+	old, err := c.fileContents(name)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := c.localFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	new := make(map[string][]byte)
+	for file, path := range paths {
+		if new[file], err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return diffContents(name, dir, old, new), nil
+}
+
+// Unified returns the difference of the file in unified diff format
+// with the given number of context lines.
+func (d FileDiff) Unified(context int) string {
+	if bytes.IndexByte(d.Old, 0) >= 0 || bytes.IndexByte(d.New, 0) >= 0 {
+		return fmt.Sprintf("Binary file %s differs\n", d.File)
+	}
+	oldName, newName := "a/"+d.File, "b/"+d.File
+	if d.Status == DiffAdded {
+		oldName = "/dev/null"
+	}
+	if d.Status == DiffRemoved {
+		newName = "/dev/null"
+	}
+	return unifiedDiff(oldName, newName, splitLines(d.Old), splitLines(d.New), context)
+}
+
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffOp is a line of an edit script: ' ' kept, '-' deleted or '+' inserted
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines computes the shortest edit script from a to b (Myers' algorithm)
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	max := n + m
+	v := make([]int, 2*max+2)
+	off := max
+	// trace[d] holds the furthest x reached for diagonals -d..d before round d
+	var trace [][]int
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		snap := make([]int, 2*d+1)
+		copy(snap, v[off-d:off+d+1])
+		trace = append(trace, snap)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	var ops []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		snap := trace[d]
+		get := func(k int) int { return snap[k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && get(k-1) < get(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := get(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, diffOp{'+', b[y-1]})
+			y--
+		} else {
+			ops = append(ops, diffOp{'-', a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, diffOp{' ', a[x-1]})
+		x--
+		y--
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// unifiedDiff formats the edit script between a and b as a unified diff
+func unifiedDiff(oldName, newName string, a, b []string, context int) string {
+	ops := diffLines(a, b)
+	var changes []int
+	for i, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", oldName, newName)
+	// Line numbers (0 based) in a and b at the start of each op
+	oldLine := make([]int, len(ops)+1)
+	newLine := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if op.kind != '+' {
+			oldLine[i+1]++
+		}
+		if op.kind != '-' {
+			newLine[i+1]++
+		}
+	}
+	for i := 0; i < len(changes); {
+		start := changes[i] - context
+		if start < 0 {
+			start = 0
+		}
+		end := changes[i]
+		// Merge the changes whose context overlaps
+		for i < len(changes) && changes[i]-end <= 2*context+1 {
+			end = changes[i]
+			i++
+		}
+		end += context + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+		oldCount, newCount := oldLine[end]-oldLine[start], newLine[end]-newLine[start]
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(oldLine[start], oldCount), hunkRange(newLine[start], newCount))
+		for _, op := range ops[start:end] {
+			buf.WriteByte(op.kind)
+			buf.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return buf.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,265 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/demisto/pb-go/aiml"
+)
+
+// maxSamples is the number of sample patterns and entries documented per topic, set or map
+const maxSamples = 10
+
+// TopicDocs documents a topic of the bot
+type TopicDocs struct {
+	Name       string   `json:"name"` // Empty for the categories outside of any topic
+	Categories int      `json:"categories"`
+	FollowUps  int      `json:"followUps"` // The categories answering a specific previous response, with a that
+	Files      []string `json:"files"`
+	Patterns   []string `json:"patterns"` // Samples, the ones without wildcards first
+}
+
+// ListDocs documents a set or a map of the bot
+type ListDocs struct {
+	Name    string   `json:"name"`
+	Entries int      `json:"entries"`
+	Samples []string `json:"samples"`
+}
+
+// BotDocs documents the behavior of a bot for the people who do not read
+// AIML: its topics with sample patterns, its properties, sets and maps, and
+// the inventory of its files
+type BotDocs struct {
+	*BotStats
+	Topics     []TopicDocs `json:"topics"`
+	Properties [][]string  `json:"properties"`
+	Sets       []ListDocs  `json:"sets"`
+	Maps       []ListDocs  `json:"maps"`
+}
+
+// Docs builds the documentation of the bot. The bot files are downloaded.
+func (c *Client) Docs(name string) (*BotDocs, error) {
+	list, err := c.ListFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := c.fileContents(name)
+	if err != nil {
+		return nil, err
+	}
+	docs := &BotDocs{BotStats: buildStats(name, list, contents), Topics: []TopicDocs{}, Properties: [][]string{}, Sets: []ListDocs{}, Maps: []ListDocs{}}
+	names := make([]string, 0, len(contents))
+	for file := range contents {
+		names = append(names, file)
+	}
+	sort.Strings(names)
+	topics := make(map[string]*TopicDocs)
+	var order []string
+	for _, file := range names {
+		data := contents[file]
+		var entries [][]string
+		switch ext := filepath.Ext(file); ext {
+		case ".aiml":
+			doc, err := aiml.Parse(data)
+			if err != nil {
+				return nil, fmt.Errorf("File is not valid AIML [%s] - %v", file, err)
+			}
+			for _, cat := range doc.Categories() {
+				topic := cat.Topic
+				if topic == "*" {
+					topic = ""
+				}
+				t := topics[topic]
+				if t == nil {
+					t = &TopicDocs{Name: topic}
+					topics[topic] = t
+					order = append(order, topic)
+				}
+				t.Categories++
+				if cat.That != "" && cat.That != "*" {
+					t.FollowUps++
+				}
+				if len(t.Files) == 0 || t.Files[len(t.Files)-1] != file {
+					t.Files = append(t.Files, file)
+				}
+				t.Patterns = append(t.Patterns, cat.Pattern)
+			}
+		case ".properties", ".set", ".map":
+			if err := json.Unmarshal(data, &entries); err != nil {
+				return nil, fmt.Errorf("File is not a JSON list [%s] - %v", file, err)
+			}
+			if ext == ".properties" {
+				docs.Properties = append(docs.Properties, entries...)
+				continue
+			}
+			l := ListDocs{Name: strings.TrimSuffix(file, ext), Entries: len(entries), Samples: []string{}}
+			for _, e := range entries {
+				if len(l.Samples) == maxSamples {
+					break
+				}
+				if ext == ".map" && len(e) == 2 {
+					l.Samples = append(l.Samples, e[0]+" → "+e[1])
+				} else {
+					l.Samples = append(l.Samples, strings.Join(e, " "))
+				}
+			}
+			if ext == ".set" {
+				docs.Sets = append(docs.Sets, l)
+			} else {
+				docs.Maps = append(docs.Maps, l)
+			}
+		}
+	}
+	sort.Strings(order)
+	for _, topic := range order {
+		t := topics[topic]
+		t.Patterns = samplePatterns(t.Patterns)
+		docs.Topics = append(docs.Topics, *t)
+	}
+	return docs, nil
+}
+
+// samplePatterns returns up to maxSamples distinct patterns, the ones without wildcards first
+func samplePatterns(patterns []string) []string {
+	seen := make(map[string]bool)
+	var plain, wild []string
+	for _, p := range patterns {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		if strings.ContainsAny(p, "*_#^<") {
+			wild = append(wild, p)
+		} else {
+			plain = append(plain, p)
+		}
+	}
+	res := append(plain, wild...)
+	if len(res) > maxSamples {
+		res = res[:maxSamples]
+	}
+	return res
+}
+
+// topicTitle returns the title of the topic, General for the categories outside of any topic
+func topicTitle(name string) string {
+	if name == "" {
+		return "General"
+	}
+	return name
+}
+
+var docsFuncs = template.FuncMap{
+	"time":  formatTime,
+	"topic": topicTitle,
+	"join":  strings.Join,
+}
+
+var htmlDocs = template.Must(template.New("docs").Funcs(docsFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Bot}} - bot documentation</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; max-width: 60em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; }
+td.num { text-align: right; }
+code { background: #f6f6f6; padding: 0 3px; }
+nav a { margin-right: 1em; }
+</style>
+</head>
+<body>
+<h1>{{.Bot}}</h1>
+<p>Generated {{time .Generated}} - {{.Categories}} categories in {{len .Files}} files, last modified {{time .LastModified}}</p>
+<nav><a href="#topics">Topics</a><a href="#properties">Properties</a><a href="#sets">Sets</a><a href="#maps">Maps</a><a href="#files">Files</a></nav>
+<h2 id="topics">Topics</h2>
+{{range .Topics}}<h3>{{topic .Name}}</h3>
+<p>{{.Categories}} categories{{if .FollowUps}}, {{.FollowUps}} answering a previous response{{end}}, in {{join .Files ", "}}</p>
+<ul>
+{{range .Patterns}}<li><code>{{.}}</code></li>
+{{end}}</ul>
+{{end}}<h2 id="properties">Properties</h2>
+<table>
+{{range .Properties}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+<h2 id="sets">Sets</h2>
+<table>
+<tr><th>Name</th><th>Entries</th><th>Samples</th></tr>
+{{range .Sets}}<tr><td>{{.Name}}</td><td class="num">{{.Entries}}</td><td>{{join .Samples ", "}}</td></tr>
+{{end}}</table>
+<h2 id="maps">Maps</h2>
+<table>
+<tr><th>Name</th><th>Entries</th><th>Samples</th></tr>
+{{range .Maps}}<tr><td>{{.Name}}</td><td class="num">{{.Entries}}</td><td>{{join .Samples ", "}}</td></tr>
+{{end}}</table>
+<h2 id="files">Files</h2>
+<table>
+<tr><th>Name</th><th>Kind</th><th>Items</th><th>Size</th><th>Modified</th></tr>
+{{range .Files}}<tr><td>{{.Name}}</td><td>{{.Kind}}</td><td class="num">{{.Items}}</td><td class="num">{{.Size}}</td><td>{{time .Modified}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the documentation as a standalone HTML page
+func (d *BotDocs) WriteHTML(w io.Writer) error {
+	return htmlDocs.Execute(w, d)
+}
+
+// markdownCell escapes the text for a Markdown table cell
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// WriteMarkdown writes the documentation as a Markdown document
+func (d *BotDocs) WriteMarkdown(w io.Writer) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n\nGenerated %s - %d categories in %d files, last modified %s\n\n", d.Bot, formatTime(d.Generated), d.Categories, len(d.Files), formatTime(d.LastModified))
+	buf.WriteString("## Topics\n\n")
+	for _, t := range d.Topics {
+		fmt.Fprintf(&buf, "### %s\n\n%d categories", topicTitle(t.Name), t.Categories)
+		if t.FollowUps > 0 {
+			fmt.Fprintf(&buf, ", %d answering a previous response", t.FollowUps)
+		}
+		fmt.Fprintf(&buf, ", in %s\n\n", strings.Join(t.Files, ", "))
+		for _, p := range t.Patterns {
+			fmt.Fprintf(&buf, "- `%s`\n", p)
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("## Properties\n\n| Name | Value |\n|---|---|\n")
+	for _, p := range d.Properties {
+		cells := make([]string, len(p))
+		for i, c := range p {
+			cells[i] = markdownCell(c)
+		}
+		fmt.Fprintf(&buf, "| %s |\n", strings.Join(cells, " | "))
+	}
+	for _, section := range []struct {
+		title string
+		lists []ListDocs
+	}{{"Sets", d.Sets}, {"Maps", d.Maps}} {
+		fmt.Fprintf(&buf, "\n## %s\n\n| Name | Entries | Samples |\n|---|---:|---|\n", section.title)
+		for _, l := range section.lists {
+			fmt.Fprintf(&buf, "| %s | %d | %s |\n", l.Name, l.Entries, markdownCell(strings.Join(l.Samples, ", ")))
+		}
+	}
+	fmt.Fprintf(&buf, "\n## Files\n\n| Name | Kind | Items | Size | Modified |\n|---|---|---:|---:|---|\n")
+	for _, f := range d.Files {
+		fmt.Fprintf(&buf, "| %s | %s | %d | %d | %s |\n", f.Name, f.Kind, f.Items, f.Size, formatTime(f.Modified))
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
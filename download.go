@@ -0,0 +1,101 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+)
+
+// FileTypes are the types of bot files, named after their extensions
+var FileTypes = []string{"aiml", "set", "map", "substitution", "properties", "pdefaults"}
+
+// FileFilter selects bot files by type and modification time
+type FileFilter struct {
+	Types []string  // The file types to include, see FileTypes. Empty for all types.
+	Since time.Time // Only include files modified after this time. Zero for all files.
+}
+
+// Validate checks the filter types are known
+func (f FileFilter) Validate() error {
+	for _, t := range f.Types {
+		known := false
+		for _, ft := range FileTypes {
+			known = known || t == ft
+		}
+		if !known {
+			return fmt.Errorf("File type is not recognized [%s]", t)
+		}
+	}
+	return nil
+}
+
+func (f FileFilter) includes(fileType string, file BotFile) bool {
+	if !f.Since.IsZero() && !file.Modified.After(f.Since) {
+		return false
+	}
+	if len(f.Types) == 0 {
+		return true
+	}
+	for _, t := range f.Types {
+		if t == fileType {
+			return true
+		}
+	}
+	return false
+}
+
+// names returns the file names (with extensions) of the bot files matching the filter
+func (f FileFilter) names(files BotFiles) []string {
+	var names []string
+	add := func(list []BotFile, fileType string) {
+		ext := "." + fileType
+		for _, file := range list {
+			if !f.includes(fileType, file) {
+				continue
+			}
+			if filepath.Ext(file.Name) == ext {
+				names = append(names, file.Name)
+			} else {
+				names = append(names, file.Name+ext)
+			}
+		}
+	}
+	add(files.Files, "aiml")
+	add(files.Sets, "set")
+	add(files.Maps, "map")
+	add(files.Substitutions, "substitution")
+	add(files.Properties, "properties")
+	add(files.Pdefaults, "pdefaults")
+	return names
+}
+
+// DownloadFilteredFiles writes a zip archive of the bot files matching the filter.
+// Unlike DownloadFiles the files are fetched one by one, so only the selected
+// files are transferred. Returns the number of files written.
+func (c *Client) DownloadFilteredFiles(name string, filter FileFilter, w io.Writer) (int, error) {
+	if err := filter.Validate(); err != nil {
+		return 0, err
+	}
+	list, err := c.ListFiles(name)
+	if err != nil {
+		return 0, err
+	}
+	zw := zip.NewWriter(w)
+	names := filter.names(list)
+	for _, filename := range names {
+		fw, err := zw.Create(filename)
+		if err != nil {
+			return 0, err
+		}
+		if err = c.GetFile(name, filename, fw); err != nil {
+			return 0, err
+		}
+	}
+	return len(names), zw.Close()
+}
@@ -0,0 +1,163 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// EncryptionKeyEnv is the environment variable of the encryption key, as used by pbcli
+const EncryptionKeyEnv = "PB_ENCRYPTION_KEY"
+
+// KeyFunc returns the 32 bytes AES-256 key of an Encryptor, e.g. a data key
+// decrypted by a key management service
+type KeyFunc func() ([]byte, error)
+
+// KeyFromEnv returns the key in the environment variable, base64 or hex encoded
+func KeyFromEnv(name string) KeyFunc {
+	return func() ([]byte, error) {
+		v := strings.TrimSpace(os.Getenv(name))
+		if v == "" {
+			return nil, fmt.Errorf("Encryption key is not set [%s]", name)
+		}
+		if key, err := hex.DecodeString(v); err == nil && len(key) == 32 {
+			return key, nil
+		}
+		if key, err := base64.StdEncoding.DecodeString(v); err == nil && len(key) == 32 {
+			return key, nil
+		}
+		return nil, fmt.Errorf("Encryption key is not valid [%s] - it must be 32 bytes, base64 or hex encoded", name)
+	}
+}
+
+// encryptedMagic starts the data sealed by an Encryptor
+var encryptedMagic = []byte("PBENC1\n")
+
+// ErrEncrypted is returned when reading encrypted data without an Encryptor
+var ErrEncrypted = errors.New("Data is encrypted and no encryption key is set")
+
+// IsEncrypted reports whether the data was sealed by an Encryptor
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedMagic)
+}
+
+// Encryptor encrypts the data written to disk, like backup archives,
+// transcripts and session files, with AES-256-GCM. Sealed data starts with a
+// marker, so data written before encryption was enabled is still read. The
+// key is requested once and kept. A nil Encryptor leaves the data as is.
+type Encryptor struct {
+	key  KeyFunc
+	mu   sync.Mutex
+	aead cipher.AEAD
+}
+
+// NewEncryptor creates an encryptor with the key returned by key
+func NewEncryptor(key KeyFunc) *Encryptor {
+	return &Encryptor{key: key}
+}
+
+// cipher returns the cipher of the key, requesting the key on first use
+func (e *Encryptor) cipher() (cipher.AEAD, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.aead != nil {
+		return e.aead, nil
+	}
+	key, err := e.key()
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("Encryption key is not valid - it must be 32 bytes, not %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if e.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+	return e.aead, nil
+}
+
+// Seal encrypts the data with a random nonce
+func (e *Encryptor) Seal(data []byte) ([]byte, error) {
+	if e == nil {
+		return data, nil
+	}
+	aead, err := e.cipher()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(encryptedMagic)+aead.NonceSize(), len(encryptedMagic)+aead.NonceSize()+len(data)+aead.Overhead())
+	copy(out, encryptedMagic)
+	if _, err = rand.Read(out[len(encryptedMagic):]); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, out[len(encryptedMagic):], data, encryptedMagic), nil
+}
+
+// Open decrypts data sealed by Seal. Data which is not encrypted is returned as is.
+func (e *Encryptor) Open(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	if e == nil {
+		return nil, ErrEncrypted
+	}
+	aead, err := e.cipher()
+	if err != nil {
+		return nil, err
+	}
+	data = data[len(encryptedMagic):]
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("Encrypted data is truncated")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], encryptedMagic)
+	if err != nil {
+		return nil, errors.New("Unable to decrypt the data - wrong key or corrupted data")
+	}
+	return plain, nil
+}
+
+// WriteFile writes the sealed data to the file in path
+func (e *Encryptor) WriteFile(path string, data []byte, perm os.FileMode) error {
+	sealed, err := e.Seal(data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, sealed, perm)
+}
+
+// ReadFile reads the file in path, decrypting it if it is encrypted
+func (e *Encryptor) ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = e.Open(data); err != nil {
+		return nil, fmt.Errorf("Unable to read [%s] - %v", path, err)
+	}
+	return data, nil
+}
+
+// SetEncryption encrypts the backup archives written by BackupToPath and
+// BackupToStorage, and decrypts the encrypted archives read by Restore
+func SetEncryption(e *Encryptor) OptionFunc {
+	return func(c *Client) error {
+		c.encryptor = e
+		return nil
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The formats of ExportTranscripts
+const (
+	ExportJSONLines = "jsonl"   // Newline delimited JSON, as loaded by BigQuery
+	ExportCSV       = "csv"     // CSV with a header row
+	ExportParquet   = "parquet" // Apache Parquet, uncompressed
+)
+
+// ExportRow is a turn of a conversation in the schema of the exports, the
+// same in all the formats. The JSON names are the column names.
+type ExportRow struct {
+	Time       time.Time `json:"time"` // UTC, a TIMESTAMP with microseconds in Parquet
+	Bot        string    `json:"bot"`
+	ClientName string    `json:"client_name"`
+	SessionId  int64     `json:"session_id"`
+	Turn       int64     `json:"turn"` // The index of the turn in the session, from zero
+	Input      string    `json:"input"`
+	Response   string    `json:"response"` // The responses of the bot, separated by new lines
+	LatencyMs  float64   `json:"latency_ms"`
+}
+
+// ExportColumns are the names of the columns of the exports, in order
+var ExportColumns = []string{"time", "bot", "client_name", "session_id", "turn", "input", "response", "latency_ms"}
+
+// NewExportRow converts a transcript entry to the schema of the exports
+func NewExportRow(e TranscriptEntry) ExportRow {
+	return ExportRow{
+		Time:       e.Time.UTC(),
+		Bot:        e.Bot,
+		ClientName: e.ClientName,
+		SessionId:  int64(e.SessionId),
+		Turn:       int64(e.Turn),
+		Input:      e.Input,
+		Response:   strings.Join(e.Responses, "\n"),
+		LatencyMs:  float64(e.Latency) / float64(time.Millisecond),
+	}
+}
+
+// ExportTranscripts writes the entries, e.g. queried from a TranscriptStore,
+// in the format for analytics tools: ExportJSONLines, ExportCSV or ExportParquet
+func ExportTranscripts(w io.Writer, format string, entries []TranscriptEntry) error {
+	rows := make([]ExportRow, len(entries))
+	for i, e := range entries {
+		rows[i] = NewExportRow(e)
+	}
+	switch format {
+	case ExportJSONLines:
+		return writeJSONLines(w, rows)
+	case ExportCSV:
+		return writeExportCSV(w, rows)
+	case ExportParquet:
+		return writeParquet(w, rows)
+	}
+	return fmt.Errorf("Export format is not valid [%s] - it must be jsonl, csv or parquet", format)
+}
+
+func writeJSONLines(w io.Writer, rows []ExportRow) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, r := range rows {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeExportCSV(w io.Writer, rows []ExportRow) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(ExportColumns); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		err := cw.Write([]string{
+			r.Time.Format(time.RFC3339Nano),
+			r.Bot,
+			r.ClientName,
+			strconv.FormatInt(r.SessionId, 10),
+			strconv.FormatInt(r.Turn, 10),
+			r.Input,
+			r.Response,
+			strconv.FormatFloat(r.LatencyMs, 'f', 3, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,178 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCooldown is how long a failed endpoint is avoided
+const DefaultCooldown = 30 * time.Second
+
+// SetEndpoints adds API base URLs the requests fail over to when the URL of
+// SetUrl fails, e.g. pandorabots compatible mirrors or an on-premises install.
+// The requests go to the healthy endpoint answering the fastest. An endpoint
+// failing with a network error or a 502, 503 or 504 response is avoided for
+// the cooldown, zero for DefaultCooldown, and the request is sent to the next.
+func SetEndpoints(cooldown time.Duration, urls ...string) OptionFunc {
+	return func(c *Client) error {
+		for _, rawurl := range urls {
+			if err := c.checkUrl(rawurl); err != nil {
+				return err
+			}
+		}
+		if cooldown <= 0 {
+			cooldown = DefaultCooldown
+		}
+		c.mirrors, c.cooldown = urls, cooldown
+		return nil
+	}
+}
+
+// EndpointStatus is the health of an API endpoint
+type EndpointStatus struct {
+	Url      string
+	Healthy  bool
+	Latency  time.Duration // The moving average of the response times, zero before the first response
+	Failures int           // The failures since the last success
+}
+
+// endpoint is an API base URL of the pool
+type endpoint struct {
+	url      string
+	latency  time.Duration
+	failures int
+	down     time.Time // Avoided until then
+}
+
+// endpointPool selects the endpoint of the requests
+type endpointPool struct {
+	cooldown time.Duration
+	mu       sync.Mutex
+	list     []*endpoint // In the order of configuration
+}
+
+func newEndpointPool(cooldown time.Duration, urls []string) *endpointPool {
+	p := &endpointPool{cooldown: cooldown}
+	seen := make(map[string]bool)
+	for _, u := range urls {
+		if u = strings.TrimSuffix(u, "/"); !seen[u] {
+			seen[u] = true
+			p.list = append(p.list, &endpoint{url: u})
+		}
+	}
+	return p
+}
+
+// order returns the base URLs in the order to try them: the healthy ones by
+// latency, the endpoints not measured yet first, then the failed ones by
+// the end of their cooldown
+func (p *endpointPool) order() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	list := append([]*endpoint(nil), p.list...)
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		aDown, bDown := now.Before(a.down), now.Before(b.down)
+		switch {
+		case aDown != bDown:
+			return bDown
+		case aDown:
+			return a.down.Before(b.down)
+		}
+		return a.latency < b.latency
+	})
+	urls := make([]string, len(list))
+	for i, e := range list {
+		urls[i] = e.url
+	}
+	return urls
+}
+
+// record updates the health of the endpoint with the outcome of a request
+func (p *endpointPool) record(url string, elapsed time.Duration, failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range p.list {
+		if e.url != url {
+			continue
+		}
+		if failed {
+			e.failures++
+			e.down = time.Now().Add(p.cooldown)
+			return
+		}
+		e.failures, e.down = 0, time.Time{}
+		if e.latency == 0 {
+			e.latency = elapsed
+		} else {
+			e.latency = (7*e.latency + 3*elapsed) / 10
+		}
+		return
+	}
+}
+
+func (p *endpointPool) status() []EndpointStatus {
+	urls := p.order()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	res := make([]EndpointStatus, 0, len(urls))
+	for _, u := range urls {
+		for _, e := range p.list {
+			if e.url == u {
+				res = append(res, EndpointStatus{Url: e.url, Healthy: !now.Before(e.down), Latency: e.latency, Failures: e.failures})
+			}
+		}
+	}
+	return res
+}
+
+// endpointFailed returns true if the outcome of a request means the endpoint is not serving
+func endpointFailed(statusCode int, err error) bool {
+	switch statusCode {
+	case 0:
+		return err != nil
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// bases returns the base URLs to send a request to, in order
+func (c *Client) bases() []string {
+	if c.endpoints == nil {
+		return []string{c.url}
+	}
+	return c.endpoints.order()
+}
+
+// Endpoints returns the health of the API endpoints, in the order the requests try them
+func (c *Client) Endpoints() []EndpointStatus {
+	if c.endpoints == nil {
+		return []EndpointStatus{{Url: c.url, Healthy: true}}
+	}
+	return c.endpoints.status()
+}
+
+// CheckEndpoints probes each API endpoint with a request listing the bots,
+// measuring its latency and restoring the recovered endpoints
+func (c *Client) CheckEndpoints() []EndpointStatus {
+	if c.endpoints == nil {
+		return c.Endpoints()
+	}
+	path := strings.TrimPrefix(c.appUrl(bot), c.url)
+	for _, base := range c.bases() {
+		start := time.Now()
+		statusCode, err := c.doOnce("GET", base+path, nil, nil, nil)
+		c.endpoints.record(base, time.Since(start), endpointFailed(statusCode, err))
+	}
+	return c.endpoints.status()
+}
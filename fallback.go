@@ -0,0 +1,71 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// DefaultFallbackReply is the canned response of a Fallback
+const DefaultFallbackReply = "I'm having trouble right now, please try again in a few minutes."
+
+// Unavailable reports whether the error means the bot cannot answer for now,
+// on network errors, timeouts, rate limiting, server errors and exhausted
+// latency budgets, rather than because the request is wrong
+func Unavailable(err error) bool {
+	var apiErr *APIError
+	var netErr net.Error
+	var decodeErr *DecodeError
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &apiErr):
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	case errors.As(err, &netErr), errors.As(err, &decodeErr):
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCallDeadline) || errors.Is(err, ErrBudgetExhausted)
+}
+
+// Fallback answers the users of a Conversation with a canned reply when the
+// bot is unavailable, so they do not face silence when the API fails
+type Fallback struct {
+	Reply string // The canned response. Defaults to DefaultFallbackReply.
+	// Errors reports whether the error is answered with the reply, the other
+	// errors are returned. Defaults to Unavailable.
+	Errors func(err error) bool
+	// OnError receives the errors answered with the reply, e.g. to log them
+	OnError func(err error)
+}
+
+// NewFallback creates a fallback answering with the reply when the bot is unavailable
+func NewFallback(reply string) *Fallback {
+	return &Fallback{Reply: reply}
+}
+
+// Answer returns the fallback reply to the error of a talk request, if it applies
+func (f *Fallback) Answer(err error) (*Reply, bool) {
+	if f == nil || err == nil {
+		return nil, false
+	}
+	errs := f.Errors
+	if errs == nil {
+		errs = Unavailable
+	}
+	if !errs(err) {
+		return nil, false
+	}
+	if f.OnError != nil {
+		f.OnError(err)
+	}
+	reply := f.Reply
+	if reply == "" {
+		reply = DefaultFallbackReply
+	}
+	return &Reply{Responses: []string{reply}, Info: ReplyInfo{Fallback: true}}, true
+}
@@ -0,0 +1,82 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// ReplyFilter transforms the responses of a reply. Filters may change, split
+// or drop responses.
+type ReplyFilter func(responses []string) []string
+
+// SetReplyFilters sets the filters applied in order to the responses of every talk reply
+func SetReplyFilters(filters ...ReplyFilter) OptionFunc {
+	return func(c *Client) error {
+		c.filters = filters
+		return nil
+	}
+}
+
+// FilterResponses applies the filters in order to the responses
+func FilterResponses(responses []string, filters ...ReplyFilter) []string {
+	for _, f := range filters {
+		responses = f(responses)
+	}
+	return responses
+}
+
+// mapResponses applies fn to each response, dropping the ones left empty
+func mapResponses(responses []string, fn func(string) string) []string {
+	res := make([]string, 0, len(responses))
+	for _, r := range responses {
+		if r = fn(r); r != "" {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+var (
+	tagRe   = regexp.MustCompile(`</?[a-zA-Z][^<>]*>`)
+	breakRe = regexp.MustCompile(`(?i)<br\s*/?>`)
+)
+
+// StripHTML removes HTML tags from the responses, keeping their text
+func StripHTML(responses []string) []string {
+	return mapResponses(responses, func(r string) string {
+		return strings.TrimSpace(tagRe.ReplaceAllString(breakRe.ReplaceAllString(r, " "), ""))
+	})
+}
+
+// DecodeEntities replaces named and numeric HTML entities like &amp; and &#39; with the characters
+func DecodeEntities(responses []string) []string {
+	return mapResponses(responses, html.UnescapeString)
+}
+
+// CollapseWhitespace trims the responses and replaces runs of whitespace with single spaces
+func CollapseWhitespace(responses []string) []string {
+	return mapResponses(responses, func(r string) string {
+		return strings.Join(strings.Fields(r), " ")
+	})
+}
+
+// SplitBreaks splits the responses on <br/> tags into separate messages
+func SplitBreaks(responses []string) []string {
+	var res []string
+	for _, r := range responses {
+		for _, part := range breakRe.Split(r, -1) {
+			if part = strings.TrimSpace(part); part != "" {
+				res = append(res, part)
+			}
+		}
+	}
+	if res == nil {
+		res = []string{}
+	}
+	return res
+}
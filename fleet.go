@@ -0,0 +1,99 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+)
+
+// FleetResult is the outcome of a fleet operation for a bot
+type FleetResult struct {
+	Bot      string
+	Uploaded bool  // Whether the file was uploaded, for BroadcastUpload
+	Compiled bool  // Whether the bot verified
+	Err      error // Why the bot failed, a *CompileError when it does not compile
+}
+
+// CompileMessages returns the compile messages of the bot, if it does not compile
+func (r FleetResult) CompileMessages() []CompileMessage {
+	var ce *CompileError
+	if errors.As(r.Err, &ce) {
+		return ce.Messages
+	}
+	return nil
+}
+
+// fleet runs fn for each bot in parallel, like ForEach, and returns the
+// results in the order of the bots
+func (c *Client) fleet(ctx context.Context, bots []string, fn func(ctx context.Context, r *FleetResult) error) ([]FleetResult, error) {
+	results := make([]FleetResult, len(bots))
+	byName := make(map[string]*FleetResult, len(bots))
+	names := make([]string, 0, len(bots))
+	for i, b := range bots {
+		results[i].Bot = b
+		if _, ok := byName[b]; !ok {
+			byName[b] = &results[i]
+			names = append(names, b)
+		}
+	}
+	err := ForEach(ctx, names, c.parallelism, func(ctx context.Context, name string) error {
+		return fn(ctx, byName[name])
+	})
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		// The bots not started because the context is done have no result yet
+		for name, e := range batchErr.Errors {
+			if r := byName[name]; r.Err == nil {
+				r.Err = e
+			}
+		}
+	}
+	for i := range results {
+		results[i] = *byName[results[i].Bot]
+	}
+	return results, err
+}
+
+// BroadcastUpload uploads the same file, e.g. a shared set, map or substitution,
+// to each of the bots in parallel, with the parallelism of SetParallelism, and
+// verifies the bots it was uploaded to. The results are in the order of the
+// bots, and the bots failing to upload or compile are also returned in a
+// *BatchError.
+func (c *Client) BroadcastUpload(botNames []string, filename string, data []byte) ([]FleetResult, error) {
+	if _, err := c.fileToUrl("", filename); err != nil {
+		return nil, err
+	}
+	return c.fleet(context.Background(), botNames, func(ctx context.Context, r *FleetResult) error {
+		if r.Err = c.UploadFile(r.Bot, filename, bytes.NewReader(data)); r.Err != nil {
+			return r.Err
+		}
+		r.Uploaded = true
+		r.Err = c.Verify(r.Bot)
+		r.Compiled = r.Err == nil
+		return r.Err
+	})
+}
+
+// VerifyAll verifies all the bots of the application in parallel, with the
+// parallelism of SetParallelism, reporting which bots compile. The results are
+// in the order of List, and the bots failing to verify are also returned in a
+// *BatchError.
+func (c *Client) VerifyAll(ctx context.Context) ([]FleetResult, error) {
+	bots, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(bots))
+	for i, b := range bots {
+		names[i] = b.Name
+	}
+	return c.fleet(ctx, names, func(ctx context.Context, r *FleetResult) error {
+		r.Err = c.Verify(r.Bot)
+		r.Compiled = r.Err == nil
+		return r.Err
+	})
+}
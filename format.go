@@ -0,0 +1,127 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeFormat is the layout used when printing timestamps
+const timeFormat = "2006-01-02 15:04:05"
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(timeFormat)
+}
+
+func (e BotEntry) String() string {
+	var flags []string
+	if e.Language != "" {
+		flags = append(flags, e.Language.Name())
+	}
+	if e.Compiled {
+		flags = append(flags, "compiled")
+	} else {
+		flags = append(flags, "not compiled")
+	}
+	if e.Open {
+		flags = append(flags, "open")
+	}
+	s := fmt.Sprintf("%s (%s)", e.Name, strings.Join(flags, ", "))
+	if e.Description != "" {
+		s += " - " + e.Description
+	}
+	return s
+}
+
+func (f BotFile) String() string {
+	return fmt.Sprintf("%s (%d bytes, %d items, modified %s)", f.Name, f.Size, f.Items, formatTime(f.Modified.Time))
+}
+
+func (f BotFiles) String() string {
+	count := len(f.Files) + len(f.Sets) + len(f.Maps) + len(f.Substitutions) + len(f.Properties) + len(f.Pdefaults)
+	return fmt.Sprintf("%s (%d files)", f.Botname, count)
+}
+
+func (r Reply) String() string {
+	lines := r.Responses
+	for _, c := range r.Commands {
+		lines = append(lines[:len(lines):len(lines)], "[oob] "+c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Format returns a multi-line human readable representation of the API types
+// BotEntry, []BotEntry, BotFile, BotFiles and Reply (or pointers to them).
+// Other values are formatted using their default format.
+func Format(v interface{}) string {
+	var buf bytes.Buffer
+	switch t := v.(type) {
+	case *BotEntry:
+		return Format(*t)
+	case *BotFiles:
+		return Format(*t)
+	case *BotFile:
+		return Format(*t)
+	case *Reply:
+		return Format(*t)
+	case BotEntry:
+		fmt.Fprintf(&buf, "Name:        %s\n", t.Name)
+		fmt.Fprintf(&buf, "Description: %s\n", t.Description)
+		fmt.Fprintf(&buf, "Language:    %s\n", t.Language.Name())
+		fmt.Fprintf(&buf, "Compiled:    %v\n", t.Compiled)
+		fmt.Fprintf(&buf, "Open:        %v\n", t.Open)
+		fmt.Fprintf(&buf, "Created:     %s\n", formatTime(t.Created.Time))
+		fmt.Fprintf(&buf, "Modified:    %s\n", formatTime(t.Modified.Time))
+	case []BotEntry:
+		for _, e := range t {
+			fmt.Fprintln(&buf, e)
+		}
+	case BotFile:
+		fmt.Fprintln(&buf, t)
+	case BotFiles:
+		fmt.Fprintf(&buf, "Bot:         %s\n", t.Botname)
+		fmt.Fprintf(&buf, "Description: %s\n", t.Description)
+		fmt.Fprintf(&buf, "Language:    %s\n", t.Language.Name())
+		fmt.Fprintf(&buf, "Created:     %s\n", formatTime(t.Created.Time))
+		fmt.Fprintf(&buf, "Open:        %v\n", t.Open)
+		sections := []struct {
+			title string
+			files []BotFile
+		}{
+			{"Files", t.Files},
+			{"Sets", t.Sets},
+			{"Maps", t.Maps},
+			{"Substitutions", t.Substitutions},
+			{"Properties", t.Properties},
+			{"Pdefaults", t.Pdefaults},
+		}
+		for _, s := range sections {
+			if len(s.files) == 0 {
+				continue
+			}
+			fmt.Fprintf(&buf, "%s:\n", s.title)
+			for _, f := range s.files {
+				fmt.Fprintf(&buf, "  %s\n", f)
+			}
+		}
+	case Reply:
+		fmt.Fprintf(&buf, "Session: %d\n", t.SessionId)
+		for _, r := range t.Responses {
+			fmt.Fprintf(&buf, "  %s\n", r)
+		}
+		for _, c := range t.Commands {
+			fmt.Fprintf(&buf, "  [oob] %s\n", c)
+		}
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+	return buf.String()
+}
@@ -0,0 +1,182 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/demisto/pb-go/aiml"
+)
+
+// GitMetadataFile is the file of an exported bot describing the bot and its files
+const GitMetadataFile = "bot.json"
+
+// gitDirs are the directories of the exported files by extension, the others are at the root
+var gitDirs = map[string]string{
+	".aiml":         "aiml",
+	".set":          "sets",
+	".map":          "maps",
+	".substitution": "substitutions",
+}
+
+// GitFile describes an exported file in the metadata
+type GitFile struct {
+	Name     string    `json:"name"`
+	Path     string    `json:"path"` // Relative to the export directory, with forward slashes
+	Size     int64     `json:"size"` // The size of the remote file, before formatting
+	Modified time.Time `json:"modified"`
+}
+
+// GitMetadata is the content of the metadata file of an exported bot
+type GitMetadata struct {
+	Bot         string    `json:"bot"`
+	Description string    `json:"description,omitempty"`
+	Language    Language  `json:"language,omitempty"`
+	Files       []GitFile `json:"files"`
+}
+
+// GitExportOptions controls ExportForGit
+type GitExportOptions struct {
+	// Commit is called with the export directory and the changed paths when
+	// the export changed files, e.g. GitCommit to commit them
+	Commit func(dir string, changed []string) error
+}
+
+// GitExport is the result of ExportForGit
+type GitExport struct {
+	Metadata GitMetadata
+	Changed  []string // The paths written or deleted because their content changed, relative to the export directory
+}
+
+// GitCommit returns a commit hook for ExportForGit committing the changed
+// paths with the message, using the git command of the system. The export
+// directory must be in a git work tree.
+func GitCommit(message string) func(dir string, changed []string) error {
+	return func(dir string, changed []string) error {
+		run := func(args ...string) error {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = dir
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				return fmt.Errorf("Command failed [git %s] - %v: %s", args[0], err, bytes.TrimSpace(out))
+			}
+			return nil
+		}
+		if err := run(append([]string{"add", "-A", "--"}, changed...)...); err != nil {
+			return err
+		}
+		return run(append([]string{"commit", "-m", message, "--"}, changed...)...)
+	}
+}
+
+// gitNormalize returns the content of the file in a stable layout, so the
+// exports only differ where the bot changed: the AIML files formatted by
+// aiml.Format and the JSON lists with an entry per line. The content which
+// does not parse is kept as is.
+func gitNormalize(file string, data []byte) []byte {
+	if filepath.Ext(file) == ".aiml" {
+		if formatted, err := aiml.Format(data); err == nil {
+			return formatted
+		}
+		return data
+	}
+	var entries [][]string
+	if json.Unmarshal(data, &entries) != nil {
+		return data
+	}
+	return writeList(entries)
+}
+
+// gitPath returns the path of an exported file relative to the export directory
+func gitPath(file string) string {
+	if dir, ok := gitDirs[filepath.Ext(file)]; ok {
+		return dir + "/" + file
+	}
+	return file
+}
+
+// ExportForGit writes the files of the bot to dir in a deterministic layout
+// suited to version control: the AIML files in aiml, the sets in sets, the
+// maps in maps, the substitutions in substitutions and the properties at the
+// root, all normalized, with GitMetadataFile describing the bot. The bot
+// files previously exported which the bot no longer has are deleted, so
+// exporting again only changes what changed on the bot. The directory can
+// be uploaded back with SyncDir.
+func (c *Client) ExportForGit(bot, dir string, opts GitExportOptions) (*GitExport, error) {
+	list, err := c.ListFiles(bot)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := c.fileContents(bot)
+	if err != nil {
+		return nil, err
+	}
+	res := &GitExport{Metadata: GitMetadata{Bot: bot, Description: list.Description, Language: list.Language, Files: []GitFile{}}}
+	listed := listingFiles(list)
+	write := func(path string, data []byte) error {
+		full := filepath.Join(dir, filepath.FromSlash(path))
+		if old, err := os.ReadFile(full); err == nil && bytes.Equal(old, data) {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(full, data, 0644); err != nil {
+			return err
+		}
+		res.Changed = append(res.Changed, path)
+		return nil
+	}
+	names := make([]string, 0, len(contents))
+	for file := range contents {
+		names = append(names, file)
+	}
+	sort.Strings(names)
+	exported := make(map[string]bool)
+	for _, file := range names {
+		path := gitPath(file)
+		exported[path] = true
+		if err = write(path, gitNormalize(file, contents[file])); err != nil {
+			return nil, err
+		}
+		f := listed[file]
+		res.Metadata.Files = append(res.Metadata.Files, GitFile{Name: file, Path: path, Size: f.Size, Modified: f.Modified.Time})
+	}
+	previous, err := c.localFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for file, full := range previous {
+		path := gitPath(file)
+		if exported[path] || filepath.Join(dir, filepath.FromSlash(path)) != full {
+			continue
+		}
+		if err = os.Remove(full); err != nil {
+			return nil, err
+		}
+		res.Changed = append(res.Changed, path)
+	}
+	metadata, err := json.MarshalIndent(res.Metadata, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err = write(GitMetadataFile, append(metadata, '\n')); err != nil {
+		return nil, err
+	}
+	sort.Strings(res.Changed)
+	if len(res.Changed) > 0 && opts.Commit != nil {
+		if err = opts.Commit(dir, res.Changed); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,232 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHandoffReply is the response sent to the users escalated to a human agent
+const DefaultHandoffReply = "Let me connect you with a person. Someone will be with you shortly."
+
+// The reasons of the escalations
+const (
+	EscalationPhrase     = "phrase"     // The user asked for a human
+	EscalationDefaults   = "defaults"   // The bot had no answer several times in a row
+	EscalationManual     = "manual"     // The application escalated the conversation
+	EscalationClassifier = "classifier" // The Classifier of the Conversation escalated the input
+)
+
+// Escalation describes a conversation handed off to a human agent
+type Escalation struct {
+	Bot        string            `json:"bot"`
+	Key        string            `json:"key"` // The user key of the conversation, to Release it
+	ClientName string            `json:"clientName"`
+	Reason     string            `json:"reason"` // EscalationPhrase, EscalationDefaults, EscalationClassifier or EscalationManual
+	Input      string            `json:"input"`  // The input which triggered the escalation
+	Time       time.Time         `json:"time"`
+	Recent     []TranscriptEntry `json:"recent,omitempty"` // The last turns, if the Conversation has Transcripts
+}
+
+// Handoff hands the conversations over to human agents, e.g. by pinging a
+// Slack channel or creating a ticket
+type Handoff interface {
+	// Escalate notifies the agents of the conversation. The conversation is
+	// only paused when it succeeds.
+	Escalate(e *Escalation) error
+}
+
+// HandoffFunc is a Handoff calling a function
+type HandoffFunc func(e *Escalation) error
+
+func (f HandoffFunc) Escalate(e *Escalation) error {
+	return f(e)
+}
+
+// Escalator escalates the conversations of a Conversation to human agents
+// when the users ask for one or the bot has no answer several times in a
+// row. The escalated conversations are paused, the inputs of the users are
+// not sent to the bot, until they are released.
+type Escalator struct {
+	Handoff Handoff
+	// Phrases escalate the inputs containing one of them as words, ignoring case, like agent or human
+	Phrases []string
+	// MaxDefaults escalates after this many replies with one of the
+	// DefaultResponses in a row, zero to not escalate on the default responses
+	MaxDefaults      int
+	DefaultResponses []string
+	// Reply is sent to the users when they are escalated. Defaults to DefaultHandoffReply.
+	Reply string
+	// PausedReply is sent to the users while they are escalated, empty to send no response
+	PausedReply string
+	// ReleaseAfter resumes the bot for the conversations escalated this long ago, zero to wait for Release
+	ReleaseAfter time.Duration
+
+	once     sync.Once
+	phrases  []*regexp.Regexp
+	mu       sync.Mutex
+	defaults map[string]int         // The default responses in a row by user key
+	paused   map[string]*Escalation // The escalated conversations by user key
+}
+
+// NewEscalator creates an escalator of the inputs containing the phrases to the handoff
+func NewEscalator(h Handoff, phrases ...string) *Escalator {
+	return &Escalator{Handoff: h, Phrases: phrases}
+}
+
+func (x *Escalator) init() {
+	x.once.Do(func() {
+		for _, p := range x.Phrases {
+			x.phrases = append(x.phrases, wordPattern(p))
+		}
+		x.defaults = make(map[string]int)
+		x.paused = make(map[string]*Escalation)
+	})
+}
+
+// Paused returns the escalation of the user key, nil if the bot is answering it
+func (x *Escalator) Paused(key string) *Escalation {
+	x.init()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	e := x.paused[key]
+	if e != nil && x.ReleaseAfter > 0 && time.Since(e.Time) > x.ReleaseAfter {
+		delete(x.paused, key)
+		return nil
+	}
+	return e
+}
+
+// Escalations returns the paused conversations
+func (x *Escalator) Escalations() []Escalation {
+	x.init()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	res := make([]Escalation, 0, len(x.paused))
+	for _, e := range x.paused {
+		res = append(res, *e)
+	}
+	return res
+}
+
+// Release resumes the bot for the user key, e.g. when the agent is done
+func (x *Escalator) Release(key string) {
+	x.init()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	delete(x.paused, key)
+	delete(x.defaults, key)
+}
+
+// Escalate hands the conversation over to the agents and pauses it if the handoff succeeds
+func (x *Escalator) Escalate(e *Escalation) error {
+	x.init()
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if err := x.Handoff.Escalate(e); err != nil {
+		return err
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.paused[e.Key] = e
+	delete(x.defaults, e.Key)
+	return nil
+}
+
+// asked reports whether the input asks for a human
+func (x *Escalator) asked(input string) bool {
+	x.init()
+	for _, re := range x.phrases {
+		if re.MatchString(input) {
+			return true
+		}
+	}
+	return false
+}
+
+// answered counts the default replies of the user key in a row, reporting whether the conversation must be escalated
+func (x *Escalator) answered(key string, reply *Reply) bool {
+	if x.MaxDefaults <= 0 {
+		return false
+	}
+	x.init()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !isDefaultReply(reply, x.DefaultResponses) {
+		delete(x.defaults, key)
+		return false
+	}
+	x.defaults[key]++
+	return x.defaults[key] >= x.MaxDefaults
+}
+
+func (x *Escalator) reply() string {
+	if x.Reply == "" {
+		return DefaultHandoffReply
+	}
+	return x.Reply
+}
+
+// escalate escalates the conversation of the session, returning the response for the user, empty if the handoff failed
+func (cv *Conversation) escalate(key, reason, input string, s *Session) string {
+	e := &Escalation{Bot: cv.botOf(s), Key: key, ClientName: cv.Client.Anonymized(s.ClientName), Reason: reason, Input: input}
+	if cv.Transcripts != nil {
+		recent, err := cv.Transcripts.Query(TranscriptQuery{Bot: e.Bot, ClientName: s.ClientName, Limit: 10})
+		if err == nil {
+			e.Recent = recent
+		}
+	}
+	if err := cv.Escalator.Escalate(e); err != nil {
+		cv.Client.errorf("Unable to escalate the conversation of [%s] - %v\n", s.ClientName, err)
+		return ""
+	}
+	return cv.Escalator.reply()
+}
+
+// SlackHandoff is a Handoff posting the escalations to a Slack channel with an incoming webhook
+type SlackHandoff struct {
+	WebhookUrl string
+	// HTTPClient posts the messages. Defaults to a client with a 10 seconds timeout.
+	HTTPClient *http.Client
+}
+
+// NewSlackHandoff creates a handoff posting to the Slack incoming webhook
+func NewSlackHandoff(webhookUrl string) *SlackHandoff {
+	return &SlackHandoff{WebhookUrl: webhookUrl}
+}
+
+func (h *SlackHandoff) Escalate(e *Escalation) error {
+	var text strings.Builder
+	fmt.Fprintf(&text, "*%s* needs a human agent (%s) on bot %s, user key `%s`:\n", e.ClientName, e.Reason, e.Bot, e.Key)
+	for _, t := range e.Recent {
+		fmt.Fprintf(&text, "> %s\n%s\n", t.Input, strings.Join(t.Responses, " "))
+	}
+	fmt.Fprintf(&text, "> %s", e.Input)
+	body, err := json.Marshal(map[string]string{"text": text.String()})
+	if err != nil {
+		return err
+	}
+	client := h.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(h.WebhookUrl, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Slack webhook failed - %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"errors"
+	"time"
+)
+
+// SetHedging sends a second talk request when the first has not been answered
+// after the delay, and returns the first reply, trading API quota for the tail
+// latency of interactive chats. Zero, the default, disables hedging.
+//
+// The bot processes the input of both requests, so avoid hedging bots whose
+// answers depend on how many times they got an input. The slower request is
+// not cancelled, its reply is discarded.
+func SetHedging(after time.Duration) OptionFunc {
+	return func(c *Client) error {
+		if after < 0 {
+			return errors.New("Hedging delay cannot be negative")
+		}
+		c.hedgeAfter = after
+		return nil
+	}
+}
+
+// hedge sends the request, and a second one if the first is slower than the
+// hedging delay, returning the first success and whether it hedged. A request
+// failing before the delay is not hedged, the retries cover it.
+func (c *Client) hedge(send func() (Reply, error)) (Reply, bool, error) {
+	type result struct {
+		reply Reply
+		err   error
+	}
+	results := make(chan result, 2)
+	run := func() {
+		reply, err := send()
+		results <- result{reply, err}
+	}
+	go run()
+	timer := time.NewTimer(c.hedgeAfter)
+	defer timer.Stop()
+	pending, hedged := 1, false
+	for {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil || pending == 0 {
+				return r.reply, hedged, r.err
+			}
+		case <-timer.C:
+			if !hedged {
+				hedged = true
+				pending++
+				c.tracef("Hedging the talk request after %v\n", c.hedgeAfter)
+				go run()
+			}
+		}
+	}
+}
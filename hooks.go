@@ -0,0 +1,77 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// RequestInfo describes a single API call and is passed to the request hooks
+type RequestInfo struct {
+	Method     string        // The HTTP method
+	Endpoint   string        // The resource called, e.g. "bot/APP_ID/BOTNAME/verify"
+	Bot        string        // The bot name if the call targets a bot
+	Attempt    int           // The attempt number, starting from 1
+	Elapsed    time.Duration // Time the call took. Only set on request end
+	StatusCode int           // The HTTP status code if a response was received. Only set on request end
+	Err        error         // The error the call ended with. Only set on request end
+}
+
+// ReplyInfo describes how a talk reply was obtained, for the accounting of the
+// service levels of chat services
+type ReplyInfo struct {
+	Attempts  int           // The HTTP requests sent, including the retries, failovers, hedged requests and chunks
+	Latency   time.Duration // The time the reply took
+	Cached    bool          // The reply is from the reply cache
+	Hedged    bool          // A hedged request was sent
+	Fallback  bool          // The bot was unavailable and the reply is the fallback of the Conversation
+	Throttled bool          // The input was throttled by the limiter of the Conversation
+	Selected  bool          // The reply was replaced by the ReplySelector of the Conversation
+	HandedOff bool          // The conversation was escalated to a human agent by the Escalator of the Conversation
+	Moderated bool          // The input was rejected by a Moderate middleware
+	Tags      []string      // The tags of the input by the Classifier of the Conversation
+	Language  string        // The language of the user the responses were translated into by a Translation
+	Skipped   []string      // The calls of a FallbackChain skipped for lack of latency budget
+}
+
+// count adds a sent request to the counter, if any
+func count(sent *int64) {
+	if sent != nil {
+		atomic.AddInt64(sent, 1)
+	}
+}
+
+// RequestHook is a callback invoked around API calls
+type RequestHook func(info RequestInfo)
+
+// SetOnRequestStart sets a hook that is called before every API call
+func SetOnRequestStart(hook RequestHook) OptionFunc {
+	return func(c *Client) error {
+		c.onStart = hook
+		return nil
+	}
+}
+
+// SetOnRequestEnd sets a hook that is called after every API call with the
+// elapsed time and the outcome of the call
+func SetOnRequestEnd(hook RequestHook) OptionFunc {
+	return func(c *Client) error {
+		c.onEnd = hook
+		return nil
+	}
+}
+
+// requestInfo builds the request description from the URL of the call
+func (c *Client) requestInfo(method, rawurl string) RequestInfo {
+	endpoint := strings.TrimPrefix(strings.TrimPrefix(rawurl, c.url), "/")
+	info := RequestInfo{Method: method, Endpoint: endpoint}
+	// Resources look like action/APP_ID/BOTNAME/...
+	if parts := strings.Split(endpoint, "/"); len(parts) > 2 {
+		info.Bot = parts[2]
+	}
+	return info
+}
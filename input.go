@@ -0,0 +1,264 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// InputFilter transforms a user input before it is sent to the bot. Raw input
+// from chat channels, with its emoji, decomposed accents and stray whitespace,
+// frequently misses the AIML patterns.
+type InputFilter func(input string) string
+
+// SetInputFilters sets the filters applied in order to the input of every talk request
+func SetInputFilters(filters ...InputFilter) OptionFunc {
+	return func(c *Client) error {
+		c.inputFilters = filters
+		return nil
+	}
+}
+
+// FilterInput applies the filters in order to the input. When the filters
+// leave nothing, like for a message of emoji alone, the input is kept as is.
+func FilterInput(input string, filters ...InputFilter) string {
+	res := input
+	for _, f := range filters {
+		res = f(res)
+	}
+	if strings.TrimSpace(res) == "" {
+		return input
+	}
+	return res
+}
+
+// SetMaxInputLength splits the inputs longer than maxLength characters at the
+// sentence boundaries and sends the chunks in order within the session, in as
+// many talk requests, instead of letting pandorabots truncate the input. The
+// responses to the chunks are merged into one reply. The chunks only share
+// the bot memory when a client name is given. Zero, the default, sends the
+// inputs whole.
+func SetMaxInputLength(maxLength int) OptionFunc {
+	return func(c *Client) error {
+		if maxLength < 0 {
+			return errors.New("Max input length cannot be negative")
+		}
+		c.maxInput = maxLength
+		return nil
+	}
+}
+
+// NormalizeSpace trims the input and replaces runs of whitespace with single spaces
+func NormalizeSpace(input string) string {
+	return strings.Join(strings.Fields(input), " ")
+}
+
+// isEmoji reports whether the rune is part of an emoji, including the
+// modifiers, variation selectors and joiners of the emoji sequences
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF, r >= 0x2600 && r <= 0x27BF, r >= 0x2300 && r <= 0x23FF,
+		r >= 0x2B00 && r <= 0x2BFF, r >= 0xE0020 && r <= 0xE007F:
+		return true
+	case r == 0x200D, r == 0x20E3, r == 0xFE0E, r == 0xFE0F:
+		return true
+	}
+	return false
+}
+
+// StripEmoji removes the emoji from the input
+func StripEmoji(input string) string {
+	return NormalizeSpace(strings.Map(func(r rune) rune {
+		if isEmoji(r) {
+			return ' '
+		}
+		return r
+	}, input))
+}
+
+// EmojiNames are the words the common emoji are replaced with by NameEmoji.
+// Add to it to name more emoji.
+var EmojiNames = map[rune]string{
+	'😀': "grinning", '😁': "grinning", '😃': "smiling", '😄': "smiling", '😊': "smiling", '🙂': "smiling",
+	'😂': "laughing", '🤣': "laughing", '😆': "laughing", '😅': "laughing", '😉': "winking", '😎': "cool",
+	'😍': "love", '🥰': "love", '😘': "kiss", '❤': "heart", '💔': "broken heart", '😢': "crying",
+	'😭': "crying", '🙁': "sad", '☹': "sad", '😞': "sad", '😔': "sad", '😠': "angry", '😡': "angry",
+	'😮': "surprised", '😲': "surprised", '😱': "scared", '🤔': "thinking", '😐': "neutral", '😕': "confused",
+	'🙄': "eye roll", '😴': "sleeping", '🤷': "shrug", '👍': "thumbs up", '👎': "thumbs down", '👋': "hello",
+	'🙏': "thank you", '👏': "applause", '👌': "ok", '🔥': "fire", '🎉': "congratulations", '⭐': "star",
+	'✅': "yes", '❌': "no", '❓': "question", '💯': "hundred",
+}
+
+// NameEmoji replaces the emoji of EmojiNames with their names and removes the others
+func NameEmoji(input string) string {
+	var b strings.Builder
+	for _, r := range input {
+		if !isEmoji(r) {
+			b.WriteRune(r)
+		} else if name, ok := EmojiNames[r]; ok {
+			b.WriteString(" " + name + " ")
+		}
+	}
+	return NormalizeSpace(b.String())
+}
+
+// composeTable maps a letter and a combining mark to the precomposed letter.
+// It is generated from the canonical decompositions of the Latin letters.
+var composeTable = map[[2]rune]rune{}
+
+func init() {
+	runes := []rune(composeTriples)
+	for i := 0; i+2 < len(runes); i += 3 {
+		composeTable[[2]rune{runes[i], runes[i+1]}] = runes[i+2]
+	}
+}
+
+// combiningClass returns the canonical combining class of the marks of composeTable,
+// ordering the marks below the letters before the marks above
+func combiningClass(r rune) int {
+	switch {
+	case r == 0x315, r == 0x31A:
+		return 232
+	case r == 0x31B:
+		return 216
+	case r == 0x321, r == 0x322, r == 0x327, r == 0x328:
+		return 202
+	case r >= 0x316 && r <= 0x333:
+		return 220
+	}
+	return 230
+}
+
+// NormalizeNFC composes the letters followed by combining accents into the
+// precomposed letters, as keyboards and some platforms send them decomposed
+// and the patterns would not match. It covers the Latin scripts, including
+// Vietnamese, rather than the whole of the Unicode normalization form C.
+func NormalizeNFC(input string) string {
+	isMark := func(r rune) bool { return unicode.Is(unicode.Mn, r) }
+	if !strings.ContainsFunc(input, isMark) {
+		return input
+	}
+	runes := []rune(input)
+	// Put the marks of each letter in the canonical order first
+	for i := 1; i < len(runes); i++ {
+		for j := i; j > 0 && isMark(runes[j-1]) && isMark(runes[j]) && combiningClass(runes[j-1]) > combiningClass(runes[j]); j-- {
+			runes[j-1], runes[j] = runes[j], runes[j-1]
+		}
+	}
+	res := runes[:0]
+	base := -1 // The index in res of the letter the following marks compose with
+	for _, r := range runes {
+		if !isMark(r) {
+			base = len(res)
+		} else if base >= 0 {
+			if c, ok := composeTable[[2]rune{res[base], r}]; ok {
+				res[base] = c
+				continue
+			}
+			// The marks after a mark left apart are not composed
+			base = -1
+		}
+		res = append(res, r)
+	}
+	return string(res)
+}
+
+// MaskWords returns an InputFilter masking the words of the deny list, e.g.
+// profanity, with asterisks. The words are matched whole and ignoring case.
+func MaskWords(words []string) InputFilter {
+	return (&Scrubber{Words: words}).Scrub
+}
+
+// composeTriples lists the letters, combining marks and precomposed letters of composeTable
+const composeTriples = "" +
+	"A\u0300\u00c0A\u0301\u00c1A\u0302\u00c2A\u0303\u00c3A\u0308\u00c4A\u030a\u00c5" +
+	"C\u0327\u00c7E\u0300\u00c8E\u0301\u00c9E\u0302\u00caE\u0308\u00cbI\u0300\u00cc" +
+	"I\u0301\u00cdI\u0302\u00ceI\u0308\u00cfN\u0303\u00d1O\u0300\u00d2O\u0301\u00d3" +
+	"O\u0302\u00d4O\u0303\u00d5O\u0308\u00d6U\u0300\u00d9U\u0301\u00daU\u0302\u00db" +
+	"U\u0308\u00dcY\u0301\u00dda\u0300\u00e0a\u0301\u00e1a\u0302\u00e2a\u0303\u00e3" +
+	"a\u0308\u00e4a\u030a\u00e5c\u0327\u00e7e\u0300\u00e8e\u0301\u00e9e\u0302\u00ea" +
+	"e\u0308\u00ebi\u0300\u00eci\u0301\u00edi\u0302\u00eei\u0308\u00efn\u0303\u00f1" +
+	"o\u0300\u00f2o\u0301\u00f3o\u0302\u00f4o\u0303\u00f5o\u0308\u00f6u\u0300\u00f9" +
+	"u\u0301\u00fau\u0302\u00fbu\u0308\u00fcy\u0301\u00fdy\u0308\u00ffA\u0304\u0100" +
+	"a\u0304\u0101A\u0306\u0102a\u0306\u0103A\u0328\u0104a\u0328\u0105C\u0301\u0106" +
+	"c\u0301\u0107C\u0302\u0108c\u0302\u0109C\u0307\u010ac\u0307\u010bC\u030c\u010c" +
+	"c\u030c\u010dD\u030c\u010ed\u030c\u010fE\u0304\u0112e\u0304\u0113E\u0306\u0114" +
+	"e\u0306\u0115E\u0307\u0116e\u0307\u0117E\u0328\u0118e\u0328\u0119E\u030c\u011a" +
+	"e\u030c\u011bG\u0302\u011cg\u0302\u011dG\u0306\u011eg\u0306\u011fG\u0307\u0120" +
+	"g\u0307\u0121G\u0327\u0122g\u0327\u0123H\u0302\u0124h\u0302\u0125I\u0303\u0128" +
+	"i\u0303\u0129I\u0304\u012ai\u0304\u012bI\u0306\u012ci\u0306\u012dI\u0328\u012e" +
+	"i\u0328\u012fI\u0307\u0130J\u0302\u0134j\u0302\u0135K\u0327\u0136k\u0327\u0137" +
+	"L\u0301\u0139l\u0301\u013aL\u0327\u013bl\u0327\u013cL\u030c\u013dl\u030c\u013e" +
+	"N\u0301\u0143n\u0301\u0144N\u0327\u0145n\u0327\u0146N\u030c\u0147n\u030c\u0148" +
+	"O\u0304\u014co\u0304\u014dO\u0306\u014eo\u0306\u014fO\u030b\u0150o\u030b\u0151" +
+	"R\u0301\u0154r\u0301\u0155R\u0327\u0156r\u0327\u0157R\u030c\u0158r\u030c\u0159" +
+	"S\u0301\u015as\u0301\u015bS\u0302\u015cs\u0302\u015dS\u0327\u015es\u0327\u015f" +
+	"S\u030c\u0160s\u030c\u0161T\u0327\u0162t\u0327\u0163T\u030c\u0164t\u030c\u0165" +
+	"U\u0303\u0168u\u0303\u0169U\u0304\u016au\u0304\u016bU\u0306\u016cu\u0306\u016d" +
+	"U\u030a\u016eu\u030a\u016fU\u030b\u0170u\u030b\u0171U\u0328\u0172u\u0328\u0173" +
+	"W\u0302\u0174w\u0302\u0175Y\u0302\u0176y\u0302\u0177Y\u0308\u0178Z\u0301\u0179" +
+	"z\u0301\u017aZ\u0307\u017bz\u0307\u017cZ\u030c\u017dz\u030c\u017eO\u031b\u01a0" +
+	"o\u031b\u01a1U\u031b\u01afu\u031b\u01b0A\u030c\u01cda\u030c\u01ceI\u030c\u01cf" +
+	"i\u030c\u01d0O\u030c\u01d1o\u030c\u01d2U\u030c\u01d3u\u030c\u01d4\u00dc\u0304\u01d5" +
+	"\u00fc\u0304\u01d6\u00dc\u0301\u01d7\u00fc\u0301\u01d8\u00dc\u030c\u01d9\u00fc\u030c\u01da" +
+	"\u00dc\u0300\u01db\u00fc\u0300\u01dc\u00c4\u0304\u01de\u00e4\u0304\u01df\u0226\u0304\u01e0" +
+	"\u0227\u0304\u01e1\u00c6\u0304\u01e2\u00e6\u0304\u01e3G\u030c\u01e6g\u030c\u01e7" +
+	"K\u030c\u01e8k\u030c\u01e9O\u0328\u01eao\u0328\u01eb\u01ea\u0304\u01ec\u01eb\u0304\u01ed" +
+	"\u01b7\u030c\u01ee\u0292\u030c\u01efj\u030c\u01f0G\u0301\u01f4g\u0301\u01f5N\u0300\u01f8" +
+	"n\u0300\u01f9\u00c5\u0301\u01fa\u00e5\u0301\u01fb\u00c6\u0301\u01fc\u00e6\u0301\u01fd" +
+	"\u00d8\u0301\u01fe\u00f8\u0301\u01ffA\u030f\u0200a\u030f\u0201A\u0311\u0202a\u0311\u0203" +
+	"E\u030f\u0204e\u030f\u0205E\u0311\u0206e\u0311\u0207I\u030f\u0208i\u030f\u0209" +
+	"I\u0311\u020ai\u0311\u020bO\u030f\u020co\u030f\u020dO\u0311\u020eo\u0311\u020f" +
+	"R\u030f\u0210r\u030f\u0211R\u0311\u0212r\u0311\u0213U\u030f\u0214u\u030f\u0215" +
+	"U\u0311\u0216u\u0311\u0217S\u0326\u0218s\u0326\u0219T\u0326\u021at\u0326\u021b" +
+	"H\u030c\u021eh\u030c\u021fA\u0307\u0226a\u0307\u0227E\u0327\u0228e\u0327\u0229" +
+	"\u00d6\u0304\u022a\u00f6\u0304\u022b\u00d5\u0304\u022c\u00f5\u0304\u022dO\u0307\u022e" +
+	"o\u0307\u022f\u022e\u0304\u0230\u022f\u0304\u0231Y\u0304\u0232y\u0304\u0233A\u0325\u1e00" +
+	"a\u0325\u1e01B\u0307\u1e02b\u0307\u1e03B\u0323\u1e04b\u0323\u1e05B\u0331\u1e06" +
+	"b\u0331\u1e07\u00c7\u0301\u1e08\u00e7\u0301\u1e09D\u0307\u1e0ad\u0307\u1e0bD\u0323\u1e0c" +
+	"d\u0323\u1e0dD\u0331\u1e0ed\u0331\u1e0fD\u0327\u1e10d\u0327\u1e11D\u032d\u1e12" +
+	"d\u032d\u1e13\u0112\u0300\u1e14\u0113\u0300\u1e15\u0112\u0301\u1e16\u0113\u0301\u1e17" +
+	"E\u032d\u1e18e\u032d\u1e19E\u0330\u1e1ae\u0330\u1e1b\u0228\u0306\u1e1c\u0229\u0306\u1e1d" +
+	"F\u0307\u1e1ef\u0307\u1e1fG\u0304\u1e20g\u0304\u1e21H\u0307\u1e22h\u0307\u1e23" +
+	"H\u0323\u1e24h\u0323\u1e25H\u0308\u1e26h\u0308\u1e27H\u0327\u1e28h\u0327\u1e29" +
+	"H\u032e\u1e2ah\u032e\u1e2bI\u0330\u1e2ci\u0330\u1e2d\u00cf\u0301\u1e2e\u00ef\u0301\u1e2f" +
+	"K\u0301\u1e30k\u0301\u1e31K\u0323\u1e32k\u0323\u1e33K\u0331\u1e34k\u0331\u1e35" +
+	"L\u0323\u1e36l\u0323\u1e37\u1e36\u0304\u1e38\u1e37\u0304\u1e39L\u0331\u1e3al\u0331\u1e3b" +
+	"L\u032d\u1e3cl\u032d\u1e3dM\u0301\u1e3em\u0301\u1e3fM\u0307\u1e40m\u0307\u1e41" +
+	"M\u0323\u1e42m\u0323\u1e43N\u0307\u1e44n\u0307\u1e45N\u0323\u1e46n\u0323\u1e47" +
+	"N\u0331\u1e48n\u0331\u1e49N\u032d\u1e4an\u032d\u1e4b\u00d5\u0301\u1e4c\u00f5\u0301\u1e4d" +
+	"\u00d5\u0308\u1e4e\u00f5\u0308\u1e4f\u014c\u0300\u1e50\u014d\u0300\u1e51\u014c\u0301\u1e52" +
+	"\u014d\u0301\u1e53P\u0301\u1e54p\u0301\u1e55P\u0307\u1e56p\u0307\u1e57R\u0307\u1e58" +
+	"r\u0307\u1e59R\u0323\u1e5ar\u0323\u1e5b\u1e5a\u0304\u1e5c\u1e5b\u0304\u1e5dR\u0331\u1e5e" +
+	"r\u0331\u1e5fS\u0307\u1e60s\u0307\u1e61S\u0323\u1e62s\u0323\u1e63\u015a\u0307\u1e64" +
+	"\u015b\u0307\u1e65\u0160\u0307\u1e66\u0161\u0307\u1e67\u1e62\u0307\u1e68\u1e63\u0307\u1e69" +
+	"T\u0307\u1e6at\u0307\u1e6bT\u0323\u1e6ct\u0323\u1e6dT\u0331\u1e6et\u0331\u1e6f" +
+	"T\u032d\u1e70t\u032d\u1e71U\u0324\u1e72u\u0324\u1e73U\u0330\u1e74u\u0330\u1e75" +
+	"U\u032d\u1e76u\u032d\u1e77\u0168\u0301\u1e78\u0169\u0301\u1e79\u016a\u0308\u1e7a" +
+	"\u016b\u0308\u1e7bV\u0303\u1e7cv\u0303\u1e7dV\u0323\u1e7ev\u0323\u1e7fW\u0300\u1e80" +
+	"w\u0300\u1e81W\u0301\u1e82w\u0301\u1e83W\u0308\u1e84w\u0308\u1e85W\u0307\u1e86" +
+	"w\u0307\u1e87W\u0323\u1e88w\u0323\u1e89X\u0307\u1e8ax\u0307\u1e8bX\u0308\u1e8c" +
+	"x\u0308\u1e8dY\u0307\u1e8ey\u0307\u1e8fZ\u0302\u1e90z\u0302\u1e91Z\u0323\u1e92" +
+	"z\u0323\u1e93Z\u0331\u1e94z\u0331\u1e95h\u0331\u1e96t\u0308\u1e97w\u030a\u1e98" +
+	"y\u030a\u1e99\u017f\u0307\u1e9bA\u0323\u1ea0a\u0323\u1ea1A\u0309\u1ea2a\u0309\u1ea3" +
+	"\u00c2\u0301\u1ea4\u00e2\u0301\u1ea5\u00c2\u0300\u1ea6\u00e2\u0300\u1ea7\u00c2\u0309\u1ea8" +
+	"\u00e2\u0309\u1ea9\u00c2\u0303\u1eaa\u00e2\u0303\u1eab\u1ea0\u0302\u1eac\u1ea1\u0302\u1ead" +
+	"\u0102\u0301\u1eae\u0103\u0301\u1eaf\u0102\u0300\u1eb0\u0103\u0300\u1eb1\u0102\u0309\u1eb2" +
+	"\u0103\u0309\u1eb3\u0102\u0303\u1eb4\u0103\u0303\u1eb5\u1ea0\u0306\u1eb6\u1ea1\u0306\u1eb7" +
+	"E\u0323\u1eb8e\u0323\u1eb9E\u0309\u1ebae\u0309\u1ebbE\u0303\u1ebce\u0303\u1ebd" +
+	"\u00ca\u0301\u1ebe\u00ea\u0301\u1ebf\u00ca\u0300\u1ec0\u00ea\u0300\u1ec1\u00ca\u0309\u1ec2" +
+	"\u00ea\u0309\u1ec3\u00ca\u0303\u1ec4\u00ea\u0303\u1ec5\u1eb8\u0302\u1ec6\u1eb9\u0302\u1ec7" +
+	"I\u0309\u1ec8i\u0309\u1ec9I\u0323\u1ecai\u0323\u1ecbO\u0323\u1ecco\u0323\u1ecd" +
+	"O\u0309\u1eceo\u0309\u1ecf\u00d4\u0301\u1ed0\u00f4\u0301\u1ed1\u00d4\u0300\u1ed2" +
+	"\u00f4\u0300\u1ed3\u00d4\u0309\u1ed4\u00f4\u0309\u1ed5\u00d4\u0303\u1ed6\u00f4\u0303\u1ed7" +
+	"\u1ecc\u0302\u1ed8\u1ecd\u0302\u1ed9\u01a0\u0301\u1eda\u01a1\u0301\u1edb\u01a0\u0300\u1edc" +
+	"\u01a1\u0300\u1edd\u01a0\u0309\u1ede\u01a1\u0309\u1edf\u01a0\u0303\u1ee0\u01a1\u0303\u1ee1" +
+	"\u01a0\u0323\u1ee2\u01a1\u0323\u1ee3U\u0323\u1ee4u\u0323\u1ee5U\u0309\u1ee6u\u0309\u1ee7" +
+	"\u01af\u0301\u1ee8\u01b0\u0301\u1ee9\u01af\u0300\u1eea\u01b0\u0300\u1eeb\u01af\u0309\u1eec" +
+	"\u01b0\u0309\u1eed\u01af\u0303\u1eee\u01b0\u0303\u1eef\u01af\u0323\u1ef0\u01b0\u0323\u1ef1" +
+	"Y\u0300\u1ef2y\u0300\u1ef3Y\u0323\u1ef4y\u0323\u1ef5Y\u0309\u1ef6y\u0309\u1ef7" +
+	"Y\u0303\u1ef8y\u0303\u1ef9"
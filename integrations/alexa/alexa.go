@@ -0,0 +1,260 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package alexa backs an Alexa skill with a pandorabots bot. It implements the
+// Alexa Skills Kit request and response JSON as an http.Handler.
+//
+// Each Alexa user gets a pandorabots client name, so the bot remembers the user
+// across sessions, and each Alexa session maps to a new pandorabots session.
+package alexa
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// Request types
+const (
+	LaunchRequest       = "LaunchRequest"
+	IntentRequest       = "IntentRequest"
+	SessionEndedRequest = "SessionEndedRequest"
+)
+
+// Slot is an intent slot value
+type Slot struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Intent is the intent of an IntentRequest
+type Intent struct {
+	Name  string          `json:"name"`
+	Slots map[string]Slot `json:"slots"`
+}
+
+type application struct {
+	ApplicationId string `json:"applicationId"`
+}
+
+type user struct {
+	UserId string `json:"userId"`
+}
+
+// RequestEnvelope is the body of the requests sent by Alexa
+type RequestEnvelope struct {
+	Version string `json:"version"`
+	Session struct {
+		New         bool        `json:"new"`
+		SessionId   string      `json:"sessionId"`
+		Application application `json:"application"`
+		User        user        `json:"user"`
+	} `json:"session"`
+	Context struct {
+		System struct {
+			Application application `json:"application"`
+			User        user        `json:"user"`
+		} `json:"System"`
+	} `json:"context"`
+	Request struct {
+		Type      string `json:"type"`
+		RequestId string `json:"requestId"`
+		Timestamp string `json:"timestamp"`
+		Locale    string `json:"locale"`
+		Intent    Intent `json:"intent"`
+		Reason    string `json:"reason"`
+	} `json:"request"`
+}
+
+// applicationId returns the skill ID the request was sent to
+func (r *RequestEnvelope) applicationId() string {
+	if r.Session.Application.ApplicationId != "" {
+		return r.Session.Application.ApplicationId
+	}
+	return r.Context.System.Application.ApplicationId
+}
+
+// userId returns the ID of the Alexa user
+func (r *RequestEnvelope) userId() string {
+	if r.Session.User.UserId != "" {
+		return r.Session.User.UserId
+	}
+	return r.Context.System.User.UserId
+}
+
+// OutputSpeech is the speech of a response
+type OutputSpeech struct {
+	Type string `json:"type"`
+	SSML string `json:"ssml,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+// ResponseEnvelope is the body of the responses to Alexa
+type ResponseEnvelope struct {
+	Version  string `json:"version"`
+	Response struct {
+		OutputSpeech *OutputSpeech `json:"outputSpeech,omitempty"`
+		Reprompt     *struct {
+			OutputSpeech OutputSpeech `json:"outputSpeech"`
+		} `json:"reprompt,omitempty"`
+		ShouldEndSession bool `json:"shouldEndSession"`
+	} `json:"response"`
+}
+
+// Handler is an http.Handler answering Alexa skill requests with the bot
+type Handler struct {
+	Conversation *pb.Conversation
+	// ApplicationIds are the skill IDs accepted. Empty accepts any skill.
+	ApplicationIds []string
+	// InputSlot is the slot holding the free text of the user, typically of
+	// the AMAZON.SearchQuery type. Defaults to "query". Intents without the slot
+	// are sent to the bot as the intent name followed by the slot values.
+	InputSlot string
+	// LaunchInput is the input sent to the bot when the skill is opened. Defaults to "HELLO".
+	LaunchInput string
+	// StopInput is the input sent to the bot on the stop and cancel intents,
+	// whose reply ends the session. Defaults to "GOODBYE".
+	StopInput string
+	// Reprompt is spoken if the user does not answer. Empty to not reprompt.
+	Reprompt string
+	// Verifier checks the requests were sent by Alexa. Required by Amazon for
+	// published skills; nil skips the verification, which is only meant for testing.
+	Verifier *Verifier
+	// ErrorLog receives the errors of the requests, nil to discard them
+	ErrorLog *log.Logger
+}
+
+// NewHandler creates a handler answering with the bot, verifying the requests
+func NewHandler(c *pb.Client, bot string, store pb.SessionStore) *Handler {
+	cv := pb.NewConversation(c, bot, store)
+	cv.ClientName = pb.HashedClientName("alexa-")
+	return &Handler{Conversation: cv, Verifier: NewVerifier()}
+}
+
+func (h *Handler) errorf(format string, args ...interface{}) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+	}
+}
+
+// Input converts an intent to the bot input
+func (h *Handler) Input(intent Intent) string {
+	slot := h.InputSlot
+	if slot == "" {
+		slot = "query"
+	}
+	if s, ok := intent.Slots[slot]; ok && s.Value != "" {
+		return s.Value
+	}
+	names := make([]string, 0, len(intent.Slots))
+	for name := range intent.Slots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	words := []string{strings.TrimPrefix(intent.Name, "AMAZON.")}
+	for _, name := range names {
+		if v := intent.Slots[name].Value; v != "" {
+			words = append(words, v)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// Handle answers a parsed Alexa request
+func (h *Handler) Handle(req *RequestEnvelope) (*ResponseEnvelope, error) {
+	if len(h.ApplicationIds) > 0 {
+		ok := false
+		for _, id := range h.ApplicationIds {
+			ok = ok || id == req.applicationId()
+		}
+		if !ok {
+			return nil, errors.New("Application ID is not accepted [" + req.applicationId() + "]")
+		}
+	}
+	key := req.userId()
+	resp := &ResponseEnvelope{Version: "1.0"}
+	if req.Session.New || req.Request.Type == LaunchRequest {
+		if err := h.Conversation.Reset(key); err != nil {
+			return nil, err
+		}
+	}
+	var input string
+	switch req.Request.Type {
+	case LaunchRequest:
+		input = orDefault(h.LaunchInput, "HELLO")
+	case IntentRequest:
+		switch req.Request.Intent.Name {
+		case "AMAZON.StopIntent", "AMAZON.CancelIntent":
+			input = orDefault(h.StopInput, "GOODBYE")
+			resp.Response.ShouldEndSession = true
+		default:
+			input = h.Input(req.Request.Intent)
+		}
+	case SessionEndedRequest:
+		// No speech is allowed in the response to a session end
+		return resp, h.Conversation.Reset(key)
+	default:
+		return resp, nil
+	}
+	reply, err := h.Conversation.Talk(key, input)
+	if err != nil {
+		return nil, err
+	}
+	resp.Response.OutputSpeech = &OutputSpeech{Type: "SSML", SSML: pb.ToSSML(reply.Responses)}
+	if resp.Response.ShouldEndSession {
+		h.Conversation.Reset(key)
+	} else if h.Reprompt != "" {
+		resp.Response.Reprompt = &struct {
+			OutputSpeech OutputSpeech `json:"outputSpeech"`
+		}{OutputSpeech{Type: "PlainText", Text: h.Reprompt}}
+	}
+	return resp, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 128*1024))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req := &RequestEnvelope{}
+	if err = json.Unmarshal(body, req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if h.Verifier != nil {
+		if err = h.Verifier.Verify(r, body, req.Request.Timestamp); err != nil {
+			h.errorf("Alexa request verification failed - %v", err)
+			http.Error(w, "Request verification failed", http.StatusBadRequest)
+			return
+		}
+	}
+	resp, err := h.Handle(req)
+	if err != nil {
+		h.errorf("Alexa request failed - %v", err)
+		http.Error(w, "Request failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(resp)
+}
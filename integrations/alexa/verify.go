@@ -0,0 +1,161 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package alexa
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Verifier checks that requests were sent by Alexa, as required for the skills
+// hosted outside of AWS Lambda: the signing certificate chain is downloaded
+// from Amazon, validated and used to check the request signature, and stale
+// requests are rejected to prevent replays.
+type Verifier struct {
+	// Tolerance is the maximum age of a request. Defaults to 150 seconds, the
+	// maximum allowed by Amazon.
+	Tolerance time.Duration
+	// Roots are the trusted root certificates, nil for the system roots
+	Roots *x509.CertPool
+	// HTTPClient downloads the certificate chains. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate // The verified signing certificates by URL
+}
+
+// NewVerifier creates a verifier with the default settings
+func NewVerifier() *Verifier {
+	return &Verifier{}
+}
+
+// signingName is the subject alternative name of the Alexa signing certificate
+const signingName = "echo-api.amazon.com"
+
+// validCertUrl checks the certificate chain URL points to the Amazon location
+func validCertUrl(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("Certificate URL is not valid [%s]", raw)
+	}
+	if !strings.EqualFold(u.Scheme, "https") || !strings.EqualFold(u.Hostname(), "s3.amazonaws.com") ||
+		(u.Port() != "" && u.Port() != "443") || !strings.HasPrefix(path.Clean(u.Path), "/echo.api/") {
+		return fmt.Errorf("Certificate URL is not an Alexa URL [%s]", raw)
+	}
+	return nil
+}
+
+// certificate returns the verified signing certificate at the URL
+func (v *Verifier) certificate(certUrl string) (*x509.Certificate, error) {
+	v.mu.Lock()
+	cert := v.certs[certUrl]
+	v.mu.Unlock()
+	if cert != nil && time.Now().Before(cert.NotAfter) {
+		return cert, nil
+	}
+	if err := validCertUrl(certUrl); err != nil {
+		return nil, err
+	}
+	client := v.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(certUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Certificate download failed with status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return nil, err
+	}
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, c)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("No certificate found at [%s]", certUrl)
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+	// Verify checks the validity period and the subject alternative name
+	_, err = chain[0].Verify(x509.VerifyOptions{
+		DNSName:       signingName,
+		Intermediates: intermediates,
+		Roots:         v.Roots,
+	})
+	if err != nil {
+		return nil, err
+	}
+	v.mu.Lock()
+	if v.certs == nil {
+		v.certs = make(map[string]*x509.Certificate)
+	}
+	v.certs[certUrl] = chain[0]
+	v.mu.Unlock()
+	return chain[0], nil
+}
+
+// Verify checks the request with the body and request timestamp was signed by Alexa
+func (v *Verifier) Verify(r *http.Request, body []byte, timestamp string) error {
+	tolerance := v.Tolerance
+	if tolerance == 0 {
+		tolerance = 150 * time.Second
+	}
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return fmt.Errorf("Request timestamp is not valid [%s]", timestamp)
+	}
+	if d := time.Since(t); d > tolerance || d < -tolerance {
+		return fmt.Errorf("Request timestamp is out of tolerance [%s]", timestamp)
+	}
+	certUrl := r.Header.Get("SignatureCertChainUrl")
+	if certUrl == "" {
+		return fmt.Errorf("Request is not signed")
+	}
+	signature, err := base64.StdEncoding.DecodeString(r.Header.Get("Signature-256"))
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("Request signature is not valid")
+	}
+	cert, err := v.certificate(certUrl)
+	if err != nil {
+		return err
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("Certificate key is not an RSA key")
+	}
+	sum := sha256.Sum256(body)
+	if err = rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], signature); err != nil {
+		return fmt.Errorf("Request signature does not match")
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package botframework
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	tokenUrl  = "https://login.microsoftonline.com/botframework.com/oauth2/v2.0/token"
+	openIdUrl = "https://login.botframework.com/v1/.well-known/openidconfiguration"
+)
+
+const (
+	tokenScope  = "https://api.botframework.com/.default"
+	tokenIssuer = "https://api.botframework.com"
+	clockSkew   = 5 * time.Minute
+)
+
+// getJSON fetches the JSON document at the URL into v
+func getJSON(client *http.Client, u string, v interface{}) error {
+	resp, err := client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Fetching [%s] failed with status %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// tokenSource caches the access token of the bot for the Connector API
+type tokenSource struct {
+	mu      sync.Mutex
+	value   string
+	expires time.Time
+}
+
+func (t *tokenSource) token(client *http.Client, appId, appPassword string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.value != "" && time.Now().Before(t.expires) {
+		return t.value, nil
+	}
+	resp, err := client.PostForm(tokenUrl, url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {appId},
+		"client_secret": {appPassword},
+		"scope":         {tokenScope},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Getting the access token failed with status %d", resp.StatusCode)
+	}
+	var res struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", err
+	}
+	// Renew the token a bit before it expires
+	t.value, t.expires = res.AccessToken, time.Now().Add(time.Duration(res.ExpiresIn)*time.Second-clockSkew)
+	return t.value, nil
+}
+
+// signingKey is a Bot Framework token signing key
+type signingKey struct {
+	key          *rsa.PublicKey
+	endorsements []string // The channels the key may sign for
+}
+
+// keySet caches the token signing keys of the Bot Framework
+type keySet struct {
+	mu      sync.Mutex
+	keys    map[string]signingKey
+	fetched time.Time
+}
+
+func (s *keySet) key(client *http.Client, kid string) (signingKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[kid]
+	// The keys roll over, so refresh them daily and on unknown key IDs, but
+	// not more often than every few minutes
+	if (ok && time.Since(s.fetched) < 24*time.Hour) || (!ok && time.Since(s.fetched) < clockSkew) {
+		if !ok {
+			return k, fmt.Errorf("Signing key is not recognized [%s]", kid)
+		}
+		return k, nil
+	}
+	var config struct {
+		JwksUri string `json:"jwks_uri"`
+	}
+	if err := getJSON(client, openIdUrl, &config); err != nil {
+		return k, err
+	}
+	var jwks struct {
+		Keys []struct {
+			Kid          string   `json:"kid"`
+			Kty          string   `json:"kty"`
+			N            string   `json:"n"`
+			E            string   `json:"e"`
+			Endorsements []string `json:"endorsements"`
+		} `json:"keys"`
+	}
+	if err := getJSON(client, config.JwksUri, &jwks); err != nil {
+		return k, err
+	}
+	s.keys = make(map[string]signingKey)
+	for _, jk := range jwks.Keys {
+		n, err1 := base64.RawURLEncoding.DecodeString(jk.N)
+		e, err2 := base64.RawURLEncoding.DecodeString(jk.E)
+		if jk.Kty != "RSA" || err1 != nil || err2 != nil {
+			continue
+		}
+		s.keys[jk.Kid] = signingKey{
+			key:          &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())},
+			endorsements: jk.Endorsements,
+		}
+	}
+	s.fetched = time.Now()
+	if k, ok = s.keys[kid]; !ok {
+		return k, fmt.Errorf("Signing key is not recognized [%s]", kid)
+	}
+	return k, nil
+}
+
+// authenticate checks the bearer token of a request sent by the Bot Framework
+// for the activity
+func (h *Handler) authenticate(r *http.Request, a *Activity) error {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("Request has no bearer token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	var claims struct {
+		Iss        string  `json:"iss"`
+		Aud        string  `json:"aud"`
+		Exp        float64 `json:"exp"`
+		Nbf        float64 `json:"nbf"`
+		ServiceUrl string  `json:"serviceurl"`
+	}
+	for i, v := range []interface{}{&header, &claims} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			return fmt.Errorf("Bearer token is not valid")
+		}
+		if err = json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("Bearer token is not valid")
+		}
+	}
+	if header.Alg != "RS256" {
+		return fmt.Errorf("Bearer token algorithm is not supported [%s]", header.Alg)
+	}
+	k, err := h.keys.key(h.httpClient(), header.Kid)
+	if err != nil {
+		return err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("Bearer token is not valid")
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err = rsa.VerifyPKCS1v15(k.key, crypto.SHA256, sum[:], signature); err != nil {
+		return fmt.Errorf("Bearer token signature does not match")
+	}
+	if len(k.endorsements) > 0 {
+		endorsed := false
+		for _, e := range k.endorsements {
+			endorsed = endorsed || e == a.ChannelId
+		}
+		if !endorsed {
+			return fmt.Errorf("Signing key is not endorsed for the channel [%s]", a.ChannelId)
+		}
+	}
+	now := time.Now()
+	switch {
+	case claims.Iss != tokenIssuer:
+		return fmt.Errorf("Bearer token issuer is not accepted [%s]", claims.Iss)
+	case claims.Aud != h.AppId:
+		return fmt.Errorf("Bearer token audience is not accepted [%s]", claims.Aud)
+	case now.After(time.Unix(int64(claims.Exp), 0).Add(clockSkew)):
+		return fmt.Errorf("Bearer token is expired")
+	case claims.Nbf != 0 && now.Add(clockSkew).Before(time.Unix(int64(claims.Nbf), 0)):
+		return fmt.Errorf("Bearer token is not valid yet")
+	case claims.ServiceUrl != a.ServiceUrl:
+		return fmt.Errorf("Bearer token service URL does not match [%s]", a.ServiceUrl)
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package botframework connects a pandorabots bot to the Microsoft Bot Framework,
+// and through it to Teams, Skype and the other Azure Bot Service channels.
+//
+// The Handler implements the messaging endpoint of the Activity protocol: the
+// text of incoming message activities is sent to the bot, within a pandorabots
+// session per Bot Framework conversation, and the responses are posted back
+// to the conversation as reply activities with the Connector API.
+package botframework
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// Activity types
+const (
+	MessageActivity            = "message"
+	ConversationUpdateActivity = "conversationUpdate"
+	TypingActivity             = "typing"
+)
+
+// ChannelAccount is a user or bot on a channel
+type ChannelAccount struct {
+	Id   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// ConversationAccount is a conversation on a channel
+type ConversationAccount struct {
+	Id      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	IsGroup bool   `json:"isGroup,omitempty"`
+}
+
+// Activity is a Bot Framework activity. Only the fields used by the adapter are decoded.
+type Activity struct {
+	Type         string              `json:"type"`
+	Id           string              `json:"id,omitempty"`
+	Timestamp    string              `json:"timestamp,omitempty"`
+	ServiceUrl   string              `json:"serviceUrl,omitempty"`
+	ChannelId    string              `json:"channelId,omitempty"`
+	From         ChannelAccount      `json:"from"`
+	Recipient    ChannelAccount      `json:"recipient"`
+	Conversation ConversationAccount `json:"conversation"`
+	ReplyToId    string              `json:"replyToId,omitempty"`
+	Text         string              `json:"text,omitempty"`
+	TextFormat   string              `json:"textFormat,omitempty"`
+	Speak        string              `json:"speak,omitempty"`
+	Locale       string              `json:"locale,omitempty"`
+}
+
+// Handler is the messaging endpoint of a Bot Framework bot answering with the pandorabots bot
+type Handler struct {
+	Conversation *pb.Conversation
+	// AppId and AppPassword are the Microsoft app credentials of the bot
+	// registration. When empty, as with the Bot Framework Emulator, requests
+	// are not authenticated and replies are sent without a token.
+	AppId, AppPassword string
+	// Filters clean the responses before they are sent. Defaults to splitting
+	// the breaks into messages, stripping the HTML and decoding the entities.
+	Filters []pb.ReplyFilter
+	// TextFormat is the format of the texts of the replies, markdown when the
+	// Filters convert the HTML with pb.Markdown. Defaults to plain.
+	TextFormat string
+	// Typing delays the replies as if they were typed, showing the typing
+	// indicator, nil to send them at once. The replies are then sent after
+	// the request is answered, as the channels time out slow requests.
+	Typing *pb.Typing
+	// Speak adds the SSML of the responses to the replies, for voice channels
+	Speak bool
+	// HTTPClient sends the replies and fetches the tokens and signing keys.
+	// Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// ErrorLog receives the errors of the requests, nil to discard them
+	ErrorLog *log.Logger
+
+	tokens tokenSource
+	keys   keySet
+}
+
+// NewHandler creates a messaging endpoint answering with the bot
+func NewHandler(c *pb.Client, bot string, store pb.SessionStore, appId, appPassword string) *Handler {
+	cv := pb.NewConversation(c, bot, store)
+	cv.ClientName = pb.HashedClientName("botframework-")
+	return &Handler{Conversation: cv, AppId: appId, AppPassword: appPassword}
+}
+
+func (h *Handler) errorf(format string, args ...interface{}) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+	}
+}
+
+func (h *Handler) httpClient() *http.Client {
+	if h.HTTPClient != nil {
+		return h.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// conversationKey identifies the conversation across the channels
+func conversationKey(a *Activity) string {
+	return a.ChannelId + "/" + a.Conversation.Id
+}
+
+// Handle answers a message activity with the reply activities of the bot
+func (h *Handler) Handle(a *Activity) ([]*Activity, error) {
+	if a.Type != MessageActivity || strings.TrimSpace(a.Text) == "" {
+		return nil, nil
+	}
+	reply, err := h.Conversation.Talk(conversationKey(a), a.Text)
+	if err != nil {
+		return nil, err
+	}
+	filters := h.Filters
+	if filters == nil {
+		filters = []pb.ReplyFilter{pb.SplitBreaks, pb.StripHTML, pb.DecodeEntities, pb.CollapseWhitespace}
+	}
+	textFormat := h.TextFormat
+	if textFormat == "" {
+		textFormat = "plain"
+	}
+	var replies []*Activity
+	for _, text := range pb.FilterResponses(reply.Responses, filters...) {
+		replies = append(replies, &Activity{
+			Type:         MessageActivity,
+			From:         a.Recipient,
+			Recipient:    a.From,
+			Conversation: a.Conversation,
+			ReplyToId:    a.Id,
+			Text:         text,
+			TextFormat:   textFormat,
+			Locale:       a.Locale,
+		})
+	}
+	if h.Speak && len(replies) > 0 {
+		replies[0].Speak = pb.ToSSML(reply.Responses)
+	}
+	return replies, nil
+}
+
+// Send posts an activity to its conversation with the Connector API of the service URL
+func (h *Handler) Send(serviceUrl string, a *Activity) error {
+	endpoint := strings.TrimSuffix(serviceUrl, "/") + "/v3/conversations/" + url.PathEscape(a.Conversation.Id) + "/activities"
+	if a.ReplyToId != "" {
+		endpoint += "/" + url.PathEscape(a.ReplyToId)
+	}
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.AppId != "" {
+		token, err := h.tokens.token(h.httpClient(), h.AppId, h.AppPassword)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := h.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("Sending the activity failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 256*1024))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	a := &Activity{}
+	if err = json.Unmarshal(body, a); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if h.AppId != "" {
+		if err = h.authenticate(r, a); err != nil {
+			h.errorf("Bot Framework request authentication failed - %v", err)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	replies, err := h.Handle(a)
+	if err != nil {
+		h.errorf("Bot Framework request failed - %v", err)
+		http.Error(w, "Request failed", http.StatusInternalServerError)
+		return
+	}
+	if h.Typing != nil && len(replies) > 0 {
+		go func() {
+			if err := h.sendTyping(a, replies); err != nil {
+				h.errorf("Bot Framework reply failed - %v", err)
+			}
+		}()
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	for _, reply := range replies {
+		if err = h.Send(a.ServiceUrl, reply); err != nil {
+			h.errorf("Bot Framework reply failed - %v", err)
+			http.Error(w, "Reply failed", http.StatusBadGateway)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// sendTyping sends the replies to the activity, each after its typing delay
+func (h *Handler) sendTyping(a *Activity, replies []*Activity) error {
+	for _, reply := range replies {
+		typing := &Activity{Type: TypingActivity, From: a.Recipient, Recipient: a.From, Conversation: a.Conversation}
+		if err := h.Send(a.ServiceUrl, typing); err != nil {
+			return err
+		}
+		h.Typing.Wait(reply.Text)
+		if err := h.Send(a.ServiceUrl, reply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dialogflow delegates Dialogflow agents, and the Google Assistant
+// actions built on them, to a pandorabots bot. It implements the Dialogflow ES
+// webhook fulfillment format as an http.Handler: the query text is sent to the
+// bot and the reply is returned as the fulfillment messages.
+//
+// Each Dialogflow session maps to a pandorabots session.
+package dialogflow
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// Intent is the intent matched by Dialogflow
+type Intent struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// QueryResult is the result of the Dialogflow query
+type QueryResult struct {
+	QueryText    string                 `json:"queryText"`
+	LanguageCode string                 `json:"languageCode"`
+	Parameters   map[string]interface{} `json:"parameters"`
+	Intent       Intent                 `json:"intent"`
+}
+
+// WebhookRequest is the body of the fulfillment requests sent by Dialogflow
+type WebhookRequest struct {
+	ResponseId                  string      `json:"responseId"`
+	Session                     string      `json:"session"` // projects/PROJECT/agent/sessions/SESSION
+	QueryResult                 QueryResult `json:"queryResult"`
+	OriginalDetectIntentRequest struct {
+		Source string `json:"source"` // "google" for the Google Assistant
+	} `json:"originalDetectIntentRequest"`
+}
+
+// Text is a text fulfillment message
+type Text struct {
+	Text []string `json:"text"`
+}
+
+// Message is a fulfillment message
+type Message struct {
+	Text *Text `json:"text,omitempty"`
+}
+
+type simpleResponse struct {
+	TextToSpeech string `json:"textToSpeech"`
+	DisplayText  string `json:"displayText"`
+}
+
+type richItem struct {
+	SimpleResponse simpleResponse `json:"simpleResponse"`
+}
+
+type googlePayload struct {
+	ExpectUserResponse bool `json:"expectUserResponse"`
+	RichResponse       struct {
+		Items []richItem `json:"items"`
+	} `json:"richResponse"`
+}
+
+// Payload holds the platform specific responses
+type Payload struct {
+	Google *googlePayload `json:"google,omitempty"`
+}
+
+// WebhookResponse is the body of the fulfillment responses
+type WebhookResponse struct {
+	FulfillmentText     string    `json:"fulfillmentText"`
+	FulfillmentMessages []Message `json:"fulfillmentMessages"`
+	Payload             *Payload  `json:"payload,omitempty"`
+}
+
+// Handler is an http.Handler answering Dialogflow fulfillment requests with the bot
+type Handler struct {
+	Conversation *pb.Conversation
+	// User and Password are the basic authentication credentials configured
+	// for the webhook in the Dialogflow console. Empty to not require them.
+	User, Password string
+	// Filters clean the responses for display. Defaults to splitting the
+	// breaks into messages, stripping the HTML and decoding the entities.
+	Filters []pb.ReplyFilter
+	// ErrorLog receives the errors of the requests, nil to discard them
+	ErrorLog *log.Logger
+}
+
+// NewHandler creates a handler answering with the bot
+func NewHandler(c *pb.Client, bot string, store pb.SessionStore) *Handler {
+	cv := pb.NewConversation(c, bot, store)
+	cv.ClientName = pb.HashedClientName("dialogflow-")
+	return &Handler{Conversation: cv}
+}
+
+func (h *Handler) errorf(format string, args ...interface{}) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+	}
+}
+
+// Handle answers a parsed fulfillment request
+func (h *Handler) Handle(req *WebhookRequest) (*WebhookResponse, error) {
+	reply, err := h.Conversation.Talk(req.Session, req.QueryResult.QueryText)
+	if err != nil {
+		return nil, err
+	}
+	filters := h.Filters
+	if filters == nil {
+		filters = []pb.ReplyFilter{pb.SplitBreaks, pb.StripHTML, pb.DecodeEntities, pb.CollapseWhitespace}
+	}
+	texts := pb.FilterResponses(reply.Responses, filters...)
+	resp := &WebhookResponse{FulfillmentMessages: []Message{}}
+	for i, t := range texts {
+		if i > 0 {
+			resp.FulfillmentText += " "
+		}
+		resp.FulfillmentText += t
+		resp.FulfillmentMessages = append(resp.FulfillmentMessages, Message{Text: &Text{Text: []string{t}}})
+	}
+	if req.OriginalDetectIntentRequest.Source == "google" {
+		// The Assistant speaks the SSML and displays the text
+		g := &googlePayload{ExpectUserResponse: true}
+		g.RichResponse.Items = []richItem{{simpleResponse{TextToSpeech: pb.ToSSML(reply.Responses), DisplayText: resp.FulfillmentText}}}
+		resp.Payload = &Payload{Google: g}
+	}
+	return resp, nil
+}
+
+// authorized checks the basic authentication credentials of the request
+func (h *Handler) authorized(r *http.Request) bool {
+	if h.User == "" && h.Password == "" {
+		return true
+	}
+	user, pass, ok := r.BasicAuth()
+	return ok && subtle.ConstantTimeCompare([]byte(user), []byte(h.User)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(pass), []byte(h.Password)) == 1
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="dialogflow"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 256*1024))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req := &WebhookRequest{}
+	if err = json.Unmarshal(body, req); err != nil || req.Session == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	resp, err := h.Handle(req)
+	if err != nil {
+		h.errorf("Dialogflow request failed - %v", err)
+		http.Error(w, "Request failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(resp)
+}
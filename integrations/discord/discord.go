@@ -0,0 +1,433 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package discord runs a pandorabots bot as a Discord bot. The Bot connects to
+// the Discord gateway and answers the direct messages and the guild messages
+// mentioning it, within a pandorabots session per user and guild. Each guild
+// can be answered by a different pandorabots bot, and long replies are split
+// to fit the Discord message length limit.
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+)
+
+const (
+	// DefaultApiUrl is the Discord REST API endpoint
+	DefaultApiUrl = "https://discord.com/api/v10"
+	// MaxMessageLength is the Discord limit of the message content length
+	MaxMessageLength = pb.DiscordLength
+)
+
+// Gateway intents of the bot: guild messages, direct messages and the
+// privileged message content, which must be enabled for the application
+const intents = 1<<9 | 1<<12 | 1<<15
+
+// Gateway opcodes
+const (
+	opDispatch       = 0
+	opHeartbeat      = 1
+	opIdentify       = 2
+	opReconnect      = 7
+	opInvalidSession = 9
+	opHello          = 10
+	opHeartbeatAck   = 11
+)
+
+// Close codes after which reconnecting cannot help
+var fatalCloseCodes = map[int]string{
+	4004: "authentication failed",
+	4010: "invalid shard",
+	4011: "sharding required",
+	4012: "invalid API version",
+	4013: "invalid intents",
+	4014: "disallowed intents, enable the message content intent of the application",
+}
+
+// User is a Discord user
+type User struct {
+	Id       string `json:"id"`
+	Username string `json:"username"`
+	Bot      bool   `json:"bot"`
+}
+
+// Message is a Discord message
+type Message struct {
+	Id        string `json:"id"`
+	ChannelId string `json:"channel_id"`
+	GuildId   string `json:"guild_id"`
+	Author    User   `json:"author"`
+	Content   string `json:"content"`
+	Mentions  []User `json:"mentions"`
+}
+
+type payload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d,omitempty"`
+	S  *int64          `json:"s,omitempty"`
+	T  string          `json:"t,omitempty"`
+}
+
+// Bot answers Discord messages with pandorabots bots
+type Bot struct {
+	Client *pb.Client
+	// Token is the token of the Discord bot user
+	Token string
+	// Bot is the pandorabots bot answering the direct messages and the guilds
+	// not in Guilds
+	Bot string
+	// Guilds maps guild IDs to the pandorabots bots answering in them
+	Guilds map[string]string
+	// Store keeps the sessions, nil to keep them in memory
+	Store pb.SessionStore
+	// Configure sets up the conversations when they are created, e.g. their
+	// Policy, Limiter and Fallback, nil to keep the defaults
+	Configure func(cv *pb.Conversation)
+	// Filters clean the responses before they are sent. Defaults to splitting
+	// the breaks into messages, stripping the HTML and decoding the entities.
+	Filters []pb.ReplyFilter
+	// Typing delays the messages as if they were typed, nil to send them at once
+	Typing *pb.Typing
+	// MaxLength is the length the responses are split at, on the sentence
+	// boundaries where possible. Defaults to MaxMessageLength.
+	MaxLength int
+	// ApiUrl is the Discord REST API endpoint. Defaults to DefaultApiUrl.
+	ApiUrl string
+	// HTTPClient sends the REST API requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// ErrorLog receives the errors of the messages and connections, nil to discard them
+	ErrorLog *log.Logger
+
+	mu            sync.Mutex
+	conversations map[string]*pb.Conversation
+	self          User // The bot user, known once connected
+}
+
+// New creates a Discord bot answering with the pandorabots bot
+func New(c *pb.Client, bot, token string) *Bot {
+	return &Bot{Client: c, Bot: bot, Token: token}
+}
+
+func (b *Bot) errorf(format string, args ...interface{}) {
+	if b.ErrorLog != nil {
+		b.ErrorLog.Printf(format, args...)
+	}
+}
+
+// conversation returns the conversation answering in the guild, empty for direct messages
+func (b *Bot) conversation(guildId string) *pb.Conversation {
+	bot := b.Bot
+	if g, ok := b.Guilds[guildId]; ok && guildId != "" {
+		bot = g
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.conversations == nil {
+		b.conversations = make(map[string]*pb.Conversation)
+		if b.Store == nil {
+			b.Store = pb.NewMemorySessionStore()
+		}
+	}
+	cv, ok := b.conversations[bot]
+	if !ok {
+		cv = pb.NewConversation(b.Client, bot, b.Store)
+		cv.ClientName = pb.HashedClientName("discord-")
+		if b.Configure != nil {
+			b.Configure(cv)
+		}
+		b.conversations[bot] = cv
+	}
+	return cv
+}
+
+// request sends a REST API request, waiting out the rate limits
+func (b *Bot) request(method, path string, body interface{}, v interface{}) error {
+	apiUrl := b.ApiUrl
+	if apiUrl == "" {
+		apiUrl = DefaultApiUrl
+	}
+	client := b.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, apiUrl+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bot "+b.Token)
+		req.Header.Set("User-Agent", "DiscordBot (https://github.com/demisto/pb-go, 1.0)")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < 3 {
+			var limit struct {
+				RetryAfter float64 `json:"retry_after"`
+			}
+			json.NewDecoder(resp.Body).Decode(&limit)
+			resp.Body.Close()
+			time.Sleep(time.Duration(limit.RetryAfter*float64(time.Second)) + 100*time.Millisecond)
+			continue
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("Discord request %s %s failed with status %d", method, path, resp.StatusCode)
+		}
+		if v != nil {
+			return json.NewDecoder(resp.Body).Decode(v)
+		}
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+}
+
+// Split splits the text into parts of at most limit characters, on sentence
+// boundaries, line breaks or spaces where possible
+func Split(text string, limit int) []string {
+	return pb.Splitter{Limit: limit}.Split(text)
+}
+
+// input returns the bot input of a message, empty if the bot should not answer it
+func (b *Bot) input(m *Message) string {
+	b.mu.Lock()
+	self := b.self
+	b.mu.Unlock()
+	if m.Author.Bot || m.Author.Id == self.Id {
+		return ""
+	}
+	if m.GuildId == "" {
+		return strings.TrimSpace(m.Content)
+	}
+	for _, u := range m.Mentions {
+		if u.Id == self.Id {
+			content := strings.NewReplacer("<@"+u.Id+">", "", "<@!"+u.Id+">", "").Replace(m.Content)
+			return strings.TrimSpace(content)
+		}
+	}
+	return ""
+}
+
+// Handle answers a message
+func (b *Bot) Handle(m *Message) error {
+	text := b.input(m)
+	if text == "" {
+		return nil
+	}
+	b.request(http.MethodPost, "/channels/"+m.ChannelId+"/typing", nil, nil)
+	reply, err := b.conversation(m.GuildId).Talk(m.GuildId+"/"+m.Author.Id, text)
+	if err != nil {
+		return err
+	}
+	filters := b.Filters
+	if filters == nil {
+		filters = []pb.ReplyFilter{pb.SplitBreaks, pb.StripHTML, pb.DecodeEntities, pb.CollapseWhitespace}
+	}
+	limit := b.MaxLength
+	if limit <= 0 || limit > MaxMessageLength {
+		limit = MaxMessageLength
+	}
+	first := true
+	for _, r := range pb.FilterResponses(reply.Responses, filters...) {
+		for _, part := range Split(r, limit) {
+			if b.Typing != nil {
+				if !first {
+					b.request(http.MethodPost, "/channels/"+m.ChannelId+"/typing", nil, nil)
+				}
+				b.Typing.Wait(part)
+			}
+			msg := map[string]interface{}{"content": part, "allowed_mentions": map[string]interface{}{"parse": []string{}}}
+			if first && m.GuildId != "" {
+				// Reply to the mention so the answer is threaded in busy channels
+				msg["message_reference"] = map[string]string{"message_id": m.Id}
+			}
+			first = false
+			if err = b.request(http.MethodPost, "/channels/"+m.ChannelId+"/messages", msg, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Run connects to the gateway and answers the messages until the context is
+// cancelled, reconnecting when the connection is lost
+func (b *Bot) Run(ctx context.Context) error {
+	wait := time.Second
+	for {
+		start := time.Now()
+		err := b.session(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var ce *CloseError
+		if errors.As(err, &ce) {
+			if reason, ok := fatalCloseCodes[ce.Code]; ok {
+				return fmt.Errorf("Discord gateway closed the connection - %s", reason)
+			}
+		}
+		if time.Since(start) > time.Minute {
+			wait = time.Second
+		}
+		b.errorf("Discord gateway connection lost, reconnecting in %v - %v", wait, err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+		if wait < time.Minute {
+			wait *= 2
+		}
+	}
+}
+
+// session runs a single gateway connection
+func (b *Bot) session(ctx context.Context) error {
+	var gateway struct {
+		Url string `json:"url"`
+	}
+	if err := b.request(http.MethodGet, "/gateway/bot", nil, &gateway); err != nil {
+		return err
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	conn, err := dialWebSocket(dialCtx, gateway.Url+"/?v=10&encoding=json")
+	cancel()
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close(1000)
+		case <-done:
+			conn.Close(1000)
+		}
+	}()
+
+	send := func(op int, d interface{}) error {
+		data, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		msg, _ := json.Marshal(payload{Op: op, D: data})
+		return conn.WriteText(msg)
+	}
+	var (
+		seqMu sync.Mutex
+		seq   *int64
+		acked = true
+	)
+	heartbeat := func() error {
+		seqMu.Lock()
+		defer seqMu.Unlock()
+		if !acked {
+			// A zombied connection, drop it to reconnect
+			conn.conn.Close()
+			return errors.New("Heartbeat was not acknowledged")
+		}
+		acked = false
+		return send(opHeartbeat, seq)
+	}
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var p payload
+		if err = json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		if p.S != nil {
+			seqMu.Lock()
+			seq = p.S
+			seqMu.Unlock()
+		}
+		switch p.Op {
+		case opHello:
+			var hello struct {
+				HeartbeatInterval int64 `json:"heartbeat_interval"`
+			}
+			json.Unmarshal(p.D, &hello)
+			if hello.HeartbeatInterval <= 0 {
+				return fmt.Errorf("Discord gateway hello is not valid [%s] - it has no heartbeat interval", p.D)
+			}
+			go func(interval time.Duration) {
+				t := time.NewTicker(interval)
+				defer t.Stop()
+				for {
+					select {
+					case <-done:
+						return
+					case <-t.C:
+						if heartbeat() != nil {
+							return
+						}
+					}
+				}
+			}(time.Duration(hello.HeartbeatInterval) * time.Millisecond)
+			err = send(opIdentify, map[string]interface{}{
+				"token":      b.Token,
+				"intents":    intents,
+				"properties": map[string]string{"os": "linux", "browser": "pb-go", "device": "pb-go"},
+			})
+			if err != nil {
+				return err
+			}
+		case opHeartbeat:
+			seqMu.Lock()
+			send(opHeartbeat, seq)
+			seqMu.Unlock()
+		case opHeartbeatAck:
+			seqMu.Lock()
+			acked = true
+			seqMu.Unlock()
+		case opReconnect, opInvalidSession:
+			return errors.New("Discord gateway requested a new session")
+		case opDispatch:
+			switch p.T {
+			case "READY":
+				var ready struct {
+					User User `json:"user"`
+				}
+				json.Unmarshal(p.D, &ready)
+				b.mu.Lock()
+				b.self = ready.User
+				b.mu.Unlock()
+			case "MESSAGE_CREATE":
+				m := &Message{}
+				if json.Unmarshal(p.D, m) == nil {
+					go func() {
+						if err := b.Handle(m); err != nil {
+							b.errorf("Discord message failed - %v", err)
+						}
+					}()
+				}
+			}
+		}
+	}
+}
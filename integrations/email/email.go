@@ -0,0 +1,354 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package email answers emails with a pandorabots bot. The Gateway sends the
+// body of each received message to the bot, within a pandorabots session per
+// sender address, and replies by SMTP in the same thread. Messages are
+// received by polling an IMAP mailbox with Poll, or from the inbound email
+// webhook of a mail service with the Gateway http.Handler.
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"regexp"
+	"strings"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// Mail is a received email
+type Mail struct {
+	From       string `json:"from"`
+	Subject    string `json:"subject"`
+	MessageId  string `json:"messageId"`
+	References string `json:"references"`
+	Text       string `json:"text"`
+	// AutoSubmitted marks automatic messages, like out of office replies,
+	// which are not answered to avoid mail loops
+	AutoSubmitted bool `json:"autoSubmitted"`
+}
+
+// SMTP is the server the replies are sent with
+type SMTP struct {
+	Addr     string // host:port of the submission server
+	User     string // Empty to send without authentication
+	Password string
+	From     string // The address of the bot
+}
+
+// Gateway answers emails with the bot
+type Gateway struct {
+	Conversation *pb.Conversation
+	SMTP         SMTP
+	// Filters clean the responses before they are sent. Defaults to splitting
+	// the breaks into paragraphs, stripping the HTML and decoding the entities.
+	Filters []pb.ReplyFilter
+	// WebhookToken authenticates the webhook requests, which must carry it in
+	// the token query parameter or as a bearer token. The webhook refuses all
+	// requests when empty, as anyone could otherwise make the bot send emails.
+	WebhookToken string
+	// ErrorLog receives the errors of the messages, nil to discard them
+	ErrorLog *log.Logger
+}
+
+// NewGateway creates a gateway answering with the bot and replying with the SMTP server
+func NewGateway(c *pb.Client, bot string, store pb.SessionStore, s SMTP) *Gateway {
+	cv := pb.NewConversation(c, bot, store)
+	cv.ClientName = pb.HashedClientName("email-")
+	return &Gateway{Conversation: cv, SMTP: s}
+}
+
+func (g *Gateway) errorf(format string, args ...interface{}) {
+	if g.ErrorLog != nil {
+		g.ErrorLog.Printf(format, args...)
+	}
+}
+
+var (
+	quoteHeaderRe = regexp.MustCompile(`(?m)^On .+wrote:\s*$`)
+	wordDecoder   = &mime.WordDecoder{}
+)
+
+// StripQuoted removes the quoted previous messages and the signature from a reply body
+func StripQuoted(text string) string {
+	if loc := quoteHeaderRe.FindStringIndex(text); loc != nil {
+		text = text[:loc[0]]
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if line == "-- " || line == "--" {
+			break
+		}
+		if !strings.HasPrefix(line, ">") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// textPart returns the decoded text/plain body of a message part
+func textPart(header map[string][]string, body io.Reader) (string, error) {
+	h := mail.Header(header)
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	switch strings.ToLower(h.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := mr.NextRawPart()
+			if err != nil {
+				return "", nil
+			}
+			if text, err := textPart(p.Header, p); err != nil || text != "" {
+				return text, err
+			}
+		}
+	}
+	if mediaType != "text/plain" {
+		return "", nil
+	}
+	data, err := io.ReadAll(io.LimitReader(body, 1024*1024))
+	return string(data), err
+}
+
+// ParseMail parses a raw RFC 5322 message
+func ParseMail(raw []byte) (*Mail, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	subject, err := wordDecoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+	m := &Mail{
+		From:       msg.Header.Get("Reply-To"),
+		Subject:    subject,
+		MessageId:  msg.Header.Get("Message-Id"),
+		References: msg.Header.Get("References"),
+	}
+	if m.From == "" {
+		m.From = msg.Header.Get("From")
+	}
+	auto := strings.ToLower(msg.Header.Get("Auto-Submitted"))
+	precedence := strings.ToLower(msg.Header.Get("Precedence"))
+	m.AutoSubmitted = (auto != "" && auto != "no") || precedence == "bulk" || precedence == "list" || precedence == "junk"
+	if m.Text, err = textPart(msg.Header, msg.Body); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Handle answers a received email
+func (g *Gateway) Handle(m *Mail) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("Sender address is not valid [%s]", m.From)
+	}
+	if m.AutoSubmitted || strings.EqualFold(from.Address, g.SMTP.From) {
+		return nil
+	}
+	text := StripQuoted(m.Text)
+	if text == "" {
+		return nil
+	}
+	// The lines of a body are wrapped, so join them into a single input
+	reply, err := g.Conversation.Talk(strings.ToLower(from.Address), strings.Join(strings.Fields(text), " "))
+	if err != nil {
+		return err
+	}
+	filters := g.Filters
+	if filters == nil {
+		filters = []pb.ReplyFilter{pb.SplitBreaks, pb.StripHTML, pb.DecodeEntities, pb.CollapseWhitespace}
+	}
+	responses := pb.FilterResponses(reply.Responses, filters...)
+	if len(responses) == 0 {
+		return nil
+	}
+	return g.reply(from.Address, m, strings.Join(responses, "\n\n"))
+}
+
+// reply sends the text as a reply to the message
+func (g *Gateway) reply(to string, m *Mail, text string) error {
+	subject := m.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+	var buf bytes.Buffer
+	header := func(k, v string) {
+		if v = strings.NewReplacer("\r", "", "\n", "").Replace(v); v != "" {
+			fmt.Fprintf(&buf, "%s: %s\r\n", k, v)
+		}
+	}
+	header("From", g.SMTP.From)
+	header("To", to)
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("In-Reply-To", m.MessageId)
+	header("References", strings.TrimSpace(m.References+" "+m.MessageId))
+	header("Auto-Submitted", "auto-replied")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+	qp := quotedprintable.NewWriter(&buf)
+	qp.Write([]byte(strings.ReplaceAll(text, "\n", "\r\n")))
+	qp.Close()
+
+	var auth smtp.Auth
+	if g.SMTP.User != "" {
+		host := g.SMTP.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", g.SMTP.User, g.SMTP.Password, host)
+	}
+	return smtp.SendMail(g.SMTP.Addr, auth, g.SMTP.From, []string{to}, buf.Bytes())
+}
+
+// ServeHTTP accepts an inbound email webhook, as a JSON Mail or as the form
+// fields of the common mail services (from or sender, subject, text or
+// body-plain, Message-Id)
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if g.WebhookToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(g.WebhookToken)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	m := &Mail{}
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/json" {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024*1024)).Decode(m); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		r.Body = http.MaxBytesReader(w, r.Body, 8*1024*1024)
+		if err := r.ParseMultipartForm(1024 * 1024); err != nil && err != http.ErrNotMultipart {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		first := func(keys ...string) string {
+			for _, k := range keys {
+				if v := r.FormValue(k); v != "" {
+					return v
+				}
+			}
+			return ""
+		}
+		m.From = first("from", "sender")
+		m.Subject = first("subject")
+		m.Text = first("text", "body-plain")
+		m.MessageId = first("Message-Id", "message-id")
+		m.References = first("References", "references")
+	}
+	if m.From == "" {
+		http.Error(w, "Sender is required", http.StatusBadRequest)
+		return
+	}
+	if err := g.Handle(m); err != nil {
+		g.errorf("Email from %s failed - %v", m.From, err)
+		http.Error(w, "Request failed", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// IMAP is the mailbox polled for messages
+type IMAP struct {
+	Addr     string // host:port of the server, TLS unless Insecure
+	User     string
+	Password string
+	Mailbox  string        // Defaults to INBOX
+	Interval time.Duration // Defaults to a minute
+	Insecure bool          // Connect without TLS, for local servers only
+}
+
+// poll answers the unseen messages of the mailbox once
+func (g *Gateway) poll(cfg IMAP) error {
+	c, err := dialIMAP(cfg.Addr, cfg.Insecure)
+	if err != nil {
+		return err
+	}
+	defer c.logout()
+	if err = c.login(cfg.User, cfg.Password); err != nil {
+		return err
+	}
+	mailbox := cfg.Mailbox
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+	if err = c.selectMailbox(mailbox); err != nil {
+		return err
+	}
+	uids, err := c.unseen()
+	if err != nil {
+		return err
+	}
+	for _, uid := range uids {
+		raw, err := c.fetch(uid)
+		if err != nil {
+			return err
+		}
+		if m, err := ParseMail(raw); err != nil {
+			g.errorf("Email %s is not valid - %v", uid, err)
+		} else if err = g.Handle(m); err != nil {
+			// Leave the message unseen to retry it on the next poll
+			g.errorf("Email from %s failed - %v", m.From, err)
+			continue
+		}
+		if err = c.markSeen(uid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Poll answers the unseen messages of the IMAP mailbox, checking it at the
+// interval until the context is cancelled. Answered messages are marked seen.
+func (g *Gateway) Poll(ctx context.Context, cfg IMAP) error {
+	interval := cfg.Interval
+	if interval == 0 {
+		interval = time.Minute
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := g.poll(cfg); err != nil {
+			g.errorf("Polling %s failed - %v", cfg.Addr, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package email
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imapResponse is a response line with the literals it carried
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+// imapConn is a minimal IMAP4rev1 client, enough to fetch the unseen messages
+type imapConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	tag  int
+}
+
+// dialIMAP connects to the IMAP server over TLS, or in plain text if insecure
+func dialIMAP(addr string, insecure bool) (*imapConn, error) {
+	var conn net.Conn
+	var err error
+	d := &net.Dialer{Timeout: 30 * time.Second}
+	if insecure {
+		conn, err = d.Dial("tcp", addr)
+	} else {
+		conn, err = tls.DialWithDialer(d, "tcp", addr, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &imapConn{conn: conn, br: bufio.NewReader(conn)}
+	conn.SetDeadline(time.Now().Add(time.Minute))
+	greeting, err := c.readResponse()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting.line, "* OK") {
+		conn.Close()
+		return nil, fmt.Errorf("IMAP server refused the connection [%s]", greeting.line)
+	}
+	return c, nil
+}
+
+// readResponse reads a response line, including the literals inside it
+func (c *imapConn) readResponse() (*imapResponse, error) {
+	r := &imapResponse{}
+	for {
+		line, err := c.br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		r.line += line
+		// A literal is announced by {SIZE} at the end of the line
+		if !strings.HasSuffix(line, "}") {
+			return r, nil
+		}
+		i := strings.LastIndex(line, "{")
+		if i < 0 {
+			return r, nil
+		}
+		n, err := strconv.Atoi(line[i+1 : len(line)-1])
+		if err != nil {
+			return r, nil
+		}
+		literal := make([]byte, n)
+		if _, err = io.ReadFull(c.br, literal); err != nil {
+			return nil, err
+		}
+		r.literals = append(r.literals, literal)
+	}
+}
+
+// quote quotes an IMAP string
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// command sends a command and returns its untagged responses
+func (c *imapConn) command(format string, args ...interface{}) ([]*imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%d", c.tag)
+	c.conn.SetDeadline(time.Now().Add(time.Minute))
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, err
+	}
+	var untagged []*imapResponse
+	for {
+		r, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(r.line, tag+" ") {
+			status := strings.TrimPrefix(r.line, tag+" ")
+			if !strings.HasPrefix(status, "OK") {
+				return nil, fmt.Errorf("IMAP command failed [%s]", status)
+			}
+			return untagged, nil
+		}
+		untagged = append(untagged, r)
+	}
+}
+
+func (c *imapConn) login(user, password string) error {
+	_, err := c.command("LOGIN %s %s", quote(user), quote(password))
+	return err
+}
+
+func (c *imapConn) selectMailbox(mailbox string) error {
+	_, err := c.command("SELECT %s", quote(mailbox))
+	return err
+}
+
+// unseen returns the UIDs of the unseen messages
+func (c *imapConn) unseen() ([]string, error) {
+	res, err := c.command("UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+	var uids []string
+	for _, r := range res {
+		if strings.HasPrefix(r.line, "* SEARCH") {
+			uids = append(uids, strings.Fields(strings.TrimPrefix(r.line, "* SEARCH"))...)
+		}
+	}
+	return uids, nil
+}
+
+// fetch returns the raw message with the UID, without marking it seen
+func (c *imapConn) fetch(uid string) ([]byte, error) {
+	res, err := c.command("UID FETCH %s BODY.PEEK[]", uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range res {
+		if strings.Contains(r.line, "FETCH") && len(r.literals) > 0 {
+			return r.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("IMAP message not found [%s]", uid)
+}
+
+func (c *imapConn) markSeen(uid string) error {
+	_, err := c.command(`UID STORE %s +FLAGS.SILENT (\Seen)`, uid)
+	return err
+}
+
+func (c *imapConn) logout() {
+	c.command("LOGOUT")
+	c.conn.Close()
+}
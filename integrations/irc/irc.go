@@ -0,0 +1,324 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package irc runs a pandorabots bot as an IRC client. The Bot joins the
+// channels and answers the messages addressed to its nick ("nick: hello") and
+// the private messages, within a pandorabots session per nick.
+package irc
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// MaxTextLength is the length of the text sent per message. IRC lines are
+// limited to 512 bytes, including the prefix added by the server.
+const MaxTextLength = 400
+
+// sendInterval spaces the lines sent, so the server does not disconnect the bot for flooding
+const sendInterval = 500 * time.Millisecond
+
+// Bot answers IRC messages with a pandorabots bot
+type Bot struct {
+	Client *pb.Client
+	Bot    string
+	// Addr is the host:port of the server, TLS unless Insecure
+	Addr     string
+	Insecure bool
+	// Nick is the nick of the bot. An underscore is appended while it is taken.
+	Nick string
+	// Password is the server password, empty for none
+	Password string
+	// Channels are the channels to join
+	Channels []string
+	// Store keeps the sessions, nil to keep them in memory
+	Store pb.SessionStore
+	// Configure sets up the conversations when they are created, e.g. their
+	// Policy, Limiter and Fallback, nil to keep the defaults
+	Configure func(cv *pb.Conversation)
+	// Filters clean the responses before they are sent. Defaults to splitting
+	// the breaks into messages, stripping the HTML and decoding the entities.
+	Filters []pb.ReplyFilter
+	// Typing delays the messages as if they were typed, nil to send them at once
+	Typing *pb.Typing
+	// MaxLength is the length in bytes the responses are split at, on the
+	// sentence boundaries where possible. Defaults to MaxTextLength.
+	MaxLength int
+	// ErrorLog receives the errors of the messages and connections, nil to discard them
+	ErrorLog *log.Logger
+
+	once sync.Once
+	cv   *pb.Conversation
+}
+
+// New creates an IRC bot answering with the pandorabots bot
+func New(c *pb.Client, bot, addr, nick string, channels ...string) *Bot {
+	return &Bot{Client: c, Bot: bot, Addr: addr, Nick: nick, Channels: channels}
+}
+
+func (b *Bot) errorf(format string, args ...interface{}) {
+	if b.ErrorLog != nil {
+		b.ErrorLog.Printf(format, args...)
+	}
+}
+
+func (b *Bot) conversation() *pb.Conversation {
+	b.once.Do(func() {
+		b.cv = pb.NewConversation(b.Client, b.Bot, b.Store)
+		b.cv.ClientName = pb.HashedClientName("irc-")
+		if b.Configure != nil {
+			b.Configure(b.cv)
+		}
+	})
+	return b.cv
+}
+
+// Message is a parsed IRC message
+type Message struct {
+	Prefix  string // nick!user@host of the sender
+	Command string
+	Params  []string
+}
+
+// Nick returns the nick of the sender
+func (m *Message) Nick() string {
+	nick, _, _ := strings.Cut(m.Prefix, "!")
+	return nick
+}
+
+// ParseMessage parses an IRC protocol line
+func ParseMessage(line string) *Message {
+	m := &Message{}
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, "@") {
+		// Skip the IRCv3 message tags
+		_, line, _ = strings.Cut(line, " ")
+	}
+	if strings.HasPrefix(line, ":") {
+		m.Prefix, line, _ = strings.Cut(line[1:], " ")
+	}
+	for line != "" {
+		if strings.HasPrefix(line, ":") {
+			m.Params = append(m.Params, line[1:])
+			break
+		}
+		var param string
+		param, line, _ = strings.Cut(line, " ")
+		if param == "" {
+			continue
+		}
+		if m.Command == "" {
+			m.Command = strings.ToUpper(param)
+		} else {
+			m.Params = append(m.Params, param)
+		}
+	}
+	return m
+}
+
+// addressed returns the text of a channel message addressed to the nick, as
+// "nick: text" or "nick, text"
+func addressed(text, nick string) (string, bool) {
+	if len(text) <= len(nick) || !strings.EqualFold(text[:len(nick)], nick) {
+		return "", false
+	}
+	rest := text[len(nick):]
+	if rest[0] != ':' && rest[0] != ',' {
+		return "", false
+	}
+	return strings.TrimSpace(rest[1:]), true
+}
+
+// connection is a single connection to the server
+type connection struct {
+	conn net.Conn
+	out  chan string
+	nick string
+	mu   sync.Mutex
+}
+
+// send queues a line to send, dropping the line breaks that would inject commands
+func (c *connection) send(format string, args ...interface{}) {
+	line := strings.NewReplacer("\r", " ", "\n", " ").Replace(fmt.Sprintf(format, args...))
+	select {
+	case c.out <- line:
+	default:
+		// The queue is full, the server is not keeping up
+	}
+}
+
+func (c *connection) currentNick() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nick
+}
+
+// handle answers a PRIVMSG
+func (b *Bot) handle(c *connection, m *Message) error {
+	if len(m.Params) < 2 || m.Params[0] == "" || strings.HasPrefix(m.Params[1], "\x01") {
+		// CTCP requests, including actions, are not answered
+		return nil
+	}
+	target, text, nick := m.Params[0], m.Params[1], m.Nick()
+	replyTo, prefix := nick, ""
+	if strings.ContainsAny(target[:1], "#&+!") {
+		var ok bool
+		if text, ok = addressed(text, c.currentNick()); !ok {
+			return nil
+		}
+		replyTo, prefix = target, nick+": "
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return nil
+	}
+	reply, err := b.conversation().Talk(strings.ToLower(nick), text)
+	if err != nil {
+		return err
+	}
+	filters := b.Filters
+	if filters == nil {
+		filters = []pb.ReplyFilter{pb.SplitBreaks, pb.StripHTML, pb.DecodeEntities, pb.CollapseWhitespace}
+	}
+	limit := b.MaxLength
+	if limit <= 0 || limit > MaxTextLength {
+		limit = MaxTextLength
+	}
+	splitter := pb.Splitter{Limit: limit - len(prefix), Bytes: true}
+	for _, r := range pb.FilterResponses(reply.Responses, filters...) {
+		for _, part := range splitter.Split(r) {
+			b.Typing.Wait(part)
+			c.send("PRIVMSG %s :%s%s", replyTo, prefix, part)
+		}
+	}
+	return nil
+}
+
+// Run connects to the server and answers the messages until the context is
+// cancelled, reconnecting when the connection is lost
+func (b *Bot) Run(ctx context.Context) error {
+	if b.Nick == "" {
+		return errors.New("A nick is required")
+	}
+	wait := time.Second
+	for {
+		start := time.Now()
+		err := b.session(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if time.Since(start) > time.Minute {
+			wait = time.Second
+		}
+		b.errorf("IRC connection to %s lost, reconnecting in %v - %v", b.Addr, wait, err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+		if wait < 5*time.Minute {
+			wait *= 2
+		}
+	}
+}
+
+// session runs a single connection to the server
+func (b *Bot) session(ctx context.Context) error {
+	d := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	if b.Insecure {
+		conn, err = d.DialContext(ctx, "tcp", b.Addr)
+	} else {
+		conn, err = (&tls.Dialer{NetDialer: d}).DialContext(ctx, "tcp", b.Addr)
+	}
+	if err != nil {
+		return err
+	}
+	c := &connection{conn: conn, out: make(chan string, 100), nick: b.Nick}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		t := time.NewTicker(sendInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				fmt.Fprintf(conn, "QUIT :Bye\r\n")
+				conn.Close()
+				return
+			case <-done:
+				conn.Close()
+				return
+			case line := <-c.out:
+				conn.SetWriteDeadline(time.Now().Add(30 * time.Second))
+				if _, err := fmt.Fprintf(conn, "%s\r\n", line); err != nil {
+					conn.Close()
+					return
+				}
+				<-t.C
+			}
+		}
+	}()
+
+	if b.Password != "" {
+		c.send("PASS %s", b.Password)
+	}
+	c.send("NICK %s", b.Nick)
+	c.send("USER %s 0 * :%s", b.Nick, b.Bot)
+	br := bufio.NewReader(conn)
+	for {
+		// Servers ping idle clients every few minutes
+		conn.SetReadDeadline(time.Now().Add(10 * time.Minute))
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		m := ParseMessage(line)
+		switch m.Command {
+		case "PING":
+			c.send("PONG :%s", strings.Join(m.Params, " "))
+		case "001":
+			// Registered, the nick the server accepted is the first parameter
+			if len(m.Params) > 0 {
+				c.mu.Lock()
+				c.nick = m.Params[0]
+				c.mu.Unlock()
+			}
+			for _, ch := range b.Channels {
+				c.send("JOIN %s", ch)
+			}
+		case "433":
+			// The nick is taken
+			c.mu.Lock()
+			c.nick += "_"
+			nick := c.nick
+			c.mu.Unlock()
+			c.send("NICK %s", nick)
+		case "NICK":
+			if len(m.Params) > 0 && m.Nick() == c.currentNick() {
+				c.mu.Lock()
+				c.nick = m.Params[0]
+				c.mu.Unlock()
+			}
+		case "ERROR":
+			return fmt.Errorf("IRC server closed the connection [%s]", strings.Join(m.Params, " "))
+		case "PRIVMSG":
+			go func() {
+				if err := b.handle(c, m); err != nil {
+					b.errorf("IRC message from %s failed - %v", m.Nick(), err)
+				}
+			}()
+		}
+	}
+}
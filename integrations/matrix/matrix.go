@@ -0,0 +1,307 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package matrix runs a pandorabots bot as a Matrix bot with the client-server
+// API. The Bot follows its rooms with long polling sync requests and answers
+// the text messages, within a pandorabots session per room and sender. The
+// replies are sent as notices, which other bots do not answer, and the rate
+// limits of the homeserver are waited out.
+//
+// End-to-end encrypted rooms are not supported, their messages are ignored.
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// Bot answers Matrix messages with a pandorabots bot
+type Bot struct {
+	Client *pb.Client
+	Bot    string
+	// Homeserver is the base URL of the homeserver, e.g. https://matrix.org
+	Homeserver string
+	// AccessToken is the access token of the bot user
+	AccessToken string
+	// AutoJoin accepts the room invitations
+	AutoJoin bool
+	// RequireMention answers only the messages mentioning the bot, for busy rooms
+	RequireMention bool
+	// Store keeps the sessions, nil to keep them in memory
+	Store pb.SessionStore
+	// Configure sets up the conversations when they are created, e.g. their
+	// Policy, Limiter and Fallback, nil to keep the defaults
+	Configure func(cv *pb.Conversation)
+	// Filters clean the responses before they are sent. Defaults to splitting
+	// the breaks into messages, stripping the HTML and decoding the entities.
+	Filters []pb.ReplyFilter
+	// Typing delays the messages as if they were typed, nil to send them at once
+	Typing *pb.Typing
+	// HTTPClient sends the API requests. Defaults to a client with a timeout
+	// longer than the sync long polling.
+	HTTPClient *http.Client
+	// ErrorLog receives the errors of the messages and sync requests, nil to discard them
+	ErrorLog *log.Logger
+
+	once   sync.Once
+	cv     *pb.Conversation
+	userId string
+	txn    int64
+}
+
+// New creates a Matrix bot answering with the pandorabots bot
+func New(c *pb.Client, bot, homeserver, accessToken string) *Bot {
+	return &Bot{Client: c, Bot: bot, Homeserver: homeserver, AccessToken: accessToken, AutoJoin: true}
+}
+
+func (b *Bot) errorf(format string, args ...interface{}) {
+	if b.ErrorLog != nil {
+		b.ErrorLog.Printf(format, args...)
+	}
+}
+
+func (b *Bot) conversation() *pb.Conversation {
+	b.once.Do(func() {
+		b.cv = pb.NewConversation(b.Client, b.Bot, b.Store)
+		b.cv.ClientName = pb.HashedClientName("matrix-")
+		if b.Configure != nil {
+			b.Configure(b.cv)
+		}
+	})
+	return b.cv
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 90 * time.Second}
+
+// MatrixError is an error returned by the homeserver
+type MatrixError struct {
+	StatusCode   int
+	ErrCode      string `json:"errcode"`
+	Err          string `json:"error"`
+	RetryAfterMs int64  `json:"retry_after_ms"`
+}
+
+func (e *MatrixError) Error() string {
+	return fmt.Sprintf("Matrix request failed with status %d [%s: %s]", e.StatusCode, e.ErrCode, e.Err)
+}
+
+// request sends an API request, waiting out the rate limits
+func (b *Bot) request(ctx context.Context, method, path string, body interface{}, v interface{}) error {
+	client := b.HTTPClient
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(b.Homeserver, "/")+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+b.AccessToken)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode/100 == 2 {
+			defer resp.Body.Close()
+			if v != nil {
+				return json.NewDecoder(resp.Body).Decode(v)
+			}
+			io.Copy(io.Discard, resp.Body)
+			return nil
+		}
+		e := &MatrixError{StatusCode: resp.StatusCode}
+		json.NewDecoder(resp.Body).Decode(e)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= 5 {
+			return e
+		}
+		wait := time.Duration(e.RetryAfterMs) * time.Millisecond
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && wait == 0 {
+			wait = time.Duration(secs) * time.Second
+		}
+		if wait == 0 {
+			wait = time.Second << attempt
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// event is a room event
+type event struct {
+	Type    string `json:"type"`
+	Sender  string `json:"sender"`
+	EventId string `json:"event_id"`
+	Content struct {
+		MsgType string `json:"msgtype"`
+		Body    string `json:"body"`
+	} `json:"content"`
+}
+
+// syncResponse holds the parts of a sync response used by the bot
+type syncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []event `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+		Invite map[string]json.RawMessage `json:"invite"`
+	} `json:"rooms"`
+}
+
+// Send sends a text notice to the room
+func (b *Bot) Send(ctx context.Context, roomId, text string) error {
+	txn := fmt.Sprintf("pb%d.%d", time.Now().UnixNano(), atomic.AddInt64(&b.txn, 1))
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomId) + "/send/m.room.message/" + txn
+	return b.request(ctx, http.MethodPut, path, map[string]string{"msgtype": "m.notice", "body": text}, nil)
+}
+
+// typing sets the typing notification of the bot in the room
+func (b *Bot) typing(ctx context.Context, roomId string, typing bool) {
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomId) + "/typing/" + url.PathEscape(b.userId)
+	b.request(ctx, http.MethodPut, path, map[string]interface{}{"typing": typing, "timeout": 30000}, nil)
+}
+
+// mentioned returns the body without the mention of the bot, and whether it mentioned the bot
+func (b *Bot) mentioned(body string) (string, bool) {
+	localpart := strings.TrimPrefix(strings.SplitN(b.userId, ":", 2)[0], "@")
+	for _, name := range []string{b.userId, localpart} {
+		if i := strings.Index(strings.ToLower(body), strings.ToLower(name)); i >= 0 && name != "" {
+			body = body[:i] + body[i+len(name):]
+			return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(body), ":,")), true
+		}
+	}
+	return body, false
+}
+
+// handle answers a room event
+func (b *Bot) handle(ctx context.Context, roomId string, e *event) error {
+	if e.Type != "m.room.message" || e.Sender == b.userId || e.Content.MsgType != "m.text" {
+		return nil
+	}
+	text := strings.TrimSpace(e.Content.Body)
+	if b.RequireMention {
+		var ok bool
+		if text, ok = b.mentioned(text); !ok {
+			return nil
+		}
+	}
+	if text == "" {
+		return nil
+	}
+	b.typing(ctx, roomId, true)
+	defer b.typing(ctx, roomId, false)
+	reply, err := b.conversation().Talk(roomId+"/"+e.Sender, text)
+	if err != nil {
+		return err
+	}
+	filters := b.Filters
+	if filters == nil {
+		filters = []pb.ReplyFilter{pb.SplitBreaks, pb.StripHTML, pb.DecodeEntities, pb.CollapseWhitespace}
+	}
+	for i, r := range pb.FilterResponses(reply.Responses, filters...) {
+		if b.Typing != nil {
+			if i > 0 {
+				b.typing(ctx, roomId, true)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(b.Typing.Delay(r)):
+			}
+		}
+		if err = b.Send(ctx, roomId, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run follows the rooms of the bot and answers the messages until the context is cancelled
+func (b *Bot) Run(ctx context.Context) error {
+	var whoami struct {
+		UserId string `json:"user_id"`
+	}
+	if err := b.request(ctx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, &whoami); err != nil {
+		var e *MatrixError
+		if errors.As(err, &e) && e.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("Matrix access token is not valid - %v", err)
+		}
+		return err
+	}
+	b.userId = whoami.UserId
+
+	since, wait := "", time.Second
+	for ctx.Err() == nil {
+		q := url.Values{"timeout": {"30000"}}
+		if since != "" {
+			q.Set("since", since)
+		}
+		var res syncResponse
+		if err := b.request(ctx, http.MethodGet, "/_matrix/client/v3/sync?"+q.Encode(), nil, &res); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			b.errorf("Matrix sync failed, retrying in %v - %v", wait, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(wait):
+			}
+			if wait < time.Minute {
+				wait *= 2
+			}
+			continue
+		}
+		wait = time.Second
+		if b.AutoJoin {
+			for roomId := range res.Rooms.Invite {
+				if err := b.request(ctx, http.MethodPost, "/_matrix/client/v3/rooms/"+url.PathEscape(roomId)+"/join", struct{}{}, nil); err != nil {
+					b.errorf("Joining Matrix room %s failed - %v", roomId, err)
+				}
+			}
+		}
+		// The first sync returns the recent history, which was answered before.
+		// The messages are answered in order before the next sync.
+		if since != "" {
+			for roomId, room := range res.Rooms.Join {
+				for i := range room.Timeline.Events {
+					if err := b.handle(ctx, roomId, &room.Timeline.Events[i]); err != nil {
+						b.errorf("Matrix message in %s failed - %v", roomId, err)
+					}
+				}
+			}
+		}
+		since = res.NextBatch
+	}
+	return nil
+}
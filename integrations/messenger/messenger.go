@@ -0,0 +1,276 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package messenger connects a pandorabots bot to a Facebook page with the
+// Messenger Platform. The Handler is the webhook of the page app: it answers
+// the subscription verification, checks the signature of the events, sends
+// the text of the messages and postbacks to the bot, within a pandorabots
+// session per sender, and replies with the Send API, showing the typing
+// indicator while the bot is answering.
+package messenger
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+)
+
+// DefaultGraphUrl is the Graph API endpoint the Send API requests are sent to
+const DefaultGraphUrl = "https://graph.facebook.com/v19.0"
+
+// MaxTextLength is the Messenger limit of the text message length
+const MaxTextLength = 2000
+
+// User identifies the sender or recipient of a message
+type User struct {
+	Id string `json:"id"`
+}
+
+// Message is a received message
+type Message struct {
+	Mid        string `json:"mid"`
+	Text       string `json:"text"`
+	IsEcho     bool   `json:"is_echo"`
+	QuickReply *struct {
+		Payload string `json:"payload"`
+	} `json:"quick_reply"`
+}
+
+// Postback is a received button press
+type Postback struct {
+	Title   string `json:"title"`
+	Payload string `json:"payload"`
+}
+
+// Event is a messaging event of the webhook
+type Event struct {
+	Sender    User      `json:"sender"`
+	Recipient User      `json:"recipient"`
+	Timestamp int64     `json:"timestamp"`
+	Message   *Message  `json:"message"`
+	Postback  *Postback `json:"postback"`
+}
+
+// Callback is the body of the webhook requests
+type Callback struct {
+	Object string `json:"object"`
+	Entry  []struct {
+		Id        string  `json:"id"`
+		Time      int64   `json:"time"`
+		Messaging []Event `json:"messaging"`
+	} `json:"entry"`
+}
+
+// Handler is the Messenger webhook answering with the bot
+type Handler struct {
+	Conversation *pb.Conversation
+	// PageToken is the page access token the replies are sent with
+	PageToken string
+	// AppSecret is the secret of the app, used to check the event signatures.
+	// Empty skips the check, which is only meant for testing.
+	AppSecret string
+	// VerifyToken is the token entered when subscribing the webhook
+	VerifyToken string
+	// Filters clean the responses before they are sent. Defaults to splitting
+	// the breaks into messages, stripping the HTML and decoding the entities.
+	Filters []pb.ReplyFilter
+	// Typing delays the messages as if they were typed, nil to send them at once
+	Typing *pb.Typing
+	// MaxLength is the length the responses are split at, on the sentence
+	// boundaries where possible. Defaults to MaxTextLength.
+	MaxLength int
+	// GraphUrl is the Graph API endpoint. Defaults to DefaultGraphUrl.
+	GraphUrl string
+	// HTTPClient sends the Send API requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// ErrorLog receives the errors of the events, nil to discard them
+	ErrorLog *log.Logger
+}
+
+// NewHandler creates a webhook answering with the bot
+func NewHandler(c *pb.Client, bot string, store pb.SessionStore, pageToken, appSecret, verifyToken string) *Handler {
+	cv := pb.NewConversation(c, bot, store)
+	cv.ClientName = pb.HashedClientName("messenger-")
+	return &Handler{Conversation: cv, PageToken: pageToken, AppSecret: appSecret, VerifyToken: verifyToken}
+}
+
+func (h *Handler) errorf(format string, args ...interface{}) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+	}
+}
+
+// send posts a Send API request
+func (h *Handler) send(body interface{}) error {
+	graphUrl := h.GraphUrl
+	if graphUrl == "" {
+		graphUrl = DefaultGraphUrl
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	client := h.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(graphUrl+"/me/messages?access_token="+url.QueryEscape(h.PageToken), "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var res struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&res)
+		return fmt.Errorf("Send API request failed with status %d [%s]", resp.StatusCode, res.Error.Message)
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// SendText sends a text message to the user
+func (h *Handler) SendText(userId, text string) error {
+	return h.send(map[string]interface{}{
+		"recipient":      User{Id: userId},
+		"messaging_type": "RESPONSE",
+		"message":        map[string]string{"text": text},
+	})
+}
+
+// SendAction sends a sender action, like typing_on or typing_off, to the user
+func (h *Handler) SendAction(userId, action string) error {
+	return h.send(map[string]interface{}{
+		"recipient":     User{Id: userId},
+		"sender_action": action,
+	})
+}
+
+// input returns the bot input of the event, empty if it has none
+func input(e *Event) string {
+	switch {
+	case e.Message != nil && !e.Message.IsEcho:
+		if e.Message.QuickReply != nil && e.Message.QuickReply.Payload != "" {
+			return e.Message.QuickReply.Payload
+		}
+		return e.Message.Text
+	case e.Postback != nil:
+		if e.Postback.Payload != "" {
+			return e.Postback.Payload
+		}
+		return e.Postback.Title
+	}
+	return ""
+}
+
+// Handle answers a messaging event
+func (h *Handler) Handle(e *Event) error {
+	text := strings.TrimSpace(input(e))
+	if text == "" {
+		return nil
+	}
+	if err := h.SendAction(e.Sender.Id, "typing_on"); err != nil {
+		h.errorf("Messenger typing indicator failed - %v", err)
+	}
+	reply, err := h.Conversation.Talk(e.Sender.Id, text)
+	if err != nil {
+		h.SendAction(e.Sender.Id, "typing_off")
+		return err
+	}
+	filters := h.Filters
+	if filters == nil {
+		filters = []pb.ReplyFilter{pb.SplitBreaks, pb.StripHTML, pb.DecodeEntities, pb.CollapseWhitespace}
+	}
+	limit := h.MaxLength
+	if limit <= 0 || limit > MaxTextLength {
+		limit = MaxTextLength
+	}
+	responses := pb.SplitLength(limit)(pb.FilterResponses(reply.Responses, filters...))
+	if len(responses) == 0 {
+		return h.SendAction(e.Sender.Id, "typing_off")
+	}
+	for i, r := range responses {
+		if h.Typing != nil {
+			if i > 0 {
+				// Sending a message turns the typing indicator off
+				h.SendAction(e.Sender.Id, "typing_on")
+			}
+			h.Typing.Wait(r)
+		}
+		if err = h.SendText(e.Sender.Id, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validSignature checks the X-Hub-Signature-256 header of an event request
+func (h *Handler) validSignature(r *http.Request, body []byte) bool {
+	if h.AppSecret == "" {
+		return true
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(h.AppSecret))
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		// The subscription verification
+		q := r.URL.Query()
+		if q.Get("hub.mode") != "subscribe" || h.VerifyToken == "" || !hmac.Equal([]byte(q.Get("hub.verify_token")), []byte(h.VerifyToken)) {
+			http.Error(w, "Verification failed", http.StatusForbidden)
+			return
+		}
+		io.WriteString(w, q.Get("hub.challenge"))
+	case http.MethodPost:
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1024*1024))
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if !h.validSignature(r, body) {
+			h.errorf("Messenger event signature does not match")
+			http.Error(w, "Signature does not match", http.StatusForbidden)
+			return
+		}
+		cb := &Callback{}
+		if err = json.Unmarshal(body, cb); err != nil || cb.Object != "page" {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		// Messenger retries the events not acknowledged quickly, so answer
+		// them in the background, in order
+		go func() {
+			for _, entry := range cb.Entry {
+				for i := range entry.Messaging {
+					if err := h.Handle(&entry.Messaging[i]); err != nil {
+						h.errorf("Messenger event failed - %v", err)
+					}
+				}
+			}
+		}()
+		io.WriteString(w, "EVENT_RECEIVED")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
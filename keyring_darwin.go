@@ -0,0 +1,57 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build darwin
+
+package pb
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// securityNotFound is the exit code of the security command when the item is not in the keychain
+const securityNotFound = 44
+
+// securityQuote quotes the argument for the interactive mode of the security command
+func securityQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// security runs the security command, with the commands on standard input in interactive mode
+func security(stdin string, args ...string) (string, error) {
+	cmd := exec.Command("security", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var out, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &stderr
+	err := cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok && exit.ExitCode() == securityNotFound {
+		return "", ErrCredentialNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("Keychain command failed [security %s] - %v: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return strings.TrimSuffix(out.String(), "\n"), nil
+}
+
+func keyringGet(service, account string) (string, error) {
+	return security("", "find-generic-password", "-s", service, "-a", account, "-w")
+}
+
+// keyringSet passes the key on standard input, so it does not show in the process list
+func keyringSet(service, account, key string) error {
+	_, err := security(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		securityQuote(service), securityQuote(account), securityQuote(key)), "-i")
+	return err
+}
+
+func keyringDelete(service, account string) error {
+	_, err := security("", "delete-generic-password", "-s", service, "-a", account)
+	if err == ErrCredentialNotFound {
+		return nil
+	}
+	return err
+}
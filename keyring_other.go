@@ -0,0 +1,19 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !linux && !freebsd && !netbsd && !openbsd && !windows
+
+package pb
+
+func keyringGet(service, account string) (string, error) {
+	return "", ErrKeyringUnsupported
+}
+
+func keyringSet(service, account, key string) error {
+	return ErrKeyringUnsupported
+}
+
+func keyringDelete(service, account string) error {
+	return ErrKeyringUnsupported
+}
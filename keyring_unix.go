@@ -0,0 +1,58 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//go:build linux || freebsd || netbsd || openbsd
+
+package pb
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// secretTool runs the secret-tool command of libsecret with the input on standard input
+func secretTool(stdin string, args ...string) (string, *exec.ExitError, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return "", nil, fmt.Errorf("%v - install secret-tool (libsecret) to use the keyring", ErrKeyringUnsupported)
+	}
+	cmd := exec.Command("secret-tool", args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var out, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &stderr
+	err := cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok && stderr.Len() == 0 {
+		return "", exit, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("Keyring command failed [secret-tool %s] - %v: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return out.String(), nil, nil
+}
+
+func keyringGet(service, account string) (string, error) {
+	out, exit, err := secretTool("", "lookup", "service", service, "account", account)
+	if err != nil {
+		return "", err
+	}
+	// lookup fails silently when there is no such secret
+	if exit != nil || out == "" {
+		return "", ErrCredentialNotFound
+	}
+	return strings.TrimSuffix(out, "\n"), nil
+}
+
+func keyringSet(service, account, key string) error {
+	_, exit, err := secretTool(key, "store", "--label="+service+" "+account, "service", service, "account", account)
+	if err == nil && exit != nil {
+		err = fmt.Errorf("Keyring command failed [secret-tool store] - %v", exit)
+	}
+	return err
+}
+
+func keyringDelete(service, account string) error {
+	_, _, err := secretTool("", "clear", "service", service, "account", account)
+	return err
+}
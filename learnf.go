@@ -0,0 +1,105 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/demisto/pb-go/aiml"
+)
+
+// LearnfFile is the bot file the categories learned with <learnf> are saved to
+const LearnfFile = "learnf.aiml"
+
+// LearnedCategory is a category the bot learned from its users with <learnf>.
+// The pattern, that, topic and template are the content of the elements as XML.
+type LearnedCategory struct {
+	Pattern  string `json:"pattern"`
+	That     string `json:"that,omitempty"`
+	Topic    string `json:"topic,omitempty"`
+	Template string `json:"template"`
+	Line     int    `json:"line,omitempty"` // The line in LearnfFile, zero for new categories
+}
+
+// Learned returns the categories the bot learned, none if it has not learned any yet
+func (c *Client) Learned(bot string) ([]LearnedCategory, error) {
+	var buf bytes.Buffer
+	err := c.GetFile(bot, LearnfFile, &buf)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return []LearnedCategory{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc, err := aiml.Parse(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("File is not valid AIML [%s] - %v", LearnfFile, err)
+	}
+	learned := []LearnedCategory{}
+	for _, cat := range doc.Categories() {
+		l := LearnedCategory{Pattern: cat.Pattern, That: cat.That, Topic: cat.Topic, Line: cat.Line}
+		if cat.Template != nil {
+			l.Template = cat.Template.Inner()
+		}
+		learned = append(learned, l)
+	}
+	return learned, nil
+}
+
+// UploadLearned replaces the learned categories of the bot. The file is
+// uploaded as is, without rendering its placeholders, since the categories
+// come from the users. The bot must be verified for the change to apply.
+func (c *Client) UploadLearned(bot string, learned []LearnedCategory) error {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n<aiml>\n")
+	for _, l := range learned {
+		fmt.Fprintf(&buf, "<category><pattern>%s</pattern>", l.Pattern)
+		if l.That != "" {
+			fmt.Fprintf(&buf, "<that>%s</that>", l.That)
+		}
+		if l.Topic != "" {
+			fmt.Fprintf(&buf, "<topic>%s</topic>", l.Topic)
+		}
+		fmt.Fprintf(&buf, "<template>%s</template></category>\n", l.Template)
+	}
+	buf.WriteString("</aiml>\n")
+	data, err := aiml.Format(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("Learned categories are not valid AIML - %v", err)
+	}
+	rawurl, err := c.fileToUrl(bot, LearnfFile)
+	if err != nil {
+		return err
+	}
+	return c.do("PUT", rawurl, nil, bytes.NewReader(data), nil)
+}
+
+// PruneLearned removes the learned categories for which drop returns true,
+// e.g. the abusive ones, uploads the others and verifies the bot. It returns
+// the number of categories removed, and does not change the bot if none was.
+func (c *Client) PruneLearned(bot string, drop func(l LearnedCategory) bool) (int, error) {
+	learned, err := c.Learned(bot)
+	if err != nil {
+		return 0, err
+	}
+	kept := learned[:0]
+	for _, l := range learned {
+		if !drop(l) {
+			kept = append(kept, l)
+		}
+	}
+	removed := len(learned) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	if err = c.UploadLearned(bot, kept); err != nil {
+		return 0, err
+	}
+	return removed, c.Verify(bot)
+}
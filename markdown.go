@@ -0,0 +1,321 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// markdownDialect is the markup of a Markdown flavor
+type markdownDialect struct {
+	bold, italic, strike, code string
+	bullet                     string
+	link                       func(text, url string) string
+	escape                     func(text string) string
+}
+
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "~", `\~`, "[", `\[`, "]", `\]`)
+
+// commonMark is the Markdown of Discord, Teams and most chat clients
+var commonMark = &markdownDialect{
+	bold:   "**",
+	italic: "*",
+	strike: "~~",
+	code:   "`",
+	bullet: "- ",
+	link: func(text, url string) string {
+		return "[" + text + "](" + strings.NewReplacer("(", "%28", ")", "%29", " ", "%20").Replace(url) + ")"
+	},
+	escape: markdownEscaper.Replace,
+}
+
+// slackMrkdwn is the mrkdwn of Slack messages
+var slackMrkdwn = &markdownDialect{
+	bold:   "*",
+	italic: "_",
+	strike: "~",
+	code:   "`",
+	bullet: "• ",
+	link: func(text, url string) string {
+		url = strings.NewReplacer("|", "%7C", ">", "%3E", " ", "%20").Replace(url)
+		if text == "" {
+			return "<" + url + ">"
+		}
+		return "<" + url + "|" + text + ">"
+	},
+	escape: strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace,
+}
+
+// Markdown converts the HTML of the responses, like links, bold text and
+// lists, to Markdown so the formatting survives in chat clients rendering it.
+// The other tags are removed and the entities decoded.
+func Markdown(responses []string) []string {
+	return mapResponses(responses, commonMark.convert)
+}
+
+// SlackMarkdown converts the HTML of the responses to the mrkdwn of Slack
+func SlackMarkdown(responses []string) []string {
+	return mapResponses(responses, slackMrkdwn.convert)
+}
+
+var (
+	tagNameRe = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9]*)`)
+	hrefRe    = regexp.MustCompile(`(?i)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	srcRe     = regexp.MustCompile(`(?i)\bsrc\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	altRe     = regexp.MustCompile(`(?i)\balt\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	blankRe   = regexp.MustCompile(`\n{3,}`)
+)
+
+// attr returns the value of the attribute the regexp matches in the tag
+func attr(re *regexp.Regexp, tag string) string {
+	m := re.FindStringSubmatch(tag)
+	if m == nil {
+		return ""
+	}
+	return html.UnescapeString(m[1] + m[2] + m[3])
+}
+
+// markdownList is an open list of a conversion
+type markdownList struct {
+	ordered bool
+	n       int
+}
+
+// markdownLink is an open link of a conversion
+type markdownLink struct {
+	url   string
+	start int // The start of the link text in the output
+}
+
+// markdownWriter converts a response
+type markdownWriter struct {
+	d       *markdownDialect
+	out     []byte
+	pending string // Opening markers written before the next text, so they enclose no spaces
+	lists   []markdownList
+	links   []markdownLink
+	pre     bool
+	code    bool
+}
+
+func (w *markdownWriter) lastByte() byte {
+	if len(w.out) == 0 {
+		return '\n'
+	}
+	return w.out[len(w.out)-1]
+}
+
+// newlines ends the current line followed by n-1 blank lines
+func (w *markdownWriter) newlines(n int) {
+	if len(w.out) == 0 {
+		return
+	}
+	w.out = bytes.TrimRight(w.out, " ")
+	for i := len(w.out) - 1; i >= 0 && w.out[i] == '\n'; i-- {
+		n--
+	}
+	for ; n > 0; n-- {
+		w.out = append(w.out, '\n')
+	}
+}
+
+func (w *markdownWriter) flush() {
+	w.out = append(w.out, w.pending...)
+	w.pending = ""
+}
+
+func (w *markdownWriter) text(s string) {
+	s = html.UnescapeString(s)
+	if s == "" {
+		return
+	}
+	if !w.pre {
+		// Runs of whitespace are single spaces in HTML
+		first, _ := utf8.DecodeRuneInString(s)
+		last, _ := utf8.DecodeLastRuneInString(s)
+		words := strings.Join(strings.Fields(s), " ")
+		if unicode.IsSpace(first) && w.lastByte() != ' ' && w.lastByte() != '\n' {
+			words = " " + words
+		}
+		if unicode.IsSpace(last) && strings.TrimSpace(words) != "" {
+			words += " "
+		}
+		if s = words; s == "" {
+			return
+		}
+	}
+	if w.d != commonMark || !(w.code || w.pre) {
+		// The Markdown of code is literal
+		s = w.d.escape(s)
+	}
+	if w.pending != "" && s[0] == ' ' {
+		w.out = append(w.out, ' ')
+		s = s[1:]
+	}
+	if s == "" {
+		return
+	}
+	w.flush()
+	w.out = append(w.out, s...)
+}
+
+// open starts an inline markup
+func (w *markdownWriter) open(marker string) {
+	w.pending += marker
+}
+
+// close ends an inline markup, keeping the spaces out of it
+func (w *markdownWriter) close(marker string) {
+	if strings.HasSuffix(w.pending, marker) {
+		// Empty markup
+		w.pending = strings.TrimSuffix(w.pending, marker)
+		return
+	}
+	space := w.lastByte() == ' '
+	if space {
+		w.out = w.out[:len(w.out)-1]
+	}
+	w.out = append(w.out, marker...)
+	if space {
+		w.out = append(w.out, ' ')
+	}
+}
+
+func (w *markdownWriter) tag(tag string) {
+	m := tagNameRe.FindStringSubmatch(tag)
+	if m == nil {
+		return
+	}
+	closing, name := m[1] == "/", strings.ToLower(m[2])
+	if w.pre && name != "pre" {
+		return
+	}
+	switch name {
+	case "b", "strong", "h1", "h2", "h3", "h4", "h5", "h6":
+		if name[0] == 'h' && !closing {
+			w.newlines(2)
+		}
+		if closing {
+			w.close(w.d.bold)
+		} else {
+			w.open(w.d.bold)
+		}
+		if name[0] == 'h' && closing {
+			w.newlines(2)
+		}
+	case "i", "em":
+		if closing {
+			w.close(w.d.italic)
+		} else {
+			w.open(w.d.italic)
+		}
+	case "s", "strike", "del":
+		if closing {
+			w.close(w.d.strike)
+		} else {
+			w.open(w.d.strike)
+		}
+	case "code", "tt":
+		if closing {
+			w.close(w.d.code)
+		} else {
+			w.open(w.d.code)
+		}
+		w.code = !closing
+	case "pre":
+		if closing {
+			w.newlines(1)
+			w.out = append(w.out, "```"...)
+			w.newlines(2)
+		} else {
+			w.newlines(2)
+			w.flush()
+			w.out = append(w.out, "```\n"...)
+		}
+		w.pre = !closing
+	case "a":
+		if !closing {
+			w.flush()
+			w.links = append(w.links, markdownLink{url: attr(hrefRe, tag), start: len(w.out)})
+		} else if n := len(w.links); n > 0 {
+			l := w.links[n-1]
+			w.links = w.links[:n-1]
+			text := strings.TrimSpace(string(w.out[l.start:]))
+			w.out = w.out[:l.start]
+			w.pending = ""
+			switch {
+			case l.url == "":
+				w.out = append(w.out, text...)
+			case text == "" && w.d == commonMark:
+				w.out = append(w.out, w.d.link(w.d.escape(l.url), l.url)...)
+			default:
+				w.out = append(w.out, w.d.link(text, l.url)...)
+			}
+		}
+	case "img":
+		if src := attr(srcRe, tag); src != "" {
+			w.flush()
+			w.out = append(w.out, w.d.link(w.d.escape(attr(altRe, tag)), src)...)
+		}
+	case "br":
+		if len(w.out) > 0 {
+			w.out = append(bytes.TrimRight(w.out, " "), '\n')
+		}
+	case "p", "div", "blockquote":
+		w.newlines(2)
+	case "ul", "ol":
+		if closing {
+			if n := len(w.lists); n > 0 {
+				w.lists = w.lists[:n-1]
+			}
+			w.newlines(2)
+		} else {
+			w.newlines(1)
+			w.lists = append(w.lists, markdownList{ordered: name == "ol"})
+		}
+	case "li":
+		if closing {
+			w.newlines(1)
+			break
+		}
+		w.newlines(1)
+		bullet := w.d.bullet
+		if n := len(w.lists); n > 0 {
+			l := &w.lists[n-1]
+			l.n++
+			w.out = append(w.out, strings.Repeat("  ", n-1)...)
+			if l.ordered {
+				bullet = strconv.Itoa(l.n) + ". "
+			}
+		}
+		w.out = append(w.out, bullet...)
+	}
+}
+
+// convert returns the Markdown of the HTML response
+func (d *markdownDialect) convert(response string) string {
+	w := &markdownWriter{d: d}
+	for len(response) > 0 {
+		loc := tagRe.FindStringIndex(response)
+		if loc == nil {
+			w.text(response)
+			break
+		}
+		w.text(response[:loc[0]])
+		w.tag(response[loc[0]:loc[1]])
+		response = response[loc[1]:]
+	}
+	lines := strings.Split(string(w.out), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.TrimSpace(blankRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
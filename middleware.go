@@ -0,0 +1,82 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"log"
+	"time"
+)
+
+// TalkFunc sends the input of the user with the key to the bot, like Conversation.Talk
+type TalkFunc func(key, input string) (*Reply, error)
+
+// Middleware wraps the talks of a Conversation, the way net/http middleware
+// wraps handlers. It can change the input before calling next, change the
+// reply after, or answer without calling next at all, e.g. to log, translate,
+// cache or moderate the conversations.
+type Middleware func(next TalkFunc) TalkFunc
+
+// Chain returns talk wrapped by the middleware, the first being the outermost
+func Chain(talk TalkFunc, middleware ...Middleware) TalkFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		talk = middleware[i](talk)
+	}
+	return talk
+}
+
+// Use appends the middleware to the Middleware of the conversation. It must
+// not be called while the conversation is talking.
+func (cv *Conversation) Use(middleware ...Middleware) {
+	cv.Middleware = append(cv.Middleware, middleware...)
+}
+
+// LogTalks logs the inputs, the responses, the latency and the errors of the talks
+func LogTalks(l *log.Logger) Middleware {
+	return func(next TalkFunc) TalkFunc {
+		return func(key, input string) (*Reply, error) {
+			start := time.Now()
+			reply, err := next(key, input)
+			elapsed := time.Since(start).Round(time.Millisecond)
+			if err != nil {
+				l.Printf("[%s] %q failed after %v - %v", key, input, elapsed, err)
+			} else {
+				l.Printf("[%s] %q -> %q in %v", key, input, reply.Responses, elapsed)
+			}
+			return reply, err
+		}
+	}
+}
+
+// Moderate answers the inputs rejected by the function with the reply,
+// without sending them to the bot. Empty to answer with no responses.
+func Moderate(reject func(key, input string) bool, reply string) Middleware {
+	return func(next TalkFunc) TalkFunc {
+		return func(key, input string) (*Reply, error) {
+			if !reject(key, input) {
+				return next(key, input)
+			}
+			res := &Reply{Responses: []string{}, Info: ReplyInfo{Moderated: true}}
+			if reply != "" {
+				res.Responses = append(res.Responses, reply)
+			}
+			return res, nil
+		}
+	}
+}
+
+// Middleware answers the errors of the talks with the fallback reply, like
+// the Fallback of a Conversation but around the middleware after it in the
+// chain, e.g. to answer the errors of a translation service too
+func (f *Fallback) Middleware() Middleware {
+	return func(next TalkFunc) TalkFunc {
+		return func(key, input string) (*Reply, error) {
+			reply, err := next(key, input)
+			if fb, ok := f.Answer(err); ok {
+				return fb, nil
+			}
+			return reply, err
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"encoding/xml"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Command is an out of band command of a response, an element of an <oob>
+// element which the client carries out instead of displaying, e.g.
+//
+//	<oob><alarm><hour>7</hour><minute>30</minute></alarm></oob>
+//
+// is the command alarm with the arguments hour and minute.
+type Command struct {
+	Name string            `json:"name"`           // The element name, e.g. dial, url, map
+	Text string            `json:"text,omitempty"` // The text of the element, e.g. the number to dial
+	Args map[string]string `json:"args,omitempty"` // The attributes and the texts of the child elements
+}
+
+func (c Command) String() string {
+	s := c.Name
+	if c.Text != "" {
+		s += " " + c.Text
+	}
+	keys := make([]string, 0, len(c.Args))
+	for k := range c.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s += " " + k + "=" + c.Args[k]
+	}
+	return s
+}
+
+var oobRe = regexp.MustCompile(`(?is)[ \t]*<oob\s*>(.*?)</oob\s*>`)
+
+// ParseOOB removes the <oob> elements from the response, returning the rest
+// of the response and the commands of the elements
+func ParseOOB(response string) (string, []Command) {
+	var commands []Command
+	text := oobRe.ReplaceAllStringFunc(response, func(element string) string {
+		commands = append(commands, parseCommands(oobRe.FindStringSubmatch(element)[1])...)
+		return ""
+	})
+	if commands == nil && text == response {
+		return response, nil
+	}
+	return strings.TrimSpace(text), commands
+}
+
+// parseCommands parses the elements of an <oob> element. The bots output
+// HTML rather than XML, so the parsing is lenient.
+func parseCommands(oob string) []Command {
+	d := xml.NewDecoder(strings.NewReader(oob))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	var commands []Command
+	var cmd *Command
+	var arg string
+	var text strings.Builder
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch depth {
+			case 1:
+				commands = append(commands, Command{Name: strings.ToLower(t.Name.Local)})
+				cmd = &commands[len(commands)-1]
+				for _, a := range t.Attr {
+					cmd.setArg(a.Name.Local, a.Value)
+				}
+			case 2:
+				arg = strings.ToLower(t.Name.Local)
+				text.Reset()
+			default:
+				// Keep the words of nested markup like <br> apart
+				text.WriteByte(' ')
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				cmd.setArg(arg, strings.Join(strings.Fields(text.String()), " "))
+			}
+			if depth > 0 {
+				depth--
+			}
+		case xml.CharData:
+			switch {
+			case depth == 1:
+				cmd.Text += string(t)
+			case depth > 1:
+				text.Write(t)
+			}
+		}
+	}
+	for i := range commands {
+		commands[i].Text = strings.Join(strings.Fields(commands[i].Text), " ")
+	}
+	return commands
+}
+
+func (c *Command) setArg(name, value string) {
+	if c.Args == nil {
+		c.Args = make(map[string]string)
+	}
+	c.Args[strings.ToLower(name)] = value
+}
+
+// extractCommands moves the out of band commands of the responses to the Commands of the reply
+func (r *Reply) extractCommands() {
+	responses := r.Responses[:0]
+	for _, response := range r.Responses {
+		text, commands := ParseOOB(response)
+		r.Commands = append(r.Commands, commands...)
+		if text != "" {
+			responses = append(responses, text)
+		}
+	}
+	r.Responses = responses
+}
@@ -0,0 +1,231 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// The Thrift compact protocol types of the Parquet metadata
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the Parquet metadata structures with the Thrift compact protocol
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16 // The id of the last field written, per nested struct
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	t.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func (t *thriftWriter) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.last[len(t.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, thriftBinary)
+	t.varint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+// list writes the header of a list field of n elements of the type
+func (t *thriftWriter) list(id int16, typ byte, n int) {
+	t.field(id, thriftList)
+	if n < 15 {
+		t.buf.WriteByte(byte(n)<<4 | typ)
+		return
+	}
+	t.buf.WriteByte(0xf0 | typ)
+	t.varint(uint64(n))
+}
+
+// begin starts a struct, a field if id is not zero or else a list element or the top level struct
+func (t *thriftWriter) begin(id int16) {
+	if id != 0 {
+		t.field(id, thriftStruct)
+	}
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) end() {
+	t.buf.WriteByte(0)
+	t.last = t.last[:len(t.last)-1]
+}
+
+// The Parquet enums used by writeParquet
+const (
+	parquetInt64           = 2
+	parquetDouble          = 5
+	parquetByteArray       = 6
+	parquetRequired        = 0
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+	parquetPlain           = 0
+	parquetRLE             = 3
+	parquetDataPage        = 0
+)
+
+// parquetColumn is a column of the export schema with its values PLAIN encoded
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32 // -1 for none
+	data      bytes.Buffer
+}
+
+func (c *parquetColumn) int64(v int64) {
+	binary.Write(&c.data, binary.LittleEndian, v)
+}
+
+func (c *parquetColumn) double(v float64) {
+	binary.Write(&c.data, binary.LittleEndian, math.Float64bits(v))
+}
+
+func (c *parquetColumn) string(s string) {
+	binary.Write(&c.data, binary.LittleEndian, uint32(len(s)))
+	c.data.WriteString(s)
+}
+
+// writeParquet writes the rows as a Parquet file of a single row group, with
+// a single uncompressed data page per column. All the columns are required,
+// so the pages have no definition and repetition levels.
+func writeParquet(w io.Writer, rows []ExportRow) error {
+	columns := []*parquetColumn{
+		{name: "time", typ: parquetInt64, converted: parquetTimestampMicros},
+		{name: "bot", typ: parquetByteArray, converted: parquetUTF8},
+		{name: "client_name", typ: parquetByteArray, converted: parquetUTF8},
+		{name: "session_id", typ: parquetInt64, converted: -1},
+		{name: "turn", typ: parquetInt64, converted: -1},
+		{name: "input", typ: parquetByteArray, converted: parquetUTF8},
+		{name: "response", typ: parquetByteArray, converted: parquetUTF8},
+		{name: "latency_ms", typ: parquetDouble, converted: -1},
+	}
+	for _, r := range rows {
+		columns[0].int64(r.Time.UnixMicro())
+		columns[1].string(r.Bot)
+		columns[2].string(r.ClientName)
+		columns[3].int64(r.SessionId)
+		columns[4].int64(r.Turn)
+		columns[5].string(r.Input)
+		columns[6].string(r.Response)
+		columns[7].double(r.LatencyMs)
+	}
+
+	var file bytes.Buffer
+	file.WriteString("PAR1")
+	type chunk struct {
+		offset, size int64
+	}
+	chunks := make([]chunk, len(columns))
+	if len(rows) > 0 {
+		for i, c := range columns {
+			header := &thriftWriter{}
+			header.begin(0)
+			header.i32(1, parquetDataPage)
+			header.i32(2, int32(c.data.Len()))
+			header.i32(3, int32(c.data.Len()))
+			header.begin(5)
+			header.i32(1, int32(len(rows)))
+			header.i32(2, parquetPlain)
+			header.i32(3, parquetRLE)
+			header.i32(4, parquetRLE)
+			header.end()
+			header.end()
+			chunks[i] = chunk{int64(file.Len()), int64(header.buf.Len() + c.data.Len())}
+			file.Write(header.buf.Bytes())
+			file.Write(c.data.Bytes())
+		}
+	}
+
+	meta := &thriftWriter{}
+	meta.begin(0)
+	meta.i32(1, 1)
+	meta.list(2, thriftStruct, len(columns)+1)
+	meta.begin(0)
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(columns)))
+	meta.end()
+	for _, c := range columns {
+		meta.begin(0)
+		meta.i32(1, c.typ)
+		meta.i32(3, parquetRequired)
+		meta.binary(4, c.name)
+		if c.converted >= 0 {
+			meta.i32(6, c.converted)
+		}
+		meta.end()
+	}
+	meta.i64(3, int64(len(rows)))
+	if len(rows) == 0 {
+		meta.list(4, thriftStruct, 0)
+	} else {
+		meta.list(4, thriftStruct, 1)
+		meta.begin(0)
+		meta.list(1, thriftStruct, len(columns))
+		total := int64(0)
+		for i, c := range columns {
+			meta.begin(0)
+			meta.i64(2, chunks[i].offset)
+			meta.begin(3)
+			meta.i32(1, c.typ)
+			meta.list(2, thriftI32, 1)
+			meta.zigzag(parquetPlain)
+			meta.list(3, thriftBinary, 1)
+			meta.varint(uint64(len(c.name)))
+			meta.buf.WriteString(c.name)
+			meta.i32(4, 0) // Uncompressed
+			meta.i64(5, int64(len(rows)))
+			meta.i64(6, chunks[i].size)
+			meta.i64(7, chunks[i].size)
+			meta.i64(9, chunks[i].offset)
+			meta.end()
+			meta.end()
+			total += chunks[i].size
+		}
+		meta.i64(2, total)
+		meta.i64(3, int64(len(rows)))
+		meta.end()
+	}
+	meta.binary(6, "pb-go")
+	meta.end()
+
+	file.Write(meta.buf.Bytes())
+	binary.Write(&file, binary.LittleEndian, uint32(meta.buf.Len()))
+	file.WriteString("PAR1")
+	_, err := w.Write(file.Bytes())
+	return err
+}
@@ -6,6 +6,8 @@
 package pb
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -18,7 +20,9 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -32,6 +36,32 @@ var (
 	ErrNoCred = errors.New("Missing application ID or user key")
 )
 
+// DecodeError is returned when a response body could not be decoded.
+// The raw body is attached so schema changes on the pandorabots side can be diagnosed.
+type DecodeError struct {
+	Err  error  // The underlying JSON error
+	Body []byte // The raw response body
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("Unable to decode response - %v: %s", e.Err, string(e.Body))
+}
+
+// Unwrap returns the underlying JSON error
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// APIError is returned when pandorabots responds with a status code different from success
+type APIError struct {
+	StatusCode int    // The HTTP status code
+	Body       []byte // The raw response body which might contain more details
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Unexpected status code: %d (%s)", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
 // Client interacts with the services provided by pandorabots.
 type Client struct {
 	appId    string       // ID of the application we are using
@@ -40,6 +70,34 @@ type Client struct {
 	errorlog *log.Logger  // Optional logger to write errors to
 	tracelog *log.Logger  // Optional logger to write trace and debug data to
 	c        *http.Client // The client to use for requests
+	strict   bool         // Fail on unknown fields in responses
+	onStart  RequestHook  // Optional hook called before each request
+	onEnd    RequestHook  // Optional hook called after each request
+
+	timeout   time.Duration // Optional limit of each request
+	retries   int           // How many times to retry failed requests
+	retryWait time.Duration // The wait before the first retry
+
+	mirrors   []string      // The endpoints to fail over to
+	cooldown  time.Duration // How long the failed endpoints are avoided
+	endpoints *endpointPool // Selects the endpoint of each request when failing over
+
+	parallelism int // How many bots or files the batch operations process at once
+
+	filters      []ReplyFilter // Applied to the responses of talk replies
+	inputFilters []InputFilter // Applied to the inputs of talk requests
+	maxInput     int           // Longer inputs are sent in chunks, zero for no limit
+	callouts     *Callouts     // Expanded in the responses of talk replies
+	cache        *ReplyCache   // Answers the repeated talk requests, nil for no cache
+	hedgeAfter   time.Duration // Talk requests slower than this are sent again, zero to not hedge
+	anonymizer   *Anonymizer   // Replaces the client names of talk requests by pseudonyms, nil to send them as is
+
+	values map[string]string // The values of the placeholders of the uploaded files, nil to upload them as is
+	files  *FileCache        // Keeps the files of the searched bots, nil to download them on each search
+
+	encryptor   *Encryptor        // Encrypts the backup archives, nil to write them as is
+	credentials CredentialStore   // Gives the user key when only the application ID is set, nil for none
+	progress    ProgressEventFunc // Receives the progress of the long running operations, nil for none
 }
 
 // OptionFunc is a function that configures a Client.
@@ -66,9 +124,9 @@ func (c *Client) tracef(format string, args ...interface{}) {
 //
 // Example:
 //
-//   client, err := pb.New(
-//     pb.SetErrorLog(log.New(os.Stderr, "PB: ", log.Lshortfile),
-//     pb.SetCredentials(appId, userKey))
+//	client, err := pb.New(
+//	  pb.SetErrorLog(log.New(os.Stderr, "PB: ", log.Lshortfile),
+//	  pb.SetCredentials(appId, userKey))
 //
 // If no URL is configured, Client uses DefaultURL by default.
 //
@@ -88,8 +146,11 @@ func New(options ...OptionFunc) (*Client, error) {
 			return nil, err
 		}
 	}
+	if err := c.storedUserKey(); err != nil {
+		return nil, err
+	}
 	if c.appId == "" || c.userKey == "" {
-		c.errorf(ErrNoCred.Error())
+		c.errorf("%v\n", ErrNoCred)
 		return nil, ErrNoCred
 	}
 	if c.url == "" {
@@ -99,6 +160,10 @@ func New(options ...OptionFunc) (*Client, error) {
 		c.url = c.url[0 : len(c.url)-1]
 	}
 	c.tracef("Using URL [%s]\n", c.url)
+	if len(c.mirrors) > 0 {
+		c.endpoints = newEndpointPool(c.cooldown, append([]string{c.url}, c.mirrors...))
+		c.tracef("Failing over to %v\n", c.mirrors)
+	}
 
 	return c, nil
 }
@@ -132,21 +197,39 @@ func SetUrl(rawurl string) OptionFunc {
 		if rawurl == "" {
 			rawurl = DefaultURL
 		}
-		u, err := url.Parse(rawurl)
-		if err != nil {
-			c.errorf("Invalid URL [%s] - %v\n", rawurl, err)
+		if err := c.checkUrl(rawurl); err != nil {
 			return err
 		}
-		if u.Scheme != "http" && u.Scheme != "https" {
-			msg := fmt.Sprintf("Invalid schema specified [%s]", rawurl)
-			c.errorf(msg)
-			return errors.New(msg)
-		}
 		c.url = rawurl
 		return nil
 	}
 }
 
+// checkUrl validates an API URL
+func (c *Client) checkUrl(rawurl string) error {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		c.errorf("Invalid URL [%s] - %v\n", rawurl, err)
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		msg := fmt.Sprintf("Invalid schema specified [%s]", rawurl)
+		c.errorf("%s\n", msg)
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// SetStrictDecoding controls how responses are decoded. In strict mode unknown
+// fields in a response are treated as an error, which helps detecting changes
+// in the pandorabots API. The default is lenient decoding.
+func SetStrictDecoding(strict bool) OptionFunc {
+	return func(c *Client) error {
+		c.strict = strict
+		return nil
+	}
+}
+
 // SetErrorLog sets the logger for critical messages. It is nil by default.
 func SetErrorLog(logger *log.Logger) func(*Client) error {
 	return func(c *Client) error {
@@ -195,9 +278,10 @@ func (c *Client) handleError(resp *http.Response) error {
 				c.errorf("%s\n", string(out))
 			}
 		}
-		msg := fmt.Sprintf("Unexpected status code: %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
-		c.errorf(msg)
-		return errors.New(msg)
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		apiErr.Body, _ = io.ReadAll(resp.Body)
+		c.errorf("%v\n", apiErr)
+		return apiErr
 	}
 	return nil
 }
@@ -210,61 +294,161 @@ func (c *Client) botUrl(action, botName string) string {
 	return c.appUrl(action) + "/" + botName
 }
 
+// bodyReader consumes the body of a successful response
+type bodyReader func(r io.Reader) error
+
 // do executes the API request.
 // Returns the response if the status code is between 200 and 299
 // `body` is an optional body for the POST requests.
-func (c *Client) do(method, rawurl string, params map[string]string, body io.Reader, result interface{}) error {
+// `read` is an optional function that consumes the response body.
+func (c *Client) do(method, rawurl string, params map[string]string, body io.Reader, read bodyReader) error {
+	return c.doCounted(method, rawurl, params, body, read, nil)
+}
+
+// doCounted executes the API request like do, adding the HTTP requests sent to sent
+func (c *Client) doCounted(method, rawurl string, params map[string]string, body io.Reader, read bodyReader, sent *int64) error {
+	info := c.requestInfo(method, rawurl)
+	isTalk := strings.HasPrefix(info.Endpoint, talk+"/")
+	wait := c.retryWait
+	for info.Attempt = 1; ; info.Attempt++ {
+		info.StatusCode, info.Err, info.Elapsed = 0, nil, 0
+		if c.onStart != nil {
+			c.onStart(info)
+		}
+		start := time.Now()
+		info.StatusCode, info.Err = c.doFailover(method, rawurl, params, body, read, sent)
+		info.Elapsed = time.Since(start)
+		if c.onEnd != nil {
+			c.onEnd(info)
+		}
+		if info.Attempt > c.retries || !retryable(info.StatusCode, info.Err, isTalk) || !rewind(body) {
+			return info.Err
+		}
+		c.tracef("Retrying %s %s in %v - %v\n", method, info.Endpoint, wait, info.Err)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// doFailover sends the request to the endpoints in order until one serves it
+func (c *Client) doFailover(method, rawurl string, params map[string]string, body io.Reader, read bodyReader, sent *int64) (int, error) {
+	if c.endpoints == nil {
+		count(sent)
+		return c.doOnce(method, rawurl, params, body, read)
+	}
+	path := strings.TrimPrefix(rawurl, c.url)
+	var statusCode int
+	var err error
+	for i, base := range c.bases() {
+		if i > 0 {
+			if !rewind(body) {
+				break
+			}
+			c.tracef("Failing over %s %s to %s - %v\n", method, path, base, err)
+		}
+		start := time.Now()
+		count(sent)
+		statusCode, err = c.doOnce(method, base+path, params, body, read)
+		failed := endpointFailed(statusCode, err)
+		c.endpoints.record(base, time.Since(start), failed)
+		if !failed {
+			break
+		}
+	}
+	return statusCode, err
+}
+
+// doOnce executes a single HTTP round trip and returns the status code received (if any)
+func (c *Client) doOnce(method, rawurl string, params map[string]string, body io.Reader, read bodyReader) (int, error) {
 	values := url.Values{}
 	values.Set("user_key", c.userKey)
 	for k, v := range params {
 		values.Add(k, v)
 	}
 
-	req, err := http.NewRequest(method, rawurl+"?"+values.Encode(), body)
+	ctx := context.Background()
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	if _, ok := body.(io.Closer); ok {
+		// Keep the transport from closing the body so it can be rewound for retries
+		body = io.NopCloser(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawurl+"?"+values.Encode(), body)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	c.dumpRequest(req)
 
 	resp, err := c.c.Do(req)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if resp.Body != nil {
 		defer resp.Body.Close()
 	}
 	if err = c.handleError(resp); err != nil {
-		return err
+		return resp.StatusCode, err
 	}
 	c.dumpResponse(resp)
-	if result != nil {
-		switch result.(type) {
-		// Should we just dump the response body
-		case io.Writer:
-			if _, err = io.Copy(result.(io.Writer), resp.Body); err != nil {
-				return err
-			}
-		default:
-			if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
-				return err
-			}
-		}
+	if read != nil {
+		return resp.StatusCode, read(resp.Body)
+	}
+	return resp.StatusCode, nil
+}
+
+// doJSON executes the API request and decodes the JSON response into a T
+func doJSON[T any](c *Client, method, rawurl string, params map[string]string, body io.Reader) (T, error) {
+	var result T
+	err := c.do(method, rawurl, params, body, func(r io.Reader) error {
+		return decode(c, r, &result)
+	})
+	return result, err
+}
+
+// doWriter executes the API request and copies the raw response body to w
+func (c *Client) doWriter(method, rawurl string, params map[string]string, body io.Reader, w io.Writer) error {
+	return c.do(method, rawurl, params, body, func(r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// decode reads the JSON body into result according to the decoding mode
+func decode[T any](c *Client, r io.Reader, result *T) error {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if c.strict {
+		dec.DisallowUnknownFields()
+	}
+	if err = dec.Decode(result); err != nil {
+		c.errorf("Unable to decode response - %v\n", err)
+		return &DecodeError{Err: err, Body: raw}
 	}
 	return nil
 }
 
 type BotEntry struct {
-	Name        string `json:"botname"`
-	Description string `json:"description"`
-	Language    string `json:"language"`
-	Compiled    string `json:"compiled"`
-	Open        string `json:"open"`
+	Name        string   `json:"botname"`
+	Description string   `json:"description"`
+	Language    Language `json:"language"`
+	Compiled    Bool     `json:"compiled"`
+	Open        Bool     `json:"open"`
+	Created     Time     `json:"created"`
+	Modified    Time     `json:"modified"`
 }
 
 // See https://developer.pandorabots.com/docs#!/pandorabots_api_swagger_1_2_beta/listBots
 func (c *Client) List() ([]BotEntry, error) {
-	result := make([]BotEntry, 0)
-	err := c.do("GET", c.appUrl(bot), nil, nil, &result)
+	result, err := doJSON[[]BotEntry](c, "GET", c.appUrl(bot), nil, nil)
+	if result == nil {
+		result = make([]BotEntry, 0)
+	}
 	return result, err
 }
 
@@ -279,11 +463,11 @@ func (c *Client) DeleteBot(name string) error {
 }
 
 type BotFile struct {
-	Name      string    `json:"name"`
-	Size      int64     `json:"size"`
-	Modified  time.Time `json:"modified"`
-	LoadOrder int       `json:"loadorder"`
-	Items     int       `json:"items"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	Modified  Time   `json:"modified"`
+	LoadOrder int    `json:"loadorder"`
+	Items     int    `json:"items"`
 }
 
 type BotFiles struct {
@@ -291,9 +475,9 @@ type BotFiles struct {
 	Appname       string    `json:"appname"`
 	Botname       string    `json:"botname"`
 	Description   string    `json:"description"`
-	Language      string    `json:"language"`
-	Created       time.Time `json:"created"`
-	Open          string    `json:"open"`
+	Language      Language  `json:"language"`
+	Created       Time      `json:"created"`
+	Open          Bool      `json:"open"`
 	Files         []BotFile `json:"files"`
 	Sets          []BotFile `json:"sets"`
 	Maps          []BotFile `json:"maps"`
@@ -304,14 +488,12 @@ type BotFiles struct {
 
 // See https://developer.pandorabots.com/docs#!/pandorabots_api_swagger_1_2_beta/listBotFiles
 func (c *Client) ListFiles(name string) (BotFiles, error) {
-	var result BotFiles
-	err := c.do("GET", c.botUrl(bot, name), nil, nil, &result)
-	return result, err
+	return doJSON[BotFiles](c, "GET", c.botUrl(bot, name), nil, nil)
 }
 
 // See https://developer.pandorabots.com/docs#!/pandorabots_api_swagger_1_2_beta/listBotFiles
 func (c *Client) DownloadFiles(name string, zip io.Writer) error {
-	return c.do("GET", c.botUrl(bot, name), map[string]string{"return": "zip"}, nil, zip)
+	return c.doWriter("GET", c.botUrl(bot, name), map[string]string{"return": "zip"}, nil, zip)
 }
 
 // See https://developer.pandorabots.com/docs#!/pandorabots_api_swagger_1_2_beta/listBotFiles
@@ -321,7 +503,7 @@ func (c *Client) DownloadFilesToPath(name, path string) error {
 		return err
 	}
 	defer f.Close()
-	return c.do("GET", c.botUrl(bot, name), map[string]string{"return": "zip"}, nil, f)
+	return c.doWriter("GET", c.botUrl(bot, name), map[string]string{"return": "zip"}, nil, f)
 }
 
 func (c *Client) fileToUrl(name, filename string) (string, error) {
@@ -347,6 +529,9 @@ func (c *Client) UploadFile(name, filename string, data io.Reader) error {
 	if err != nil {
 		return err
 	}
+	if data, err = c.renderReader(filename, data); err != nil {
+		return err
+	}
 	return c.do("PUT", rawurl, nil, data, nil)
 }
 
@@ -362,7 +547,11 @@ func (c *Client) UploadFileFromPath(name, path string) error {
 	if err != nil {
 		return err
 	}
-	return c.do("PUT", rawurl, nil, f, nil)
+	data, err := c.renderReader(filepath.Base(path), f)
+	if err != nil {
+		return err
+	}
+	return c.do("PUT", rawurl, nil, data, nil)
 }
 
 // See https://developer.pandorabots.com/docs#!/pandorabots_api_swagger_1_2_beta/deleteBotFile1
@@ -382,7 +571,7 @@ func (c *Client) GetFile(name, filename string, out io.Writer) error {
 	if err != nil {
 		return err
 	}
-	return c.do("GET", rawurl, nil, nil, out)
+	return c.doWriter("GET", rawurl, nil, nil, out)
 }
 
 // See https://developer.pandorabots.com/docs#!/pandorabots_api_swagger_1_2_beta/getBotFile1
@@ -397,17 +586,21 @@ func (c *Client) GetFileToPath(name, path string) error {
 	if err != nil {
 		return err
 	}
-	return c.do("GET", rawurl, nil, nil, f)
+	return c.doWriter("GET", rawurl, nil, nil, f)
 }
 
+// Verify compiles the bot. If the bot does not compile a *CompileError is returned.
 // See https://developer.pandorabots.com/docs#!/pandorabots_api_swagger_1_2_beta/compileBot
 func (c *Client) Verify(name string) error {
-	return c.do("GET", c.botUrl(bot, name)+"/verify", nil, nil, nil)
+	return verifyError(c.do("GET", c.botUrl(bot, name)+"/verify", nil, nil, nil))
 }
 
 type Reply struct {
-	SessionId int      `json:"sessionid"`
-	Responses []string `json:"responses"`
+	SessionId int             `json:"sessionid"`
+	Responses []string        `json:"responses"`
+	Commands  []Command       `json:"commands,omitempty"` // The out of band commands parsed out of the responses
+	Trace     json.RawMessage `json:"trace,omitempty"`    // The matching trace, only returned when debugging with trace
+	Info      ReplyInfo       `json:"-"`                  // How the reply was obtained
 }
 
 // See https://developer.pandorabots.com/docs#!/pandorabots_api_swagger_1_2_beta/talkBot
@@ -417,6 +610,65 @@ func (c *Client) Talk(name, input, clientName string, sessionId int, recent bool
 
 // See https://developer.pandorabots.com/docs#!/pandorabots_api_swagger_1_2_beta/debugBot
 func (c *Client) TalkDebug(name, input, clientName string, sessionId int, recent bool, that, topic string, extra, reset, trace, reload bool) (*Reply, error) {
+	if c.anonymizer != nil {
+		var err error
+		if clientName, err = c.anonymizer.Pseudonym(clientName); err != nil {
+			return nil, err
+		}
+	}
+	start := time.Now()
+	reply, err := c.talkCached(name, FilterInput(input, c.inputFilters...), clientName, sessionId, recent, that, topic, extra, reset, trace, reload)
+	if reply != nil {
+		reply.Info.Latency = time.Since(start)
+	}
+	return reply, err
+}
+
+// talkCached answers the input from the reply cache, if any, or sends it
+func (c *Client) talkCached(name, input, clientName string, sessionId int, recent bool, that, topic string, extra, reset, trace, reload bool) (*Reply, error) {
+	if c.cache == nil || that != "" || topic != "" || extra || reset || trace || reload {
+		return c.talkChunks(name, input, clientName, sessionId, recent, that, topic, extra, reset, trace, reload)
+	}
+	if reply, ok := c.cache.Get(name, input); ok {
+		reply.SessionId = sessionId
+		reply.Info.Cached = true
+		return reply, nil
+	}
+	reply, err := c.talkChunks(name, input, clientName, sessionId, recent, that, topic, extra, reset, trace, reload)
+	if err == nil {
+		c.cache.Put(name, input, reply)
+	}
+	return reply, err
+}
+
+// talkChunks sends the input, in chunks if it is longer than the max input length
+func (c *Client) talkChunks(name, input, clientName string, sessionId int, recent bool, that, topic string, extra, reset, trace, reload bool) (*Reply, error) {
+	if c.maxInput <= 0 || utf8.RuneCountInString(input) <= c.maxInput {
+		return c.talk(name, input, clientName, sessionId, recent, that, topic, extra, reset, trace, reload)
+	}
+	// Send the chunks in order within the session, the state options only apply to the first
+	merged := &Reply{Responses: []string{}}
+	for i, chunk := range (Splitter{Limit: c.maxInput}).Split(input) {
+		if i > 0 {
+			that, topic, reset, reload = "", "", false, false
+		}
+		reply, err := c.talk(name, chunk, clientName, sessionId, recent, that, topic, extra, reset, trace, reload)
+		merged.Info.Attempts += reply.Info.Attempts
+		merged.Info.Hedged = merged.Info.Hedged || reply.Info.Hedged
+		if err != nil {
+			return merged, err
+		}
+		sessionId = reply.SessionId
+		merged.SessionId = reply.SessionId
+		merged.Responses = append(merged.Responses, reply.Responses...)
+		merged.Commands = append(merged.Commands, reply.Commands...)
+		merged.Trace = reply.Trace
+	}
+	return merged, nil
+}
+
+// talk sends a single talk request
+func (c *Client) talk(name, input, clientName string, sessionId int, recent bool, that, topic string, extra, reset, trace, reload bool) (*Reply, error) {
 	params := make(map[string]string)
 	params["input"] = input
 	if clientName != "" {
@@ -446,7 +698,31 @@ func (c *Client) TalkDebug(name, input, clientName string, sessionId int, recent
 	if reload {
 		params["reload"] = "true"
 	}
+	var sent int64
+	send := func() (Reply, error) {
+		var reply Reply
+		err := c.doCounted("POST", c.botUrl(talk, name), params, nil, func(r io.Reader) error {
+			return decode(c, r, &reply)
+		}, &sent)
+		return reply, err
+	}
 	var reply Reply
-	err := c.do("POST", c.botUrl(talk, name), params, nil, &reply)
+	var err error
+	hedged := false
+	if c.hedgeAfter > 0 {
+		reply, hedged, err = c.hedge(send)
+	} else {
+		reply, err = send()
+	}
+	reply.Info.Attempts, reply.Info.Hedged = int(atomic.LoadInt64(&sent)), hedged
+	if err == nil && c.callouts != nil {
+		reply.Responses = c.callouts.Filter(reply.Responses)
+	}
+	if err == nil {
+		reply.extractCommands()
+	}
+	if err == nil && len(c.filters) > 0 {
+		reply.Responses = FilterResponses(reply.Responses, c.filters...)
+	}
 	return &reply, err
 }
@@ -0,0 +1,106 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The API has no calls for the predicates of a client, so they are set and
+// read with inputs the categories of PredicateAIML answer. Upload the file to
+// the bot to use SetPredicate and GetPredicate.
+const (
+	SetPredicatePattern = "XSETPREDICATE"
+	GetPredicatePattern = "XGETPREDICATE"
+)
+
+// PredicateAIML holds the categories setting and returning the predicates
+// named in the inputs of SetPredicateInput and GetPredicateInput
+const PredicateAIML = `<?xml version="1.0" encoding="UTF-8"?>
+<aiml version="2.0">
+  <category>
+    <pattern>` + SetPredicatePattern + ` * XVALUE *</pattern>
+    <template><think><set><name><star/></name><star index="2"/></set></think></template>
+  </category>
+  <category>
+    <pattern>` + GetPredicatePattern + ` *</pattern>
+    <template><get><name><star/></name></get></template>
+  </category>
+</aiml>
+`
+
+var predicateNameRe = regexp.MustCompile(`^[A-Za-z]\w*$`)
+
+func validPredicate(name string) error {
+	if !predicateNameRe.MatchString(name) {
+		return fmt.Errorf("Predicate name is not valid [%s]", name)
+	}
+	return nil
+}
+
+// SetPredicateInput returns the input setting the predicate to the value. The
+// value is normalized by the bot like any input, so punctuation may be lost.
+func SetPredicateInput(name, value string) (string, error) {
+	if err := validPredicate(name); err != nil {
+		return "", err
+	}
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return "", fmt.Errorf("Value of predicate [%s] is empty", name)
+	}
+	return SetPredicatePattern + " " + name + " XVALUE " + value, nil
+}
+
+// GetPredicateInput returns the input the bot answers with the value of the predicate
+func GetPredicateInput(name string) (string, error) {
+	if err := validPredicate(name); err != nil {
+		return "", err
+	}
+	return GetPredicatePattern + " " + name, nil
+}
+
+// SetPredicate sets the predicate of the user with the key, e.g. to seed the
+// user name before chatting. The bot must have the categories of PredicateAIML.
+func (cv *Conversation) SetPredicate(key, name, value string) error {
+	input, err := SetPredicateInput(name, value)
+	if err != nil {
+		return err
+	}
+	_, err = cv.Talk(key, input)
+	return err
+}
+
+// SetPredicates sets the predicates of the user with the key, in the order of their names
+func (cv *Conversation) SetPredicates(key string, predicates map[string]string) error {
+	names := make([]string, 0, len(predicates))
+	for name := range predicates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := cv.SetPredicate(key, name, predicates[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPredicate returns the predicate of the user with the key, or the default
+// of the bot, usually "unknown", if it is not set. The bot must have the
+// categories of PredicateAIML.
+func (cv *Conversation) GetPredicate(key, name string) (string, error) {
+	input, err := GetPredicateInput(name)
+	if err != nil {
+		return "", err
+	}
+	reply, err := cv.Talk(key, input)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(reply.Responses, " "), nil
+}
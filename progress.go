@@ -0,0 +1,110 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// The phases of the progress events
+const (
+	ProgressStart  = "start"    // The operation started, with the total when known
+	ProgressStep   = "progress" // An item was processed
+	ProgressVerify = "verify"   // The bot is being verified
+	ProgressDone   = "done"     // The operation ended, with the error if it failed
+)
+
+// ProgressEvent reports the progress of a long running operation of the
+// client, like SyncDir, CloneBot, Promote, Restore, Backup or Bench
+type ProgressEvent struct {
+	Operation string        `json:"operation"` // sync, clone, promote, rollback, restore, backup or bench
+	Bot       string        `json:"bot"`
+	Phase     string        `json:"phase"`
+	Item      string        `json:"item,omitempty"`   // The file processed by a step
+	Action    string        `json:"action,omitempty"` // What was done to the item, like upload or delete
+	Done      int           `json:"done"`             // The number of items processed so far
+	Total     int           `json:"total,omitempty"`  // The number of items to process, zero if unknown
+	Elapsed   time.Duration `json:"elapsed"`          // Since the operation started
+	Time      time.Time     `json:"time"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// ProgressEventFunc receives the progress events of a client. It can be
+// called from several goroutines at once.
+type ProgressEventFunc func(e ProgressEvent)
+
+// SetProgress sends the progress events of the long running operations to f
+func SetProgress(f ProgressEventFunc) OptionFunc {
+	return func(c *Client) error {
+		c.progress = f
+		return nil
+	}
+}
+
+// JSONProgress returns a progress func writing each event to w as a line of
+// JSON, e.g. for CI systems following the progress of pbcli
+func JSONProgress(w io.Writer) ProgressEventFunc {
+	var mu sync.Mutex
+	return func(e ProgressEvent) {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write(append(data, '\n'))
+	}
+}
+
+// progressTracker emits the events of an operation. A nil tracker emits nothing.
+type progressTracker struct {
+	f         ProgressEventFunc
+	operation string
+	bot       string
+	total     int
+	start     time.Time
+}
+
+// track emits the start of an operation, returning nil if the client has no progress func
+func (c *Client) track(operation, bot string, total int) *progressTracker {
+	if c.progress == nil {
+		return nil
+	}
+	t := &progressTracker{f: c.progress, operation: operation, bot: bot, total: total, start: time.Now()}
+	t.emit(ProgressEvent{Phase: ProgressStart}, nil)
+	return t
+}
+
+func (t *progressTracker) emit(e ProgressEvent, err error) {
+	if t == nil {
+		return
+	}
+	e.Operation, e.Bot, e.Total = t.operation, t.bot, t.total
+	e.Time = time.Now()
+	e.Elapsed = e.Time.Sub(t.start)
+	if err != nil {
+		e.Error = err.Error()
+	}
+	t.f(e)
+}
+
+// step emits the processing of an item
+func (t *progressTracker) step(item, action string, done int, err error) {
+	t.emit(ProgressEvent{Phase: ProgressStep, Item: item, Action: action, Done: done}, err)
+}
+
+// verify emits the start of the verification of the bot
+func (t *progressTracker) verify(done int) {
+	t.emit(ProgressEvent{Phase: ProgressVerify, Done: done}, nil)
+}
+
+// finish emits the end of the operation and returns its error
+func (t *progressTracker) finish(done int, err error) error {
+	t.emit(ProgressEvent{Phase: ProgressDone, Done: done}, err)
+	return err
+}
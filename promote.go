@@ -0,0 +1,109 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// PromoteOptions controls Promote
+type PromoteOptions struct {
+	Delete    bool         // Delete the files of the destination bot that the source bot does not have
+	DryRun    bool         // Only compute the diff without changing the destination bot
+	Storage   Storage      // Optional storage to also keep the backup of the destination bot in, under BackupKey
+	BackupKey string       // The key of the backup in the storage
+	Progress  ProgressFunc // Optional progress callback
+}
+
+// PromoteResult is the outcome of Promote
+type PromoteResult struct {
+	Src         string   `json:"src"`
+	Dst         string   `json:"dst"`
+	Diff        *BotDiff `json:"diff"`       // The changes to the destination bot, with the removed files only if deleted
+	Backup      []byte   `json:"-"`          // The backup zip archive of the destination bot before the promotion
+	Promoted    bool     `json:"promoted"`   // True if the destination bot compiles with the files of the source bot
+	RolledBack  bool     `json:"rolledBack"` // True if the destination bot was restored from the backup
+	VerifyErr   error    `json:"-"`          // The verification error of the promoted bot, if it does not compile
+	RollbackErr error    `json:"-"`          // The error of the rollback, if it failed
+}
+
+// Promote releases the files of the src bot, e.g. staging, to the dst bot,
+// e.g. production. The destination bot is backed up first, then the files
+// which differ are uploaded and the bot is verified. If an upload fails or
+// the bot does not compile, the destination bot is rolled back to the backup
+// and the error is returned with the result.
+func (c *Client) Promote(src, dst string, opts PromoteOptions) (*PromoteResult, error) {
+	contents, err := c.fileContents(src)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = c.Backup(dst, &buf); err != nil {
+		return nil, err
+	}
+	result := &PromoteResult{Src: src, Dst: dst, Backup: buf.Bytes()}
+	previous, err := unzipFiles(result.Backup)
+	if err != nil {
+		return nil, err
+	}
+	d := diffContents(dst, src, previous, contents)
+	changes := d.Files[:0]
+	for _, f := range d.Files {
+		if f.Status != DiffRemoved || opts.Delete {
+			changes = append(changes, f)
+		}
+	}
+	d.Files = changes
+	result.Diff = d
+	if opts.DryRun || len(changes) == 0 {
+		return result, nil
+	}
+	if opts.Storage != nil {
+		data, err := c.encryptor.Seal(result.Backup)
+		if err != nil {
+			return nil, err
+		}
+		if err = opts.Storage.Put(opts.BackupKey, bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	t := c.track("promote", dst, len(changes))
+	var failure error
+	for i, f := range changes {
+		action := "upload"
+		if f.Status == DiffRemoved {
+			action = "delete"
+			err = c.DeleteFile(dst, f.File)
+		} else {
+			err = c.UploadFile(dst, f.File, bytes.NewReader(f.New))
+		}
+		if opts.Progress != nil {
+			opts.Progress(f.File, i+1, len(changes), err)
+		}
+		t.step(f.File, action, i+1, err)
+		if err != nil {
+			failure = fmt.Errorf("Unable to %s [%s] to bot [%s] - %v", action, f.File, dst, err)
+			break
+		}
+	}
+	if failure == nil {
+		t.verify(len(changes))
+		result.VerifyErr = c.Verify(dst)
+		result.Promoted = result.VerifyErr == nil
+		failure = result.VerifyErr
+	}
+	t.finish(len(changes), failure)
+	if failure == nil {
+		return result, nil
+	}
+	result.RollbackErr = c.copyContents("rollback", dst, previous, CopyOptions{Delete: true, Verify: true, Progress: opts.Progress})
+	if result.RollbackErr != nil {
+		return result, fmt.Errorf("Unable to roll back bot [%s] after the failed promotion - %v. The promotion failed with: %v", dst, result.RollbackErr, failure)
+	}
+	result.RolledBack = true
+	return result, failure
+}
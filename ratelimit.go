@@ -0,0 +1,96 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultRateLimitReply is the response to the first input of a throttled user
+const DefaultRateLimitReply = "You are sending messages too fast, please slow down."
+
+// RateLimiter throttles the inputs of each user, so a single abusive user of a
+// channel cannot exhaust the pandorabots quota of everyone. Each user can send
+// Inputs inputs per Period, in bursts or spread over the period.
+type RateLimiter struct {
+	Inputs int           // The inputs allowed per period
+	Period time.Duration // Defaults to a minute
+	Reply  string        // The response to the first throttled input. Defaults to DefaultRateLimitReply.
+
+	mu    sync.Mutex
+	users map[string]*bucket
+	swept time.Time
+}
+
+// bucket holds the inputs a user has left
+type bucket struct {
+	tokens   float64
+	last     time.Time
+	notified bool // The user was answered the limit reply since the last allowed input
+}
+
+// NewRateLimiter creates a limiter allowing the inputs per minute to each user
+func NewRateLimiter(inputs int) *RateLimiter {
+	return &RateLimiter{Inputs: inputs}
+}
+
+func (l *RateLimiter) period() time.Duration {
+	if l.Period <= 0 {
+		return time.Minute
+	}
+	return l.Period
+}
+
+func (l *RateLimiter) reply() string {
+	if l.Reply == "" {
+		return DefaultRateLimitReply
+	}
+	return l.Reply
+}
+
+// take uses an input of the user with the key, returning whether it is
+// allowed and, if not, whether the user should be told to slow down
+func (l *RateLimiter) take(key string, now time.Time) (ok, notify bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	period := l.period()
+	if l.users == nil {
+		l.users = make(map[string]*bucket)
+	}
+	if now.Sub(l.swept) > period {
+		// The users idle for a period are back to a full bucket
+		for k, b := range l.users {
+			if now.Sub(b.last) > period {
+				delete(l.users, k)
+			}
+		}
+		l.swept = now
+	}
+	b, found := l.users[key]
+	if !found {
+		b = &bucket{tokens: float64(l.Inputs), last: now}
+		l.users[key] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * float64(l.Inputs) / period.Seconds()
+	if b.tokens > float64(l.Inputs) {
+		b.tokens = float64(l.Inputs)
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		b.notified = false
+		return true, false
+	}
+	notify = !b.notified
+	b.notified = true
+	return false, notify
+}
+
+// Allow reports whether the user with the key may send an input now, using it if so
+func (l *RateLimiter) Allow(key string) bool {
+	ok, _ := l.take(key, time.Now())
+	return ok
+}
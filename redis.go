@@ -0,0 +1,347 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redisError is an error reply of the Redis server, which leaves the connection usable
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// redisClient is a minimal client of the Redis protocol, with the commands of
+// the stores of the package. It keeps a single connection, reconnecting after
+// network errors.
+type redisClient struct {
+	addr     string
+	username string
+	password string
+	db       int
+	tls      bool
+	timeout  time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// newRedisClient parses a redis://[[USER]:PASSWORD@]HOST[:PORT][/DB] URL, or rediss:// for TLS
+func newRedisClient(rawurl string) (*redisClient, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil || (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+		return nil, fmt.Errorf("Redis URL is not valid [%s] - it must be like redis://:PASSWORD@HOST:6379/0", rawurl)
+	}
+	c := &redisClient{addr: u.Host, tls: u.Scheme == "rediss", timeout: 10 * time.Second}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.username = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("Redis database is not valid [%s] - it must be a number", db)
+		}
+	}
+	return c, nil
+}
+
+func (c *redisClient) dial() error {
+	var err error
+	dialer := &net.Dialer{Timeout: c.timeout}
+	if c.tls {
+		c.conn, err = tls.DialWithDialer(dialer, "tcp", c.addr, nil)
+	} else {
+		c.conn, err = dialer.Dial("tcp", c.addr)
+	}
+	if err != nil {
+		return err
+	}
+	c.r = bufio.NewReader(c.conn)
+	if c.password != "" {
+		args := []string{"AUTH", c.password}
+		if c.username != "" {
+			args = []string{"AUTH", c.username, c.password}
+		}
+		if _, err = c.roundTrip(args); err != nil {
+			return err
+		}
+	}
+	if c.db != 0 {
+		_, err = c.roundTrip([]string{"SELECT", strconv.Itoa(c.db)})
+	}
+	return err
+}
+
+// do sends the command and returns its reply: a string, an int64, a []interface{} or nil
+func (c *redisClient) do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		if err := c.dial(); err != nil {
+			c.drop()
+			return nil, fmt.Errorf("Unable to connect to Redis [%s] - %v", c.addr, err)
+		}
+	}
+	reply, err := c.roundTrip(args)
+	if err != nil {
+		if _, ok := err.(redisError); !ok {
+			c.drop()
+		}
+		return nil, fmt.Errorf("Redis command failed [%s] - %v", args[0], err)
+	}
+	return reply, nil
+}
+
+// drop closes the connection after a network error, if any
+func (c *redisClient) drop() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+func (c *redisClient) roundTrip(args []string) (interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readRedisReply(c.r)
+}
+
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("Unexpected empty Redis reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err = io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readRedisReply(r); err != nil {
+				if _, ok := err.(redisError); !ok {
+					return nil, err
+				}
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("Unexpected Redis reply [%s]", line)
+}
+
+// close closes the connection, a new one is opened by the next command
+func (c *redisClient) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// redisStrings converts an array reply to strings
+func redisStrings(reply interface{}) []string {
+	items, _ := reply.([]interface{})
+	res := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// RedisTranscriptStore is a TranscriptStore keeping the entries in Redis, a
+// list of JSON entries per bot and client name, so several gateways can
+// share the transcripts
+type RedisTranscriptStore struct {
+	Prefix string        // The prefix of the keys, pb:transcripts by default
+	TTL    time.Duration // Expire the conversations inactive this long, zero to keep them
+
+	client *redisClient
+}
+
+// NewRedisTranscriptStore creates a store in the Redis server of the URL, like
+// redis://:PASSWORD@localhost:6379/0, or rediss:// for TLS
+func NewRedisTranscriptStore(rawurl string) (*RedisTranscriptStore, error) {
+	c, err := newRedisClient(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisTranscriptStore{Prefix: "pb:transcripts", client: c}, nil
+}
+
+// conversationKey returns the key of the list of entries of the bot and the client name
+func (s *RedisTranscriptStore) conversationKey(bot, clientName string) string {
+	return s.Prefix + ":c:" + bot + ":" + clientName
+}
+
+// turnsKey returns the key of the counter of the turns of a session
+func (s *RedisTranscriptStore) turnsKey(bot, clientName string, sessionId int) string {
+	return fmt.Sprintf("%s:t:%s:%s:%d", s.Prefix, bot, clientName, sessionId)
+}
+
+// expire sets the TTL of the key, if any
+func (s *RedisTranscriptStore) expire(key string) error {
+	if s.TTL <= 0 {
+		return nil
+	}
+	_, err := s.client.do("PEXPIRE", key, strconv.FormatInt(s.TTL.Milliseconds(), 10))
+	return err
+}
+
+func (s *RedisTranscriptStore) Append(e *TranscriptEntry) error {
+	counter := s.turnsKey(e.Bot, e.ClientName, e.SessionId)
+	reply, err := s.client.do("INCR", counter)
+	if err != nil {
+		return err
+	}
+	n, _ := reply.(int64)
+	e.Turn = int(n) - 1
+	if err = s.expire(counter); err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	key := s.conversationKey(e.Bot, e.ClientName)
+	if _, err = s.client.do("RPUSH", key, string(data)); err != nil {
+		return err
+	}
+	if err = s.expire(key); err != nil {
+		return err
+	}
+	// The bot names cannot contain a colon, the client names can
+	_, err = s.client.do("SADD", s.Prefix+":conversations", e.Bot+":"+e.ClientName)
+	return err
+}
+
+func (s *RedisTranscriptStore) Query(q TranscriptQuery) ([]TranscriptEntry, error) {
+	conversations := []string{q.Bot + ":" + q.ClientName}
+	if q.Bot == "" || q.ClientName == "" {
+		reply, err := s.client.do("SMEMBERS", s.Prefix+":conversations")
+		if err != nil {
+			return nil, err
+		}
+		conversations = redisStrings(reply)
+	}
+	res := make([]TranscriptEntry, 0)
+	for _, conversation := range conversations {
+		bot, clientName, _ := strings.Cut(conversation, ":")
+		if (q.Bot != "" && bot != q.Bot) || (q.ClientName != "" && clientName != q.ClientName) {
+			continue
+		}
+		reply, err := s.client.do("LRANGE", s.conversationKey(bot, clientName), "0", "-1")
+		if err != nil {
+			return nil, err
+		}
+		items := redisStrings(reply)
+		if len(items) == 0 {
+			// The conversation expired
+			if _, err = s.client.do("SREM", s.Prefix+":conversations", conversation); err != nil {
+				return nil, err
+			}
+		}
+		for _, item := range items {
+			var e TranscriptEntry
+			if err = json.Unmarshal([]byte(item), &e); err != nil {
+				return nil, fmt.Errorf("Transcript entry of [%s] is not valid - %v", conversation, err)
+			}
+			if q.Match(&e) {
+				res = append(res, e)
+			}
+		}
+	}
+	return q.limit(res), nil
+}
+
+func (s *RedisTranscriptStore) Delete(clientName string) (int, error) {
+	reply, err := s.client.do("SMEMBERS", s.Prefix+":conversations")
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, conversation := range redisStrings(reply) {
+		bot, name, _ := strings.Cut(conversation, ":")
+		if name != clientName {
+			continue
+		}
+		key := s.conversationKey(bot, clientName)
+		reply, err := s.client.do("LRANGE", key, "0", "-1")
+		if err != nil {
+			return n, err
+		}
+		// The turn counters of the sessions are deleted with the list
+		keys := []string{"DEL", key}
+		sessions := make(map[int]bool)
+		for _, item := range redisStrings(reply) {
+			var e TranscriptEntry
+			if err = json.Unmarshal([]byte(item), &e); err == nil && !sessions[e.SessionId] {
+				sessions[e.SessionId] = true
+				keys = append(keys, s.turnsKey(bot, clientName, e.SessionId))
+			}
+			n++
+		}
+		if _, err = s.client.do(keys...); err != nil {
+			return n, err
+		}
+		if _, err = s.client.do("SREM", s.Prefix+":conversations", conversation); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Close closes the connection to Redis
+func (s *RedisTranscriptStore) Close() error {
+	return s.client.close()
+}
@@ -0,0 +1,76 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// SetTimeout limits the time of each API request, including reading the response.
+// Zero, the default, means no limit beyond the one of the http.Client.
+func SetTimeout(timeout time.Duration) OptionFunc {
+	return func(c *Client) error {
+		if timeout < 0 {
+			return errors.New("Timeout cannot be negative")
+		}
+		c.timeout = timeout
+		return nil
+	}
+}
+
+// SetRetries retries failed requests up to retries times, waiting wait before the
+// first retry and doubling the wait for each retry after it. Requests are retried
+// on network errors and on 429, 502, 503 and 504 responses, unless the request
+// body cannot be rewound. The talk requests are only retried when the bot did
+// not receive them, on connection failures and on 429 and 503 responses, so
+// an input is not processed twice and its <set> and <learn> are not repeated.
+func SetRetries(retries int, wait time.Duration) OptionFunc {
+	return func(c *Client) error {
+		if retries < 0 || wait < 0 {
+			return errors.New("Retries and wait cannot be negative")
+		}
+		c.retries, c.retryWait = retries, wait
+		return nil
+	}
+}
+
+// retryable returns true if the outcome of an attempt is worth retrying.
+// The attempts of a talk are only retried if they did not reach the bot.
+func retryable(statusCode int, err error, isTalk bool) bool {
+	switch statusCode {
+	case 0:
+		if isTalk {
+			return notSent(err)
+		}
+		return err != nil
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return !isTalk
+	}
+	return false
+}
+
+// notSent returns true if the request failed before it was sent, when connecting to the server
+func notSent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// rewind prepares the body to be sent again, returning false if it cannot be
+func rewind(body io.Reader) bool {
+	if body == nil {
+		return true
+	}
+	if s, ok := body.(io.Seeker); ok {
+		_, err := s.Seek(0, io.SeekStart)
+		return err == nil
+	}
+	return false
+}
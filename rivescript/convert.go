@@ -0,0 +1,386 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package rivescript converts RiveScript bots into the AIML and personality
+// files of pandorabots bots, to migrate them onto pandorabots. The conversion
+// is best effort: the constructs AIML has no equivalent for are reported.
+package rivescript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pb "github.com/demisto/pb-go"
+	"github.com/demisto/pb-go/aiml"
+)
+
+// defaultTopic is the topic of the triggers outside of a topic block
+const defaultTopic = "random"
+
+// Result is the outcome of a conversion
+type Result struct {
+	Files map[string][]byte // The personality files by name, e.g. greetings.aiml, colors.set or bot.properties
+	// The constructs which were not translated, as errors, or only approximately, as warnings
+	Issues []aiml.Issue
+}
+
+// command is a RiveScript line with its continuations
+type command struct {
+	line  int
+	cmd   byte
+	data  string   // The data with the continuations joined
+	parts []string // The data and each continuation, for the definitions
+}
+
+// trigger is a trigger with its replies
+type trigger struct {
+	line       int
+	trigger    string
+	previous   string
+	topic      string
+	replies    []string
+	redirect   string
+	conditions []string
+}
+
+// category is a translated AIML category
+type category struct {
+	topic, pattern, that, template string
+}
+
+// location is where a category was translated from
+type location struct {
+	file string
+	line int
+}
+
+// converter holds the state of a conversion across the files
+type converter struct {
+	file     string // The file being converted
+	issues   []aiml.Issue
+	reported map[aiml.Issue]bool
+	seen     map[string]location // The translated categories by topic, pattern and that
+	files    map[string][]byte
+
+	arrays       map[string][][]string // The entries of the arrays, each one a list of words
+	arrayNames   []string              // In the order of definition
+	vars         [][]string
+	subs, person [][]string
+}
+
+// add reports an issue, once as the replies are translated for each pattern of their trigger
+func (cv *converter) add(line int, severity aiml.Severity, format string, args ...interface{}) {
+	issue := aiml.Issue{File: cv.file, Line: line, Severity: severity, Message: fmt.Sprintf(format, args...)}
+	if !cv.reported[issue] {
+		cv.reported[issue] = true
+		cv.issues = append(cv.issues, issue)
+	}
+}
+
+// Convert translates RiveScript documents, by file name, into the personality
+// files of a bot. Each document becomes an AIML file of the same base name,
+// the arrays become sets, the bot variables the bot.properties file and the
+// substitutions the normal and person substitution files.
+func Convert(files map[string][]byte) *Result {
+	cv := &converter{
+		reported: make(map[aiml.Issue]bool),
+		seen:     make(map[string]location),
+		files:    make(map[string][]byte),
+		arrays:   make(map[string][][]string),
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cv.file = name
+		cv.convert(files[name])
+	}
+	for _, name := range cv.arrayNames {
+		cv.files[name+".set"] = listFile(cv.arrays[name])
+	}
+	if len(cv.vars) > 0 {
+		cv.files["bot.properties"] = listFile(cv.vars)
+	}
+	if len(cv.subs) > 0 {
+		cv.files["normal.substitution"] = listFile(cv.subs)
+		cv.file = "normal.substitution"
+		cv.add(0, aiml.SeverityWarning, "The substitutions replace the default normal substitutions of the bot")
+	}
+	if len(cv.person) > 0 {
+		cv.files["person.substitution"] = listFile(cv.person)
+	}
+	aiml.SortIssues(cv.issues)
+	return &Result{Files: cv.files, Issues: cv.issues}
+}
+
+// ConvertFiles converts the RiveScript files in paths, walking the directories for .rive files
+func ConvertFiles(paths []string) (*Result, error) {
+	files := make(map[string][]byte)
+	read := func(path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[path] = data
+		return nil
+	}
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if err = read(p); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err = filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || filepath.Ext(path) != ".rive" {
+				return err
+			}
+			return read(path)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return Convert(files), nil
+}
+
+// Upload uploads the converted files to the bot and verifies it
+func (r *Result) Upload(c *pb.Client, bot string) error {
+	names := make([]string, 0, len(r.Files))
+	for name := range r.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := c.UploadFile(bot, name, bytes.NewReader(r.Files[name])); err != nil {
+			return err
+		}
+	}
+	return c.Verify(bot)
+}
+
+// listFile writes the entries in the JSON list format of the sets, maps and properties
+func listFile(entries [][]string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("[\n")
+	for i, e := range entries {
+		data, _ := json.Marshal(e)
+		buf.WriteString("  ")
+		buf.Write(bytes.ReplaceAll(data, []byte(`","`), []byte(`", "`)))
+		if i < len(entries)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("]\n")
+	return buf.Bytes()
+}
+
+// commands splits the document into its commands, joining the continuations
+// and dropping the comments
+func (cv *converter) commands(data []byte) []command {
+	var res []command
+	concat := ""
+	comment := false
+	for i, s := range strings.Split(string(data), "\n") {
+		s = strings.TrimSpace(s)
+		if comment {
+			end := strings.Index(s, "*/")
+			if end < 0 {
+				continue
+			}
+			comment, s = false, strings.TrimSpace(s[end+2:])
+		}
+		if strings.HasPrefix(s, "/*") {
+			comment = !strings.Contains(s, "*/")
+			continue
+		}
+		if n := strings.Index(s, " //"); n >= 0 {
+			s = strings.TrimSpace(s[:n])
+		}
+		if s == "" || strings.HasPrefix(s, "//") {
+			continue
+		}
+		c := command{line: i + 1, cmd: s[0], data: strings.TrimSpace(s[1:])}
+		if c.cmd != '^' {
+			c.parts = []string{c.data}
+			res = append(res, c)
+			if f := strings.Fields(strings.ReplaceAll(c.data, "=", " ")); c.cmd == '!' && len(f) == 3 && f[0] == "local" && f[1] == "concat" {
+				concat = map[string]string{"space": " ", "newline": `\n`}[f[2]]
+			}
+			continue
+		}
+		if len(res) == 0 {
+			cv.add(c.line, aiml.SeverityError, "Continuation without a command")
+			continue
+		}
+		prev := &res[len(res)-1]
+		prev.data += concat + c.data
+		prev.parts = append(prev.parts, c.data)
+	}
+	return res
+}
+
+// convert translates a document into an AIML file
+func (cv *converter) convert(data []byte) {
+	var (
+		cats  []category
+		cur   *trigger
+		topic = defaultTopic
+		skip  string // The begin or object block being skipped
+	)
+	flush := func() {
+		if cur != nil {
+			cats = append(cats, cv.categories(cur)...)
+			cur = nil
+		}
+	}
+	for _, c := range cv.commands(data) {
+		if skip != "" {
+			if f := strings.Fields(c.data); c.cmd == '<' && len(f) > 0 && f[0] == skip {
+				skip = ""
+			}
+			continue
+		}
+		if cur == nil && strings.IndexByte("%-@*", c.cmd) >= 0 {
+			cv.add(c.line, aiml.SeverityError, "Command [%c] outside of a trigger", c.cmd)
+			continue
+		}
+		switch c.cmd {
+		case '!':
+			cv.define(c)
+		case '>':
+			flush()
+			f := strings.Fields(c.data)
+			switch {
+			case len(f) >= 2 && f[0] == "topic":
+				topic = strings.ToLower(f[1])
+				if len(f) > 2 {
+					cv.add(c.line, aiml.SeverityWarning, "Topic [%s] includes or inherits other topics, which is not translated", f[1])
+				}
+			case len(f) >= 1 && f[0] == "begin":
+				skip = "begin"
+				cv.add(c.line, aiml.SeverityError, "The begin block is not translated")
+			case len(f) >= 2 && f[0] == "object":
+				skip = "object"
+				cv.add(c.line, aiml.SeverityError, "Object macro [%s] is not translated", f[1])
+			default:
+				cv.add(c.line, aiml.SeverityError, "Label is not recognized [%s]", c.data)
+			}
+		case '<':
+			flush()
+			topic = defaultTopic
+		case '+':
+			flush()
+			cur = &trigger{line: c.line, trigger: c.data, topic: topic}
+		case '%':
+			cur.previous = c.data
+		case '-':
+			cur.replies = append(cur.replies, c.data)
+		case '@':
+			cur.redirect = c.data
+		case '*':
+			cur.conditions = append(cur.conditions, c.data)
+		default:
+			cv.add(c.line, aiml.SeverityError, "Command is not recognized [%c]", c.cmd)
+		}
+	}
+	flush()
+	if len(cats) > 0 {
+		name := strings.TrimSuffix(filepath.Base(cv.file), filepath.Ext(cv.file)) + ".aiml"
+		cv.files[name] = aimlFile(cats)
+	}
+}
+
+// define handles a definition
+func (cv *converter) define(c command) {
+	kind, name, value := definition(c.data)
+	switch kind {
+	case "version", "local":
+	case "global":
+		cv.add(c.line, aiml.SeverityWarning, "Global [%s] is not translated", name)
+	case "var":
+		if value != "<undef>" {
+			cv.vars = append(cv.vars, []string{name, value})
+		}
+	case "array":
+		if _, ok := cv.arrays[name]; !ok {
+			cv.arrayNames = append(cv.arrayNames, name)
+		}
+		// Each line of the array lists words, or phrases separated by pipes
+		_, _, first := definition(c.parts[0])
+		for _, v := range append([]string{first}, c.parts[1:]...) {
+			var items []string
+			if strings.Contains(v, "|") {
+				items = strings.Split(v, "|")
+			} else {
+				items = strings.Fields(v)
+			}
+			for _, item := range items {
+				if words := strings.Fields(item); len(words) > 0 {
+					cv.arrays[name] = append(cv.arrays[name], words)
+				}
+			}
+		}
+	case "sub":
+		cv.subs = append(cv.subs, []string{name, value})
+	case "person":
+		cv.person = append(cv.person, []string{name, value})
+	default:
+		cv.add(c.line, aiml.SeverityError, "Definition is not recognized [%s]", kind)
+	}
+}
+
+// definition splits the data of a definition into its kind, name and value
+func definition(data string) (kind, name, value string) {
+	kind, name = data, ""
+	if i := strings.IndexAny(data, " \t"); i >= 0 {
+		kind, name = data[:i], data[i+1:]
+	}
+	if i := strings.Index(name, "="); i >= 0 {
+		name, value = name[:i], strings.TrimSpace(name[i+1:])
+	}
+	return kind, strings.TrimSpace(name), value
+}
+
+// aimlFile writes the categories as an AIML document, grouped by topic
+func aimlFile(cats []category) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("<aiml version=\"2.0\">\n")
+	topic := defaultTopic
+	for _, c := range cats {
+		if c.topic != topic {
+			if topic != defaultTopic {
+				buf.WriteString("</topic>\n")
+			}
+			if topic = c.topic; topic != defaultTopic {
+				fmt.Fprintf(&buf, "<topic name=\"%s\">\n", attrEscaper.Replace(strings.ToUpper(topic)))
+			}
+		}
+		buf.WriteString("<category><pattern>" + c.pattern + "</pattern>")
+		if c.that != "" {
+			buf.WriteString("<that>" + c.that + "</that>")
+		}
+		buf.WriteString("<template>" + c.template + "</template></category>\n")
+	}
+	if topic != defaultTopic {
+		buf.WriteString("</topic>\n")
+	}
+	buf.WriteString("</aiml>\n")
+	if formatted, err := aiml.Format(buf.Bytes()); err == nil {
+		return formatted
+	}
+	return buf.Bytes()
+}
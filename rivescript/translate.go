@@ -0,0 +1,419 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rivescript
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/demisto/pb-go/aiml"
+)
+
+const (
+	maxPatterns = 64 // The most patterns a trigger with alternatives and optionals expands to
+	maxWeight   = 10 // The most times a weighted reply is repeated in the random replies
+)
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+	weightRe     = regexp.MustCompile(`\s*\{weight=(\d+)\}\s*`)
+	groupRe      = regexp.MustCompile(`\(([^()\[\]]*)\)|\[([^()\[\]]*)\]`)
+	triggerTagRe = regexp.MustCompile(`<(\w+)(?:\s+([^<>]*))?>`)
+	tokenRe      = regexp.MustCompile(`<[^<>]*(?:<[^<>]*>[^<>]*)*>|\{[^{}]*\}|\\[sn#/]`) // Tags may hold tags, like <set name=<formal>>
+	indexedRe    = regexp.MustCompile(`^(star|botstar|input|reply)(\d*)$`)
+	conditionRe  = regexp.MustCompile(`^(<[^<>]*>|[^\s<>=!]+)\s*(==|eq|!=|ne|<>|<=|>=|<|>)\s*(.*?)\s*=>\s*(.*)$`)
+	getRe        = regexp.MustCompile(`^<get\s+([^<>\s]+)>$`)
+)
+
+// Capture group markers of the expanded triggers
+const (
+	openCapture  = "\x02"
+	closeCapture = "\x03"
+	botWord      = "\x01" // Prefix of the bot variables of the triggers
+)
+
+// wrapTags are the tags applying a transformation, to the star or the enclosed text
+var wrapTags = map[string]bool{"person": true, "formal": true, "sentence": true, "uppercase": true, "lowercase": true}
+
+// htmlTags are the HTML elements kept as is in the replies
+var htmlTags = map[string]bool{
+	"a": true, "b": true, "br": true, "div": true, "em": true, "font": true, "i": true, "img": true,
+	"li": true, "ol": true, "p": true, "span": true, "strong": true, "u": true, "ul": true,
+}
+
+// pattern is a translated trigger
+type pattern struct {
+	text  string
+	stars []string // The AIML of each RiveScript star, the wildcard or the alternative matched
+}
+
+// refs are the stars of the trigger and previous patterns of a category
+type refs struct {
+	stars, thatstars []string
+}
+
+func starRef(elem string, n int) string {
+	if n == 1 {
+		return "<" + elem + "/>"
+	}
+	return fmt.Sprintf(`<%s index="%d"/>`, elem, n)
+}
+
+func (r refs) star(n int) string {
+	if n >= 1 && n <= len(r.stars) {
+		return r.stars[n-1]
+	}
+	return starRef("star", n)
+}
+
+func (r refs) thatstar(n int) string {
+	if n >= 1 && n <= len(r.thatstars) {
+		return r.thatstars[n-1]
+	}
+	return starRef("thatstar", n)
+}
+
+// categories translates the trigger into a category per pattern
+func (cv *converter) categories(t *trigger) []category {
+	patterns := cv.patterns(t.line, t.trigger, "star")
+	thats := []pattern{{}}
+	if t.previous != "" {
+		thats = cv.patterns(t.line, t.previous, "thatstar")
+	}
+	var cats []category
+	for _, p := range patterns {
+		for _, that := range thats {
+			key := t.topic + "\x00" + p.text + "\x00" + that.text
+			if prev, ok := cv.seen[key]; ok {
+				cv.add(t.line, aiml.SeverityWarning, "Pattern [%s] duplicates the one of %s:%d, skipped", p.text, prev.file, prev.line)
+				continue
+			}
+			template := cv.template(t, refs{p.stars, that.stars})
+			if strings.TrimSpace(template) == "" {
+				cv.add(t.line, aiml.SeverityError, "Trigger [%s] has no translated reply", t.trigger)
+				return cats
+			}
+			cv.seen[key] = location{cv.file, t.line}
+			cats = append(cats, category{topic: t.topic, pattern: p.text, that: that.text, template: template})
+		}
+	}
+	return cats
+}
+
+// expand expands the alternatives and optionals of the trigger, marking the
+// alternatives captured as stars. It returns false if there were more than
+// limit combinations.
+func expand(s string, limit int) ([]string, bool) {
+	res, all := []string{s}, true
+	for {
+		var next []string
+		expanded := false
+		for _, p := range res {
+			loc := groupRe.FindStringSubmatchIndex(p)
+			if loc == nil {
+				next = append(next, p)
+				continue
+			}
+			expanded = true
+			if loc[2] >= 0 {
+				for _, alt := range strings.Split(p[loc[2]:loc[3]], "|") {
+					next = append(next, p[:loc[0]]+" "+openCapture+" "+alt+" "+closeCapture+" "+p[loc[1]:])
+				}
+				continue
+			}
+			for _, alt := range append(strings.Split(p[loc[4]:loc[5]], "|"), "") {
+				next = append(next, p[:loc[0]]+" "+alt+" "+p[loc[1]:])
+			}
+		}
+		if len(next) > limit {
+			next, all = next[:limit], false
+		}
+		if res = next; !expanded {
+			return res, all
+		}
+	}
+}
+
+// patterns translates a trigger, or a previous, into AIML patterns. The
+// captures of the stars are the AIML star elements of the given name.
+func (cv *converter) patterns(line int, s, star string) []pattern {
+	if weightRe.MatchString(s) {
+		s = weightRe.ReplaceAllString(s, " ")
+		cv.add(line, aiml.SeverityWarning, "Trigger weights are not translated")
+	}
+	var unsupported string
+	s = triggerTagRe.ReplaceAllStringFunc(s, func(tag string) string {
+		if m := triggerTagRe.FindStringSubmatch(tag); m[1] == "bot" && m[2] != "" {
+			return " " + botWord + strings.TrimSpace(m[2]) + " "
+		}
+		unsupported = tag
+		return ""
+	})
+	if unsupported != "" {
+		cv.add(line, aiml.SeverityError, "Trigger tag is not translated [%s]", unsupported)
+		return nil
+	}
+	// Optional wildcards match zero or more words, as the AIML ^ wildcard
+	s = strings.ReplaceAll(s, "[*]", " ^ ")
+	expanded, all := expand(s, maxPatterns)
+	if !all {
+		cv.add(line, aiml.SeverityError, "Trigger has more than %d combinations of alternatives, the others are not translated", maxPatterns)
+	}
+	type capture struct {
+		xml []string
+	}
+	var res []pattern
+	seen := make(map[string]bool)
+	for _, e := range expanded {
+		var (
+			words []string
+			caps  []*capture
+			open  []*capture
+			n     int // The AIML wildcards so far
+		)
+		e = strings.NewReplacer(openCapture, " "+openCapture+" ", closeCapture, " "+closeCapture+" ").Replace(e)
+		for _, w := range strings.Fields(e) {
+			switch w {
+			case openCapture:
+				c := &capture{}
+				caps, open = append(caps, c), append(open, c)
+				continue
+			case closeCapture:
+				open = open[:len(open)-1]
+				continue
+			}
+			var word, xml string
+			captured := false
+			switch {
+			case w == "^":
+				n++
+				word, xml = w, starRef(star, n)
+			case w == "*" || w == "#" || w == "_":
+				if w != "*" {
+					cv.add(line, aiml.SeverityWarning, "The %s wildcard is translated as *", w)
+				}
+				n++
+				word, xml, captured = "*", starRef(star, n), true
+			case w[0] == '@':
+				// Arrays are only captured within parentheses, but sets always are
+				n++
+				word, xml = "<set>"+textEscaper.Replace(w[1:])+"</set>", starRef(star, n)
+			case strings.HasPrefix(w, botWord):
+				word = `<bot name="` + attrEscaper.Replace(w[len(botWord):]) + `"/>`
+				xml = word
+			default:
+				word, xml = textEscaper.Replace(strings.ToUpper(w)), textEscaper.Replace(w)
+			}
+			words = append(words, word)
+			for _, c := range open {
+				c.xml = append(c.xml, xml)
+			}
+			if captured && len(open) == 0 {
+				caps = append(caps, &capture{xml: []string{xml}})
+			}
+		}
+		text := strings.Join(words, " ")
+		if text == "" || seen[text] {
+			continue
+		}
+		seen[text] = true
+		p := pattern{text: text}
+		for _, c := range caps {
+			p.stars = append(p.stars, strings.Join(c.xml, " "))
+		}
+		res = append(res, p)
+	}
+	return res
+}
+
+// template translates the replies, redirect and conditions of the trigger
+func (cv *converter) template(t *trigger, r refs) string {
+	def := cv.random(t.line, t.replies, r)
+	if t.redirect != "" {
+		def = "<srai>" + cv.reply(t.line, t.redirect, r) + "</srai>"
+	}
+	var items []string
+	for _, c := range t.conditions {
+		m := conditionRe.FindStringSubmatch(c)
+		if m == nil {
+			cv.add(t.line, aiml.SeverityError, "Condition is not recognized [%s]", c)
+			continue
+		}
+		get := getRe.FindStringSubmatch(m[1])
+		if get == nil || m[2] != "==" && m[2] != "eq" || strings.ContainsAny(m[3], "<>{}") {
+			cv.add(t.line, aiml.SeverityError, "Condition is not translated, only the <get> variables equal to a value are [%s]", c)
+			continue
+		}
+		value := m[3]
+		if value == "undefined" {
+			value = "unknown" // The value of the predicates not set of pandorabots
+		}
+		items = append(items, fmt.Sprintf(`<li name="%s" value="%s">%s</li>`, attrEscaper.Replace(get[1]), attrEscaper.Replace(value), cv.reply(t.line, m[4], r)))
+	}
+	if len(items) == 0 {
+		return def
+	}
+	if def != "" {
+		items = append(items, "<li>"+def+"</li>")
+	}
+	return "<condition>" + strings.Join(items, "") + "</condition>"
+}
+
+// random translates the replies, picking one at random if there are several
+func (cv *converter) random(line int, replies []string, r refs) string {
+	var items []string
+	for _, reply := range replies {
+		n := 1
+		if m := weightRe.FindStringSubmatch(reply); m != nil {
+			n, _ = strconv.Atoi(m[1])
+			reply = weightRe.ReplaceAllString(reply, " ")
+			switch {
+			case n < 1:
+				n = 1
+			case n > maxWeight:
+				cv.add(line, aiml.SeverityWarning, "Reply weights over %d are translated as %d", maxWeight, maxWeight)
+				n = maxWeight
+			}
+		}
+		xml := cv.reply(line, strings.TrimSpace(reply), r)
+		for i := 0; i < n; i++ {
+			items = append(items, xml)
+		}
+	}
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	}
+	return "<random><li>" + strings.Join(items, "</li><li>") + "</li></random>"
+}
+
+// reply translates the text of a reply
+func (cv *converter) reply(line int, s string, r refs) string {
+	var b strings.Builder
+	for {
+		loc := tokenRe.FindStringIndex(s)
+		if loc == nil {
+			b.WriteString(textEscaper.Replace(s))
+			return b.String()
+		}
+		b.WriteString(textEscaper.Replace(s[:loc[0]]))
+		tok := s[loc[0]:loc[1]]
+		s = s[loc[1]:]
+		switch tok {
+		case "{random}":
+			end := strings.Index(s, "{/random}")
+			if end < 0 {
+				cv.add(line, aiml.SeverityError, "{random} is not closed")
+				continue
+			}
+			inner := s[:end]
+			s = s[end+len("{/random}"):]
+			var alts []string
+			if strings.Contains(inner, "|") {
+				alts = strings.Split(inner, "|")
+			} else {
+				alts = strings.Fields(inner)
+			}
+			b.WriteString(cv.random(line, alts, r))
+		case "<call>":
+			if end := strings.Index(s, "</call>"); end >= 0 {
+				s = s[end+len("</call>"):]
+			}
+			cv.add(line, aiml.SeverityError, "Object macro calls are not translated")
+		default:
+			b.WriteString(cv.tag(line, tok, r))
+		}
+	}
+}
+
+// tag translates a tag of a reply
+func (cv *converter) tag(line int, tok string, r refs) string {
+	switch tok {
+	case `\s`:
+		return " "
+	case `\n`:
+		return "<br/>"
+	case `\#`:
+		return "#"
+	case `\/`:
+		return "/"
+	}
+	inner := strings.TrimSpace(tok[1 : len(tok)-1])
+	if tok[0] == '{' {
+		return cv.braceTag(line, tok, inner, r)
+	}
+	name, arg := inner, ""
+	if i := strings.IndexAny(inner, " \t"); i >= 0 {
+		name, arg = inner[:i], strings.TrimSpace(inner[i+1:])
+	}
+	if m := indexedRe.FindStringSubmatch(name); m != nil && arg == "" {
+		n := 1
+		if m[2] != "" {
+			n, _ = strconv.Atoi(m[2])
+		}
+		switch m[1] {
+		case "star":
+			return r.star(n)
+		case "botstar":
+			return r.thatstar(n)
+		case "input":
+			return starRef("input", n)
+		}
+		if n == 1 {
+			return "<that/>"
+		}
+		return fmt.Sprintf(`<that index="%d,1"/>`, n)
+	}
+	switch name {
+	case "id":
+		return "<id/>"
+	case "@":
+		return "<srai>" + r.star(1) + "</srai>"
+	case "bot", "get":
+		if arg != "" && !strings.Contains(arg, "=") {
+			return fmt.Sprintf(`<%s name="%s"/>`, name, attrEscaper.Replace(arg))
+		}
+	case "set":
+		if k, v, ok := strings.Cut(arg, "="); ok {
+			return `<think><set name="` + attrEscaper.Replace(strings.TrimSpace(k)) + `">` + cv.reply(line, strings.TrimSpace(v), r) + "</set></think>"
+		}
+	case "add", "sub", "mult", "div", "env":
+		cv.add(line, aiml.SeverityError, "Tag is not translated [%s]", tok)
+		return ""
+	}
+	if wrapTags[name] && arg == "" {
+		return "<" + name + ">" + r.star(1) + "</" + name + ">"
+	}
+	if htmlTags[strings.ToLower(strings.Trim(name, "/"))] {
+		return tok
+	}
+	cv.add(line, aiml.SeverityError, "Tag is not translated [%s]", tok)
+	return ""
+}
+
+// braceTag translates a {tag} of a reply
+func (cv *converter) braceTag(line int, tok, inner string, r refs) string {
+	lower := strings.ToLower(inner)
+	switch {
+	case strings.HasPrefix(inner, "@"):
+		return "<srai>" + cv.reply(line, strings.TrimSpace(inner[1:]), r) + "</srai>"
+	case strings.HasPrefix(lower, "topic="):
+		return `<think><set name="topic">` + textEscaper.Replace(strings.TrimSpace(inner[len("topic="):])) + "</set></think>"
+	case wrapTags[lower]:
+		return "<" + lower + ">"
+	case strings.HasPrefix(lower, "/") && wrapTags[lower[1:]]:
+		return "</" + lower[1:] + ">"
+	case strings.HasPrefix(lower, "weight="), lower == "ok":
+		return ""
+	}
+	cv.add(line, aiml.SeverityError, "Tag is not translated [%s]", tok)
+	return ""
+}
@@ -0,0 +1,227 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Storage is a Storage keeping the artifacts as objects of an Amazon S3 or
+// S3 compatible bucket, like MinIO, signing the requests with AWS Signature
+// Version 4
+type S3Storage struct {
+	Bucket string
+	Prefix string // Prepended to the names, like backups/
+	Region string // Defaults to us-east-1
+	// Endpoint is the URL of an S3 compatible service, like
+	// http://localhost:9000, addressing the bucket in the path. Empty for
+	// Amazon S3, addressing the bucket in the host name.
+	Endpoint     string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string       // The token of temporary credentials, empty for none
+	Client       *http.Client // Defaults to http.DefaultClient
+}
+
+// NewS3Storage creates a storage in the bucket of Amazon S3 with the credentials
+// of the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN and
+// AWS_REGION environment variables. S3_ENDPOINT selects an S3 compatible service.
+func NewS3Storage(bucket, prefix string) *S3Storage {
+	return &S3Storage{
+		Bucket:       bucket,
+		Prefix:       prefix,
+		Region:       os.Getenv("AWS_REGION"),
+		Endpoint:     os.Getenv("S3_ENDPOINT"),
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+func (s *S3Storage) region() string {
+	if s.Region == "" {
+		return "us-east-1"
+	}
+	return s.Region
+}
+
+// objectUrl returns the URL of the object, or of the bucket if key is empty
+func (s *S3Storage) objectUrl(key string) string {
+	if s.Endpoint != "" {
+		return strings.TrimSuffix(s.Endpoint, "/") + "/" + s.Bucket + "/" + key
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.Bucket, s.region(), key)
+}
+
+// s3Escape escapes the string as Signature Version 4 expects, keeping the slashes if path is true
+func s3Escape(s string, path bool) string {
+	var buf strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~', path && b == '/':
+			buf.WriteByte(b)
+		default:
+			fmt.Fprintf(&buf, "%%%02X", b)
+		}
+	}
+	return buf.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sign adds the Signature Version 4 authorization of the request, signing
+// the host and the headers already set
+func (s *S3Storage) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := make([]string, len(keys))
+	for i, k := range keys {
+		params[i] = s3Escape(k, false) + "=" + s3Escape(query.Get(k), false)
+	}
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	canonical := strings.Join([]string{req.Method, s3Escape(path, true), strings.Join(params, "&"), canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	sum := sha256.Sum256([]byte(canonical))
+	scope := amzDate[:8] + "/" + s.region() + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), amzDate[:8])
+	key = hmacSHA256(key, s.region())
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, toSign))))
+}
+
+// do sends a signed request and returns the response body. A missing object is reported as os.ErrNotExist.
+func (s *S3Storage) do(method, key string, query url.Values, body []byte) ([]byte, error) {
+	rawurl := s.objectUrl(s3Escape(key, true))
+	if len(query) > 0 {
+		rawurl += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, rawurl, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	s.sign(req, hex.EncodeToString(sum[:]), time.Now())
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound && method == "GET" && key != "":
+		return nil, &os.PathError{Op: "get", Path: key, Err: os.ErrNotExist}
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, fmt.Errorf("S3 request failed [%s %s] - %s: %s", method, key, resp.Status, bytes.TrimSpace(data))
+	}
+	return data, nil
+}
+
+func (s *S3Storage) Put(name string, data io.Reader) error {
+	body, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	_, err = s.do("PUT", s.Prefix+name, nil, body)
+	return err
+}
+
+func (s *S3Storage) Get(name string, w io.Writer) error {
+	data, err := s.do("GET", s.Prefix+name, nil, nil)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// listResult is the response of ListObjectsV2
+type listResult struct {
+	Contents []struct {
+		Key string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+func (s *S3Storage) List(prefix string) ([]string, error) {
+	var names []string
+	query := url.Values{"list-type": {"2"}, "prefix": {s.Prefix + prefix}}
+	for {
+		data, err := s.do("GET", "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var res listResult
+		if err = xml.Unmarshal(data, &res); err != nil {
+			return nil, fmt.Errorf("S3 list response is not valid - %v", err)
+		}
+		for _, c := range res.Contents {
+			names = append(names, strings.TrimPrefix(c.Key, s.Prefix))
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			break
+		}
+		query.Set("continuation-token", res.NextContinuationToken)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *S3Storage) Delete(name string) error {
+	_, err := s.do("DELETE", s.Prefix+name, nil, nil)
+	return err
+}
@@ -0,0 +1,153 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is when a recurring job runs, parsed from a cron-like spec by ParseSchedule
+type Schedule struct {
+	every time.Duration // The interval of an @every spec, zero for the field specs
+	// The allowed values of the minute, hour, day of month, month and day of week fields, as bits
+	fields      [5]uint64
+	anyDay      bool // The day of month field is *
+	anyWeekday  bool // The day of week field is *
+	description string
+}
+
+// scheduleFields are the ranges of the cron fields
+var scheduleFields = [5]struct {
+	name     string
+	min, max int
+}{{"minute", 0, 59}, {"hour", 0, 23}, {"day of month", 1, 31}, {"month", 1, 12}, {"day of week", 0, 7}}
+
+// scheduleAliases are the cron shortcuts
+var scheduleAliases = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+}
+
+// ParseSchedule parses a cron spec with the minute, hour, day of month, month
+// and day of week fields, like "30 2 * * 1-5" for 2:30 on week days. The
+// fields take *, numbers, ranges, lists and steps, like */15 or 1,15. The
+// day of week is 0 or 7 for Sunday, and a day matches if either of the day
+// fields matches when both are restricted, like cron does. The @hourly,
+// @daily, @weekly, @monthly and @yearly shortcuts and "@every DURATION",
+// like "@every 6h", are also accepted.
+func ParseSchedule(spec string) (Schedule, error) {
+	s := Schedule{description: spec}
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(spec[len("@every "):]))
+		if err != nil || d <= 0 {
+			return s, fmt.Errorf("Schedule is not valid [%s] - the interval must be a positive duration", spec)
+		}
+		s.every = d
+		return s, nil
+	}
+	if alias, ok := scheduleAliases[spec]; ok {
+		spec = alias
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return s, fmt.Errorf("Schedule is not valid [%s] - it must have 5 fields", spec)
+	}
+	for i, field := range fields {
+		f := scheduleFields[i]
+		for _, part := range strings.Split(field, ",") {
+			rng, step := part, 1
+			if n := strings.Index(part, "/"); n >= 0 {
+				var err error
+				if step, err = strconv.Atoi(part[n+1:]); err != nil || step <= 0 {
+					return s, fmt.Errorf("Schedule is not valid [%s] - bad step in the %s field", spec, f.name)
+				}
+				rng = part[:n]
+			}
+			lo, hi := f.min, f.max
+			if rng != "*" {
+				bounds := strings.SplitN(rng, "-", 2)
+				var err error
+				if lo, err = strconv.Atoi(bounds[0]); err != nil {
+					return s, fmt.Errorf("Schedule is not valid [%s] - bad value in the %s field", spec, f.name)
+				}
+				hi = lo
+				if len(bounds) == 2 {
+					if hi, err = strconv.Atoi(bounds[1]); err != nil {
+						return s, fmt.Errorf("Schedule is not valid [%s] - bad value in the %s field", spec, f.name)
+					}
+				} else if step > 1 {
+					hi = f.max
+				}
+				if lo < f.min || hi > f.max || lo > hi {
+					return s, fmt.Errorf("Schedule is not valid [%s] - the %s field must be within %d-%d", spec, f.name, f.min, f.max)
+				}
+			}
+			for v := lo; v <= hi; v += step {
+				s.fields[i] |= 1 << uint(v)
+			}
+		}
+	}
+	// Sunday is both 0 and 7
+	if s.fields[4]&(1<<7) != 0 {
+		s.fields[4] |= 1
+	}
+	s.anyDay, s.anyWeekday = fields[2] == "*", fields[4] == "*"
+	return s, nil
+}
+
+func (s Schedule) String() string {
+	return s.description
+}
+
+// has reports whether the value is allowed in the field
+func (s Schedule) has(field, v int) bool {
+	return s.fields[field]&(1<<uint(v)) != 0
+}
+
+// dayMatches reports whether the day of t is allowed by the day of month and day of week fields
+func (s Schedule) dayMatches(t time.Time) bool {
+	mday, wday := s.has(2, t.Day()), s.has(4, int(t.Weekday()))
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return wday
+	case s.anyWeekday:
+		return mday
+	}
+	return mday || wday
+}
+
+// Next returns the first time the schedule runs after t, in the location of
+// t, or the zero time if it never does, like on February 30
+func (s Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		switch {
+		case !s.has(3, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !s.has(1, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !s.has(0, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
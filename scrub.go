@@ -0,0 +1,115 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ScrubRule finds a kind of sensitive data in texts
+type ScrubRule struct {
+	Kind    string // The kind of data, e.g. email, passed to the redaction
+	Pattern *regexp.Regexp
+}
+
+// PIIRules are the rules of NewPIIScrubber, finding email addresses, payment
+// card numbers, phone numbers, US social security numbers and IP addresses
+var PIIRules = []ScrubRule{
+	{"email", regexp.MustCompile(`[\w.+-]+@[\w-]+(\.[\w-]+)+`)},
+	{"card", regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)},
+	{"ssn", regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{"phone", regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,4}\)[ .-]?)?\d{2,4}[ .-]\d{3,4}(?:[ .-]?\d{3,4})?\b`)},
+	{"ip", regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)},
+}
+
+// Scrubber redacts sensitive data, like personal data and profanity, from the
+// texts of a conversation. Its Scrub method is an InputFilter, so the inputs
+// can be scrubbed before they are sent, and it can scrub the transcripts.
+type Scrubber struct {
+	// Rules find sensitive data, applied in order
+	Rules []ScrubRule
+	// Words are denied words, matched whole and ignoring case, of the kind "word"
+	Words []string
+	// Redact returns the replacement of the sensitive data of the kind.
+	// Defaults to the kind in brackets, e.g. [email], and to asterisks for the words.
+	Redact func(kind, match string) string
+
+	once sync.Once
+	deny map[string]bool
+}
+
+// NewPIIScrubber creates a scrubber redacting the personal data of PIIRules
+func NewPIIScrubber() *Scrubber {
+	return &Scrubber{Rules: PIIRules}
+}
+
+func (s *Scrubber) redact(kind, match string) string {
+	if s.Redact != nil {
+		return s.Redact(kind, match)
+	}
+	if kind == "word" {
+		return strings.Repeat("*", utf8.RuneCountInString(match))
+	}
+	return "[" + kind + "]"
+}
+
+// Scrub returns the text with the sensitive data redacted
+func (s *Scrubber) Scrub(text string) string {
+	for _, rule := range s.Rules {
+		text = rule.Pattern.ReplaceAllStringFunc(text, func(match string) string {
+			return s.redact(rule.Kind, match)
+		})
+	}
+	if len(s.Words) > 0 {
+		s.once.Do(func() { s.deny = denyList(s.Words) })
+		text = replaceWords(text, s.deny, func(word string) string {
+			return s.redact("word", word)
+		})
+	}
+	return text
+}
+
+// denyList returns the set of the lower case words
+func denyList(words []string) map[string]bool {
+	deny := make(map[string]bool, len(words))
+	for _, w := range words {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			deny[w] = true
+		}
+	}
+	return deny
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\''
+}
+
+// replaceWords replaces the whole words of the text in the deny list with the result of fn
+func replaceWords(text string, deny map[string]bool, fn func(word string) string) string {
+	var b strings.Builder
+	for len(text) > 0 {
+		end := strings.IndexFunc(text, func(r rune) bool { return !isWordRune(r) })
+		if end < 0 {
+			end = len(text)
+		}
+		if end == 0 {
+			_, size := utf8.DecodeRuneInString(text)
+			b.WriteString(text[:size])
+			text = text[size:]
+			continue
+		}
+		if word := text[:end]; deny[strings.ToLower(word)] {
+			b.WriteString(fn(word))
+		} else {
+			b.WriteString(word)
+		}
+		text = text[end:]
+	}
+	return b.String()
+}
@@ -0,0 +1,211 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/demisto/pb-go/aiml"
+)
+
+// FileCache keeps the downloaded files of the bots, so repeated searches do
+// not download them again. The files are downloaded again when the listing
+// of the bot shows a file was added, removed or modified.
+type FileCache struct {
+	mu      sync.Mutex
+	bots    map[string]cachedFiles
+	storage Storage
+}
+
+// cachedFiles are the files of a bot with the listing they were downloaded at
+type cachedFiles struct {
+	Stamp string            `json:"stamp"`
+	Files map[string][]byte `json:"files"`
+}
+
+// fileCachePrefix is the prefix of the names of the cached files in the storage
+const fileCachePrefix = "files/"
+
+// NewFileCache creates an empty file cache
+func NewFileCache() *FileCache {
+	return &FileCache{}
+}
+
+// NewStorageFileCache creates a file cache also keeping the files in the
+// storage, as files/BOT.json, so they outlive the process
+func NewStorageFileCache(s Storage) *FileCache {
+	return &FileCache{storage: s}
+}
+
+// stored returns the files of the bot kept in the storage, if any
+func (fc *FileCache) stored(name string) (cachedFiles, bool) {
+	var buf bytes.Buffer
+	var cached cachedFiles
+	if fc.storage == nil || fc.storage.Get(fileCachePrefix+name+".json", &buf) != nil {
+		return cached, false
+	}
+	return cached, json.Unmarshal(buf.Bytes(), &cached) == nil
+}
+
+// store keeps the files of the bot in the storage, if any
+func (fc *FileCache) store(name string, cached cachedFiles) error {
+	if fc.storage == nil {
+		return nil
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	return fc.storage.Put(fileCachePrefix+name+".json", bytes.NewReader(data))
+}
+
+// SetFileCache keeps the files downloaded by SearchBot in the cache
+func SetFileCache(cache *FileCache) OptionFunc {
+	return func(c *Client) error {
+		c.files = cache
+		return nil
+	}
+}
+
+// Purge removes the cached files of all the bots, including from the storage
+func (fc *FileCache) Purge() error {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.bots = nil
+	if fc.storage == nil {
+		return nil
+	}
+	names, err := fc.storage.List(fileCachePrefix)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err = fc.storage.Delete(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listingStamp identifies the state of the files of a listing
+func listingStamp(list BotFiles) string {
+	var buf bytes.Buffer
+	for _, files := range [][]BotFile{list.Files, list.Sets, list.Maps, list.Substitutions, list.Properties, list.Pdefaults} {
+		for _, f := range files {
+			fmt.Fprintf(&buf, "%s %d %d\n", f.Name, f.Size, f.Modified.UnixNano())
+		}
+	}
+	return buf.String()
+}
+
+// cachedContents returns the files of the bot by file name from the file cache, if any, or downloads them
+func (c *Client) cachedContents(name string) (map[string][]byte, error) {
+	if c.files == nil {
+		return c.fileContents(name)
+	}
+	list, err := c.ListFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	stamp := listingStamp(list)
+	c.files.mu.Lock()
+	cached, ok := c.files.bots[name]
+	c.files.mu.Unlock()
+	if !ok || cached.Stamp != stamp {
+		cached, ok = c.files.stored(name)
+	}
+	if !ok || cached.Stamp != stamp {
+		files, err := c.fileContents(name)
+		if err != nil {
+			return nil, err
+		}
+		cached = cachedFiles{Stamp: stamp, Files: files}
+		// The files are still cached in memory if the storage fails
+		if err = c.files.store(name, cached); err != nil {
+			c.errorf("Unable to store the files of %s in the file cache - %v", name, err)
+		}
+	}
+	c.files.mu.Lock()
+	if c.files.bots == nil {
+		c.files.bots = make(map[string]cachedFiles)
+	}
+	c.files.bots[name] = cached
+	c.files.mu.Unlock()
+	return cached.Files, nil
+}
+
+// SearchMatch is a line of a bot file matching a search
+type SearchMatch struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Kind    string `json:"kind"`              // pattern, that, topic or template for AIML files, else the file kind, like set
+	Pattern string `json:"pattern,omitempty"` // The pattern of the category of the line, for AIML files
+	Text    string `json:"text"`              // The line, trimmed
+}
+
+// SearchBot searches the files of the bot for the lines containing the query, ignoring case
+func (c *Client) SearchBot(bot, query string) ([]SearchMatch, error) {
+	return c.SearchBotRegexp(bot, regexp.MustCompile("(?i)"+regexp.QuoteMeta(query)))
+}
+
+// SearchBotRegexp searches the files of the bot for the lines matching the
+// regular expression, e.g. to find where a response comes from. The matches
+// of the AIML files tell the pattern of the category and the element the
+// line is in. The files are downloaded, or taken from the file cache.
+func (c *Client) SearchBotRegexp(bot string, re *regexp.Regexp) ([]SearchMatch, error) {
+	files, err := c.cachedContents(bot)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	matches := []SearchMatch{}
+	for _, name := range names {
+		matches = append(matches, searchFile(name, files[name], re)...)
+	}
+	return matches, nil
+}
+
+// searchFile returns the lines of the file matching the regular expression
+func searchFile(name string, data []byte, re *regexp.Regexp) []SearchMatch {
+	var cats []aiml.Category
+	kind := strings.TrimPrefix(filepath.Ext(name), ".")
+	if kind == "aiml" {
+		if doc, err := aiml.Parse(data); err == nil {
+			cats = doc.Categories()
+		}
+	}
+	var matches []SearchMatch
+	for i, line := range strings.Split(string(data), "\n") {
+		if !re.MatchString(line) {
+			continue
+		}
+		m := SearchMatch{File: name, Line: i + 1, Kind: kind, Text: strings.TrimSpace(line)}
+		// The line is in the last category starting before it, and in its last element starting before it
+		for _, c := range cats {
+			if c.Line > m.Line {
+				break
+			}
+			m.Kind, m.Pattern = "category", c.Pattern
+			for _, e := range c.Node.Children {
+				if e.Kind == aiml.ElementNode && e.Line <= m.Line {
+					m.Kind = e.Name
+				}
+			}
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
@@ -0,0 +1,71 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"regexp"
+)
+
+// ReplyContext is the conversation a reply of the bot was matched in
+type ReplyContext struct {
+	Bot     string
+	Key     string // The user key of the channel
+	Input   string // The input of the user, before the input filters
+	Session Session
+}
+
+// ReplySelector chooses the reply sent to the user of a Conversation from the
+// reply of the bot and the conversation, e.g. to answer the users of a beta
+// cohort with new pricing answers without editing the AIML. It returns the
+// reply of the bot, possibly changed, or another reply. A nil reply keeps the
+// reply of the bot.
+type ReplySelector func(ctx ReplyContext, reply *Reply) (*Reply, error)
+
+// selectReply runs the selector of the conversation on the reply of the bot
+func (cv *Conversation) selectReply(ctx ReplyContext, reply *Reply) (*Reply, error) {
+	selected, err := cv.Selector(ctx, reply)
+	if err != nil || selected == nil {
+		return reply, err
+	}
+	if selected != reply {
+		selected.SessionId = reply.SessionId
+		selected.Info = reply.Info
+		selected.Info.Selected = true
+	}
+	return selected, nil
+}
+
+// FeatureFlag returns a ReplySelector applying the selector only in the
+// conversations the flag is enabled for, keeping the replies of the bot otherwise
+func FeatureFlag(enabled func(ctx ReplyContext) bool, selector ReplySelector) ReplySelector {
+	return func(ctx ReplyContext, reply *Reply) (*Reply, error) {
+		if !enabled(ctx) {
+			return reply, nil
+		}
+		return selector(ctx, reply)
+	}
+}
+
+// Cohort returns a feature flag enabled for the user keys, e.g. the beta testers
+func Cohort(keys ...string) func(ctx ReplyContext) bool {
+	members := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		members[k] = true
+	}
+	return func(ctx ReplyContext) bool {
+		return members[ctx.Key]
+	}
+}
+
+// OverrideReply returns a ReplySelector answering the inputs matching re with
+// the responses instead of the responses of the bot
+func OverrideReply(re *regexp.Regexp, responses ...string) ReplySelector {
+	return func(ctx ReplyContext, reply *Reply) (*Reply, error) {
+		if !re.MatchString(ctx.Input) {
+			return reply, nil
+		}
+		return &Reply{Responses: append([]string{}, responses...)}, nil
+	}
+}
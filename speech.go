@@ -0,0 +1,230 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// ErrNoSpeech is returned when no speech was recognized in the audio
+var ErrNoSpeech = errors.New("No speech recognized in the audio")
+
+// STT is a speech to text engine
+type STT interface {
+	// Transcribe returns the text spoken in the audio
+	Transcribe(audio io.Reader) (string, error)
+}
+
+// TalkAudio transcribes the audio with the engine and sends the text to the bot
+func (c *Client) TalkAudio(stt STT, name string, audio io.Reader, clientName string, sessionId int, recent bool) (*Reply, error) {
+	input, err := transcribe(stt, audio)
+	if err != nil {
+		return nil, err
+	}
+	return c.Talk(name, input, clientName, sessionId, recent)
+}
+
+// TalkAudio transcribes the audio with the engine and sends the text within the session of the key
+func (cv *Conversation) TalkAudio(stt STT, key string, audio io.Reader) (*Reply, error) {
+	input, err := transcribe(stt, audio)
+	if err != nil {
+		return nil, err
+	}
+	return cv.Talk(key, input)
+}
+
+func transcribe(stt STT, audio io.Reader) (string, error) {
+	text, err := stt.Transcribe(audio)
+	if err != nil {
+		return "", err
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return "", ErrNoSpeech
+	}
+	return text, nil
+}
+
+// HTTPTranscriber is an STT sending the audio to an external speech to text HTTP
+// endpoint. The audio is posted as the body or, when FormField is set, as a
+// multipart form file like the OpenAI compatible transcription APIs expect.
+// The transcript is read from the TextField of a JSON response, or is the
+// whole body of a text response.
+type HTTPTranscriber struct {
+	Url         string
+	ContentType string            // The content type of the audio, e.g. audio/wav. Defaults to application/octet-stream.
+	FormField   string            // The form field of the audio file, empty to post the audio as the body
+	FileName    string            // The file name of the form file, some services infer the format from it. Defaults to audio.
+	Params      map[string]string // Additional form fields, e.g. the model
+	Header      http.Header       // Additional headers, e.g. the authorization
+	TextField   string            // The field of the JSON response holding the transcript. Defaults to text.
+	HttpClient  *http.Client      // Defaults to http.DefaultClient
+}
+
+// Transcribe sends the audio to the endpoint and returns the transcript
+func (t *HTTPTranscriber) Transcribe(audio io.Reader) (string, error) {
+	contentType := t.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	body := audio
+	if t.FormField != "" {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		for k, v := range t.Params {
+			mw.WriteField(k, v)
+		}
+		fileName := t.FileName
+		if fileName == "" {
+			fileName = "audio"
+		}
+		h := make(textproto.MIMEHeader)
+		h["Content-Disposition"] = []string{fmt.Sprintf(`form-data; name="%s"; filename="%s"`, t.FormField, fileName)}
+		h["Content-Type"] = []string{contentType}
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return "", err
+		}
+		if _, err = io.Copy(part, audio); err != nil {
+			return "", err
+		}
+		if err = mw.Close(); err != nil {
+			return "", err
+		}
+		body, contentType = &buf, mw.FormDataContentType()
+	}
+	req, err := http.NewRequest(http.MethodPost, t.Url, body)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range t.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	client := t.HttpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", &APIError{StatusCode: resp.StatusCode, Body: data}
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "application/json" {
+		return string(data), nil
+	}
+	field := t.TextField
+	if field == "" {
+		field = "text"
+	}
+	var res map[string]interface{}
+	if err = json.Unmarshal(data, &res); err != nil {
+		return "", &DecodeError{Err: err, Body: data}
+	}
+	text, ok := res[field].(string)
+	if !ok {
+		return "", fmt.Errorf("Transcript field [%s] not found in the response", field)
+	}
+	return text, nil
+}
+
+// TTS is a text to speech engine
+type TTS interface {
+	// Synthesize writes the audio of the SSML speech to w
+	Synthesize(ssml string, w io.Writer) error
+}
+
+// SpeakReply renders the responses of the reply to audio with the engine. The
+// responses are converted to SSML with the default settings, so voice channels
+// speak the bot markup the same way.
+func SpeakReply(tts TTS, reply *Reply, w io.Writer) error {
+	return SpeakResponses(tts, SSML{}, reply.Responses, w)
+}
+
+// SpeakResponses renders the responses to audio with the engine, converted to SSML with the settings
+func SpeakResponses(tts TTS, s SSML, responses []string, w io.Writer) error {
+	if len(responses) == 0 {
+		return nil
+	}
+	return tts.Synthesize(s.Convert(responses), w)
+}
+
+// HTTPSynthesizer is a TTS sending the speech to an external text to speech
+// HTTP endpoint and writing the audio of the response. The SSML is posted as
+// the body, as the SSML engines expect, or, when TextField is set, in a JSON
+// request like the OpenAI compatible speech APIs expect.
+type HTTPSynthesizer struct {
+	Url        string
+	TextField  string                 // The field of the JSON request holding the speech, empty to post the SSML as the body
+	PlainText  bool                   // Send the text without the SSML markup, for the engines not supporting SSML
+	Params     map[string]interface{} // Additional fields of the JSON request, e.g. the model and the voice
+	Header     http.Header            // Additional headers, e.g. the authorization or the audio format
+	HttpClient *http.Client           // Defaults to http.DefaultClient
+}
+
+// plainText returns the text of the SSML speech
+func plainText(ssml string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(tagRe.ReplaceAllString(ssml, " "))), " ")
+}
+
+// Synthesize sends the speech to the endpoint and writes the audio to w
+func (t *HTTPSynthesizer) Synthesize(ssml string, w io.Writer) error {
+	speech, contentType := ssml, "application/ssml+xml"
+	if t.PlainText {
+		speech, contentType = plainText(ssml), "text/plain; charset=utf-8"
+	}
+	body := io.Reader(strings.NewReader(speech))
+	if t.TextField != "" {
+		req := make(map[string]interface{}, len(t.Params)+1)
+		for k, v := range t.Params {
+			req[k] = v
+		}
+		req[t.TextField] = speech
+		data, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body, contentType = bytes.NewReader(data), "application/json"
+	}
+	req, err := http.NewRequest(http.MethodPost, t.Url, body)
+	if err != nil {
+		return err
+	}
+	for k, v := range t.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	client := t.HttpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return &APIError{StatusCode: resp.StatusCode, Body: data}
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
@@ -0,0 +1,112 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Message length limits of common channels, in characters
+const (
+	SMSLength      = 160
+	DiscordLength  = 2000
+	TelegramLength = 4096
+)
+
+// Splitter breaks long responses into messages fitting the length limit of a
+// channel. Responses are split at the end of a sentence where possible, then
+// between words, and only cut inside a word when it is longer than the limit.
+type Splitter struct {
+	Limit int  // The maximum length of a message, zero for no limit
+	Bytes bool // Measure the length in bytes instead of characters, e.g. for IRC
+}
+
+// SplitLength returns a ReplyFilter splitting the responses longer than limit characters
+func SplitLength(limit int) ReplyFilter {
+	return Splitter{Limit: limit}.Filter
+}
+
+// sentenceEndRe matches the end of a sentence with the space following it.
+// The full stops of the CJK scripts are not followed by spaces.
+var sentenceEndRe = regexp.MustCompile(`[.!?…]["'”’)\]]*\s|[。！？]|\n`)
+
+// Filter splits the long responses, a ReplyFilter
+func (s Splitter) Filter(responses []string) []string {
+	res := make([]string, 0, len(responses))
+	for _, r := range responses {
+		res = append(res, s.Split(r)...)
+	}
+	return res
+}
+
+// prefix returns the byte length of the longest prefix of text within the limit
+func (s Splitter) prefix(text string) int {
+	if s.Bytes {
+		if len(text) <= s.Limit {
+			return len(text)
+		}
+		end := s.Limit
+		for end > 0 && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		return end
+	}
+	n := 0
+	for i := range text {
+		if n == s.Limit {
+			return i
+		}
+		n++
+	}
+	return len(text)
+}
+
+// Split splits the text into messages within the limit
+func (s Splitter) Split(text string) []string {
+	text = strings.TrimSpace(text)
+	if s.Limit <= 0 {
+		if text == "" {
+			return nil
+		}
+		return []string{text}
+	}
+	var parts []string
+	for {
+		end := s.prefix(text)
+		if end == len(text) {
+			break
+		}
+		cut := 0
+		// Include the rune after the limit, a space ending a sentence can follow it
+		_, size := utf8.DecodeRuneInString(text[end:])
+		window := text[:end+size]
+		for _, m := range sentenceEndRe.FindAllStringIndex(window, -1) {
+			if c := len(strings.TrimRightFunc(window[:m[1]], unicode.IsSpace)); c <= end {
+				cut = c
+			}
+		}
+		if cut == 0 {
+			cut = strings.LastIndexFunc(text[:end+size], unicode.IsSpace)
+		}
+		if cut <= 0 {
+			cut = end
+		}
+		if cut == 0 {
+			// The limit is shorter than the first rune
+			_, cut = utf8.DecodeRuneInString(text)
+		}
+		if part := strings.TrimSpace(text[:cut]); part != "" {
+			parts = append(parts, part)
+		}
+		text = strings.TrimSpace(text[cut:])
+	}
+	if text != "" {
+		parts = append(parts, text)
+	}
+	return parts
+}
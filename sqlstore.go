@@ -0,0 +1,149 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SQLTranscriptStore is a TranscriptStore keeping the entries in the
+// transcripts table of a SQL database. It is written for SQLite, opened with
+// the database/sql driver of the application, like modernc.org/sqlite or
+// github.com/mattn/go-sqlite3, and uses the ? placeholders also understood by
+// MySQL. The responses are kept as a JSON array and the times as Unix nanoseconds.
+type SQLTranscriptStore struct {
+	DB *sql.DB
+
+	mu sync.Mutex // Serializes the appends, so the turns of a session are counted once
+}
+
+// transcriptsSchema creates the transcripts table and its indexes
+var transcriptsSchema = []string{
+	`CREATE TABLE IF NOT EXISTS transcripts (
+		bot TEXT NOT NULL,
+		client_name TEXT NOT NULL,
+		session_id INTEGER NOT NULL,
+		turn INTEGER NOT NULL,
+		input TEXT NOT NULL,
+		responses TEXT NOT NULL,
+		latency INTEGER NOT NULL,
+		at INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS transcripts_client ON transcripts (client_name, bot, session_id)`,
+	`CREATE INDEX IF NOT EXISTS transcripts_at ON transcripts (at)`,
+}
+
+// NewSQLTranscriptStore creates a store in the database, creating the transcripts table if needed
+func NewSQLTranscriptStore(db *sql.DB) (*SQLTranscriptStore, error) {
+	for _, stmt := range transcriptsSchema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("Unable to create the transcripts table - %v", err)
+		}
+	}
+	return &SQLTranscriptStore{DB: db}, nil
+}
+
+func (s *SQLTranscriptStore) Append(e *TranscriptEntry) error {
+	responses, err := json.Marshal(e.Responses)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = tx.QueryRow(`SELECT COUNT(*) FROM transcripts WHERE client_name = ? AND bot = ? AND session_id = ?`,
+		e.ClientName, e.Bot, e.SessionId).Scan(&e.Turn)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO transcripts (bot, client_name, session_id, turn, input, responses, latency, at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Bot, e.ClientName, e.SessionId, e.Turn, e.Input, string(responses), int64(e.Latency), e.Time.UnixNano())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// where returns the conditions of the query and their arguments
+func (q TranscriptQuery) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		conds = append(conds, cond)
+		args = append(args, arg)
+	}
+	if q.Bot != "" {
+		add("bot = ?", q.Bot)
+	}
+	if q.ClientName != "" {
+		add("client_name = ?", q.ClientName)
+	}
+	if q.SessionId != 0 {
+		add("session_id = ?", q.SessionId)
+	}
+	if !q.Since.IsZero() {
+		add("at >= ?", q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		add("at < ?", q.Until.UnixNano())
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (s *SQLTranscriptStore) Query(q TranscriptQuery) ([]TranscriptEntry, error) {
+	where, args := q.where()
+	query := `SELECT bot, client_name, session_id, turn, input, responses, latency, at FROM transcripts` + where + ` ORDER BY at DESC`
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := make([]TranscriptEntry, 0)
+	for rows.Next() {
+		var e TranscriptEntry
+		var responses string
+		var latency, at int64
+		if err = rows.Scan(&e.Bot, &e.ClientName, &e.SessionId, &e.Turn, &e.Input, &responses, &latency, &at); err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal([]byte(responses), &e.Responses); err != nil {
+			return nil, fmt.Errorf("Transcript entry of [%s] is not valid - %v", e.ClientName, err)
+		}
+		e.Latency, e.Time = time.Duration(latency), time.Unix(0, at).UTC()
+		res = append(res, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	// The most recent entries were selected, the oldest first is returned
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+	return res, nil
+}
+
+func (s *SQLTranscriptStore) Delete(clientName string) (int, error) {
+	res, err := s.DB.Exec(`DELETE FROM transcripts WHERE client_name = ?`, clientName)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
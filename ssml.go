@@ -0,0 +1,162 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SSML converts bot responses to Speech Synthesis Markup Language for text to
+// speech engines. Emphasis and line breaks are kept, visual only markup like
+// images, buttons and cards is dropped and other HTML tags are stripped.
+type SSML struct {
+	ResponseBreak time.Duration // The pause between responses. Defaults to 500ms.
+	OmitSpeak     bool          // Do not wrap the result in a <speak> element
+}
+
+// ToSSML converts the responses to SSML with the default settings
+func ToSSML(responses []string) string {
+	return SSML{}.Convert(responses)
+}
+
+var markupRe = regexp.MustCompile(`<(/?)([a-zA-Z][\w:-]*)[^<>]*?(/?)>`)
+
+// visualOnly are the elements dropped with their content
+var visualOnly = map[string]bool{
+	"button": true, "card": true, "carousel": true, "hint": true, "image": true, "img": true,
+	"link": true, "oob": true, "postback": true, "reply": true, "url": true, "video": true,
+}
+
+// ssmlElements maps HTML elements to the SSML element and attributes replacing them
+var ssmlElements = map[string][2]string{
+	"b":      {"emphasis", ` level="strong"`},
+	"strong": {"emphasis", ` level="strong"`},
+	"em":     {"emphasis", ` level="moderate"`},
+	"i":      {"emphasis", ` level="moderate"`},
+	"p":      {"p", ""},
+}
+
+// ssmlWriter holds the state of a response conversion
+type ssmlWriter struct {
+	sb    strings.Builder
+	open  []string // The SSML elements open, to keep the output well formed
+	skip  int      // Depth inside visual only elements
+	delay *strings.Builder
+}
+
+func (w *ssmlWriter) text(s string) {
+	switch {
+	case w.skip > 0:
+	case w.delay != nil:
+		w.delay.WriteString(s)
+	default:
+		w.sb.WriteString(xmlEscaper.Replace(html.UnescapeString(s)))
+	}
+}
+
+func (w *ssmlWriter) tag(closing bool, name string, selfClosing bool) {
+	name = strings.ToLower(name)
+	if visualOnly[name] {
+		if !selfClosing {
+			if closing {
+				if w.skip > 0 {
+					w.skip--
+				}
+			} else {
+				w.skip++
+			}
+		}
+		return
+	}
+	if w.skip > 0 {
+		return
+	}
+	switch name {
+	case "br", "split":
+		w.sb.WriteString(`<break strength="medium"/>`)
+		return
+	case "li":
+		if closing {
+			w.sb.WriteString(`<break strength="weak"/>`)
+		}
+		return
+	case "delay":
+		// Pandorabots delays hold the number of seconds to wait
+		if !closing && !selfClosing {
+			w.delay = &strings.Builder{}
+		} else if closing && w.delay != nil {
+			if secs, err := strconv.ParseFloat(strings.TrimSpace(w.delay.String()), 64); err == nil && secs > 0 {
+				if secs > 10 {
+					secs = 10
+				}
+				w.sb.WriteString(fmt.Sprintf(`<break time="%dms"/>`, int(secs*1000)))
+			}
+			w.delay = nil
+		}
+		return
+	}
+	el, ok := ssmlElements[name]
+	if !ok || selfClosing {
+		return
+	}
+	if !closing {
+		w.sb.WriteString("<" + el[0] + el[1] + ">")
+		w.open = append(w.open, el[0])
+		return
+	}
+	// Close the innermost matching element, and the ones opened inside it
+	for i := len(w.open) - 1; i >= 0; i-- {
+		if w.open[i] == el[0] {
+			for j := len(w.open) - 1; j >= i; j-- {
+				w.sb.WriteString("</" + w.open[j] + ">")
+			}
+			w.open = w.open[:i]
+			return
+		}
+	}
+}
+
+func (w *ssmlWriter) convert(response string) {
+	last := 0
+	for _, m := range markupRe.FindAllStringSubmatchIndex(response, -1) {
+		w.text(response[last:m[0]])
+		w.tag(m[3] > m[2], response[m[4]:m[5]], m[7] > m[6])
+		last = m[1]
+	}
+	w.text(response[last:])
+	for i := len(w.open) - 1; i >= 0; i-- {
+		w.sb.WriteString("</" + w.open[i] + ">")
+	}
+	w.open, w.skip, w.delay = nil, 0, nil
+}
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+
+// Convert converts the responses to an SSML document
+func (s SSML) Convert(responses []string) string {
+	pause := s.ResponseBreak
+	if pause == 0 {
+		pause = 500 * time.Millisecond
+	}
+	w := &ssmlWriter{}
+	if !s.OmitSpeak {
+		w.sb.WriteString("<speak>")
+	}
+	for i, r := range responses {
+		if i > 0 {
+			w.sb.WriteString(fmt.Sprintf(`<break time="%dms"/>`, pause.Milliseconds()))
+		}
+		w.convert(strings.TrimSpace(r))
+	}
+	if !s.OmitSpeak {
+		w.sb.WriteString("</speak>")
+	}
+	return w.sb.String()
+}
@@ -0,0 +1,160 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"time"
+)
+
+// FileStats holds the statistics of a single bot file
+type FileStats struct {
+	Name     string    `json:"name"`
+	Kind     string    `json:"kind"` // aiml, set, map, substitution, properties or pdefaults
+	Size     int64     `json:"size"`
+	Items    int       `json:"items"` // Categories for AIML files, entries for the other kinds
+	Modified time.Time `json:"modified"`
+}
+
+// BotStats is the statistics report of a bot
+type BotStats struct {
+	Bot          string      `json:"bot"`
+	Language     Language    `json:"language"`
+	Files        []FileStats `json:"files"`
+	Categories   int         `json:"categories"`
+	SetEntries   int         `json:"setEntries"`
+	MapEntries   int         `json:"mapEntries"`
+	TotalSize    int64       `json:"totalSize"`
+	LastModified time.Time   `json:"lastModified"`
+	Generated    time.Time   `json:"generated"`
+}
+
+// countItems counts the categories of AIML content or the entries of JSON list content
+func countItems(kind string, data []byte) int {
+	if kind == "aiml" {
+		return bytes.Count(bytes.ToLower(data), []byte("<category"))
+	}
+	var entries []json.RawMessage
+	if json.Unmarshal(data, &entries) == nil {
+		return len(entries)
+	}
+	return 0
+}
+
+// Stats builds the statistics report of the bot. The bot files are downloaded
+// to count categories and entries.
+func (c *Client) Stats(name string) (*BotStats, error) {
+	list, err := c.ListFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := c.fileContents(name)
+	if err != nil {
+		return nil, err
+	}
+	return buildStats(name, list, contents), nil
+}
+
+// buildStats builds the statistics report of the bot from its listing and the content of its files
+func buildStats(name string, list BotFiles, contents map[string][]byte) *BotStats {
+	stats := &BotStats{Bot: name, Language: list.Language, Generated: time.Now()}
+	add := func(files []BotFile, kind string) {
+		for _, f := range files {
+			fileName := f.Name
+			if filepath.Ext(fileName) != "."+kind {
+				fileName += "." + kind
+			}
+			fs := FileStats{Name: fileName, Kind: kind, Size: f.Size, Items: f.Items, Modified: f.Modified.Time}
+			if data, ok := contents[fileName]; ok {
+				fs.Items = countItems(kind, data)
+			}
+			switch kind {
+			case "aiml":
+				stats.Categories += fs.Items
+			case "set":
+				stats.SetEntries += fs.Items
+			case "map":
+				stats.MapEntries += fs.Items
+			}
+			stats.TotalSize += f.Size
+			if f.Modified.After(stats.LastModified) {
+				stats.LastModified = f.Modified.Time
+			}
+			stats.Files = append(stats.Files, fs)
+		}
+	}
+	add(list.Files, "aiml")
+	add(list.Sets, "set")
+	add(list.Maps, "map")
+	add(list.Substitutions, "substitution")
+	add(list.Properties, "properties")
+	add(list.Pdefaults, "pdefaults")
+	return stats
+}
+
+var reportFuncs = template.FuncMap{
+	"time": formatTime,
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Bot}} - bot report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: left; }
+th { background: #f0f0f0; }
+td.num { text-align: right; }
+</style>
+</head>
+<body>
+<h1>{{.Bot}}</h1>
+<p>Generated {{time .Generated}}</p>
+<h2>Summary</h2>
+<table>
+<tr><th>Language</th><td>{{.Language.Name}}</td></tr>
+<tr><th>Files</th><td class="num">{{len .Files}}</td></tr>
+<tr><th>Categories</th><td class="num">{{.Categories}}</td></tr>
+<tr><th>Set entries</th><td class="num">{{.SetEntries}}</td></tr>
+<tr><th>Map entries</th><td class="num">{{.MapEntries}}</td></tr>
+<tr><th>Total size</th><td class="num">{{.TotalSize}} bytes</td></tr>
+<tr><th>Last modified</th><td>{{time .LastModified}}</td></tr>
+</table>
+<h2>Files</h2>
+<table>
+<tr><th>Name</th><th>Kind</th><th>Items</th><th>Size</th><th>Modified</th></tr>
+{{range .Files}}<tr><td>{{.Name}}</td><td>{{.Kind}}</td><td class="num">{{.Items}}</td><td class="num">{{.Size}}</td><td>{{time .Modified}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as a standalone HTML page
+func (s *BotStats) WriteHTML(w io.Writer) error {
+	return htmlReport.Execute(w, s)
+}
+
+// WriteMarkdown writes the report as a Markdown document
+func (s *BotStats) WriteMarkdown(w io.Writer) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# %s\n\nGenerated %s\n\n", s.Bot, formatTime(s.Generated))
+	fmt.Fprintf(&buf, "| | |\n|---|---|\n")
+	fmt.Fprintf(&buf, "| Language | %s |\n| Files | %d |\n| Categories | %d |\n", s.Language.Name(), len(s.Files), s.Categories)
+	fmt.Fprintf(&buf, "| Set entries | %d |\n| Map entries | %d |\n", s.SetEntries, s.MapEntries)
+	fmt.Fprintf(&buf, "| Total size | %d bytes |\n| Last modified | %s |\n\n", s.TotalSize, formatTime(s.LastModified))
+	fmt.Fprintf(&buf, "## Files\n\n| Name | Kind | Items | Size | Modified |\n|---|---|---:|---:|---|\n")
+	for _, f := range s.Files {
+		fmt.Fprintf(&buf, "| %s | %s | %d | %d | %s |\n", f.Name, f.Kind, f.Items, f.Size, formatTime(f.Modified))
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
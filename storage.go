@@ -0,0 +1,104 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Storage keeps named artifacts, like the backups of a BackupScheduler or the
+// bot files of a FileCache. The names are slash separated paths, like
+// mybot/mybot-20150601-100000.zip. DirStorage keeps them on the local disk
+// and S3Storage in object storage.
+type Storage interface {
+	// Put stores the data under the name, replacing the previous data if any
+	Put(name string, data io.Reader) error
+	// Get writes the data of the name to w, failing with an error satisfying os.IsNotExist if there is none
+	Get(name string, w io.Writer) error
+	// List returns the names starting with the prefix, sorted
+	List(prefix string) ([]string, error)
+	// Delete removes the data of the name, if any
+	Delete(name string) error
+}
+
+// DirStorage is a Storage keeping the artifacts as files under a directory
+type DirStorage struct {
+	Dir string
+}
+
+// NewDirStorage creates a storage keeping the artifacts under dir, which is created when needed
+func NewDirStorage(dir string) *DirStorage {
+	return &DirStorage{Dir: dir}
+}
+
+// Put writes the data to a temporary file renamed once complete, so a failed write does not leave a partial file
+func (s *DirStorage) Put(name string, data io.Reader) error {
+	path := filepath.Join(s.Dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func (s *DirStorage) Get(name string, w io.Writer) error {
+	f, err := os.Open(filepath.Join(s.Dir, filepath.FromSlash(name)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func (s *DirStorage) List(prefix string) ([]string, error) {
+	var names []string
+	err := filepath.Walk(s.Dir, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && path == s.Dir {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".tmp-") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.Dir, path)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+func (s *DirStorage) Delete(name string) error {
+	err := os.Remove(filepath.Join(s.Dir, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
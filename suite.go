@@ -0,0 +1,158 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SuiteTurn is a single input sent to the bot and the checks on its reply.
+// The reply is the bot responses joined with a space.
+type SuiteTurn struct {
+	Input    string `json:"input"`
+	Expect   string `json:"expect,omitempty"`   // The exact expected reply
+	Contains string `json:"contains,omitempty"` // A string the reply must contain
+	Match    string `json:"match,omitempty"`    // A regular expression the reply must match
+}
+
+// SuiteCase is a conversation with the bot. Each case runs in a new session.
+// A case with a single turn can put the turn fields on the case itself.
+type SuiteCase struct {
+	Name  string      `json:"name"`
+	Turns []SuiteTurn `json:"turns,omitempty"`
+	SuiteTurn
+}
+
+// Suite is a list of regression cases to run against a bot
+type Suite struct {
+	Name  string      `json:"name"`
+	Bot   string      `json:"bot,omitempty"` // The default bot to run the suite against
+	Cases []SuiteCase `json:"cases"`
+}
+
+// TurnResult is the outcome of a single turn
+type TurnResult struct {
+	Input   string        `json:"input"`
+	Reply   string        `json:"reply"`
+	Failure string        `json:"failure,omitempty"` // Why the turn failed, empty if it passed
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// CaseResult is the outcome of a suite case
+type CaseResult struct {
+	Name    string        `json:"name"`
+	Passed  bool          `json:"passed"`
+	Turns   []TurnResult  `json:"turns"`
+	Error   string        `json:"error,omitempty"` // Set if talking to the bot failed
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// Failure returns the first failure message of the case
+func (r CaseResult) Failure() string {
+	if r.Error != "" {
+		return r.Error
+	}
+	for _, t := range r.Turns {
+		if t.Failure != "" {
+			return fmt.Sprintf("input [%s]: %s", t.Input, t.Failure)
+		}
+	}
+	return ""
+}
+
+// SuiteResult is the report of a suite run
+type SuiteResult struct {
+	Name    string        `json:"name"`
+	Bot     string        `json:"bot"`
+	Cases   []CaseResult  `json:"cases"`
+	Passed  int           `json:"passed"`
+	Failed  int           `json:"failed"`
+	Started time.Time     `json:"started"`
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+func (c SuiteCase) turns() []SuiteTurn {
+	if len(c.Turns) > 0 {
+		return c.Turns
+	}
+	return []SuiteTurn{c.SuiteTurn}
+}
+
+// check validates the reply against the turn expectations
+func (t SuiteTurn) check(reply string, re *regexp.Regexp) string {
+	switch {
+	case t.Expect != "" && strings.TrimSpace(reply) != strings.TrimSpace(t.Expect):
+		return fmt.Sprintf("expected [%s] but got [%s]", t.Expect, reply)
+	case t.Contains != "" && !strings.Contains(reply, t.Contains):
+		return fmt.Sprintf("expected reply to contain [%s] but got [%s]", t.Contains, reply)
+	case re != nil && !re.MatchString(reply):
+		return fmt.Sprintf("expected reply to match [%s] but got [%s]", t.Match, reply)
+	}
+	return ""
+}
+
+// RunSuite runs the suite cases against the bot. If bot is empty the bot of the suite is used.
+// An error is returned only if the suite is invalid, failing cases are reported in the result.
+func (c *Client) RunSuite(bot string, suite *Suite) (*SuiteResult, error) {
+	if bot == "" {
+		bot = suite.Bot
+	}
+	if bot == "" {
+		return nil, fmt.Errorf("No bot specified for suite [%s]", suite.Name)
+	}
+	patterns := make(map[string]*regexp.Regexp)
+	for _, sc := range suite.Cases {
+		for _, t := range sc.turns() {
+			if t.Input == "" {
+				return nil, fmt.Errorf("Case [%s] has a turn without input", sc.Name)
+			}
+			if t.Match == "" {
+				continue
+			}
+			re, err := regexp.Compile(t.Match)
+			if err != nil {
+				return nil, fmt.Errorf("Case [%s] has an invalid pattern [%s] - %v", sc.Name, t.Match, err)
+			}
+			patterns[t.Match] = re
+		}
+	}
+
+	result := &SuiteResult{Name: suite.Name, Bot: bot, Started: time.Now()}
+	for i, sc := range suite.Cases {
+		// A client name per case so the bot memory does not leak between cases
+		clientName := fmt.Sprintf("pbtest-%d-%d", result.Started.UnixNano(), i)
+		cr := CaseResult{Name: sc.Name, Passed: true}
+		sessionId := 0
+		caseStart := time.Now()
+		for _, t := range sc.turns() {
+			start := time.Now()
+			reply, err := c.Talk(bot, t.Input, clientName, sessionId, false)
+			if err != nil {
+				cr.Error = err.Error()
+				cr.Passed = false
+				break
+			}
+			sessionId = reply.SessionId
+			tr := TurnResult{Input: t.Input, Reply: strings.Join(reply.Responses, " "), Elapsed: time.Since(start)}
+			tr.Failure = t.check(tr.Reply, patterns[t.Match])
+			if tr.Failure != "" {
+				cr.Passed = false
+			}
+			cr.Turns = append(cr.Turns, tr)
+		}
+		cr.Elapsed = time.Since(caseStart)
+		if cr.Passed {
+			result.Passed++
+		} else {
+			result.Failed++
+		}
+		result.Cases = append(result.Cases, cr)
+	}
+	result.Elapsed = time.Since(result.Started)
+	return result, nil
+}
@@ -0,0 +1,233 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SyncOp is the operation of a sync action
+type SyncOp string
+
+const (
+	SyncUpload SyncOp = "upload" // Upload a new or changed local file
+	SyncDelete SyncOp = "delete" // Delete a remote file that does not exist locally
+)
+
+// SyncOptions controls the behavior of SyncDir
+type SyncOptions struct {
+	Delete bool // Delete remote files that do not exist in the directory
+	DryRun bool // Only compute the plan without changing the bot
+	Verify bool // Verify the bot once the files are synced
+}
+
+// SyncAction is a single step of a sync plan
+type SyncAction struct {
+	Op   SyncOp `json:"op"`
+	File string `json:"file"`           // The file name, e.g. udc.aiml or colors.set
+	Path string `json:"path,omitempty"` // The local path for uploads
+	Err  error  `json:"-"`              // The outcome of the action, nil on success
+}
+
+// SyncResult is the plan and outcome of SyncDir
+type SyncResult struct {
+	Actions   []SyncAction `json:"actions"`
+	Unchanged int          `json:"unchanged"` // Number of local files identical to the remote ones
+	Verified  bool         `json:"verified"`  // True if the bot was verified successfully
+	VerifyErr error        `json:"-"`         // The verification error if verification failed
+}
+
+// Failed returns the number of actions that failed
+func (r *SyncResult) Failed() int {
+	failed := 0
+	for _, a := range r.Actions {
+		if a.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// fileKey returns the key identifying the remote resource of a file.
+// All properties (and pdefaults) files map to the same resource of the bot.
+func fileKey(filename string) string {
+	switch ext := filepath.Ext(filename); ext {
+	case ".properties", ".pdefaults":
+		return ext
+	}
+	return filename
+}
+
+// remoteFileNames returns the file names (with extensions) of the bot files
+func remoteFileNames(files BotFiles) []string {
+	return FileFilter{}.names(files)
+}
+
+// fileContents downloads all the files of a bot and returns their content by file name
+func (c *Client) fileContents(name string) (map[string][]byte, error) {
+	var buf bytes.Buffer
+	if err := c.DownloadFiles(name, &buf); err != nil {
+		return nil, err
+	}
+	return unzipFiles(buf.Bytes())
+}
+
+// unzipFiles returns the content of the files in a zip archive by base name
+func unzipFiles(data []byte) (map[string][]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	contents := make(map[string][]byte)
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		_, err = b.ReadFrom(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		contents[filepath.Base(f.Name)] = b.Bytes()
+	}
+	return contents, nil
+}
+
+// localFiles returns the recognized bot files under dir by file name
+func (c *Client) localFiles(dir string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, err := c.fileToUrl("", info.Name()); err != nil {
+			return nil
+		}
+		if prev, ok := files[info.Name()]; ok {
+			return fmt.Errorf("Duplicate file name [%s] - %s and %s", info.Name(), prev, path)
+		}
+		files[info.Name()] = path
+		return nil
+	})
+	return files, err
+}
+
+// SyncDir makes the bot files match the personality files found in dir
+// (including sub directories). New and changed files are uploaded and, if
+// requested, remote files that do not exist locally are deleted.
+// Files with unknown extensions are ignored.
+//
+// The returned result holds the plan; with DryRun nothing is changed.
+// Failures of individual actions are reported on the actions.
+func (c *Client) SyncDir(name, dir string, opts SyncOptions) (*SyncResult, error) {
+	result, err := c.PlanSync(name, dir, opts)
+	if err != nil || opts.DryRun {
+		return result, err
+	}
+	c.ApplySync(name, result, opts)
+	return result, nil
+}
+
+// PlanSync returns the plan of SyncDir without changing the bot, e.g. to
+// confirm the deletions before applying it with ApplySync.
+func (c *Client) PlanSync(name, dir string, opts SyncOptions) (*SyncResult, error) {
+	local, err := c.localFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	list, err := c.ListFiles(name)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := c.fileContents(name)
+	if err != nil {
+		return nil, err
+	}
+	remoteByKey := make(map[string][]byte)
+	for file, data := range remote {
+		remoteByKey[fileKey(file)] = data
+	}
+
+	result := &SyncResult{}
+	localKeys := make(map[string]bool)
+	for _, file := range sortedKeys(local) {
+		localKeys[fileKey(file)] = true
+		data, err := os.ReadFile(local[file])
+		if err != nil {
+			return nil, err
+		}
+		if data, err = c.render(file, data); err != nil {
+			return nil, err
+		}
+		if existing, ok := remoteByKey[fileKey(file)]; ok && bytes.Equal(existing, data) {
+			result.Unchanged++
+			continue
+		}
+		result.Actions = append(result.Actions, SyncAction{Op: SyncUpload, File: file, Path: local[file]})
+	}
+	if opts.Delete {
+		for _, file := range remoteFileNames(list) {
+			if !localKeys[fileKey(file)] {
+				result.Actions = append(result.Actions, SyncAction{Op: SyncDelete, File: file})
+			}
+		}
+	}
+	return result, nil
+}
+
+// ApplySync runs the actions of a plan of PlanSync and verifies the bot if
+// requested, reporting the outcome on the plan.
+func (c *Client) ApplySync(name string, result *SyncResult, opts SyncOptions) {
+	t := c.track("sync", name, len(result.Actions))
+	for i := range result.Actions {
+		a := &result.Actions[i]
+		switch a.Op {
+		case SyncUpload:
+			a.Err = c.UploadFileFromPath(name, a.Path)
+		case SyncDelete:
+			a.Err = c.DeleteFile(name, a.File)
+		}
+		if a.Err != nil {
+			c.errorf("Unable to %s [%s] - %v\n", a.Op, a.File, a.Err)
+		}
+		t.step(a.File, string(a.Op), i+1, a.Err)
+	}
+	var failure error
+	if failed := result.Failed(); failed > 0 {
+		failure = fmt.Errorf("%d of %d actions failed", failed, len(result.Actions))
+	} else if opts.Verify {
+		t.verify(len(result.Actions))
+		result.VerifyErr = c.Verify(name)
+		result.Verified = result.VerifyErr == nil
+		failure = result.VerifyErr
+	}
+	t.finish(len(result.Actions), failure)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
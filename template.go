@@ -0,0 +1,168 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholderRe matches the {{name}} placeholders of the templated files
+var placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// TemplateError is returned when placeholders of a templated file have no value
+type TemplateError struct {
+	File    string
+	Missing []string // The sorted names of the placeholders without a value
+}
+
+func (e *TemplateError) Error() string {
+	return "No value for the placeholders of " + e.File + " [" + strings.Join(e.Missing, ", ") + "]"
+}
+
+// Render replaces the {{name}} placeholders of the file, e.g. {{brand}}, with
+// their values, so one template bot can be instantiated per customer or brand.
+// The spaces around the name are ignored. All the placeholders must have a
+// value, otherwise a *TemplateError naming the file is returned.
+func Render(filename string, data []byte, values map[string]string) ([]byte, error) {
+	missing := make(map[string]bool)
+	res := placeholderRe.ReplaceAllFunc(data, func(m []byte) []byte {
+		name := string(placeholderRe.FindSubmatch(m)[1])
+		value, ok := values[name]
+		if !ok {
+			missing[name] = true
+			return m
+		}
+		return []byte(value)
+	})
+	if len(missing) > 0 {
+		err := &TemplateError{File: filename}
+		for name := range missing {
+			err.Missing = append(err.Missing, name)
+		}
+		sort.Strings(err.Missing)
+		return nil, err
+	}
+	return res, nil
+}
+
+// SetTemplateValues renders the {{placeholders}} of the uploaded files with
+// the values, in UploadFile, SyncDir and the functions built on them, so the
+// files of a template bot are kept once for many bots
+func SetTemplateValues(values map[string]string) OptionFunc {
+	return func(c *Client) error {
+		c.values = values
+		return nil
+	}
+}
+
+// render renders the placeholders of the file if templating is enabled
+func (c *Client) render(filename string, data []byte) ([]byte, error) {
+	if c.values == nil {
+		return data, nil
+	}
+	return Render(filename, data, c.values)
+}
+
+// renderReader renders the placeholders of the file read from r if templating is enabled
+func (c *Client) renderReader(filename string, r io.Reader) (io.Reader, error) {
+	if c.values == nil {
+		return r, nil
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = Render(filename, data, c.values); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// templateFiles reads the personality files of a template directory or zip archive
+func (c *Client) templateFiles(template string) (map[string][]byte, error) {
+	info, err := os.Stat(template)
+	if err != nil {
+		return nil, err
+	}
+	contents := make(map[string][]byte)
+	if info.IsDir() {
+		local, err := c.localFiles(template)
+		if err != nil {
+			return nil, err
+		}
+		for file, path := range local {
+			if contents[file], err = os.ReadFile(path); err != nil {
+				return nil, err
+			}
+		}
+		return contents, nil
+	}
+	data, err := os.ReadFile(template)
+	if err != nil {
+		return nil, err
+	}
+	all, err := unzipFiles(data)
+	if err != nil {
+		return nil, fmt.Errorf("Template is not a directory or a zip archive [%s] - %v", template, err)
+	}
+	for file, data := range all {
+		if _, err := c.fileToUrl("", file); err == nil {
+			contents[file] = data
+		}
+	}
+	return contents, nil
+}
+
+// CreateBotFromTemplate stamps out a new bot from a template bot: it renders
+// the {{placeholders}} of the personality files of the template, a directory
+// or a zip archive like the ones of Backup, with the values, creates the bot,
+// uploads the files and verifies the bot. Nil values use the values of
+// SetTemplateValues.
+//
+// The files are rendered before creating the bot, so missing values do not
+// leave an empty bot behind. If an upload or the verification fails, the bot
+// is kept with the files uploaded so far.
+func (c *Client) CreateBotFromTemplate(name, template string, values map[string]string) error {
+	if values == nil {
+		values = c.values
+	}
+	contents, err := c.templateFiles(template)
+	if err != nil {
+		return err
+	}
+	if len(contents) == 0 {
+		return fmt.Errorf("Template has no personality files [%s]", template)
+	}
+	files := make([]string, 0, len(contents))
+	for file := range contents {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		if contents[file], err = Render(file, contents[file], values); err != nil {
+			return err
+		}
+	}
+	if err = c.CreateBot(name); err != nil {
+		return err
+	}
+	for _, file := range files {
+		rawurl, err := c.fileToUrl(name, file)
+		if err != nil {
+			return err
+		}
+		if err = c.do("PUT", rawurl, nil, bytes.NewReader(contents[file]), nil); err != nil {
+			c.errorf("Unable to upload [%s] - %v\n", file, err)
+			return err
+		}
+	}
+	return c.Verify(name)
+}
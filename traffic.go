@@ -0,0 +1,143 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SplitArm is a bot receiving a share of the traffic of a TrafficSplit
+type SplitArm struct {
+	Bot    string `json:"bot"`
+	Weight int    `json:"weight"` // The relative share of the traffic, e.g. 90 and 10
+}
+
+// SplitEvent is the outcome of an input routed by a TrafficSplit, to compare the arms
+type SplitEvent struct {
+	Time       time.Time     `json:"time"`
+	Bot        string        `json:"bot"` // The bot of the arm
+	ClientName string        `json:"clientName"`
+	Input      string        `json:"input"`
+	Responses  []string      `json:"responses,omitempty"`
+	Latency    time.Duration `json:"latency"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// TrafficSplit routes the users to bots by weight, e.g. 90/10 between the current
+// and a candidate bot, to canary new AIML with real traffic. A client name
+// always goes to the same arm, so the users keep the bot memory of their
+// conversation, and raising the weight of an arm only moves users to it.
+type TrafficSplit struct {
+	Arms []SplitArm
+	// OnReply receives the outcome of each input, e.g. JSONSplitLog, nil to not log
+	OnReply func(e SplitEvent)
+}
+
+// NewTrafficSplit creates a split between the arms, which must have distinct bots and positive total weight
+func NewTrafficSplit(arms ...SplitArm) (*TrafficSplit, error) {
+	total := 0
+	seen := make(map[string]bool)
+	for _, a := range arms {
+		if a.Bot == "" || a.Weight < 0 {
+			return nil, fmt.Errorf("Split arm is not valid [%s:%d] - it must have a bot and a weight of zero or more", a.Bot, a.Weight)
+		}
+		if seen[a.Bot] {
+			return nil, fmt.Errorf("Split arm is not valid [%s] - the bot is already an arm", a.Bot)
+		}
+		seen[a.Bot] = true
+		total += a.Weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("Split is not valid - the total weight of the arms must be positive")
+	}
+	return &TrafficSplit{Arms: arms}, nil
+}
+
+// ParseTrafficSplit parses a split in the form BOT:WEIGHT,BOT:WEIGHT, like mybot:90,mybot-next:10
+func ParseTrafficSplit(s string) (*TrafficSplit, error) {
+	var arms []SplitArm
+	for _, part := range strings.Split(s, ",") {
+		bot, weight, ok := strings.Cut(strings.TrimSpace(part), ":")
+		w, err := strconv.Atoi(weight)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("Split arm is not valid [%s] - it must be in the form BOT:WEIGHT", part)
+		}
+		arms = append(arms, SplitArm{Bot: bot, Weight: w})
+	}
+	return NewTrafficSplit(arms...)
+}
+
+func (s *TrafficSplit) String() string {
+	parts := make([]string, len(s.Arms))
+	for i, a := range s.Arms {
+		parts[i] = fmt.Sprintf("%s:%d", a.Bot, a.Weight)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Pick returns the bot of the arm of the client name. The client names are
+// hashed to points of the total weight, which the arms cover in order.
+func (s *TrafficSplit) Pick(clientName string) string {
+	total := 0
+	for _, a := range s.Arms {
+		total += a.Weight
+	}
+	sum := sha256.Sum256([]byte(clientName))
+	point := int(uint64(binary.BigEndian.Uint32(sum[:4])) * uint64(total) >> 32)
+	for _, a := range s.Arms {
+		if point < a.Weight {
+			return a.Bot
+		}
+		point -= a.Weight
+	}
+	return s.Arms[len(s.Arms)-1].Bot
+}
+
+// Record reports the outcome of an input sent to the bot of an arm to
+// OnReply. Pass the client name through Client.Anonymized with an anonymizer.
+func (s *TrafficSplit) Record(bot, clientName, input string, reply *Reply, err error) {
+	if s.OnReply == nil {
+		return
+	}
+	e := SplitEvent{Time: time.Now(), Bot: bot, ClientName: clientName, Input: input}
+	if reply != nil {
+		e.Responses, e.Latency = reply.Responses, reply.Info.Latency
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	s.OnReply(e)
+}
+
+// Talk sends the input to the bot of the arm of the client name and records the outcome
+func (s *TrafficSplit) Talk(c *Client, input, clientName string, sessionId int) (*Reply, error) {
+	bot := s.Pick(clientName)
+	reply, err := c.Talk(bot, input, clientName, sessionId, false)
+	s.Record(bot, c.Anonymized(clientName), input, reply, err)
+	return reply, err
+}
+
+// JSONSplitLog returns an OnReply func writing each event to w as a line of
+// JSON, to compare the responses of the arms offline
+func JSONSplitLog(w io.Writer) func(e SplitEvent) {
+	var mu sync.Mutex
+	return func(e SplitEvent) {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write(append(data, '\n'))
+	}
+}
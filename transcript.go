@@ -0,0 +1,174 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// TranscriptTurn is a single input and the bot responses to it
+type TranscriptTurn struct {
+	Input     string    `json:"input"`
+	Responses []string  `json:"responses"`
+	Time      time.Time `json:"time"`
+}
+
+// Transcript is a recorded conversation with a bot
+type Transcript struct {
+	Bot        string           `json:"bot"`
+	ClientName string           `json:"clientName,omitempty"`
+	Started    time.Time        `json:"started"`
+	Turns      []TranscriptTurn `json:"turns"`
+	// Scrub redacts the inputs and responses before they are recorded, e.g. a
+	// Scrubber removing personal data. Nil records them as is.
+	Scrub func(text string) string `json:"-"`
+	// Encryptor encrypts the transcript written by WriteToPath. Nil writes it as is.
+	Encryptor *Encryptor `json:"-"`
+}
+
+// Record adds the input and its reply to the transcript
+func (t *Transcript) Record(input string, reply *Reply) {
+	if t.Started.IsZero() {
+		t.Started = time.Now()
+	}
+	responses := make([]string, 0, len(reply.Responses))
+	responses = append(responses, reply.Responses...)
+	if t.Scrub != nil {
+		input = t.Scrub(input)
+		for i, r := range responses {
+			responses[i] = t.Scrub(r)
+		}
+	}
+	t.Turns = append(t.Turns, TranscriptTurn{Input: input, Responses: responses, Time: time.Now()})
+}
+
+// Write writes the transcript as JSON
+func (t *Transcript) Write(w io.Writer) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteToPath writes the transcript to the file in path, encrypted if the transcript has an Encryptor
+func (t *Transcript) WriteToPath(path string) error {
+	if t.Encryptor != nil {
+		var buf bytes.Buffer
+		if err := t.Write(&buf); err != nil {
+			return err
+		}
+		return t.Encryptor.WriteFile(path, buf.Bytes(), 0600)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = t.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadTranscript reads a transcript written by Write
+func ReadTranscript(r io.Reader) (*Transcript, error) {
+	t := &Transcript{}
+	if err := json.NewDecoder(r).Decode(t); err != nil {
+		return nil, fmt.Errorf("Invalid transcript - %v", err)
+	}
+	return t, nil
+}
+
+// ReadTranscriptFromPath reads the transcript in path
+func ReadTranscriptFromPath(path string) (*Transcript, error) {
+	return ReadEncryptedTranscript(path, nil)
+}
+
+// ReadEncryptedTranscript reads the transcript in path, decrypting it with
+// the Encryptor if it is encrypted. The transcript keeps the Encryptor, so
+// it is written back encrypted.
+func ReadEncryptedTranscript(path string, e *Encryptor) (*Transcript, error) {
+	data, err := e.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := ReadTranscript(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	t.Encryptor = e
+	return t, nil
+}
+
+// ReplayTurn is the outcome of replaying a single transcript turn
+type ReplayTurn struct {
+	Input    string   `json:"input"`
+	Expected []string `json:"expected"`
+	Actual   []string `json:"actual"`
+	Match    bool     `json:"match"`
+}
+
+// Diff returns the difference between the recorded and the replayed responses
+// in unified diff format, or an empty string if they match.
+func (t ReplayTurn) Diff() string {
+	if t.Match {
+		return ""
+	}
+	return unifiedDiff("recorded", "replayed", responseLines(t.Expected), responseLines(t.Actual), 3)
+}
+
+func responseLines(responses []string) []string {
+	lines := make([]string, len(responses))
+	for i, r := range responses {
+		lines[i] = r + "\n"
+	}
+	return lines
+}
+
+// ReplayResult is the report of a transcript replay
+type ReplayResult struct {
+	Bot        string        `json:"bot"`
+	Turns      []ReplayTurn  `json:"turns"`
+	Mismatches int           `json:"mismatches"`
+	Elapsed    time.Duration `json:"elapsed"`
+}
+
+// Replay sends the transcript inputs to the bot in a new session and compares the
+// responses with the recorded ones. If bot is empty the bot of the transcript is used.
+func (c *Client) Replay(bot string, t *Transcript) (*ReplayResult, error) {
+	if bot == "" {
+		bot = t.Bot
+	}
+	if bot == "" {
+		return nil, fmt.Errorf("No bot specified for transcript")
+	}
+	start := time.Now()
+	clientName := fmt.Sprintf("pbreplay-%d", start.UnixNano())
+	result := &ReplayResult{Bot: bot}
+	sessionId := 0
+	for _, turn := range t.Turns {
+		reply, err := c.Talk(bot, turn.Input, clientName, sessionId, false)
+		if err != nil {
+			return nil, err
+		}
+		sessionId = reply.SessionId
+		rt := ReplayTurn{Input: turn.Input, Expected: turn.Responses, Actual: reply.Responses}
+		rt.Match = strings.Join(rt.Expected, "\n") == strings.Join(rt.Actual, "\n")
+		if !rt.Match {
+			result.Mismatches++
+		}
+		result.Turns = append(result.Turns, rt)
+	}
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
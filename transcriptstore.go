@@ -0,0 +1,128 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// TranscriptEntry is a turn of a conversation kept by a TranscriptStore
+type TranscriptEntry struct {
+	Bot        string        `json:"bot"`
+	ClientName string        `json:"clientName"`
+	SessionId  int           `json:"sessionId"`
+	Turn       int           `json:"turn"` // The index of the turn in the session, from zero, set by the store
+	Input      string        `json:"input"`
+	Responses  []string      `json:"responses"`
+	Latency    time.Duration `json:"latency"`
+	Time       time.Time     `json:"time"`
+}
+
+// TranscriptQuery selects the entries of a TranscriptStore. The zero query selects all of them.
+type TranscriptQuery struct {
+	Bot        string    // Only the conversations with the bot, empty for all
+	ClientName string    // Only the conversations of the client name, empty for all
+	SessionId  int       // Only the turns of the session, zero for all
+	Since      time.Time // Only the turns at or after the time, zero for no limit
+	Until      time.Time // Only the turns before the time, zero for no limit
+	Limit      int       // Only the most recent entries, zero for no limit
+}
+
+// Match reports whether the query selects the entry, ignoring the limit
+func (q TranscriptQuery) Match(e *TranscriptEntry) bool {
+	return (q.Bot == "" || e.Bot == q.Bot) &&
+		(q.ClientName == "" || e.ClientName == q.ClientName) &&
+		(q.SessionId == 0 || e.SessionId == q.SessionId) &&
+		(q.Since.IsZero() || !e.Time.Before(q.Since)) &&
+		(q.Until.IsZero() || e.Time.Before(q.Until))
+}
+
+// limit sorts the entries by time and keeps the most recent ones of the query limit
+func (q TranscriptQuery) limit(entries []TranscriptEntry) []TranscriptEntry {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[len(entries)-q.Limit:]
+	}
+	return entries
+}
+
+// TranscriptStore keeps the conversations of the gateways and adapters
+// durably, to query them later. MemoryTranscriptStore keeps them in memory,
+// RedisTranscriptStore in Redis and SQLTranscriptStore in a SQL database
+// like SQLite. Implementations must be safe for concurrent use.
+type TranscriptStore interface {
+	// Append stores the entry, setting its Turn to the index of the turn in the session
+	Append(e *TranscriptEntry) error
+	// Query returns the entries selected by the query, the oldest first
+	Query(q TranscriptQuery) ([]TranscriptEntry, error)
+	// Delete removes all the entries of the client name, returning their number
+	Delete(clientName string) (int, error)
+}
+
+// MemoryTranscriptStore is a TranscriptStore keeping the entries in memory
+type MemoryTranscriptStore struct {
+	mu      sync.Mutex
+	entries []TranscriptEntry
+}
+
+// NewMemoryTranscriptStore creates an empty in memory transcript store
+func NewMemoryTranscriptStore() *MemoryTranscriptStore {
+	return &MemoryTranscriptStore{}
+}
+
+func (m *MemoryTranscriptStore) Append(e *TranscriptEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.Turn = 0
+	for i := range m.entries {
+		if prev := &m.entries[i]; prev.Bot == e.Bot && prev.ClientName == e.ClientName && prev.SessionId == e.SessionId {
+			e.Turn++
+		}
+	}
+	m.entries = append(m.entries, *e)
+	return nil
+}
+
+func (m *MemoryTranscriptStore) Query(q TranscriptQuery) ([]TranscriptEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make([]TranscriptEntry, 0)
+	for i := range m.entries {
+		if q.Match(&m.entries[i]) {
+			res = append(res, m.entries[i])
+		}
+	}
+	return q.limit(res), nil
+}
+
+func (m *MemoryTranscriptStore) Delete(clientName string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kept := m.entries[:0]
+	for _, e := range m.entries {
+		if e.ClientName != clientName {
+			kept = append(kept, e)
+		}
+	}
+	n := len(m.entries) - len(kept)
+	m.entries = kept
+	return n, nil
+}
+
+// NewTranscriptEntry builds the entry of a reply of the bot to the input of the client name
+func NewTranscriptEntry(bot, clientName, input string, reply *Reply) *TranscriptEntry {
+	responses := make([]string, 0, len(reply.Responses))
+	return &TranscriptEntry{
+		Bot:        bot,
+		ClientName: clientName,
+		SessionId:  reply.SessionId,
+		Input:      input,
+		Responses:  append(responses, reply.Responses...),
+		Latency:    reply.Info.Latency,
+		Time:       time.Now().UTC(),
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Translator translates texts between languages, e.g. with a machine translation service
+type Translator interface {
+	// Translate translates the texts from the language into the language to,
+	// as ISO 639-1 codes like en or fr. If from is empty the language of the
+	// texts is detected. It returns the translations, in the order of the
+	// texts, and the language they were translated from.
+	Translate(texts []string, from, to string) ([]string, string, error)
+}
+
+// TranslatorFunc is a Translator calling a function
+type TranslatorFunc func(texts []string, from, to string) ([]string, string, error)
+
+func (f TranslatorFunc) Translate(texts []string, from, to string) ([]string, string, error) {
+	return f(texts, from, to)
+}
+
+// Translation translates the inputs of the users into the language of the
+// bot and the responses back into the language of the users, so a single
+// English bot serves multilingual users. Add its Middleware to a Conversation.
+type Translation struct {
+	Translator Translator
+	// BotLanguage is the language of the bot. Defaults to en.
+	BotLanguage string
+	// Language returns the language of the user key, e.g. from the locale of
+	// the chat platform, empty to detect it from each input. Nil to always detect it.
+	Language func(key string) string
+	// OnError receives the errors of the translator, nil to ignore them. The
+	// inputs and the replies are sent untranslated when it fails, so the users
+	// are still answered.
+	OnError func(err error)
+}
+
+// NewTranslation creates a translation of the conversations with the bot of the language
+func NewTranslation(t Translator, botLanguage string) *Translation {
+	return &Translation{Translator: t, BotLanguage: botLanguage}
+}
+
+func (tr *Translation) botLanguage() string {
+	if tr.BotLanguage == "" {
+		return "en"
+	}
+	return tr.BotLanguage
+}
+
+func (tr *Translation) onError(err error) {
+	if tr.OnError != nil {
+		tr.OnError(err)
+	}
+}
+
+// Middleware returns the middleware translating the talks
+func (tr *Translation) Middleware() Middleware {
+	return func(next TalkFunc) TalkFunc {
+		return func(key, input string) (*Reply, error) {
+			lang := ""
+			if tr.Language != nil {
+				lang = tr.Language(key)
+			}
+			if lang != tr.botLanguage() && strings.TrimSpace(input) != "" {
+				translated, detected, err := tr.Translator.Translate([]string{input}, lang, tr.botLanguage())
+				if err == nil && len(translated) != 1 {
+					err = fmt.Errorf("Translator returned %d translations of the input, not 1", len(translated))
+				}
+				if err != nil {
+					tr.onError(fmt.Errorf("Unable to translate the input of [%s] - %v", key, err))
+					lang = ""
+				} else {
+					input, lang = translated[0], detected
+				}
+			}
+			reply, err := next(key, input)
+			if err != nil || reply == nil || lang == "" || lang == tr.botLanguage() || len(reply.Responses) == 0 {
+				return reply, err
+			}
+			translated, _, err := tr.Translator.Translate(reply.Responses, tr.botLanguage(), lang)
+			if err == nil && len(translated) != len(reply.Responses) {
+				err = fmt.Errorf("Translator returned %d translations of the %d responses", len(translated), len(reply.Responses))
+			}
+			if err != nil {
+				tr.onError(fmt.Errorf("Unable to translate the reply of [%s] - %v", key, err))
+				return reply, nil
+			}
+			translatedReply := *reply
+			translatedReply.Responses = translated
+			translatedReply.Info.Language = lang
+			return &translatedReply, nil
+		}
+	}
+}
+
+// LibreTranslator is a Translator calling the API of a LibreTranslate
+// server, see https://libretranslate.com
+type LibreTranslator struct {
+	Url    string // The URL of the server, e.g. https://libretranslate.com
+	ApiKey string // The API key, empty for the servers not requiring one
+	// HTTPClient calls the API. Defaults to a client with a 10 seconds timeout.
+	HTTPClient *http.Client
+}
+
+// NewLibreTranslator creates a translator calling the LibreTranslate server at the URL
+func NewLibreTranslator(url, apiKey string) *LibreTranslator {
+	return &LibreTranslator{Url: url, ApiKey: apiKey}
+}
+
+func (t *LibreTranslator) Translate(texts []string, from, to string) ([]string, string, error) {
+	if from == "" {
+		from = "auto"
+	}
+	body, err := json.Marshal(map[string]interface{}{"q": texts, "source": from, "target": to, "format": "text", "api_key": t.ApiKey})
+	if err != nil {
+		return nil, "", err
+	}
+	client := t.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(strings.TrimSuffix(t.Url, "/")+"/translate", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	var res struct {
+		TranslatedText []string `json:"translatedText"`
+		// An object for a single text or a list, depending on the version of the server
+		DetectedLanguage json.RawMessage `json:"detectedLanguage"`
+		Error            string          `json:"error"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&res); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, "", fmt.Errorf("Unable to decode the translation - %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if res.Error != "" {
+			return nil, "", fmt.Errorf("Translation failed - %s: %s", resp.Status, res.Error)
+		}
+		return nil, "", fmt.Errorf("Translation failed - %s", resp.Status)
+	}
+	if from == "auto" {
+		type detection struct {
+			Language string `json:"language"`
+		}
+		var one detection
+		var many []detection
+		if json.Unmarshal(res.DetectedLanguage, &many) == nil && len(many) > 0 {
+			from = many[0].Language
+		} else if json.Unmarshal(res.DetectedLanguage, &one) == nil && one.Language != "" {
+			from = one.Language
+		} else {
+			return nil, "", fmt.Errorf("Translation has no detected language")
+		}
+	}
+	return res.TranslatedText, from, nil
+}
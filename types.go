@@ -0,0 +1,136 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bool is a boolean flag as returned by the API.
+// Pandorabots is not consistent in how it encodes flags so Bool accepts
+// JSON booleans, numbers and strings like "true", "false", "yes" or "no".
+// It can be used anywhere a bool is expected (if entry.Compiled {...}).
+type Bool bool
+
+// UnmarshalJSON implements json.Unmarshaler with tolerant parsing
+func (b *Bool) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*b = false
+		return nil
+	}
+	raw := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "true", "yes", "y", "on", "1":
+		*b = true
+	case "false", "no", "n", "off", "0", "":
+		*b = false
+	default:
+		return fmt.Errorf("Invalid boolean value [%s]", raw)
+	}
+	return nil
+}
+
+// Time is a timestamp as returned by the API.
+// Pandorabots is not consistent in how it encodes dates either, so Time accepts
+// RFC 3339 dates, dates without a time zone or with a space before the time,
+// Unix times in seconds or milliseconds, and "" or null for the zero time.
+// It embeds a time.Time, so its methods can be called directly (entry.Created.IsZero()).
+type Time struct {
+	time.Time
+}
+
+// timeLayouts are the date layouts accepted by Time, the dates without a time zone are in UTC
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05Z07:00", "2006-01-02 15:04:05", "2006-01-02"}
+
+// UnmarshalJSON implements json.Unmarshaler with tolerant parsing
+func (t *Time) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		t.Time = time.Time{}
+		return nil
+	}
+	raw := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		raw = strings.TrimSpace(raw)
+	}
+	if raw == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		// The numbers past 1e10, the year 2286 in seconds, are in milliseconds
+		if n > 1e10 || n < -1e10 {
+			t.Time = time.UnixMilli(n).UTC()
+		} else {
+			t.Time = time.Unix(n, 0).UTC()
+		}
+		return nil
+	}
+	for _, layout := range timeLayouts {
+		if parsed, err := time.Parse(layout, raw); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid time value [%s]", raw)
+}
+
+// Language is a bot language code as used by pandorabots
+type Language string
+
+// Known pandorabots language codes
+const (
+	LanguageEnglish    Language = "en"
+	LanguageGerman     Language = "de"
+	LanguageSpanish    Language = "es"
+	LanguageFrench     Language = "fr"
+	LanguageItalian    Language = "it"
+	LanguageDutch      Language = "nl"
+	LanguagePortuguese Language = "pt"
+	LanguageRussian    Language = "ru"
+	LanguageJapanese   Language = "ja"
+	LanguageChinese    Language = "zh"
+)
+
+var languageNames = map[Language]string{
+	LanguageEnglish:    "English",
+	LanguageGerman:     "German",
+	LanguageSpanish:    "Spanish",
+	LanguageFrench:     "French",
+	LanguageItalian:    "Italian",
+	LanguageDutch:      "Dutch",
+	LanguagePortuguese: "Portuguese",
+	LanguageRussian:    "Russian",
+	LanguageJapanese:   "Japanese",
+	LanguageChinese:    "Chinese",
+}
+
+// Known returns true if the language is one of the known pandorabots languages
+func (l Language) Known() bool {
+	_, ok := languageNames[l]
+	return ok
+}
+
+// Name returns the English name of the language or the raw code if it is not known
+func (l Language) Name() string {
+	if name, ok := languageNames[l]; ok {
+		return name
+	}
+	return string(l)
+}
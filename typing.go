@@ -0,0 +1,54 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// Typing simulates a person typing the messages of a reply. Chat adapters wait
+// the typing delay of each message before sending it, showing their typing
+// indicator meanwhile, as instant multi-paragraph replies feel robotic.
+// A nil Typing does not delay the messages.
+type Typing struct {
+	CharsPerSecond float64       // The typing speed. Defaults to 25 characters per second.
+	Min            time.Duration // The delay of the shortest messages
+	Max            time.Duration // The delay of the longest messages. Defaults to 3 seconds.
+}
+
+// NewTyping creates a typing simulation with the speed in characters per second
+func NewTyping(charsPerSecond float64) *Typing {
+	return &Typing{CharsPerSecond: charsPerSecond}
+}
+
+// Delay returns the time to type the message
+func (t *Typing) Delay(message string) time.Duration {
+	if t == nil {
+		return 0
+	}
+	cps, max := t.CharsPerSecond, t.Max
+	if cps <= 0 {
+		cps = 25
+	}
+	if max == 0 {
+		max = 3 * time.Second
+	}
+	d := time.Duration(float64(utf8.RuneCountInString(message)) / cps * float64(time.Second))
+	if d < t.Min {
+		d = t.Min
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// Wait sleeps the time to type the message
+func (t *Typing) Wait(message string) {
+	if d := t.Delay(message); d > 0 {
+		time.Sleep(d)
+	}
+}
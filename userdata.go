@@ -0,0 +1,137 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import "fmt"
+
+// SessionLister is implemented by the session stores able to list their
+// sessions, which ExportUserData and DeleteUserData need to find the sessions
+// of a client name. MemorySessionStore implements it.
+type SessionLister interface {
+	// Keys returns the keys of the sessions
+	Keys() ([]string, error)
+}
+
+// UserDataStores are the stores keeping the data of the end users, e.g. the
+// session stores of the adapters and the transcript store of the gateway
+type UserDataStores struct {
+	Sessions    []SessionStore
+	Transcripts []TranscriptStore
+}
+
+// UserData is the data kept about the end user of a client name, for the
+// data access requests of privacy regulations like the GDPR
+type UserData struct {
+	ClientName  string             `json:"clientName"`
+	Sessions    map[string]Session `json:"sessions"` // By user key
+	Transcripts []TranscriptEntry  `json:"transcripts"`
+}
+
+// UserDataDeletion is the result of DeleteUserData
+type UserDataDeletion struct {
+	ClientName  string `json:"clientName"`
+	Sessions    int    `json:"sessions"`    // The number of sessions deleted
+	Transcripts int    `json:"transcripts"` // The number of transcript entries deleted
+}
+
+// sessionsOf returns the keys and the sessions of the client name in the store
+func sessionsOf(store SessionStore, clientName string) (map[string]Session, error) {
+	lister, ok := store.(SessionLister)
+	if !ok {
+		return nil, fmt.Errorf("Session store [%T] cannot list its sessions - it must implement SessionLister", store)
+	}
+	keys, err := lister.Keys()
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]Session)
+	for _, key := range keys {
+		s, err := store.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if s != nil && s.ClientName == clientName {
+			res[key] = *s
+		}
+	}
+	return res, nil
+}
+
+// ExportUserData returns the sessions and the transcripts of the client name in all the stores
+func (u UserDataStores) ExportUserData(clientName string) (*UserData, error) {
+	if clientName == "" {
+		return nil, fmt.Errorf("Client name is required")
+	}
+	data := &UserData{ClientName: clientName, Sessions: make(map[string]Session), Transcripts: make([]TranscriptEntry, 0)}
+	for _, store := range u.Sessions {
+		sessions, err := sessionsOf(store, clientName)
+		if err != nil {
+			return nil, err
+		}
+		for key, s := range sessions {
+			data.Sessions[key] = s
+		}
+	}
+	for _, store := range u.Transcripts {
+		entries, err := store.Query(TranscriptQuery{ClientName: clientName})
+		if err != nil {
+			return nil, err
+		}
+		data.Transcripts = append(data.Transcripts, entries...)
+	}
+	TranscriptQuery{}.limit(data.Transcripts)
+	return data, nil
+}
+
+// DeleteUserData purges the sessions and the transcripts of the client name
+// from all the stores, for the erasure requests of privacy regulations like
+// the GDPR. The bot memory of the client name, the predicates kept by
+// pandorabots, is not deleted. On error the deletion stops and the returned
+// result counts what was deleted so far, so it can be retried.
+func (u UserDataStores) DeleteUserData(clientName string) (*UserDataDeletion, error) {
+	if clientName == "" {
+		return nil, fmt.Errorf("Client name is required")
+	}
+	res := &UserDataDeletion{ClientName: clientName}
+	for _, store := range u.Sessions {
+		sessions, err := sessionsOf(store, clientName)
+		if err != nil {
+			return res, err
+		}
+		for key := range sessions {
+			if err = store.Delete(key); err != nil {
+				return res, err
+			}
+			res.Sessions++
+		}
+	}
+	for _, store := range u.Transcripts {
+		n, err := store.Delete(clientName)
+		res.Transcripts += n
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// userDataStores returns the stores of the conversation
+func (cv *Conversation) userDataStores() UserDataStores {
+	u := UserDataStores{Sessions: []SessionStore{cv.Store}}
+	if cv.Transcripts != nil {
+		u.Transcripts = append(u.Transcripts, cv.Transcripts)
+	}
+	return u
+}
+
+// ExportUserData returns the sessions and the transcripts of the client name in the stores of the conversation
+func (cv *Conversation) ExportUserData(clientName string) (*UserData, error) {
+	return cv.userDataStores().ExportUserData(clientName)
+}
+
+// DeleteUserData purges the sessions and the transcripts of the client name from the stores of the conversation
+func (cv *Conversation) DeleteUserData(clientName string) (*UserDataDeletion, error) {
+	return cv.userDataStores().DeleteUserData(clientName)
+}
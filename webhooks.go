@@ -0,0 +1,267 @@
+// Copyright 2015 Demisto. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pb
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The events of the conversations posted to the webhooks
+const (
+	EventStarted = "conversation.started" // The first input of a session
+	EventDefault = "reply.default"        // The bot answered with one of the default responses
+	EventError   = "conversation.error"   // Talking with the bot failed, even if a fallback answered
+	EventKeyword = "input.keyword"        // The input contains one of the keywords
+)
+
+// ConversationEvent is the JSON body posted to the webhooks
+type ConversationEvent struct {
+	Event      string    `json:"event"`
+	Time       time.Time `json:"time"`
+	Bot        string    `json:"bot"`
+	ClientName string    `json:"clientName"`
+	SessionId  int       `json:"sessionId,omitempty"`
+	Input      string    `json:"input"`
+	Responses  []string  `json:"responses,omitempty"`
+	Keyword    string    `json:"keyword,omitempty"` // The keyword of an EventKeyword
+	Error      string    `json:"error,omitempty"`   // The error of an EventError
+	Tags       []string  `json:"tags,omitempty"`    // The tags of the input by the Classifier of the Conversation
+}
+
+// Webhook is an URL the events of the conversations are posted to
+type Webhook struct {
+	Url string
+	// Secret signs the posts, see WebhookSignature. Empty to not sign them.
+	Secret string
+	// Events are the events posted, empty for all
+	Events []string
+}
+
+// wants reports whether the webhook receives the event
+func (h *Webhook) wants(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookSignature returns the X-Pb-Signature header of a post with the
+// X-Pb-Timestamp header and the body, the hex HMAC-SHA256 of the timestamp, a
+// dot and the body keyed by the secret. Receivers compare it with hmac.Equal
+// and reject the old timestamps to prevent replays.
+func WebhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Webhooks posts the events of the conversations of a Conversation or a
+// gateway to webhooks, e.g. so CRMs and alerting systems react to chats in
+// real time. The events are posted in order from a queue, so a slow webhook
+// does not delay the replies; the events are dropped when the queue is full.
+type Webhooks struct {
+	Hooks []Webhook
+	// Keywords fire EventKeyword when an input contains one of them as a
+	// word, ignoring case, e.g. cancel or lawyer
+	Keywords []string
+	// DefaultResponses fire EventDefault when the bot answers with one of
+	// them, ignoring case, e.g. the response of the ultimate default category
+	DefaultResponses []string
+	// HTTPClient posts the events. Defaults to a client with a 10 seconds timeout.
+	HTTPClient *http.Client
+	// QueueSize is the number of events waiting to be posted. Defaults to 1000.
+	QueueSize int
+	// OnError receives the errors of the posts and the dropped events, nil to ignore them
+	OnError func(err error)
+
+	once     sync.Once
+	keywords []*regexp.Regexp
+	mu       sync.Mutex // Guards the queue against the posts after Close
+	closed   bool
+	queue    chan ConversationEvent
+	done     chan struct{}
+}
+
+// NewWebhooks creates the webhooks posting to the hooks
+func NewWebhooks(hooks ...Webhook) *Webhooks {
+	return &Webhooks{Hooks: hooks}
+}
+
+// start compiles the keywords and starts posting the queue
+func (w *Webhooks) start() {
+	w.once.Do(func() {
+		for _, k := range w.Keywords {
+			w.keywords = append(w.keywords, wordPattern(k))
+		}
+		if w.HTTPClient == nil {
+			w.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+		}
+		size := w.QueueSize
+		if size <= 0 {
+			size = 1000
+		}
+		w.queue = make(chan ConversationEvent, size)
+		w.done = make(chan struct{})
+		go w.run()
+	})
+}
+
+func (w *Webhooks) onError(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+	}
+}
+
+func (w *Webhooks) run() {
+	defer close(w.done)
+	for e := range w.queue {
+		body, err := json.Marshal(e)
+		if err != nil {
+			w.onError(err)
+			continue
+		}
+		for i := range w.Hooks {
+			if h := &w.Hooks[i]; h.wants(e.Event) {
+				if err = w.post(h, e.Event, body); err != nil {
+					w.onError(err)
+				}
+			}
+		}
+	}
+}
+
+// post sends the event to the webhook
+func (w *Webhooks) post(h *Webhook, event string, body []byte) error {
+	req, err := http.NewRequest("POST", h.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Pb-Event", event)
+	if h.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Pb-Timestamp", timestamp)
+		req.Header.Set("X-Pb-Signature", WebhookSignature(h.Secret, timestamp, body))
+	}
+	resp, err := w.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Webhook failed [%s] - %v", h.Url, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Webhook failed [%s] - %s", h.Url, resp.Status)
+	}
+	return nil
+}
+
+// Notify queues the event to be posted to the webhooks receiving it. The
+// events notified after Close are dropped.
+func (w *Webhooks) Notify(e ConversationEvent) {
+	w.start()
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		w.onError(fmt.Errorf("Webhooks are closed, dropping the %s event of [%s]", e.Event, e.ClientName))
+		return
+	}
+	select {
+	case w.queue <- e:
+	default:
+		w.onError(fmt.Errorf("Webhook queue is full, dropping the %s event of [%s]", e.Event, e.ClientName))
+	}
+}
+
+// Observe notifies the events of a talk with the bot: EventStarted if the
+// input started a session, EventKeyword, EventDefault, and EventError if
+// talking failed. It does nothing on nil webhooks.
+func (w *Webhooks) Observe(bot, clientName, input string, started bool, reply *Reply, err error) {
+	if w == nil {
+		return
+	}
+	w.start()
+	e := ConversationEvent{Bot: bot, ClientName: clientName, Input: input, Time: time.Now().UTC()}
+	if reply != nil {
+		e.SessionId, e.Responses, e.Tags = reply.SessionId, reply.Responses, reply.Info.Tags
+	}
+	notify := func(event string, set func(e *ConversationEvent)) {
+		ev := e
+		ev.Event = event
+		if set != nil {
+			set(&ev)
+		}
+		w.Notify(ev)
+	}
+	if started {
+		notify(EventStarted, nil)
+	}
+	for i, re := range w.keywords {
+		if re.MatchString(input) {
+			notify(EventKeyword, func(e *ConversationEvent) { e.Keyword = w.Keywords[i] })
+			break
+		}
+	}
+	if err != nil {
+		notify(EventError, func(e *ConversationEvent) { e.Error = err.Error() })
+		return
+	}
+	if reply != nil && !reply.Info.Fallback && !reply.Info.Throttled {
+		if isDefaultReply(reply, w.DefaultResponses) {
+			notify(EventDefault, nil)
+		}
+	}
+}
+
+// wordPattern matches the inputs containing the words, ignoring case
+func wordPattern(words string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(^|\W)` + regexp.QuoteMeta(words) + `($|\W)`)
+}
+
+// isDefaultReply reports whether one of the responses of the reply is one of the default responses, ignoring case
+func isDefaultReply(reply *Reply, defaults []string) bool {
+	for _, r := range reply.Responses {
+		for _, d := range defaults {
+			if strings.EqualFold(strings.TrimSpace(r), strings.TrimSpace(d)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Close posts the queued events and stops the webhooks, dropping the events
+// notified afterwards. It does nothing on nil webhooks.
+func (w *Webhooks) Close() {
+	if w == nil {
+		return
+	}
+	w.start()
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+	<-w.done
+}